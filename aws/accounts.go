@@ -0,0 +1,127 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/organizations"
+)
+
+// DefaultOrganizationRole is the IAM role assumed in member accounts of an
+// organization unless configured otherwise. AWS Organizations creates it in
+// all accounts created via the organization.
+const DefaultOrganizationRole = "OrganizationAccountAccessRole"
+
+// Account is a member account of an AWS organization.
+type Account struct {
+	ID    string `json:"id" yaml:"id"`
+	Name  string `json:"name" yaml:"name"`
+	Email string `json:"email" yaml:"email"`
+}
+
+// Accounts returns the active member accounts of the organization. The
+// client must use credentials of the management account or of a delegated
+// administrator.
+func (c *Client) Accounts(ctx context.Context) ([]Account, error) {
+	svc, err := c.organizations()
+	if err != nil {
+		return nil, err
+	}
+	var accounts []Account
+	err = svc.ListAccountsPagesWithContext(ctx, &organizations.ListAccountsInput{}, func(out *organizations.ListAccountsOutput, last bool) bool {
+		for _, a := range out.Accounts {
+			if aws.StringValue(a.Status) != organizations.AccountStatusActive {
+				continue
+			}
+			accounts = append(accounts, Account{
+				ID:    aws.StringValue(a.Id),
+				Name:  aws.StringValue(a.Name),
+				Email: aws.StringValue(a.Email),
+			})
+		}
+		return !last
+	})
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// AccountEligibility holds the auto scaling groups of an account split by
+// chaos eligibility, or the error that occurred while retrieving them.
+type AccountEligibility struct {
+	Account  Account
+	Eligible []AutoScalingGroup
+	Exempt   []AutoScalingGroup
+	Err      error
+}
+
+// ChaosEligibilityAllAccounts is like ChaosEligibility, but covers all active
+// accounts of the organization, in the client's region. It assumes the given
+// role (DefaultOrganizationRole if empty) in every account but the one of
+// the client's credentials, using the credentials of the client's session
+// rather than its RoleARN. At most parallelism accounts
+// (DefaultParallelism if zero) are queried concurrently. Errors in single
+// accounts, e.g. missing roles, are reported per account; an error is only
+// returned if the accounts cannot be determined.
+func (c *Client) ChaosEligibilityAllAccounts(ctx context.Context, role string, optIn bool, parallelism int) ([]AccountEligibility, error) {
+	if role == "" {
+		role = DefaultOrganizationRole
+	}
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+	arn, err := c.CallerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// arn:<partition>:sts::<account>:assumed-role/...
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return nil, fmt.Errorf("unexpected caller ARN %q", arn)
+	}
+	partition, self := parts[1], parts[4]
+
+	accounts, err := c.Accounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]AccountEligibility, len(accounts))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, a := range accounts {
+		wg.Add(1)
+		go func(i int, a Account) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			client := c
+			if a.ID != self {
+				client = c.inAccount(fmt.Sprintf("arn:%s:iam::%s:role/%s", partition, a.ID, role))
+			}
+			eligible, exempt, err := client.ChaosEligibility(ctx, optIn)
+			results[i] = AccountEligibility{Account: a, Eligible: eligible, Exempt: exempt, Err: err}
+		}(i, a)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// inAccount returns a new client like c, but assuming the given role.
+func (c *Client) inAccount(roleARN string) *Client {
+	return &Client{
+		Region:           c.Region,
+		Session:          c.Session,
+		Config:           c.Config,
+		Endpoints:        c.Endpoints,
+		UseFIPSEndpoint:  c.UseFIPSEndpoint,
+		RoleARN:          roleARN,
+		MaxRetries:       c.MaxRetries,
+		MinThrottleDelay: c.MinThrottleDelay,
+		MaxThrottleDelay: c.MaxThrottleDelay,
+	}
+}
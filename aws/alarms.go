@@ -0,0 +1,125 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// Alarm describes a CloudWatch alarm.
+type Alarm struct {
+	Name   string `json:"name" yaml:"name"`
+	State  string `json:"state" yaml:"state"`
+	Reason string `json:"reason" yaml:"reason"`
+
+	// Time when the alarm entered its state
+	Since time.Time `json:"since" yaml:"since"`
+
+	// Value of the AutoScalingGroupName dimension of the alarm's metric, if
+	// any
+	AutoScalingGroupName string `json:"autoScalingGroupName,omitempty" yaml:"autoScalingGroupName,omitempty"`
+}
+
+// ActiveAlarms returns all CloudWatch alarms in ALARM state whose name starts
+// with the given prefix, which may be empty.
+func (c *Client) ActiveAlarms(ctx context.Context, prefix string) ([]Alarm, error) {
+	svc, err := c.cloudWatch()
+	if err != nil {
+		return nil, err
+	}
+
+	in := &cloudwatch.DescribeAlarmsInput{
+		StateValue: aws.String(cloudwatch.StateValueAlarm),
+	}
+	if prefix != "" {
+		in.AlarmNamePrefix = aws.String(prefix)
+	}
+	var alarms []Alarm
+	err = svc.DescribeAlarmsPagesWithContext(ctx, in, func(out *cloudwatch.DescribeAlarmsOutput, last bool) bool {
+		for _, a := range out.MetricAlarms {
+			alarm := Alarm{
+				Name:   aws.StringValue(a.AlarmName),
+				State:  aws.StringValue(a.StateValue),
+				Reason: aws.StringValue(a.StateReason),
+				Since:  aws.TimeValue(a.StateUpdatedTimestamp),
+			}
+			for _, d := range a.Dimensions {
+				if aws.StringValue(d.Name) == "AutoScalingGroupName" {
+					alarm.AutoScalingGroupName = aws.StringValue(d.Value)
+				}
+			}
+			alarms = append(alarms, alarm)
+		}
+		return !last
+	})
+	if err != nil {
+		return nil, err
+	}
+	return alarms, nil
+}
+
+// AlarmGuard is a Guard that refuses chaos events while CloudWatch alarms
+// indicate an ongoing incident. Alarms on metrics of other auto scaling
+// groups are ignored; all other alarms apply to every group.
+type AlarmGuard struct {
+	// Client used to retrieve alarms in the region of the target
+	// (the region of the client if the target has none)
+	Client *Client
+
+	// Optional prefix of the names of alarms to consider
+	NamePrefix string
+}
+
+// Check refuses chaos events while matching alarms are in ALARM state. It
+// also refuses them if alarms cannot be retrieved.
+func (g *AlarmGuard) Check(t chaosmonkey.Target) error {
+	alarms, err := g.Client.forRegion(t.Region).ActiveAlarms(context.Background(), g.NamePrefix)
+	if err != nil {
+		return fmt.Errorf("failed to get CloudWatch alarms: %s", err)
+	}
+	var names []string
+	for _, a := range alarms {
+		if a.AutoScalingGroupName == "" || a.AutoScalingGroupName == t.AutoScalingGroupName {
+			names = append(names, a.Name)
+		}
+	}
+	if len(names) > 0 {
+		return fmt.Errorf("CloudWatch alarms in ALARM state: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// AlarmOutageChecker is an OutageChecker that detects an outage while any
+// CloudWatch alarm with the configured prefix is in ALARM state. Unlike
+// AlarmGuard, it does not tell groups apart, as it is consulted without a
+// target, e.g. between the steps of a campaign.
+type AlarmOutageChecker struct {
+	// Client used to retrieve alarms, which determines the region
+	Client *Client
+
+	// Optional prefix of the names of alarms to consider
+	NamePrefix string
+}
+
+// CheckOutage reports an outage while matching alarms are in ALARM state. It
+// also reports one if alarms cannot be retrieved.
+func (o *AlarmOutageChecker) CheckOutage(ctx context.Context) error {
+	alarms, err := o.Client.ActiveAlarms(ctx, o.NamePrefix)
+	if err != nil {
+		return fmt.Errorf("failed to get CloudWatch alarms: %s", err)
+	}
+	if len(alarms) == 0 {
+		return nil
+	}
+	var names []string
+	for _, a := range alarms {
+		names = append(names, a.Name)
+	}
+	return fmt.Errorf("CloudWatch alarms in ALARM state: %s", strings.Join(names, ", "))
+}
@@ -0,0 +1,61 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// S3AuditSink is an audit sink of chaosmonkey.Client storing every audit
+// record as a JSON object in an S3 bucket. Keys are made up of the prefix,
+// the date, and the time of the record, like
+// "audit/2018/12/24/20181224T103000Z-web-prod-1a2b3c4d.json", so that records
+// are listed in chronological order.
+type S3AuditSink struct {
+	// Client used to store records, which determines the region
+	Client *Client
+
+	// Name of the S3 bucket
+	Bucket string
+
+	// Optional prefix of object keys, e.g. "audit/"
+	Prefix string
+}
+
+// WriteAudit stores r in the bucket.
+func (s *S3AuditSink) WriteAudit(ctx context.Context, r chaosmonkey.AuditRecord) error {
+	svc, err := s.Client.s3()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	t := r.Time.UTC()
+	key := fmt.Sprintf("%s%s/%s-%s-%s.json", s.Prefix, t.Format("2006/01/02"),
+		t.Format("20060102T150405Z"), r.AutoScalingGroupName, hex.EncodeToString(suffix))
+	_, err = svc.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(s.Bucket),
+		Key:                  aws.String(key),
+		Body:                 bytes.NewReader(body),
+		ContentType:          aws.String("application/json"),
+		ServerSideEncryption: aws.String("AES256"),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot store audit record in bucket %s: %s", s.Bucket, err)
+	}
+	return nil
+}
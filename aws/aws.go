@@ -1,50 +1,302 @@
 // Package aws provides access to Amazon Web Services (AWS).
-// AWS credentials need to be passed via environment variables.
+// By default, AWS credentials need to be passed via environment variables.
+// Set Client.Session or Client.Config to use other credentials, endpoints, or
+// retry settings. All functions accept a context to cancel requests,
+// including the pagination of large results.
 package aws
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/simpledb"
 	"github.com/aws/aws-sdk-go/service/sts"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
 )
 
 // Client is a client to the AWS API.
 type Client struct {
+	// AWS region to use, which overrides the region of Session and Config
 	Region string
+
+	// Optional session to use instead of creating one from environment
+	// variables. AWS_ROLE is ignored if a session is given.
+	Session *session.Session
+
+	// Optional configuration of sessions created by the client, e.g. to use
+	// custom credential providers, endpoints, or retries
+	Config *aws.Config
+
+	// Optional URLs of custom endpoints by service endpoint ID, e.g. "ec2"
+	// or "autoscaling", such as VPC endpoints. Other services use the
+	// endpoints of the region's partition, which also covers GovCloud and
+	// China regions.
+	Endpoints map[string]string
+
+	// Whether to use FIPS endpoints, e.g. in GovCloud (also enabled by
+	// AWS_USE_FIPS_ENDPOINT=true)
+	UseFIPSEndpoint bool
+
+	// Optional ARN of an IAM role to assume, e.g. to target auto scaling
+	// groups of another account (defaults to AWS_ROLE unless Session is set)
+	RoleARN string
+
+	// Optional external ID required to assume the role (defaults to
+	// AWS_ROLE_EXTERNAL_ID unless Session is set)
+	ExternalID string
+
+	// Optional serial number or ARN of the MFA device required to assume
+	// the role (defaults to AWS_ROLE_MFA_SERIAL unless Session is set)
+	MFASerial string
+
+	// Optional current code of the MFA device, which can only be used once.
+	// As credentials of the role are cached for an hour, MFATokenProvider
+	// is preferable for long-running processes.
+	MFATokenCode string
+
+	// Optional function called with every assumption of the role to get the
+	// current code of the MFA device (if MFASerial is set and MFATokenCode
+	// is not, the code is prompted for on stdin by default)
+	MFATokenProvider func() (string, error)
+
+	// Maximum number of retries of failed or throttled requests (3 if zero,
+	// none if negative). Unless one of the retry fields is set, the retry
+	// settings of Session or Config apply.
+	MaxRetries int
+
+	// Minimum and maximum delay before retrying throttled requests, which
+	// grows exponentially in between (SDK defaults if zero)
+	MinThrottleDelay time.Duration
+	MaxThrottleDelay time.Duration
+
+	// Optional AWS service clients to use instead of creating them from a
+	// session, e.g. the mocks of package awsmock in tests
+	AutoScaling   AutoScalingAPI
+	EC2           EC2API
+	SimpleDB      SimpleDBAPI
+	CloudTrail    CloudTrailAPI
+	CloudWatch    CloudWatchAPI
+	CodeDeploy    CodeDeployAPI
+	ELB           ELBAPI
+	ELBV2         ELBV2API
+	EventBridge   EventBridgeAPI
+	Organizations OrganizationsAPI
+	Pricing       PricingAPI
+	S3            S3API
+	SNS           SNSAPI
+	STS           STSAPI
+
+	// Client of Chaos Monkey whose kill switch, stop sentinels, guards,
+	// outage checkers, bus, and audit log apply to chaos injected by this
+	// client, e.g. by BlockNetwork or FailZone (required to inject chaos)
+	Chaos *chaosmonkey.Client
+
+	// Optional function returning the client to use for another region,
+	// e.g. one with mocks in tests (a client like this one, but for the
+	// region, if nil)
+	ForRegion func(region string) *Client
+
+	mu    sync.Mutex
+	creds *credentials.Credentials
+	role  string
+
+	// Client whose role credentials are shared, so that clients for other
+	// regions do not ask for MFA codes again
+	parent *Client
 }
 
-// NewClient returns a new Client.
+// NewClient returns a new Client for the given region.
 func NewClient(region string) *Client {
 	return &Client{Region: region}
 }
 
+// NewClientWithSession returns a new Client that uses an existing session.
+func NewClientWithSession(sess *session.Session) *Client {
+	return &Client{Session: sess}
+}
+
 // AutoScalingGroup describes an AWS auto scaling group.
 type AutoScalingGroup struct {
-	Name               string
-	InstancesInService int
-	DesiredCapacity    int
-	MinSize            int
-	MaxSize            int
+	Name               string            `json:"name" yaml:"name"`
+	InstancesInService int               `json:"instancesInService" yaml:"instancesInService"`
+	DesiredCapacity    int               `json:"desiredCapacity" yaml:"desiredCapacity"`
+	MinSize            int               `json:"minSize" yaml:"minSize"`
+	MaxSize            int               `json:"maxSize" yaml:"maxSize"`
+	Tags               map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+
+	// Whether the group proactively replaces spot instances at an elevated
+	// risk of interruption
+	CapacityRebalance bool `json:"capacityRebalance" yaml:"capacityRebalance"`
+
+	// Number of instances in the warm pool of the group, if any, and the
+	// state they are kept in until promoted, e.g. "Stopped"
+	WarmPoolSize  int    `json:"warmPoolSize,omitempty" yaml:"warmPoolSize,omitempty"`
+	WarmPoolState string `json:"warmPoolState,omitempty" yaml:"warmPoolState,omitempty"`
+
+	// Names of suspended scaling processes, e.g. "ReplaceUnhealthy"
+	SuspendedProcesses []string `json:"suspendedProcesses,omitempty" yaml:"suspendedProcesses,omitempty"`
+
+	// Load balancers and target groups attached to the group
+	LoadBalancers []LoadBalancer `json:"loadBalancers,omitempty" yaml:"loadBalancers,omitempty"`
+
+	// Name of the launch configuration of the group, if any
+	LaunchConfiguration string `json:"launchConfiguration,omitempty" yaml:"launchConfiguration,omitempty"`
+
+	// Name (or ID) and version of the launch template of the group, if any.
+	// The version may be "$Latest" or "$Default".
+	LaunchTemplate        string `json:"launchTemplate,omitempty" yaml:"launchTemplate,omitempty"`
+	LaunchTemplateVersion string `json:"launchTemplateVersion,omitempty" yaml:"launchTemplateVersion,omitempty"`
+
+	// ID of the AMI launched by the group, only set by AutoScalingGroup
+	ImageID string `json:"imageId,omitempty" yaml:"imageId,omitempty"`
+
+	// Instance types launched by the group. Those of a mixed instances
+	// policy are always set, the one of a launch configuration or template
+	// only by AutoScalingGroup.
+	InstanceTypes []string `json:"instanceTypes,omitempty" yaml:"instanceTypes,omitempty"`
 }
 
+// ErrGroupNotFound is returned if an auto scaling group does not exist.
+var ErrGroupNotFound = errors.New("auto scaling group not found")
+
 // AutoScalingGroups returns a list of all auto scaling groups.
-func (c *Client) AutoScalingGroups() ([]AutoScalingGroup, error) {
-	sess, err := c.newSession()
+func (c *Client) AutoScalingGroups(ctx context.Context) ([]AutoScalingGroup, error) {
+	return c.describeAutoScalingGroups(ctx, nil, nil)
+}
+
+// GroupFilter selects auto scaling groups. All conditions must be met.
+type GroupFilter struct {
+	// Optional prefix of group names
+	NamePrefix string
+
+	// Optional regular expression matching group names
+	NamePattern *regexp.Regexp
+
+	// Tags the groups must have. An empty value matches any value.
+	Tags map[string]string
+}
+
+// Match reports whether the filter selects the given group.
+func (f *GroupFilter) Match(g *AutoScalingGroup) bool {
+	if !strings.HasPrefix(g.Name, f.NamePrefix) {
+		return false
+	}
+	if f.NamePattern != nil && !f.NamePattern.MatchString(g.Name) {
+		return false
+	}
+	for key, value := range f.Tags {
+		v, ok := g.Tags[key]
+		if !ok || (value != "" && v != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterAutoScalingGroups returns the auto scaling groups selected by the
+// filter. Tag conditions are passed on to AWS, so that large accounts are not
+// enumerated completely.
+func (c *Client) FilterAutoScalingGroups(ctx context.Context, f GroupFilter) ([]AutoScalingGroup, error) {
+	in := &autoscaling.DescribeAutoScalingGroupsInput{}
+	keys := make([]string, 0, len(f.Tags))
+	for key := range f.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	anyValue := false
+	for _, key := range keys {
+		if value := f.Tags[key]; value != "" {
+			in.Filters = append(in.Filters, &autoscaling.Filter{
+				Name:   aws.String("tag:" + key),
+				Values: []*string{aws.String(value)},
+			})
+		} else if !anyValue {
+			// Only one tag-key filter is passed on; Match checks the others.
+			in.Filters = append(in.Filters, &autoscaling.Filter{
+				Name:   aws.String("tag-key"),
+				Values: []*string{aws.String(key)},
+			})
+			anyValue = true
+		}
+	}
+	return c.describeAutoScalingGroups(ctx, in, f.Match)
+}
+
+// AutoScalingGroup returns the auto scaling group with the given name, or
+// ErrGroupNotFound if there is no such group. Unlike the other functions
+// returning groups, it also retrieves the health of the attached load
+// balancers and the AMI and instance type of the launch configuration or
+// template.
+func (c *Client) AutoScalingGroup(ctx context.Context, name string) (*AutoScalingGroup, error) {
+	groups, err := c.describeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(name)},
+	}, nil)
 	if err != nil {
 		return nil, err
 	}
-	svc := autoscaling.New(sess)
+	if len(groups) == 0 {
+		return nil, ErrGroupNotFound
+	}
+	if err := c.LoadBalancerHealth(ctx, &groups[0]); err != nil {
+		return nil, err
+	}
+	if err := c.launchDetails(ctx, &groups[0]); err != nil {
+		return nil, err
+	}
+	return &groups[0], nil
+}
 
+// AutoScalingGroupsPages calls fn with batches of auto scaling groups as they
+// are returned by AWS, which avoids holding all groups of large accounts in
+// memory. Enumeration stops early if fn returns false.
+func (c *Client) AutoScalingGroupsPages(ctx context.Context, fn func(groups []AutoScalingGroup) bool) error {
+	return c.describeAutoScalingGroupsPages(ctx, nil, nil, fn)
+}
+
+// describeAutoScalingGroups returns the groups described by AWS for which
+// match, if not nil, returns true.
+func (c *Client) describeAutoScalingGroups(ctx context.Context, in *autoscaling.DescribeAutoScalingGroupsInput, match func(*AutoScalingGroup) bool) ([]AutoScalingGroup, error) {
 	var groups []AutoScalingGroup
-	err = svc.DescribeAutoScalingGroupsPages(nil, func(out *autoscaling.DescribeAutoScalingGroupsOutput, last bool) bool {
+	err := c.describeAutoScalingGroupsPages(ctx, in, match, func(page []AutoScalingGroup) bool {
+		groups = append(groups, page...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// describeAutoScalingGroupsPages is like describeAutoScalingGroups, but calls
+// fn for every non-empty page of groups.
+func (c *Client) describeAutoScalingGroupsPages(ctx context.Context, in *autoscaling.DescribeAutoScalingGroupsInput, match func(*AutoScalingGroup) bool, fn func([]AutoScalingGroup) bool) error {
+	svc, err := c.autoScaling()
+	if err != nil {
+		return err
+	}
+
+	if in == nil {
+		in = &autoscaling.DescribeAutoScalingGroupsInput{}
+	}
+	return svc.DescribeAutoScalingGroupsPagesWithContext(ctx, in, func(out *autoscaling.DescribeAutoScalingGroupsOutput, last bool) bool {
+		var groups []AutoScalingGroup
 		for _, g := range out.AutoScalingGroups {
 			inService := 0
 			for _, i := range g.Instances {
@@ -52,32 +304,53 @@ func (c *Client) AutoScalingGroups() ([]AutoScalingGroup, error) {
 					inService++
 				}
 			}
-			groups = append(groups, AutoScalingGroup{
+			tags := make(map[string]string, len(g.Tags))
+			for _, t := range g.Tags {
+				tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+			}
+			group := AutoScalingGroup{
 				Name:               aws.StringValue(g.AutoScalingGroupName),
 				InstancesInService: inService,
 				DesiredCapacity:    int(aws.Int64Value(g.DesiredCapacity)),
 				MinSize:            int(aws.Int64Value(g.MinSize)),
 				MaxSize:            int(aws.Int64Value(g.MaxSize)),
-			})
+				Tags:               tags,
+				CapacityRebalance:  aws.BoolValue(g.CapacityRebalance),
+			}
+			setLaunchSpec(&group, g)
+			if wp := g.WarmPoolConfiguration; wp != nil {
+				group.WarmPoolSize = int(aws.Int64Value(g.WarmPoolSize))
+				group.WarmPoolState = aws.StringValue(wp.PoolState)
+			}
+			for _, p := range g.SuspendedProcesses {
+				group.SuspendedProcesses = append(group.SuspendedProcesses, aws.StringValue(p.ProcessName))
+			}
+			for _, name := range g.LoadBalancerNames {
+				group.LoadBalancers = append(group.LoadBalancers, LoadBalancer{Name: aws.StringValue(name)})
+			}
+			for _, arn := range g.TargetGroupARNs {
+				group.LoadBalancers = append(group.LoadBalancers, LoadBalancer{Name: aws.StringValue(arn), TargetGroup: true})
+			}
+			if match == nil || match(&group) {
+				groups = append(groups, group)
+			}
+		}
+		if len(groups) > 0 && !fn(groups) {
+			return false
 		}
 		return !last
 	})
-	if err != nil {
-		return nil, err
-	}
-	return groups, nil
 }
 
 // DeleteSimpleDBDomain deletes an existing SimpleDB domain.
-func (c *Client) DeleteSimpleDBDomain(domainName string) error {
-	sess, err := c.newSession()
+func (c *Client) DeleteSimpleDBDomain(ctx context.Context, domainName string) error {
+	svc, err := c.simpleDB()
 	if err != nil {
 		return err
 	}
-	svc := simpledb.New(sess)
 
 	var domainExists bool
-	err = svc.ListDomainsPages(nil, func(out *simpledb.ListDomainsOutput, last bool) bool {
+	err = svc.ListDomainsPagesWithContext(ctx, nil, func(out *simpledb.ListDomainsOutput, last bool) bool {
 		for _, n := range out.DomainNames {
 			if aws.StringValue(n) == domainName {
 				domainExists = true
@@ -91,44 +364,152 @@ func (c *Client) DeleteSimpleDBDomain(domainName string) error {
 	if !domainExists {
 		return fmt.Errorf("SimpleDB domain %q does not exist", domainName)
 	}
-	_, err1 := svc.DeleteDomain(&simpledb.DeleteDomainInput{
+	_, err1 := svc.DeleteDomainWithContext(ctx, &simpledb.DeleteDomainInput{
 		DomainName: aws.String(domainName),
 	})
 	return err1
 }
 
-func (c *Client) newSession() (*session.Session, error) {
-	config := &aws.Config{
-		Region:     aws.String(c.Region),
-		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+// CallerIdentity returns the ARN of the AWS identity whose credentials are
+// used, which verifies that valid credentials are available.
+func (c *Client) CallerIdentity(ctx context.Context) (string, error) {
+	svc, err := c.sts()
+	if err != nil {
+		return "", err
 	}
+	out, err := svc.GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.Arn), nil
+}
 
-	if role := os.Getenv("AWS_ROLE"); role != "" {
-		if err := assumeRole(role, config); err != nil {
+// NewSession returns a session configured like the client with region,
+// credentials, role, and retries, e.g. to create clients of further AWS
+// services.
+func (c *Client) NewSession() (*session.Session, error) {
+	role, externalID, mfaSerial := c.RoleARN, c.ExternalID, c.MFASerial
+	if role == "" && c.Session == nil {
+		role, externalID = os.Getenv("AWS_ROLE"), os.Getenv("AWS_ROLE_EXTERNAL_ID")
+	}
+	if mfaSerial == "" && c.Session == nil {
+		mfaSerial = os.Getenv("AWS_ROLE_MFA_SERIAL")
+	}
+
+	var sess *session.Session
+	if c.Session != nil {
+		sess = c.Session
+		if c.Region != "" {
+			sess = sess.Copy(&aws.Config{Region: aws.String(c.Region)})
+		}
+	} else {
+		config := &aws.Config{}
+		if c.Config != nil {
+			config = c.Config.Copy()
+		}
+		if c.Region != "" || config.Region == nil {
+			config.Region = aws.String(c.Region)
+		}
+		if config.HTTPClient == nil {
+			config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+		}
+		var err error
+		if sess, err = session.NewSession(config); err != nil {
 			return nil, err
 		}
 	}
 
-	return session.NewSession(config)
+	if len(c.Endpoints) > 0 || c.UseFIPSEndpoint {
+		sess = sess.Copy(c.endpointConfig(sess.Config.EndpointResolver))
+	}
+
+	if c.MaxRetries != 0 || c.MinThrottleDelay != 0 || c.MaxThrottleDelay != 0 {
+		retries := c.MaxRetries
+		switch {
+		case retries == 0:
+			retries = client.DefaultRetryerMaxNumRetries
+		case retries < 0:
+			retries = 0
+		}
+		sess = sess.Copy(request.WithRetryer(&aws.Config{}, client.DefaultRetryer{
+			NumMaxRetries:    retries,
+			MinThrottleDelay: c.MinThrottleDelay,
+			MaxThrottleDelay: c.MaxThrottleDelay,
+		}))
+	}
+
+	if role == "" {
+		return sess, nil
+	}
+	return sess.Copy(&aws.Config{Credentials: c.roleCredentials(sess, role, externalID, mfaSerial)}), nil
 }
 
-func assumeRole(role string, config *aws.Config) error {
-	svc := sts.New(session.New(config))
-	params := &sts.AssumeRoleInput{
-		RoleArn:         aws.String(role),
-		RoleSessionName: aws.String("chaosmonkey"),
-		DurationSeconds: aws.Int64(900),
+// endpointConfig returns the configuration of custom and FIPS endpoints.
+// Services without custom endpoint are resolved by fallback (the default
+// resolver if nil).
+func (c *Client) endpointConfig(fallback endpoints.Resolver) *aws.Config {
+	config := &aws.Config{}
+	if c.UseFIPSEndpoint {
+		config.UseFIPSEndpoint = endpoints.FIPSEndpointStateEnabled
 	}
-	out, err := svc.AssumeRole(params)
-	if err != nil {
-		return err
+	if len(c.Endpoints) == 0 {
+		return config
+	}
+	if fallback == nil {
+		fallback = endpoints.DefaultResolver()
 	}
+	config.EndpointResolver = endpoints.ResolverFunc(func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		url, ok := c.Endpoints[service]
+		if !ok {
+			return fallback.EndpointFor(service, region, opts...)
+		}
+		// Keep the signing details of the service
+		resolved, err := fallback.EndpointFor(service, region, opts...)
+		if err != nil {
+			resolved = endpoints.ResolvedEndpoint{SigningRegion: region}
+		}
+		resolved.URL = url
+		return resolved, nil
+	})
+	return config
+}
 
-	config.Credentials = credentials.NewStaticCredentials(
-		aws.StringValue(out.Credentials.AccessKeyId),
-		aws.StringValue(out.Credentials.SecretAccessKey),
-		aws.StringValue(out.Credentials.SessionToken),
-	)
+// partition returns the ID of the partition of the given region, e.g.
+// "aws-cn" for China regions or "aws-us-gov" for GovCloud regions.
+func partition(region string) string {
+	if p, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+		return p.ID()
+	}
+	return endpoints.AwsPartitionID
+}
 
-	return nil
+// roleCredentials returns credentials of the given role, which are cached
+// and refreshed before they expire.
+func (c *Client) roleCredentials(sess *session.Session, role, externalID, mfaSerial string) *credentials.Credentials {
+	if c.parent != nil {
+		return c.parent.roleCredentials(sess, role, externalID, mfaSerial)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.creds == nil || c.role != role {
+		c.creds = stscreds.NewCredentials(sess, role, func(p *stscreds.AssumeRoleProvider) {
+			p.RoleSessionName = "chaosmonkey"
+			if externalID != "" {
+				p.ExternalID = aws.String(externalID)
+			}
+			if mfaSerial != "" {
+				p.SerialNumber = aws.String(mfaSerial)
+				switch {
+				case c.MFATokenCode != "":
+					p.TokenCode = aws.String(c.MFATokenCode)
+				case c.MFATokenProvider != nil:
+					p.TokenProvider = c.MFATokenProvider
+				default:
+					p.TokenProvider = stscreds.StdinTokenProvider
+				}
+			}
+		})
+		c.role = role
+	}
+	return c.creds
 }
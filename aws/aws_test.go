@@ -0,0 +1,1959 @@
+package aws_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/simpledb"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/google/go-cmp/cmp"
+
+	chaosaws "github.com/FlyLevin/chaosmonkey/aws"
+	"github.com/FlyLevin/chaosmonkey/aws/awsmock"
+	"github.com/FlyLevin/chaosmonkey/gameday"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func group(name string, tags map[string]string) *autoscaling.Group {
+	g := &autoscaling.Group{
+		AutoScalingGroupName: aws.String(name),
+		DesiredCapacity:      aws.Int64(1),
+		MinSize:              aws.Int64(1),
+		MaxSize:              aws.Int64(2),
+	}
+	for k, v := range tags {
+		g.Tags = append(g.Tags, &autoscaling.TagDescription{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return g
+}
+
+// chaosClient returns a client of Chaos Monkey that checks chaos injected via
+// AWS against the given guards.
+func chaosClient(t *testing.T, guards ...chaosmonkey.Guard) *chaosmonkey.Client {
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{Guards: guards})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestFilterAutoScalingGroups(t *testing.T) {
+	var filters []string
+	client := &chaosaws.Client{
+		AutoScaling: &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				for _, f := range in.Filters {
+					filters = append(filters, aws.StringValue(f.Name))
+				}
+				fn(&autoscaling.DescribeAutoScalingGroupsOutput{
+					AutoScalingGroups: []*autoscaling.Group{
+						group("payments-api", map[string]string{"team": "checkout"}),
+						group("payments-db", map[string]string{"team": "storage"}),
+					},
+				}, false)
+				fn(&autoscaling.DescribeAutoScalingGroupsOutput{
+					AutoScalingGroups: []*autoscaling.Group{
+						group("search-api", map[string]string{"team": "checkout"}),
+						group("payments-web", map[string]string{"team": "checkout", "chaos": "false"}),
+					},
+				}, true)
+				return nil
+			},
+		},
+	}
+
+	groups, err := client.FilterAutoScalingGroups(context.Background(), chaosaws.GroupFilter{
+		NamePrefix: "payments-",
+		Tags:       map[string]string{"team": "checkout"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	if diff := cmp.Diff([]string{"payments-api", "payments-web"}, names); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]string{"tag:team"}, filters); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestChaosExempt(t *testing.T) {
+	tests := []struct {
+		tags   map[string]string
+		optIn  bool
+		exempt bool
+	}{
+		{nil, false, false},
+		{nil, true, true},
+		{map[string]string{"chaos": "true"}, true, false},
+		{map[string]string{"chaos": "False"}, false, true},
+		{map[string]string{"chaos-exempt": ""}, false, true},
+		{map[string]string{"chaos": "true", "chaos-exempt": "yes"}, true, true},
+	}
+	for _, tt := range tests {
+		g := &chaosaws.AutoScalingGroup{Name: "a", Tags: tt.tags}
+		if got := chaosaws.ChaosExempt(g, tt.optIn); got != tt.exempt {
+			t.Errorf("ChaosExempt(%v, %t) = %t, want %t", tt.tags, tt.optIn, got, tt.exempt)
+		}
+	}
+}
+
+func TestExemptionGuard(t *testing.T) {
+	tests := []struct {
+		groupTags    map[string]string
+		instanceTags map[string]string
+		guard        chaosaws.ExemptionGuard
+		expected     string
+	}{
+		{nil, nil, chaosaws.ExemptionGuard{}, ""},
+		{map[string]string{"chaos-exempt": "true"}, nil, chaosaws.ExemptionGuard{}, "group a is exempt from chaos by its tags"},
+		{nil, nil, chaosaws.ExemptionGuard{OptIn: true}, "group a is exempt from chaos by its tags"},
+		{map[string]string{"criticality": "Tier0"}, nil, chaosaws.ExemptionGuard{}, "group a is exempt from chaos by its tag criticality=tier0"},
+		{map[string]string{"criticality": "tier1"}, nil, chaosaws.ExemptionGuard{}, ""},
+		{map[string]string{"criticality": "tier1"}, nil, chaosaws.ExemptionGuard{Tags: []string{"criticality=tier1", "pci"}}, "group a is exempt from chaos by its tag criticality=tier1"},
+		{nil, map[string]string{"criticality": "tier0"}, chaosaws.ExemptionGuard{}, "group a has instances exempt from chaos by their tags: i-2"},
+		{nil, map[string]string{"chaos": "false"}, chaosaws.ExemptionGuard{}, "group a has instances exempt from chaos by their tags: i-2"},
+	}
+	for _, tt := range tests {
+		tt.guard.Client = &chaosaws.Client{
+			AutoScaling: &awsmock.AutoScaling{
+				DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+					g := group("a", tt.groupTags)
+					for _, id := range []string{"i-1", "i-2"} {
+						g.Instances = append(g.Instances, &autoscaling.Instance{InstanceId: aws.String(id)})
+					}
+					fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, true)
+					return nil
+				},
+			},
+			EC2: &awsmock.EC2{
+				DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+					i := &ec2.Instance{InstanceId: aws.String("i-2")}
+					for k, v := range tt.instanceTags {
+						i.Tags = append(i.Tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(v)})
+					}
+					fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{i}}}}, true)
+					return nil
+				},
+			},
+		}
+		var msg string
+		if err := tt.guard.Check(chaosmonkey.Target{AutoScalingGroupName: "a"}); err != nil {
+			msg = err.Error()
+		}
+		if msg != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, msg)
+		}
+	}
+}
+
+func TestWithSuspendedProcesses(t *testing.T) {
+	var calls []string
+	client := &chaosaws.Client{
+		AutoScaling: &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				g := group("a", nil)
+				g.SuspendedProcesses = []*autoscaling.SuspendedProcess{{ProcessName: aws.String(chaosaws.ProcessAZRebalance)}}
+				fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, true)
+				return nil
+			},
+			SuspendProcessesFunc: func(ctx aws.Context, in *autoscaling.ScalingProcessQuery) (*autoscaling.SuspendProcessesOutput, error) {
+				calls = append(calls, "suspend "+aws.StringValue(in.ScalingProcesses[0]))
+				return &autoscaling.SuspendProcessesOutput{}, nil
+			},
+			ResumeProcessesFunc: func(ctx aws.Context, in *autoscaling.ScalingProcessQuery) (*autoscaling.ResumeProcessesOutput, error) {
+				calls = append(calls, "resume "+aws.StringValue(in.ScalingProcesses[0]))
+				return &autoscaling.ResumeProcessesOutput{}, nil
+			},
+		},
+	}
+
+	failed := errors.New("experiment failed")
+	processes := []string{chaosaws.ProcessReplaceUnhealthy, chaosaws.ProcessAZRebalance}
+	err := client.WithSuspendedProcesses(context.Background(), "a", processes, func() error {
+		calls = append(calls, "run")
+		return failed
+	})
+	if err != failed {
+		t.Fatalf("expected error %q, got %v", failed, err)
+	}
+
+	expected := []string{"suspend ReplaceUnhealthy", "run", "resume ReplaceUnhealthy"}
+	if diff := cmp.Diff(expected, calls); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestChaosRecords(t *testing.T) {
+	item := func(name, group, millis string) *simpledb.Item {
+		attrs := map[string]string{
+			"id":         name,
+			"eventTime":  millis,
+			"region":     "us-east-1",
+			"recordType": "MonkeyEvent",
+			"monkeyType": "CHAOS|com.netflix.simianarmy.chaos.ChaosMonkey$Type",
+			"eventType":  "CHAOS_TERMINATION|com.netflix.simianarmy.chaos.ChaosMonkey$EventTypes",
+			"groupType":  "ASG|com.netflix.simianarmy.basic.chaos.BasicChaosMonkey$Groups",
+			"groupName":  group,
+			"chaosType":  "ShutdownInstance",
+		}
+		i := &simpledb.Item{Name: aws.String(name)}
+		for k, v := range attrs {
+			i.Attributes = append(i.Attributes, &simpledb.Attribute{Name: aws.String(k), Value: aws.String(v)})
+		}
+		return i
+	}
+
+	var expr string
+	client := &chaosaws.Client{
+		Region: "us-east-1",
+		SimpleDB: &awsmock.SimpleDB{
+			SelectPagesFunc: func(ctx aws.Context, in *simpledb.SelectInput, fn func(*simpledb.SelectOutput, bool) bool) error {
+				expr = aws.StringValue(in.SelectExpression)
+				fn(&simpledb.SelectOutput{Items: []*simpledb.Item{
+					item("i-2", "b", "1500000060000"),
+					item("i-1", "a", "1500000000000"),
+				}}, true)
+				return nil
+			},
+		},
+	}
+
+	events, err := client.ChaosRecords(context.Background(), "SIMIAN_ARMY", time.Unix(1500000000, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []chaosmonkey.Event{
+		{
+			InstanceID:           "i-1",
+			AutoScalingGroupName: "a",
+			Region:               "us-east-1",
+			Strategy:             chaosmonkey.StrategyShutdownInstance,
+			TriggeredAt:          time.Unix(1500000000, 0).UTC(),
+		},
+		{
+			InstanceID:           "i-2",
+			AutoScalingGroupName: "b",
+			Region:               "us-east-1",
+			Strategy:             chaosmonkey.StrategyShutdownInstance,
+			TriggeredAt:          time.Unix(1500000060, 0).UTC(),
+		},
+	}
+	if diff := cmp.Diff(expected, events); diff != "" {
+		t.Fatal(diff)
+	}
+	want := "select * from `SIMIAN_ARMY` where recordType = 'MonkeyEvent' and monkeyType like 'CHAOS%'" +
+		" and eventTime >= '1500000000000' and region = 'us-east-1'"
+	if expr != want {
+		t.Fatalf("expected select expression %q, got %q", want, expr)
+	}
+}
+
+// simpleDBBackupClient returns a client with a SIMIAN_ARMY domain of two
+// items, selecting them fails with selectErr, and the operations are
+// recorded in calls.
+func simpleDBBackupClient(selectErr error, calls *[]string) *chaosaws.Client {
+	return &chaosaws.Client{
+		SimpleDB: &awsmock.SimpleDB{
+			SelectPagesFunc: func(ctx aws.Context, in *simpledb.SelectInput, fn func(*simpledb.SelectOutput, bool) bool) error {
+				*calls = append(*calls, "select "+aws.StringValue(in.SelectExpression))
+				if selectErr != nil {
+					return selectErr
+				}
+				fn(&simpledb.SelectOutput{Items: []*simpledb.Item{{
+					Name: aws.String("1"),
+					Attributes: []*simpledb.Attribute{
+						{Name: aws.String("groupName"), Value: aws.String("a")},
+						{Name: aws.String("tag"), Value: aws.String("x")},
+						{Name: aws.String("tag"), Value: aws.String("y")},
+					},
+				}}}, false)
+				fn(&simpledb.SelectOutput{Items: []*simpledb.Item{{
+					Name:       aws.String("2"),
+					Attributes: []*simpledb.Attribute{{Name: aws.String("groupName"), Value: aws.String("b")}},
+				}}}, true)
+				return nil
+			},
+			ListDomainsPagesFunc: func(ctx aws.Context, in *simpledb.ListDomainsInput, fn func(*simpledb.ListDomainsOutput, bool) bool) error {
+				fn(&simpledb.ListDomainsOutput{DomainNames: []*string{aws.String("SIMIAN_ARMY")}}, true)
+				return nil
+			},
+			DeleteDomainFunc: func(ctx aws.Context, in *simpledb.DeleteDomainInput) (*simpledb.DeleteDomainOutput, error) {
+				*calls = append(*calls, "delete "+aws.StringValue(in.DomainName))
+				return &simpledb.DeleteDomainOutput{}, nil
+			},
+		},
+	}
+}
+
+func TestExportSimpleDBDomain(t *testing.T) {
+	var calls []string
+	client := simpleDBBackupClient(nil, &calls)
+	var buf strings.Builder
+	n, err := client.ExportSimpleDBDomain(context.Background(), "SIMIAN_ARMY", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("exported %d items, want 2", n)
+	}
+	var items []chaosaws.SimpleDBItem
+	if err := json.Unmarshal([]byte(buf.String()), &items); err != nil {
+		t.Fatal(err)
+	}
+	expected := []chaosaws.SimpleDBItem{
+		{Name: "1", Attributes: map[string][]string{"groupName": {"a"}, "tag": {"x", "y"}}},
+		{Name: "2", Attributes: map[string][]string{"groupName": {"b"}}},
+	}
+	if diff := cmp.Diff(expected, items); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]string{"select select * from `SIMIAN_ARMY`"}, calls); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+// failingWriter is an io.Writer that always fails.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestBackupAndDeleteSimpleDBDomain(t *testing.T) {
+	var calls []string
+	var buf strings.Builder
+	if err := simpleDBBackupClient(nil, &calls).BackupAndDeleteSimpleDBDomain(context.Background(), "SIMIAN_ARMY", &buf); err != nil {
+		t.Fatal(err)
+	}
+	// The domain is only deleted after the backup was written
+	if diff := cmp.Diff([]string{"select select * from `SIMIAN_ARMY`", "delete SIMIAN_ARMY"}, calls); diff != "" {
+		t.Fatal(diff)
+	}
+	if !strings.Contains(buf.String(), `"groupName"`) {
+		t.Fatalf("backup is missing items: %s", buf.String())
+	}
+
+	tests := []struct {
+		name      string
+		selectErr error
+		w         io.Writer
+	}{
+		{"select fails", errors.New("throttled"), &strings.Builder{}},
+		{"write fails", nil, failingWriter{}},
+	}
+	for _, tt := range tests {
+		calls = nil
+		err := simpleDBBackupClient(tt.selectErr, &calls).BackupAndDeleteSimpleDBDomain(context.Background(), "SIMIAN_ARMY", tt.w)
+		if err == nil || !strings.Contains(err.Error(), "failed to back up") {
+			t.Errorf("%s: got error %v, want backup failure", tt.name, err)
+		}
+		for _, c := range calls {
+			if strings.HasPrefix(c, "delete ") {
+				t.Errorf("%s: domain was deleted although the backup failed", tt.name)
+			}
+		}
+	}
+}
+
+func TestPruneChaosRecords(t *testing.T) {
+	var (
+		expr    string
+		batches [][]string
+	)
+	client := &chaosaws.Client{
+		SimpleDB: &awsmock.SimpleDB{
+			SelectPagesFunc: func(ctx aws.Context, in *simpledb.SelectInput, fn func(*simpledb.SelectOutput, bool) bool) error {
+				expr = aws.StringValue(in.SelectExpression)
+				var out simpledb.SelectOutput
+				for _, name := range []string{"e1", "e2", "e3"} {
+					out.Items = append(out.Items, &simpledb.Item{Name: aws.String(name)})
+				}
+				fn(&out, true)
+				return nil
+			},
+			BatchDeleteAttributesFunc: func(ctx aws.Context, in *simpledb.BatchDeleteAttributesInput) (*simpledb.BatchDeleteAttributesOutput, error) {
+				var names []string
+				for _, item := range in.Items {
+					names = append(names, aws.StringValue(item.Name))
+				}
+				batches = append(batches, names)
+				return &simpledb.BatchDeleteAttributesOutput{}, nil
+			},
+		},
+	}
+
+	n, err := client.PruneChaosRecords(context.Background(), "SIMIAN_ARMY", time.Unix(1500000000, 0), 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || batches != nil {
+		t.Errorf("expected 3 records and no deletion in dry run, got %d and %v", n, batches)
+	}
+	want := "select itemName() from `SIMIAN_ARMY` where recordType = 'MonkeyEvent' and monkeyType like 'CHAOS%'" +
+		" and eventTime < '1500000000000'"
+	if expr != want {
+		t.Errorf("expected select expression %q, got %q", want, expr)
+	}
+
+	n, err = client.PruneChaosRecords(context.Background(), "SIMIAN_ARMY", time.Unix(1500000000, 0), 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 deleted records, got %d", n)
+	}
+	if diff := cmp.Diff([][]string{{"e1", "e2"}, {"e3"}}, batches); diff != "" {
+		t.Errorf("unexpected batches (-want +got):\n%s", diff)
+	}
+}
+
+func TestMaintenanceGuard(t *testing.T) {
+	client := &chaosaws.Client{
+		AutoScaling: &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				g := group("a", nil)
+				for _, id := range []string{"i-1", "i-2"} {
+					g.Instances = append(g.Instances, &autoscaling.Instance{
+						InstanceId:     aws.String(id),
+						LifecycleState: aws.String(autoscaling.LifecycleStateInService),
+					})
+				}
+				fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, true)
+				return nil
+			},
+		},
+		EC2: &awsmock.EC2{
+			DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+				return nil
+			},
+			DescribeInstanceStatusFunc: func(ctx aws.Context, in *ec2.DescribeInstanceStatusInput) (*ec2.DescribeInstanceStatusOutput, error) {
+				ok := &ec2.InstanceStatusSummary{Status: aws.String(ec2.SummaryStatusOk)}
+				return &ec2.DescribeInstanceStatusOutput{
+					InstanceStatuses: []*ec2.InstanceStatus{
+						{InstanceId: aws.String("i-1"), SystemStatus: ok, InstanceStatus: ok},
+						{
+							InstanceId:     aws.String("i-2"),
+							SystemStatus:   ok,
+							InstanceStatus: ok,
+							Events: []*ec2.InstanceStatusEvent{
+								{Code: aws.String("system-reboot"), Description: aws.String("[Completed] Scheduled reboot")},
+								{Code: aws.String("instance-retirement"), Description: aws.String("The instance is running on degraded hardware")},
+							},
+						},
+					},
+				}, nil
+			},
+		},
+	}
+
+	guard := &chaosaws.MaintenanceGuard{Client: client}
+	err := guard.Check(chaosmonkey.Target{AutoScalingGroupName: "a"})
+	expected := "AWS is already degrading group a: i-2 (instance-retirement)"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("expected error %q, got %v", expected, err)
+	}
+
+	var warning error
+	guard.Warn = func(err error) { warning = err }
+	if err := guard.Check(chaosmonkey.Target{AutoScalingGroupName: "a"}); err != nil {
+		t.Fatalf("expected no error with Warn, got %v", err)
+	}
+	if warning == nil {
+		t.Fatal("expected warning")
+	}
+}
+
+func TestBlockNetwork(t *testing.T) {
+	restored := make(chan []string, 1)
+	client := &chaosaws.Client{
+		Chaos: chaosClient(t),
+		EC2: &awsmock.EC2{
+			DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+				fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{
+					InstanceId:     aws.String("i-1"),
+					VpcId:          aws.String("vpc-1"),
+					SecurityGroups: []*ec2.GroupIdentifier{{GroupId: aws.String("sg-web")}, {GroupId: aws.String("sg-ssh")}},
+				}}}}}, true)
+				return nil
+			},
+			DescribeSecurityGroupsFunc: func(ctx aws.Context, in *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+				return &ec2.DescribeSecurityGroupsOutput{}, nil
+			},
+			CreateSecurityGroupFunc: func(ctx aws.Context, in *ec2.CreateSecurityGroupInput) (*ec2.CreateSecurityGroupOutput, error) {
+				return &ec2.CreateSecurityGroupOutput{GroupId: aws.String("sg-deny")}, nil
+			},
+			RevokeSecurityGroupEgressFunc: func(ctx aws.Context, in *ec2.RevokeSecurityGroupEgressInput) (*ec2.RevokeSecurityGroupEgressOutput, error) {
+				return &ec2.RevokeSecurityGroupEgressOutput{}, nil
+			},
+			ModifyInstanceAttributeFunc: func(ctx aws.Context, in *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error) {
+				groups := aws.StringValueSlice(in.Groups)
+				if len(groups) != 1 || groups[0] != "sg-deny" {
+					restored <- groups
+				}
+				return &ec2.ModifyInstanceAttributeOutput{}, nil
+			},
+		},
+	}
+
+	block, err := client.BlockNetwork(context.Background(), "i-1", 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case groups := <-restored:
+		if diff := cmp.Diff([]string{"sg-web", "sg-ssh"}, groups); diff != "" {
+			t.Fatal(diff)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected security groups to be restored after timeout")
+	}
+	if err := block.Restore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !block.Restored() {
+		t.Fatal("expected block to be restored")
+	}
+	if len(restored) != 0 {
+		t.Fatal("expected security groups to be restored only once")
+	}
+}
+
+func TestBlockNetworkExistingGroup(t *testing.T) {
+	tests := []struct {
+		group    *ec2.SecurityGroup
+		revoked  []string
+		expected string
+	}{
+		{&ec2.SecurityGroup{GroupId: aws.String("sg-deny")}, nil, ""},
+		// Left behind with its default egress rule, e.g. by an interrupted run
+		{&ec2.SecurityGroup{
+			GroupId: aws.String("sg-deny"),
+			IpPermissionsEgress: []*ec2.IpPermission{{
+				IpProtocol: aws.String("-1"),
+				IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+			}},
+		}, []string{"sg-deny -1 0.0.0.0/0"}, ""},
+		{&ec2.SecurityGroup{
+			GroupId: aws.String("sg-deny"),
+			IpPermissions: []*ec2.IpPermission{{
+				IpProtocol: aws.String("tcp"),
+				IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("10.0.0.0/8")}},
+			}},
+		}, nil, "failed to get deny-all security group: security group sg-deny has ingress rules"},
+	}
+	for _, tt := range tests {
+		var revoked []string
+		client := &chaosaws.Client{
+			Chaos: chaosClient(t),
+			EC2: &awsmock.EC2{
+				DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+					fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{
+						InstanceId: aws.String("i-1"),
+						VpcId:      aws.String("vpc-1"),
+					}}}}}, true)
+					return nil
+				},
+				DescribeSecurityGroupsFunc: func(ctx aws.Context, in *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+					return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: []*ec2.SecurityGroup{tt.group}}, nil
+				},
+				RevokeSecurityGroupEgressFunc: func(ctx aws.Context, in *ec2.RevokeSecurityGroupEgressInput) (*ec2.RevokeSecurityGroupEgressOutput, error) {
+					for _, p := range in.IpPermissions {
+						revoked = append(revoked, fmt.Sprintf("%s %s %s", aws.StringValue(in.GroupId), aws.StringValue(p.IpProtocol), aws.StringValue(p.IpRanges[0].CidrIp)))
+					}
+					return &ec2.RevokeSecurityGroupEgressOutput{}, nil
+				},
+				ModifyInstanceAttributeFunc: func(ctx aws.Context, in *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error) {
+					return &ec2.ModifyInstanceAttributeOutput{}, nil
+				},
+			},
+		}
+		var msg string
+		block, err := client.BlockNetwork(context.Background(), "i-1", time.Minute)
+		if err != nil {
+			msg = err.Error()
+		} else if err := block.Restore(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if msg != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, msg)
+		}
+		if diff := cmp.Diff(tt.revoked, revoked); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
+
+func TestBlockNetworkImmediateTimeout(t *testing.T) {
+	restored := make(chan struct{}, 1)
+	client := &chaosaws.Client{
+		Chaos: chaosClient(t),
+		EC2: &awsmock.EC2{
+			DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+				fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{
+					InstanceId:     aws.String("i-1"),
+					VpcId:          aws.String("vpc-1"),
+					SecurityGroups: []*ec2.GroupIdentifier{{GroupId: aws.String("sg-web")}},
+				}}}}}, true)
+				return nil
+			},
+			DescribeSecurityGroupsFunc: func(ctx aws.Context, in *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+				return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-deny")}}}, nil
+			},
+			ModifyInstanceAttributeFunc: func(ctx aws.Context, in *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error) {
+				if aws.StringValue(in.Groups[0]) == "sg-web" {
+					restored <- struct{}{}
+				}
+				return &ec2.ModifyInstanceAttributeOutput{}, nil
+			},
+		},
+	}
+
+	// The timer may fire before BlockNetwork returns
+	block, err := client.BlockNetwork(context.Background(), "i-1", time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-restored:
+	case <-time.After(time.Second):
+		t.Fatal("expected security groups to be restored after timeout")
+	}
+	if !block.Restored() {
+		t.Fatal("expected block to be restored")
+	}
+}
+
+func TestDetachVolumes(t *testing.T) {
+	var calls []string
+	states := []string{ec2.VolumeStateInUse, ec2.VolumeStateAvailable}
+	client := &chaosaws.Client{
+		Chaos: chaosClient(t),
+		EC2: &awsmock.EC2{
+			DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+				mapping := func(device, volume string) *ec2.InstanceBlockDeviceMapping {
+					return &ec2.InstanceBlockDeviceMapping{
+						DeviceName: aws.String(device),
+						Ebs:        &ec2.EbsInstanceBlockDevice{VolumeId: aws.String(volume)},
+					}
+				}
+				fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{
+					InstanceId:     aws.String("i-1"),
+					RootDeviceName: aws.String("/dev/xvda"),
+					BlockDeviceMappings: []*ec2.InstanceBlockDeviceMapping{
+						mapping("/dev/xvda", "vol-root"),
+						mapping("/dev/sdf", "vol-data"),
+					},
+				}}}}}, true)
+				return nil
+			},
+			DetachVolumeFunc: func(ctx aws.Context, in *ec2.DetachVolumeInput) (*ec2.VolumeAttachment, error) {
+				calls = append(calls, "detach "+aws.StringValue(in.VolumeId))
+				return &ec2.VolumeAttachment{}, nil
+			},
+			DescribeVolumesFunc: func(ctx aws.Context, in *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error) {
+				state := states[0]
+				states = states[1:]
+				return &ec2.DescribeVolumesOutput{Volumes: []*ec2.Volume{{VolumeId: in.VolumeIds[0], State: aws.String(state)}}}, nil
+			},
+			AttachVolumeFunc: func(ctx aws.Context, in *ec2.AttachVolumeInput) (*ec2.VolumeAttachment, error) {
+				calls = append(calls, "attach "+aws.StringValue(in.VolumeId)+" "+aws.StringValue(in.Device))
+				return &ec2.VolumeAttachment{}, nil
+			},
+		},
+	}
+
+	volumes, err := client.DetachVolumes(context.Background(), "i-1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AttachVolumes(context.Background(), volumes, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"detach vol-data", "attach vol-data /dev/sdf"}
+	if diff := cmp.Diff(expected, calls); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestInject(t *testing.T) {
+	var modified []string
+	ec2mock := &awsmock.EC2{
+		DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+			fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{
+				InstanceId: aws.String("i-1"),
+				VpcId:      aws.String("vpc-1"),
+				Tags:       []*ec2.Tag{{Key: aws.String("aws:autoscaling:groupName"), Value: aws.String("payments-api")}},
+			}}}}}, true)
+			return nil
+		},
+		DescribeSecurityGroupsFunc: func(ctx aws.Context, in *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error) {
+			return &ec2.DescribeSecurityGroupsOutput{SecurityGroups: []*ec2.SecurityGroup{{GroupId: aws.String("sg-deny")}}}, nil
+		},
+		ModifyInstanceAttributeFunc: func(ctx aws.Context, in *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error) {
+			modified = append(modified, aws.StringValue(in.InstanceId))
+			return &ec2.ModifyInstanceAttributeOutput{}, nil
+		},
+	}
+
+	if _, err := (&chaosaws.Client{EC2: ec2mock}).BlockNetwork(context.Background(), "i-1", time.Minute); err != chaosaws.ErrNoChaosClient {
+		t.Fatalf("expected ErrNoChaosClient, got %v", err)
+	}
+
+	var targets []chaosmonkey.Target
+	client := &chaosaws.Client{
+		Region: "eu-west-1",
+		EC2:    ec2mock,
+		Chaos: chaosClient(t, chaosmonkey.GuardFunc(func(t chaosmonkey.Target) error {
+			targets = append(targets, t)
+			return errors.New("frozen")
+		})),
+	}
+	_, err := client.BlockNetwork(context.Background(), "i-1", time.Minute)
+	if _, ok := err.(*chaosmonkey.GuardError); !ok {
+		t.Fatalf("expected *GuardError, got %v", err)
+	}
+	expected := []string{"payments-api BlockAllNetworkTraffic eu-west-1"}
+	var got []string
+	for _, t := range targets {
+		got = append(got, fmt.Sprintf("%s %s %s", t.AutoScalingGroupName, t.Strategy, t.Region))
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Error(diff)
+	}
+
+	dryRun, err := chaosmonkey.NewClient(&chaosmonkey.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Chaos = dryRun
+	block, err := client.BlockNetwork(context.Background(), "i-1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !block.Restored() {
+		t.Error("expected block of dry run to be restored")
+	}
+	if len(modified) != 0 {
+		t.Errorf("expected no instances to be modified, got %v", modified)
+	}
+}
+
+func TestCapacityGuard(t *testing.T) {
+	tests := []struct {
+		inService, desired, min int
+		guard                   chaosaws.CapacityGuard
+		expected                string
+	}{
+		{3, 3, 1, chaosaws.CapacityGuard{}, ""},
+		{2, 3, 1, chaosaws.CapacityGuard{}, "group a has only 2 of 3 desired instances in service"},
+		{2, 2, 2, chaosaws.CapacityGuard{}, "group a would have 1 instances in service, below its minimum size of 2"},
+		{3, 3, 1, chaosaws.CapacityGuard{MinHealthy: 3}, "group a would have 2 instances in service, below the minimum of 3"},
+		{4, 4, 1, chaosaws.CapacityGuard{MinHealthyRatio: 0.75}, ""},
+		{3, 3, 1, chaosaws.CapacityGuard{MinHealthyRatio: 0.75}, "group a would have 2 instances in service, below 75% of its desired capacity of 3"},
+	}
+	for _, tt := range tests {
+		tt.guard.Client = &chaosaws.Client{
+			AutoScaling: &awsmock.AutoScaling{
+				DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+					g := group("a", nil)
+					g.DesiredCapacity = aws.Int64(int64(tt.desired))
+					g.MinSize = aws.Int64(int64(tt.min))
+					for i := 0; i < tt.inService; i++ {
+						g.Instances = append(g.Instances, &autoscaling.Instance{LifecycleState: aws.String(autoscaling.LifecycleStateInService)})
+					}
+					fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, true)
+					return nil
+				},
+			},
+		}
+		var msg string
+		if err := tt.guard.Check(chaosmonkey.Target{AutoScalingGroupName: "a"}); err != nil {
+			msg = err.Error()
+		}
+		if msg != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, msg)
+		}
+	}
+}
+
+// alarmsClient returns a client whose CloudWatch alarms in ALARM state are
+// the given ones, mapping names to the AutoScalingGroupName dimension, and
+// which records the requested name prefixes.
+func alarmsClient(alarms map[string]string, prefixes *[]string) *chaosaws.Client {
+	return &chaosaws.Client{
+		CloudWatch: &awsmock.CloudWatch{
+			DescribeAlarmsPagesFunc: func(ctx aws.Context, in *cloudwatch.DescribeAlarmsInput, fn func(*cloudwatch.DescribeAlarmsOutput, bool) bool) error {
+				if state := aws.StringValue(in.StateValue); state != cloudwatch.StateValueAlarm {
+					return fmt.Errorf("unexpected state %s", state)
+				}
+				*prefixes = append(*prefixes, aws.StringValue(in.AlarmNamePrefix))
+				var out cloudwatch.DescribeAlarmsOutput
+				for name, group := range alarms {
+					if !strings.HasPrefix(name, aws.StringValue(in.AlarmNamePrefix)) {
+						continue
+					}
+					a := &cloudwatch.MetricAlarm{AlarmName: aws.String(name), StateValue: aws.String(cloudwatch.StateValueAlarm)}
+					if group != "" {
+						a.Dimensions = []*cloudwatch.Dimension{{Name: aws.String("AutoScalingGroupName"), Value: aws.String(group)}}
+					}
+					out.MetricAlarms = append(out.MetricAlarms, a)
+				}
+				fn(&out, true)
+				return nil
+			},
+		},
+	}
+}
+
+func TestAlarmGuard(t *testing.T) {
+	tests := []struct {
+		alarms   map[string]string
+		prefix   string
+		expected string
+	}{
+		{nil, "", ""},
+		{map[string]string{"api-errors": ""}, "", "CloudWatch alarms in ALARM state: api-errors"},
+		{map[string]string{"a-cpu": "a"}, "", "CloudWatch alarms in ALARM state: a-cpu"},
+		{map[string]string{"b-cpu": "b"}, "", ""},
+		{map[string]string{"api-errors": ""}, "chaos-", ""},
+		{map[string]string{"chaos-errors": "", "api-errors": ""}, "chaos-", "CloudWatch alarms in ALARM state: chaos-errors"},
+	}
+	for _, tt := range tests {
+		var prefixes []string
+		guard := &chaosaws.AlarmGuard{Client: alarmsClient(tt.alarms, &prefixes), NamePrefix: tt.prefix}
+		var msg string
+		if err := guard.Check(chaosmonkey.Target{AutoScalingGroupName: "a"}); err != nil {
+			msg = err.Error()
+		}
+		if msg != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, msg)
+		}
+		if diff := cmp.Diff([]string{tt.prefix}, prefixes); diff != "" {
+			t.Error(diff)
+		}
+	}
+
+	guard := &chaosaws.AlarmGuard{Client: &chaosaws.Client{
+		CloudWatch: &awsmock.CloudWatch{
+			DescribeAlarmsPagesFunc: func(ctx aws.Context, in *cloudwatch.DescribeAlarmsInput, fn func(*cloudwatch.DescribeAlarmsOutput, bool) bool) error {
+				return errors.New("throttled")
+			},
+		},
+	}}
+	if err := guard.Check(chaosmonkey.Target{AutoScalingGroupName: "a"}); err == nil {
+		t.Fatal("expected guard to refuse chaos if alarms cannot be retrieved")
+	}
+}
+
+func TestAlarmOutageChecker(t *testing.T) {
+	tests := []struct {
+		alarms   map[string]string
+		prefix   string
+		expected string
+	}{
+		{nil, "", ""},
+		{map[string]string{"api-errors": ""}, "", "CloudWatch alarms in ALARM state: api-errors"},
+		// Unlike AlarmGuard, alarms of any group indicate an outage
+		{map[string]string{"b-cpu": "b"}, "", "CloudWatch alarms in ALARM state: b-cpu"},
+		{map[string]string{"api-errors": ""}, "chaos-", ""},
+	}
+	for _, tt := range tests {
+		var prefixes []string
+		checker := &chaosaws.AlarmOutageChecker{Client: alarmsClient(tt.alarms, &prefixes), NamePrefix: tt.prefix}
+		var msg string
+		if err := checker.CheckOutage(context.Background()); err != nil {
+			msg = err.Error()
+		}
+		if msg != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, msg)
+		}
+		if diff := cmp.Diff([]string{tt.prefix}, prefixes); diff != "" {
+			t.Error(diff)
+		}
+	}
+
+	checker := &chaosaws.AlarmOutageChecker{Client: &chaosaws.Client{
+		CloudWatch: &awsmock.CloudWatch{
+			DescribeAlarmsPagesFunc: func(ctx aws.Context, in *cloudwatch.DescribeAlarmsInput, fn func(*cloudwatch.DescribeAlarmsOutput, bool) bool) error {
+				return errors.New("throttled")
+			},
+		},
+	}}
+	if err := checker.CheckOutage(context.Background()); err == nil {
+		t.Fatal("expected outage if alarms cannot be retrieved")
+	}
+}
+
+func TestGroupSizeGuard(t *testing.T) {
+	tests := []struct {
+		inService int
+		guard     chaosaws.GroupSizeGuard
+		expected  string
+		warned    bool
+	}{
+		{2, chaosaws.GroupSizeGuard{}, "", false},
+		{1, chaosaws.GroupSizeGuard{}, "group a has only 1 instances in service, fewer than 2", false},
+		{2, chaosaws.GroupSizeGuard{MinInService: 3}, "group a has only 2 instances in service, fewer than 3", false},
+		{1, chaosaws.GroupSizeGuard{Warn: func(error) {}}, "", true},
+	}
+	for _, tt := range tests {
+		tt.guard.Client = &chaosaws.Client{
+			AutoScaling: &awsmock.AutoScaling{
+				DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+					g := group("a", nil)
+					for i := 0; i < tt.inService; i++ {
+						g.Instances = append(g.Instances, &autoscaling.Instance{LifecycleState: aws.String(autoscaling.LifecycleStateInService)})
+					}
+					fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, true)
+					return nil
+				},
+			},
+		}
+		warned := false
+		if tt.guard.Warn != nil {
+			tt.guard.Warn = func(error) { warned = true }
+		}
+		var msg string
+		if err := tt.guard.Check(chaosmonkey.Target{AutoScalingGroupName: "a"}); err != nil {
+			msg = err.Error()
+		}
+		if msg != tt.expected || warned != tt.warned {
+			t.Errorf("expected %q (warned: %t), got %q (warned: %t)", tt.expected, tt.warned, msg, warned)
+		}
+	}
+}
+
+func TestGuardsInTargetRegion(t *testing.T) {
+	// Group a exists in both regions, but only has a single instance in
+	// service and an alarm in eu-west-1
+	regional := func(inService int, alarms map[string]string) *chaosaws.Client {
+		var prefixes []string
+		client := alarmsClient(alarms, &prefixes)
+		client.AutoScaling = &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				g := group("a", nil)
+				for i := 0; i < inService; i++ {
+					g.Instances = append(g.Instances, &autoscaling.Instance{LifecycleState: aws.String(autoscaling.LifecycleStateInService)})
+				}
+				fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, true)
+				return nil
+			},
+		}
+		return client
+	}
+	eu := regional(1, map[string]string{"a-latency": "a"})
+	client := regional(2, nil)
+	client.Region = "us-east-1"
+	client.ForRegion = func(region string) *chaosaws.Client {
+		if region != "eu-west-1" {
+			t.Fatalf("unexpected region %s", region)
+		}
+		return eu
+	}
+
+	guards := map[string]chaosmonkey.Guard{
+		"alarm":      &chaosaws.AlarmGuard{Client: client},
+		"capacity":   &chaosaws.CapacityGuard{Client: client},
+		"group size": &chaosaws.GroupSizeGuard{Client: client},
+	}
+	for name, g := range guards {
+		for _, region := range []string{"", "us-east-1"} {
+			if err := g.Check(chaosmonkey.Target{AutoScalingGroupName: "a", Region: region}); err != nil {
+				t.Errorf("%s guard refused group in region %q: %s", name, region, err)
+			}
+		}
+		if err := g.Check(chaosmonkey.Target{AutoScalingGroupName: "a", Region: "eu-west-1"}); err == nil {
+			t.Errorf("%s guard did not check group in eu-west-1", name)
+		}
+	}
+}
+
+func TestProtectionGuard(t *testing.T) {
+	tests := []struct {
+		strategy              chaosmonkey.Strategy
+		terminationProtection bool
+		scaleInProtection     bool
+		guard                 chaosaws.ProtectionGuard
+		expected              string
+	}{
+		{chaosmonkey.StrategyShutdownInstance, false, false, chaosaws.ProtectionGuard{}, ""},
+		{chaosmonkey.StrategyShutdownInstance, true, false, chaosaws.ProtectionGuard{}, "group a has instances with termination protection, which cannot be shut down: i-2"},
+		{chaosmonkey.StrategyShutdownInstance, false, true, chaosaws.ProtectionGuard{}, "group a has instances protected from scale-in, whose termination may be managed elsewhere: i-2"},
+		{chaosmonkey.StrategyShutdownInstance, false, true, chaosaws.ProtectionGuard{AllowScaleInProtection: true}, ""},
+		{chaosmonkey.StrategyBurnCPU, true, true, chaosaws.ProtectionGuard{}, ""},
+	}
+	for _, tt := range tests {
+		tt.guard.Client = &chaosaws.Client{
+			AutoScaling: &awsmock.AutoScaling{
+				DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+					g := group("a", nil)
+					for _, id := range []string{"i-1", "i-2"} {
+						g.Instances = append(g.Instances, &autoscaling.Instance{
+							InstanceId:           aws.String(id),
+							LifecycleState:       aws.String(autoscaling.LifecycleStateInService),
+							ProtectedFromScaleIn: aws.Bool(id == "i-2" && tt.scaleInProtection),
+						})
+					}
+					fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, true)
+					return nil
+				},
+			},
+			EC2: &awsmock.EC2{
+				DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+					return nil
+				},
+				DescribeInstanceAttributeFunc: func(ctx aws.Context, in *ec2.DescribeInstanceAttributeInput) (*ec2.DescribeInstanceAttributeOutput, error) {
+					protected := aws.StringValue(in.InstanceId) == "i-2" && tt.terminationProtection
+					return &ec2.DescribeInstanceAttributeOutput{
+						DisableApiTermination: &ec2.AttributeBooleanValue{Value: aws.Bool(protected)},
+					}, nil
+				},
+			},
+		}
+		var msg string
+		if err := tt.guard.Check(chaosmonkey.Target{AutoScalingGroupName: "a", Strategy: tt.strategy}); err != nil {
+			msg = err.Error()
+		}
+		if msg != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, msg)
+		}
+	}
+}
+
+func TestAutoScalingGroupLaunchDetails(t *testing.T) {
+	var versions []string
+	client := &chaosaws.Client{
+		AutoScaling: &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				g := group("a", nil)
+				g.LaunchTemplate = &autoscaling.LaunchTemplateSpecification{
+					LaunchTemplateId:   aws.String("lt-0123"),
+					LaunchTemplateName: aws.String("web"),
+					Version:            aws.String("$Latest"),
+				}
+				fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, true)
+				return nil
+			},
+		},
+		EC2: &awsmock.EC2{
+			DescribeLaunchTemplateVersionsFunc: func(ctx aws.Context, in *ec2.DescribeLaunchTemplateVersionsInput) (*ec2.DescribeLaunchTemplateVersionsOutput, error) {
+				versions = append(versions, aws.StringValue(in.LaunchTemplateName)+" "+aws.StringValue(in.Versions[0]))
+				return &ec2.DescribeLaunchTemplateVersionsOutput{
+					LaunchTemplateVersions: []*ec2.LaunchTemplateVersion{{
+						LaunchTemplateData: &ec2.ResponseLaunchTemplateData{
+							ImageId:      aws.String("ami-42"),
+							InstanceType: aws.String("m5.large"),
+						},
+					}},
+				}, nil
+			},
+		},
+	}
+
+	g, err := client.AutoScalingGroup(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &chaosaws.AutoScalingGroup{
+		Name:                  "a",
+		DesiredCapacity:       1,
+		MinSize:               1,
+		MaxSize:               2,
+		Tags:                  map[string]string{},
+		LaunchTemplate:        "web",
+		LaunchTemplateVersion: "$Latest",
+		ImageID:               "ami-42",
+		InstanceTypes:         []string{"m5.large"},
+	}
+	if diff := cmp.Diff(expected, g); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]string{"web $Latest"}, versions); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestLoadBalancerHealth(t *testing.T) {
+	client := &chaosaws.Client{
+		AutoScaling: &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				g := group("a", nil)
+				g.LoadBalancerNames = []*string{aws.String("classic")}
+				g.TargetGroupARNs = []*string{aws.String("arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/web/1")}
+				fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, true)
+				return nil
+			},
+		},
+		ELB: &awsmock.ELB{
+			DescribeInstanceHealthFunc: func(ctx aws.Context, in *elb.DescribeInstanceHealthInput) (*elb.DescribeInstanceHealthOutput, error) {
+				if name := aws.StringValue(in.LoadBalancerName); name != "classic" {
+					t.Errorf("got load balancer %s, want classic", name)
+				}
+				return &elb.DescribeInstanceHealthOutput{
+					InstanceStates: []*elb.InstanceState{
+						{State: aws.String("InService")},
+						{State: aws.String("InService")},
+						{State: aws.String("OutOfService")},
+						{State: aws.String("Unknown")},
+					},
+				}, nil
+			},
+		},
+		ELBV2: &awsmock.ELBV2{
+			DescribeTargetHealthFunc: func(ctx aws.Context, in *elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error) {
+				return &elbv2.DescribeTargetHealthOutput{
+					TargetHealthDescriptions: []*elbv2.TargetHealthDescription{
+						{TargetHealth: &elbv2.TargetHealth{State: aws.String(elbv2.TargetHealthStateEnumHealthy)}},
+						{TargetHealth: &elbv2.TargetHealth{State: aws.String(elbv2.TargetHealthStateEnumUnhealthy)}},
+						{TargetHealth: &elbv2.TargetHealth{State: aws.String(elbv2.TargetHealthStateEnumUnhealthy)}},
+						{TargetHealth: &elbv2.TargetHealth{State: aws.String(elbv2.TargetHealthStateEnumDraining)}},
+						{},
+					},
+				}, nil
+			},
+		},
+	}
+
+	g, err := client.AutoScalingGroup(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []chaosaws.LoadBalancer{
+		{Name: "classic", Healthy: 2, Unhealthy: 1},
+		{Name: "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/web/1", TargetGroup: true, Healthy: 1, Unhealthy: 2},
+	}
+	if diff := cmp.Diff(expected, g.LoadBalancers); diff != "" {
+		t.Fatal(diff)
+	}
+
+	client.ELBV2 = &awsmock.ELBV2{
+		DescribeTargetHealthFunc: func(ctx aws.Context, in *elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error) {
+			return nil, errors.New("throttled")
+		},
+	}
+	if _, err := client.AutoScalingGroup(context.Background(), "a"); err == nil || !strings.Contains(err.Error(), "target group") {
+		t.Fatalf("got error %v, expected target group health to fail", err)
+	}
+}
+
+func TestNodeGroupAutoScalingGroup(t *testing.T) {
+	client := &chaosaws.Client{
+		AutoScaling: &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				fn(&autoscaling.DescribeAutoScalingGroupsOutput{
+					AutoScalingGroups: []*autoscaling.Group{
+						group("eks-workers-1a2b", map[string]string{"eks:cluster-name": "prod", "eks:nodegroup-name": "workers"}),
+						group("eks-system-3c4d", map[string]string{"eks:cluster-name": "prod", "eks:nodegroup-name": "system"}),
+					},
+				}, true)
+				return nil
+			},
+		},
+	}
+
+	name, err := client.NodeGroupAutoScalingGroup(context.Background(), "prod", "workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "eks-workers-1a2b" {
+		t.Fatalf("expected eks-workers-1a2b, got %s", name)
+	}
+	if _, err := client.NodeGroupAutoScalingGroup(context.Background(), "prod", "gpu"); err == nil {
+		t.Fatal("expected error for unknown node group")
+	}
+}
+
+func TestAutoScalingGroupsAllRegions(t *testing.T) {
+	regions := map[string]*chaosaws.Client{
+		"us-east-1": {AutoScaling: &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{group("a", nil)}}, true)
+				return nil
+			},
+		}},
+		"eu-west-1": {AutoScaling: &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				return errors.New("access denied")
+			},
+		}},
+		"ap-south-1": {AutoScaling: &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				return nil
+			},
+		}},
+	}
+	client := &chaosaws.Client{
+		Region: "us-east-1",
+		EC2: &awsmock.EC2{
+			DescribeRegionsFunc: func(ctx aws.Context, in *ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error) {
+				return &ec2.DescribeRegionsOutput{Regions: []*ec2.Region{
+					{RegionName: aws.String("us-east-1")},
+					{RegionName: aws.String("eu-west-1")},
+					{RegionName: aws.String("ap-south-1")},
+				}}, nil
+			},
+		},
+		ForRegion: func(region string) *chaosaws.Client { return regions[region] },
+	}
+
+	all, err := client.AllRegions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"ap-south-1", "eu-west-1", "us-east-1"}, all); diff != "" {
+		t.Fatal(diff)
+	}
+
+	results, err := client.AutoScalingGroupsAllRegions(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		line := r.Region + ":"
+		for _, g := range r.Groups {
+			line += " " + g.Name
+		}
+		if r.Err != nil {
+			line += " " + r.Err.Error()
+		}
+		got = append(got, line)
+	}
+	if diff := cmp.Diff([]string{"ap-south-1:", "eu-west-1: access denied", "us-east-1: a"}, got); diff != "" {
+		t.Fatal(diff)
+	}
+
+	client.EC2 = &awsmock.EC2{
+		DescribeRegionsFunc: func(ctx aws.Context, in *ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error) {
+			return nil, errors.New("access denied")
+		},
+	}
+	if _, err := client.AutoScalingGroupsAllRegions(context.Background(), 0); err == nil {
+		t.Fatal("expected error if regions cannot be determined")
+	}
+}
+
+func TestChaosEligibilityAllAccounts(t *testing.T) {
+	client := &chaosaws.Client{
+		AutoScaling: &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				fn(&autoscaling.DescribeAutoScalingGroupsOutput{
+					AutoScalingGroups: []*autoscaling.Group{
+						group("a", nil),
+						group("b", map[string]string{"chaos": "false"}),
+					},
+				}, true)
+				return nil
+			},
+		},
+		Organizations: &awsmock.Organizations{
+			ListAccountsPagesFunc: func(ctx aws.Context, in *organizations.ListAccountsInput, fn func(*organizations.ListAccountsOutput, bool) bool) error {
+				fn(&organizations.ListAccountsOutput{Accounts: []*organizations.Account{
+					{Id: aws.String("111111111111"), Name: aws.String("management"), Status: aws.String(organizations.AccountStatusActive)},
+					{Id: aws.String("222222222222"), Name: aws.String("closed"), Status: aws.String(organizations.AccountStatusSuspended)},
+				}}, true)
+				return nil
+			},
+		},
+		STS: &awsmock.STS{
+			GetCallerIdentityFunc: func(ctx aws.Context, in *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+				return &sts.GetCallerIdentityOutput{Arn: aws.String("arn:aws:iam::111111111111:user/chaos")}, nil
+			},
+		},
+	}
+
+	results, err := client.ChaosEligibilityAllAccounts(context.Background(), "", false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 account, got %d", len(results))
+	}
+	r := results[0]
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if r.Account.Name != "management" || len(r.Eligible) != 1 || r.Eligible[0].Name != "a" || len(r.Exempt) != 1 {
+		t.Fatalf("unexpected result: %+v", r)
+	}
+}
+
+func TestCostEstimator(t *testing.T) {
+	calls := 0
+	client := &chaosaws.Client{
+		Region: "us-east-1",
+		EC2: &awsmock.EC2{
+			DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+				fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{
+					InstanceId:   aws.String("i-1"),
+					InstanceType: aws.String("m5.large"),
+				}}}}}, true)
+				return nil
+			},
+		},
+		Pricing: &awsmock.Pricing{
+			GetProductsFunc: func(ctx aws.Context, in *pricing.GetProductsInput) (*pricing.GetProductsOutput, error) {
+				calls++
+				product := aws.JSONValue{"terms": map[string]interface{}{
+					"OnDemand": map[string]interface{}{
+						"ABC.JRTCKXETXF": map[string]interface{}{
+							"priceDimensions": map[string]interface{}{
+								"ABC.JRTCKXETXF.6YS6EN2CT7": map[string]interface{}{
+									"pricePerUnit": map[string]interface{}{"USD": "0.0960000000"},
+								},
+							},
+						},
+					},
+				}}
+				return &pricing.GetProductsOutput{PriceList: []aws.JSONValue{product}}, nil
+			},
+		},
+	}
+
+	estimator := &chaosaws.CostEstimator{Client: client, ReplacementTime: 30 * time.Minute}
+	for i := 0; i < 2; i++ {
+		est, err := estimator.EstimateEvent(context.Background(), chaosmonkey.Event{InstanceID: "i-1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := &chaosaws.CostEstimate{InstanceType: "m5.large", HourlyPrice: 0.096, Replacements: 1, Cost: 0.048}
+		if diff := cmp.Diff(expected, est); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected price to be retrieved once, got %d calls", calls)
+	}
+}
+
+func TestCostEstimatorGovCloud(t *testing.T) {
+	e := &chaosaws.CostEstimator{
+		Client: &chaosaws.Client{
+			Session: &session.Session{Config: &aws.Config{Region: aws.String("us-gov-west-1")}},
+		},
+	}
+	_, err := e.HourlyPrice(context.Background(), "m5.large")
+	if err == nil || err.Error() != "price list API not available in partition aws-us-gov" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestTerminationRecord(t *testing.T) {
+	triggered := time.Date(2017, 7, 14, 2, 40, 0, 0, time.UTC)
+	var lookup *cloudtrail.LookupEventsInput
+	client := &chaosaws.Client{
+		CloudTrail: &awsmock.CloudTrail{
+			LookupEventsPagesFunc: func(ctx aws.Context, in *cloudtrail.LookupEventsInput, fn func(*cloudtrail.LookupEventsOutput, bool) bool) error {
+				lookup = in
+				fn(&cloudtrail.LookupEventsOutput{Events: []*cloudtrail.Event{
+					{
+						EventId:   aws.String("e-2"),
+						EventName: aws.String("TerminateInstances"),
+						EventTime: aws.Time(triggered.Add(5 * time.Minute)),
+					},
+					{
+						EventId:   aws.String("e-1"),
+						EventName: aws.String("TerminateInstances"),
+						EventTime: aws.Time(triggered.Add(2 * time.Second)),
+						Username:  aws.String("chaosmonkey"),
+						CloudTrailEvent: aws.String(`{"userIdentity": {"arn": "arn:aws:sts::111111111111:assumed-role/chaosmonkey/i-0abc"},
+							"sourceIPAddress": "10.0.0.1", "userAgent": "aws-sdk-java", "requestID": "req-1"}`),
+					},
+					{
+						EventId:   aws.String("e-0"),
+						EventName: aws.String("StopInstances"),
+						EventTime: aws.Time(triggered),
+					},
+				}}, true)
+				return nil
+			},
+		},
+	}
+
+	rec, err := client.TerminationRecord(context.Background(), chaosmonkey.Event{InstanceID: "i-1", TriggeredAt: triggered}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &chaosaws.TerminationRecord{
+		EventID:   "e-1",
+		Time:      triggered.Add(2 * time.Second),
+		Caller:    "arn:aws:sts::111111111111:assumed-role/chaosmonkey/i-0abc",
+		SourceIP:  "10.0.0.1",
+		UserAgent: "aws-sdk-java",
+		RequestID: "req-1",
+	}
+	if diff := cmp.Diff(expected, rec); diff != "" {
+		t.Fatal(diff)
+	}
+	if v := aws.StringValue(lookup.LookupAttributes[0].AttributeValue); v != "i-1" {
+		t.Fatalf("expected lookup of i-1, got %s", v)
+	}
+}
+
+func TestSNSNotifier(t *testing.T) {
+	var published []*sns.PublishInput
+	notifier := &chaosaws.SNSNotifier{
+		Client: &chaosaws.Client{
+			SNS: &awsmock.SNS{
+				PublishFunc: func(ctx aws.Context, in *sns.PublishInput) (*sns.PublishOutput, error) {
+					published = append(published, in)
+					return &sns.PublishOutput{}, nil
+				},
+			},
+		},
+		TopicARN: "arn:aws:sns:us-east-1:111111111111:chaos",
+	}
+	bus := chaosmonkey.NewBus()
+	stop := notifier.Subscribe(bus)
+	bus.Publish(chaosmonkey.Message{
+		Topic:                chaosmonkey.TopicGuardBlocked,
+		AutoScalingGroupName: "a",
+		Err:                  errors.New("group a is frozen"),
+	})
+	stop()
+	bus.Publish(chaosmonkey.Message{Topic: chaosmonkey.TopicEventRecorded, AutoScalingGroupName: "a"})
+
+	if len(published) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(published))
+	}
+	in := published[0]
+	if v := aws.StringValue(in.MessageAttributes["type"].StringValue); v != chaosaws.NotificationBlocked {
+		t.Errorf("expected type attribute %q, got %q", chaosaws.NotificationBlocked, v)
+	}
+	var notif chaosaws.Notification
+	if err := json.Unmarshal([]byte(aws.StringValue(in.Message)), &notif); err != nil {
+		t.Fatal(err)
+	}
+	if notif.AutoScalingGroupName != "a" || notif.Reason != "group a is frozen" {
+		t.Errorf("unexpected notification: %+v", notif)
+	}
+}
+
+func TestS3AuditSink(t *testing.T) {
+	var key string
+	var record chaosmonkey.AuditRecord
+	sink := &chaosaws.S3AuditSink{
+		Client: &chaosaws.Client{
+			S3: &awsmock.S3{
+				PutObjectFunc: func(ctx aws.Context, in *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+					key = aws.StringValue(in.Key)
+					body, err := ioutil.ReadAll(in.Body)
+					if err != nil {
+						return nil, err
+					}
+					return &s3.PutObjectOutput{}, json.Unmarshal(body, &record)
+				},
+			},
+		},
+		Bucket: "audit",
+		Prefix: "chaos/",
+	}
+	r := chaosmonkey.AuditRecord{
+		Time:                 time.Date(2018, 12, 24, 10, 30, 0, 0, time.UTC),
+		Actor:                "alice",
+		AutoScalingGroupName: "web-prod",
+		Strategy:             chaosmonkey.StrategyShutdownInstance,
+		Reason:               "game day",
+		Outcome:              chaosmonkey.AuditTriggered,
+	}
+	if err := sink.WriteAudit(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	if prefix := "chaos/2018/12/24/20181224T103000Z-web-prod-"; !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, ".json") {
+		t.Errorf("expected key like %s*.json, got %s", prefix, key)
+	}
+	if diff := cmp.Diff(r, record); diff != "" {
+		t.Errorf("unexpected record (-want +got):\n%s", diff)
+	}
+}
+
+func TestEventBridgeEmitter(t *testing.T) {
+	var entries []*eventbridge.PutEventsRequestEntry
+	emitter := &chaosaws.EventBridgeEmitter{
+		Client: &chaosaws.Client{
+			EventBridge: &awsmock.EventBridge{
+				PutEventsFunc: func(ctx aws.Context, in *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+					entries = append(entries, in.Entries...)
+					return &eventbridge.PutEventsOutput{FailedEntryCount: aws.Int64(0)}, nil
+				},
+			},
+		},
+	}
+	bus := chaosmonkey.NewBus()
+	stop := emitter.Subscribe(bus)
+	bus.Publish(chaosmonkey.Message{Topic: chaosmonkey.TopicExperimentFinished, AutoScalingGroupName: "a"})
+	bus.Publish(chaosmonkey.Message{
+		Topic:                chaosmonkey.TopicExperimentFinished,
+		AutoScalingGroupName: "a",
+		Err:                  errors.New("campaign c aborted: alarm"),
+	})
+	stop()
+
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(entries))
+	}
+	e := entries[0]
+	got := []string{aws.StringValue(e.Source), aws.StringValue(e.DetailType)}
+	want := []string{chaosaws.DefaultEventSource, "Chaos Experiment Halted"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected source and detail type (-want +got):\n%s", diff)
+	}
+	if e.EventBusName != nil {
+		t.Errorf("expected default bus, got %q", aws.StringValue(e.EventBusName))
+	}
+	var notif chaosaws.Notification
+	if err := json.Unmarshal([]byte(aws.StringValue(e.Detail)), &notif); err != nil {
+		t.Fatal(err)
+	}
+	if notif.Type != chaosaws.NotificationHalted || notif.Reason != "campaign c aborted: alarm" {
+		t.Errorf("unexpected notification: %+v", notif)
+	}
+}
+
+func TestSNSNotifierAnnounce(t *testing.T) {
+	var published []*sns.PublishInput
+	notifier := &chaosaws.SNSNotifier{
+		Client: &chaosaws.Client{
+			SNS: &awsmock.SNS{
+				PublishFunc: func(ctx aws.Context, in *sns.PublishInput) (*sns.PublishOutput, error) {
+					published = append(published, in)
+					return &sns.PublishOutput{}, nil
+				},
+			},
+		},
+		TopicARN: "arn:aws:sns:us-east-1:111111111111:chaos",
+	}
+	gd := &gameday.GameDay{
+		Name:     "payments",
+		Start:    time.Date(2018, 4, 2, 13, 0, 0, 0, time.UTC),
+		Services: []string{"payments-api"},
+	}
+	if err := notifier.Announce(context.Background(), gd); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(published) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(published))
+	}
+	in := published[0]
+	if s := aws.StringValue(in.Subject); s != "Chaos Monkey GameDay payments: 2018-04-02T13:00:00Z" {
+		t.Errorf("unexpected subject %q", s)
+	}
+	if _, ok := in.MessageAttributes["autoScalingGroupName"]; ok {
+		t.Error("unexpected empty autoScalingGroupName attribute")
+	}
+	var notif chaosaws.Notification
+	if err := json.Unmarshal([]byte(aws.StringValue(in.Message)), &notif); err != nil {
+		t.Fatal(err)
+	}
+	if notif.Type != chaosaws.NotificationGameDay || notif.GameDay == nil || notif.GameDay.Name != "payments" {
+		t.Errorf("unexpected notification: %+v", notif)
+	}
+}
+
+func TestEnrichEvents(t *testing.T) {
+	launched := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := &chaosaws.Client{
+		Region: "eu-west-1",
+		EC2: &awsmock.EC2{
+			DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+				if len(in.InstanceIds) > 0 {
+					t.Error("expected instances to be filtered rather than requested by ID")
+				}
+				if got := aws.StringValueSlice(in.Filters[0].Values); !cmp.Equal(got, []string{"i-1", "i-2"}) {
+					t.Errorf("unexpected instance IDs %v", got)
+				}
+				fn(&ec2.DescribeInstancesOutput{
+					Reservations: []*ec2.Reservation{{
+						Instances: []*ec2.Instance{{
+							InstanceId:   aws.String("i-1"),
+							InstanceType: aws.String("m5.large"),
+							ImageId:      aws.String("ami-1"),
+							LaunchTime:   aws.Time(launched),
+							Placement:    &ec2.Placement{AvailabilityZone: aws.String("eu-west-1a")},
+							Tags:         []*ec2.Tag{{Key: aws.String("team"), Value: aws.String("payments")}},
+						}},
+					}},
+				}, true)
+				return nil
+			},
+		},
+	}
+	events := []chaosmonkey.Event{
+		{InstanceID: "i-1", Region: "eu-west-1"},
+		{InstanceID: "i-2"},
+		{InstanceID: "i-3", Region: "us-east-1"},
+		{InstanceID: "i-1", Region: "eu-west-1"},
+	}
+	if err := c.EnrichEvents(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &chaosmonkey.InstanceDetails{
+		AvailabilityZone: "eu-west-1a",
+		InstanceType:     "m5.large",
+		ImageID:          "ami-1",
+		LaunchTime:       launched,
+		Tags:             map[string]string{"team": "payments"},
+	}
+	var got []*chaosmonkey.InstanceDetails
+	for _, ev := range events {
+		got = append(got, ev.Instance)
+	}
+	if diff := cmp.Diff([]*chaosmonkey.InstanceDetails{want, nil, nil, want}, got); diff != "" {
+		t.Errorf("unexpected instance details (-want +got):\n%s", diff)
+	}
+}
+
+func TestVerifyTermination(t *testing.T) {
+	c := &chaosaws.Client{
+		AutoScaling: &awsmock.AutoScaling{
+			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+				g := group("a", nil)
+				g.Instances = []*autoscaling.Instance{{
+					InstanceId:     aws.String("i-2"),
+					LifecycleState: aws.String(autoscaling.LifecycleStateInService),
+				}}
+				fn(&autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{g}}, true)
+				return nil
+			},
+			DescribeLifecycleHooksFunc: func(ctx aws.Context, in *autoscaling.DescribeLifecycleHooksInput) (*autoscaling.DescribeLifecycleHooksOutput, error) {
+				return &autoscaling.DescribeLifecycleHooksOutput{
+					LifecycleHooks: []*autoscaling.LifecycleHook{{
+						LifecycleHookName:   aws.String("drain"),
+						LifecycleTransition: aws.String(chaosaws.TransitionInstanceTerminating),
+						HeartbeatTimeout:    aws.Int64(1800),
+						GlobalTimeout:       aws.Int64(172800),
+						DefaultResult:       aws.String("CONTINUE"),
+					}},
+				}, nil
+			},
+			DescribeWarmPoolFunc: func(ctx aws.Context, in *autoscaling.DescribeWarmPoolInput) (*autoscaling.DescribeWarmPoolOutput, error) {
+				return &autoscaling.DescribeWarmPoolOutput{
+					WarmPoolConfiguration: &autoscaling.WarmPoolConfiguration{PoolState: aws.String("Stopped")},
+					Instances: []*autoscaling.Instance{{
+						InstanceId:     aws.String("i-2"),
+						LifecycleState: aws.String("Warmed:Stopped"),
+					}},
+				}, nil
+			},
+		},
+		EC2: &awsmock.EC2{
+			DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+				return nil
+			},
+		},
+	}
+	res, err := c.VerifyTermination(context.Background(), "a", []chaosaws.Instance{{ID: "i-1"}}, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Replaced() {
+		t.Errorf("expected i-1 to be replaced, got %+v", res)
+	}
+	if diff := cmp.Diff([]string{"i-2"}, res.WarmPoolReplacements); diff != "" {
+		t.Errorf("unexpected warm pool replacements (-want +got):\n%s", diff)
+	}
+	expected := []chaosaws.LifecycleHook{{
+		Name:             "drain",
+		Transition:       chaosaws.TransitionInstanceTerminating,
+		HeartbeatTimeout: 30 * time.Minute,
+		GlobalTimeout:    48 * time.Hour,
+		DefaultResult:    "CONTINUE",
+	}}
+	if diff := cmp.Diff(expected, res.LifecycleHooks); diff != "" {
+		t.Errorf("unexpected lifecycle hooks (-want +got):\n%s", diff)
+	}
+	if !res.LifecycleHooks[0].Terminating() {
+		t.Error("expected hook to delay termination")
+	}
+}
+
+func TestDeploymentGuard(t *testing.T) {
+	client := &chaosaws.Client{
+		AutoScaling: &awsmock.AutoScaling{
+			DescribeInstanceRefreshesFunc: func(ctx aws.Context, in *autoscaling.DescribeInstanceRefreshesInput) (*autoscaling.DescribeInstanceRefreshesOutput, error) {
+				var out autoscaling.DescribeInstanceRefreshesOutput
+				if aws.StringValue(in.AutoScalingGroupName) == "b" {
+					out.InstanceRefreshes = []*autoscaling.InstanceRefresh{{
+						InstanceRefreshId: aws.String("r-2"),
+						Status:            aws.String(autoscaling.InstanceRefreshStatusInProgress),
+					}}
+				}
+				out.InstanceRefreshes = append(out.InstanceRefreshes, &autoscaling.InstanceRefresh{
+					InstanceRefreshId: aws.String("r-1"),
+					Status:            aws.String(autoscaling.InstanceRefreshStatusSuccessful),
+				})
+				return &out, nil
+			},
+		},
+		CodeDeploy: &awsmock.CodeDeploy{
+			ListDeploymentsPagesFunc: func(ctx aws.Context, in *codedeploy.ListDeploymentsInput, fn func(*codedeploy.ListDeploymentsOutput, bool) bool) error {
+				fn(&codedeploy.ListDeploymentsOutput{Deployments: aws.StringSlice([]string{"d-1"})}, true)
+				return nil
+			},
+			BatchGetDeploymentsFunc: func(ctx aws.Context, in *codedeploy.BatchGetDeploymentsInput) (*codedeploy.BatchGetDeploymentsOutput, error) {
+				return &codedeploy.BatchGetDeploymentsOutput{
+					DeploymentsInfo: []*codedeploy.DeploymentInfo{{
+						ApplicationName:     aws.String("web"),
+						DeploymentGroupName: aws.String("prod"),
+						DeploymentId:        aws.String("d-1"),
+						Status:              aws.String(codedeploy.DeploymentStatusInProgress),
+					}},
+				}, nil
+			},
+			GetDeploymentGroupFunc: func(ctx aws.Context, in *codedeploy.GetDeploymentGroupInput) (*codedeploy.GetDeploymentGroupOutput, error) {
+				return &codedeploy.GetDeploymentGroupOutput{
+					DeploymentGroupInfo: &codedeploy.DeploymentGroupInfo{
+						AutoScalingGroups: []*codedeploy.AutoScalingGroup{{Name: aws.String("c")}},
+					},
+				}, nil
+			},
+		},
+	}
+	tests := []struct {
+		group    string
+		guard    chaosaws.DeploymentGuard
+		expected string
+	}{
+		{"a", chaosaws.DeploymentGuard{}, ""},
+		{"b", chaosaws.DeploymentGuard{}, "group b is being deployed: instance refresh r-2 (InProgress)"},
+		{"c", chaosaws.DeploymentGuard{}, "group c is being deployed: CodeDeploy deployment d-1 of web/prod (InProgress)"},
+		{"c", chaosaws.DeploymentGuard{InstanceRefreshesOnly: true}, ""},
+	}
+	for _, tt := range tests {
+		tt.guard.Client = client
+		var msg string
+		if err := tt.guard.Check(chaosmonkey.Target{AutoScalingGroupName: tt.group}); err != nil {
+			msg = err.Error()
+		}
+		if msg != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, msg)
+		}
+	}
+}
+
+func TestMetricMathBudget(t *testing.T) {
+	now := time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)
+	var expr string
+	budget := &chaosaws.MetricMathBudget{
+		Client: &chaosaws.Client{
+			CloudWatch: &awsmock.CloudWatch{
+				GetMetricDataFunc: func(ctx aws.Context, in *cloudwatch.GetMetricDataInput) (*cloudwatch.GetMetricDataOutput, error) {
+					expr = aws.StringValue(in.MetricDataQueries[0].Expression)
+					if got, want := aws.TimeValue(in.StartTime), now.Add(-time.Hour); !got.Equal(want) {
+						t.Errorf("got start time %s, want %s", got, want)
+					}
+					return &cloudwatch.GetMetricDataOutput{
+						MetricDataResults: []*cloudwatch.MetricDataResult{{
+							Values: []*float64{aws.Float64(0.25), aws.Float64(0.3)},
+						}},
+					}, nil
+				},
+			},
+		},
+		Expression: `SEARCH('Service="$group"', 'Average', 300)`,
+	}
+	got, err := budget.ErrorBudget(context.Background(), chaosmonkey.Target{AutoScalingGroupName: "payments-api", Time: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 0.25 {
+		t.Errorf("got error budget %v, want 0.25", got)
+	}
+	if want := `SEARCH('Service="payments-api"', 'Average', 300)`; expr != want {
+		t.Errorf("got expression %q, want %q", expr, want)
+	}
+}
+
+func TestMetricsAround(t *testing.T) {
+	at := time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)
+	var requests []string
+	client := &chaosaws.Client{
+		CloudWatch: &awsmock.CloudWatch{
+			GetMetricStatisticsFunc: func(ctx aws.Context, in *cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error) {
+				requests = append(requests, fmt.Sprintf("%s/%s %s %s %s-%s %d",
+					aws.StringValue(in.Namespace), aws.StringValue(in.MetricName), aws.StringValue(in.Statistics[0]),
+					aws.StringValue(in.Dimensions[0].Value), aws.TimeValue(in.StartTime).Format("15:04"),
+					aws.TimeValue(in.EndTime).Format("15:04"), aws.Int64Value(in.Period)))
+				point := func(offset time.Duration, v float64) *cloudwatch.Datapoint {
+					return &cloudwatch.Datapoint{Timestamp: aws.Time(at.Add(offset)), Average: aws.Float64(v), Maximum: aws.Float64(2 * v)}
+				}
+				return &cloudwatch.GetMetricStatisticsOutput{
+					// Unordered, like CloudWatch returns them
+					Datapoints: []*cloudwatch.Datapoint{
+						point(15*time.Minute, 30),
+						point(-5*time.Minute, 10),
+						point(2*time.Minute, 50),
+						point(-time.Minute, 20),
+					},
+				}, nil
+			},
+		},
+	}
+
+	e := chaosmonkey.Event{AutoScalingGroupName: "a", TriggeredAt: at}
+	got, err := client.MetricsAround(context.Background(), e, []chaosaws.Metric{
+		{Name: "CPUUtilization"},
+		{Namespace: "AWS/AutoScaling", Name: "GroupInServiceInstances", Statistic: cloudwatch.StatisticMaximum},
+	}, 10*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := func(scale float64) []chaosaws.Datapoint {
+		return []chaosaws.Datapoint{
+			{Time: at.Add(-5 * time.Minute), Value: 10 * scale},
+			{Time: at.Add(-time.Minute), Value: 20 * scale},
+			{Time: at.Add(2 * time.Minute), Value: 50 * scale},
+			{Time: at.Add(15 * time.Minute), Value: 30 * scale},
+		}
+	}
+	expected := &chaosaws.EventMetrics{
+		Event:  e,
+		Window: 10 * time.Minute,
+		Series: []chaosaws.MetricSeries{{
+			Metric:     chaosaws.Metric{Namespace: "AWS/EC2", Name: "CPUUtilization", Statistic: "Average"},
+			Datapoints: points(1),
+			Before:     15,
+			During:     50,
+			After:      30,
+		}, {
+			Metric:     chaosaws.Metric{Namespace: "AWS/AutoScaling", Name: "GroupInServiceInstances", Statistic: "Maximum"},
+			Datapoints: points(2),
+			Before:     30,
+			During:     100,
+			After:      60,
+		}},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatal(diff)
+	}
+	if diff := cmp.Diff([]string{
+		"AWS/EC2/CPUUtilization Average a 09:50-10:20 60",
+		"AWS/AutoScaling/GroupInServiceInstances Maximum a 09:50-10:20 60",
+	}, requests); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// Long windows use longer periods to fit into a single request
+	requests = nil
+	if _, err := client.MetricsAround(context.Background(), e, []chaosaws.Metric{{Name: "CPUUtilization"}}, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"AWS/EC2/CPUUtilization Average a 10:00-10:00 180"}, requests); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if _, err := client.MetricsAround(context.Background(), e, nil, 0); err == nil {
+		t.Fatal("expected error for window of zero")
+	}
+}
+
+func TestActiveInstanceRefreshes(t *testing.T) {
+	tests := []struct {
+		status string
+		active bool
+	}{
+		{autoscaling.InstanceRefreshStatusPending, true},
+		{autoscaling.InstanceRefreshStatusInProgress, true},
+		{autoscaling.InstanceRefreshStatusCancelling, true},
+		{autoscaling.InstanceRefreshStatusRollbackInProgress, true},
+		{"Baking", true},
+		{autoscaling.InstanceRefreshStatusSuccessful, false},
+		{autoscaling.InstanceRefreshStatusFailed, false},
+		{autoscaling.InstanceRefreshStatusCancelled, false},
+		{autoscaling.InstanceRefreshStatusRollbackFailed, false},
+		{autoscaling.InstanceRefreshStatusRollbackSuccessful, false},
+	}
+	for _, tt := range tests {
+		client := &chaosaws.Client{
+			AutoScaling: &awsmock.AutoScaling{
+				DescribeInstanceRefreshesFunc: func(ctx aws.Context, in *autoscaling.DescribeInstanceRefreshesInput) (*autoscaling.DescribeInstanceRefreshesOutput, error) {
+					return &autoscaling.DescribeInstanceRefreshesOutput{
+						InstanceRefreshes: []*autoscaling.InstanceRefresh{{
+							InstanceRefreshId: aws.String("r-1"),
+							Status:            aws.String(tt.status),
+						}},
+					}, nil
+				},
+			},
+			CodeDeploy: &awsmock.CodeDeploy{
+				ListDeploymentsPagesFunc: func(ctx aws.Context, in *codedeploy.ListDeploymentsInput, fn func(*codedeploy.ListDeploymentsOutput, bool) bool) error {
+					return nil
+				},
+			},
+		}
+		deployments, err := client.ActiveDeployments(context.Background(), "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active := len(deployments) > 0; active != tt.active {
+			t.Errorf("instance refresh with status %s: active = %t, want %t", tt.status, active, tt.active)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	tmpl, ok := chaosaws.LookupTemplate("web-tier")
+	if !ok {
+		t.Fatal("template web-tier not found")
+	}
+	groups := []chaosaws.AutoScalingGroup{
+		{Name: "checkout-web", InstancesInService: 3, LoadBalancers: []chaosaws.LoadBalancer{{Name: "checkout"}}},
+		{Name: "search-web", InstancesInService: 2, LoadBalancers: []chaosaws.LoadBalancer{{Name: "search", TargetGroup: true}}},
+		{Name: "checkout-worker", InstancesInService: 6},
+	}
+	cp, err := tmpl.Generate("", groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []chaosmonkey.CampaignStep{
+		{Name: "lose an instance", Groups: []string{"checkout-web", "search-web"}, Strategy: chaosmonkey.StrategyShutdownInstance, Wait: 10 * time.Minute},
+		{Name: "lose capacity", Groups: []string{"checkout-web"}, Strategy: chaosmonkey.StrategyShutdownInstance, Count: 2, Interval: 2 * time.Minute, Wait: 10 * time.Minute},
+		{Name: "slow instances", Groups: []string{"checkout-web", "search-web"}, Strategy: chaosmonkey.StrategyNetworkLatency, Wait: 10 * time.Minute},
+		{Name: "saturated instances", Groups: []string{"checkout-web", "search-web"}, Strategy: chaosmonkey.StrategyBurnCPU, Wait: 10 * time.Minute},
+		{Name: "DNS failure", Groups: []string{"checkout-web", "search-web"}, Strategy: chaosmonkey.StrategyFailDNS, Wait: 10 * time.Minute},
+	}
+	if cp.Name != "web-tier" {
+		t.Errorf("got name %q, want web-tier", cp.Name)
+	}
+	if diff := cmp.Diff(want, cp.Steps); diff != "" {
+		t.Fatal(diff)
+	}
+
+	_, err = tmpl.Generate("workers", groups[2:])
+	if want := "none of the auto scaling groups fit template web-tier"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+	if _, ok := chaosaws.LookupTemplate("mainframe"); ok {
+		t.Error("unknown template found")
+	}
+}
@@ -0,0 +1,433 @@
+// Package awsmock provides mocks of the AWS services used by aws.Client.
+// Each operation calls the corresponding function field, or fails with an
+// error if the field is nil:
+//
+//	client := &aws.Client{
+//		AutoScaling: &awsmock.AutoScaling{
+//			DescribeAutoScalingGroupsPagesFunc: func(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+//				fn(&autoscaling.DescribeAutoScalingGroupsOutput{...}, true)
+//				return nil
+//			},
+//		},
+//	}
+package awsmock
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/simpledb"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sts"
+
+	chaosaws "github.com/FlyLevin/chaosmonkey/aws"
+)
+
+var (
+	_ chaosaws.AutoScalingAPI   = (*AutoScaling)(nil)
+	_ chaosaws.EC2API           = (*EC2)(nil)
+	_ chaosaws.SimpleDBAPI      = (*SimpleDB)(nil)
+	_ chaosaws.CloudTrailAPI    = (*CloudTrail)(nil)
+	_ chaosaws.CloudWatchAPI    = (*CloudWatch)(nil)
+	_ chaosaws.CodeDeployAPI    = (*CodeDeploy)(nil)
+	_ chaosaws.ELBAPI           = (*ELB)(nil)
+	_ chaosaws.ELBV2API         = (*ELBV2)(nil)
+	_ chaosaws.EventBridgeAPI   = (*EventBridge)(nil)
+	_ chaosaws.OrganizationsAPI = (*Organizations)(nil)
+	_ chaosaws.PricingAPI       = (*Pricing)(nil)
+	_ chaosaws.S3API            = (*S3)(nil)
+	_ chaosaws.SNSAPI           = (*SNS)(nil)
+	_ chaosaws.STSAPI           = (*STS)(nil)
+)
+
+func unexpected(op string) error {
+	return fmt.Errorf("awsmock: unexpected call to %s", op)
+}
+
+// AutoScaling mocks chaosaws.AutoScalingAPI.
+type AutoScaling struct {
+	DescribeAutoScalingGroupsPagesFunc func(aws.Context, *autoscaling.DescribeAutoScalingGroupsInput, func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error
+	SuspendProcessesFunc               func(aws.Context, *autoscaling.ScalingProcessQuery) (*autoscaling.SuspendProcessesOutput, error)
+	ResumeProcessesFunc                func(aws.Context, *autoscaling.ScalingProcessQuery) (*autoscaling.ResumeProcessesOutput, error)
+	DescribeLaunchConfigurationsFunc   func(aws.Context, *autoscaling.DescribeLaunchConfigurationsInput) (*autoscaling.DescribeLaunchConfigurationsOutput, error)
+	DescribeLifecycleHooksFunc         func(aws.Context, *autoscaling.DescribeLifecycleHooksInput) (*autoscaling.DescribeLifecycleHooksOutput, error)
+	DescribeWarmPoolFunc               func(aws.Context, *autoscaling.DescribeWarmPoolInput) (*autoscaling.DescribeWarmPoolOutput, error)
+	DescribeInstanceRefreshesFunc      func(aws.Context, *autoscaling.DescribeInstanceRefreshesInput) (*autoscaling.DescribeInstanceRefreshesOutput, error)
+}
+
+func (m *AutoScaling) DescribeAutoScalingGroupsPagesWithContext(ctx aws.Context, in *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool, _ ...request.Option) error {
+	if m.DescribeAutoScalingGroupsPagesFunc == nil {
+		return unexpected("DescribeAutoScalingGroupsPages")
+	}
+	return m.DescribeAutoScalingGroupsPagesFunc(ctx, in, fn)
+}
+
+func (m *AutoScaling) SuspendProcessesWithContext(ctx aws.Context, in *autoscaling.ScalingProcessQuery, _ ...request.Option) (*autoscaling.SuspendProcessesOutput, error) {
+	if m.SuspendProcessesFunc == nil {
+		return nil, unexpected("SuspendProcesses")
+	}
+	return m.SuspendProcessesFunc(ctx, in)
+}
+
+func (m *AutoScaling) ResumeProcessesWithContext(ctx aws.Context, in *autoscaling.ScalingProcessQuery, _ ...request.Option) (*autoscaling.ResumeProcessesOutput, error) {
+	if m.ResumeProcessesFunc == nil {
+		return nil, unexpected("ResumeProcesses")
+	}
+	return m.ResumeProcessesFunc(ctx, in)
+}
+
+func (m *AutoScaling) DescribeLaunchConfigurationsWithContext(ctx aws.Context, in *autoscaling.DescribeLaunchConfigurationsInput, _ ...request.Option) (*autoscaling.DescribeLaunchConfigurationsOutput, error) {
+	if m.DescribeLaunchConfigurationsFunc == nil {
+		return nil, unexpected("DescribeLaunchConfigurations")
+	}
+	return m.DescribeLaunchConfigurationsFunc(ctx, in)
+}
+
+func (m *AutoScaling) DescribeLifecycleHooksWithContext(ctx aws.Context, in *autoscaling.DescribeLifecycleHooksInput, _ ...request.Option) (*autoscaling.DescribeLifecycleHooksOutput, error) {
+	if m.DescribeLifecycleHooksFunc == nil {
+		return nil, unexpected("DescribeLifecycleHooks")
+	}
+	return m.DescribeLifecycleHooksFunc(ctx, in)
+}
+
+func (m *AutoScaling) DescribeWarmPoolWithContext(ctx aws.Context, in *autoscaling.DescribeWarmPoolInput, _ ...request.Option) (*autoscaling.DescribeWarmPoolOutput, error) {
+	if m.DescribeWarmPoolFunc == nil {
+		return nil, unexpected("DescribeWarmPool")
+	}
+	return m.DescribeWarmPoolFunc(ctx, in)
+}
+
+func (m *AutoScaling) DescribeInstanceRefreshesWithContext(ctx aws.Context, in *autoscaling.DescribeInstanceRefreshesInput, _ ...request.Option) (*autoscaling.DescribeInstanceRefreshesOutput, error) {
+	if m.DescribeInstanceRefreshesFunc == nil {
+		return nil, unexpected("DescribeInstanceRefreshes")
+	}
+	return m.DescribeInstanceRefreshesFunc(ctx, in)
+}
+
+// EC2 mocks chaosaws.EC2API.
+type EC2 struct {
+	DescribeInstancesPagesFunc         func(aws.Context, *ec2.DescribeInstancesInput, func(*ec2.DescribeInstancesOutput, bool) bool) error
+	DescribeInstanceStatusFunc         func(aws.Context, *ec2.DescribeInstanceStatusInput) (*ec2.DescribeInstanceStatusOutput, error)
+	DescribeRegionsFunc                func(aws.Context, *ec2.DescribeRegionsInput) (*ec2.DescribeRegionsOutput, error)
+	TerminateInstancesFunc             func(aws.Context, *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error)
+	ModifyInstanceAttributeFunc        func(aws.Context, *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error)
+	DescribeInstanceAttributeFunc      func(aws.Context, *ec2.DescribeInstanceAttributeInput) (*ec2.DescribeInstanceAttributeOutput, error)
+	DescribeSecurityGroupsFunc         func(aws.Context, *ec2.DescribeSecurityGroupsInput) (*ec2.DescribeSecurityGroupsOutput, error)
+	CreateSecurityGroupFunc            func(aws.Context, *ec2.CreateSecurityGroupInput) (*ec2.CreateSecurityGroupOutput, error)
+	RevokeSecurityGroupEgressFunc      func(aws.Context, *ec2.RevokeSecurityGroupEgressInput) (*ec2.RevokeSecurityGroupEgressOutput, error)
+	DescribeVolumesFunc                func(aws.Context, *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error)
+	DetachVolumeFunc                   func(aws.Context, *ec2.DetachVolumeInput) (*ec2.VolumeAttachment, error)
+	AttachVolumeFunc                   func(aws.Context, *ec2.AttachVolumeInput) (*ec2.VolumeAttachment, error)
+	DescribeLaunchTemplateVersionsFunc func(aws.Context, *ec2.DescribeLaunchTemplateVersionsInput) (*ec2.DescribeLaunchTemplateVersionsOutput, error)
+}
+
+func (m *EC2) DescribeInstancesPagesWithContext(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool, _ ...request.Option) error {
+	if m.DescribeInstancesPagesFunc == nil {
+		return unexpected("DescribeInstancesPages")
+	}
+	return m.DescribeInstancesPagesFunc(ctx, in, fn)
+}
+
+func (m *EC2) DescribeInstanceStatusWithContext(ctx aws.Context, in *ec2.DescribeInstanceStatusInput, _ ...request.Option) (*ec2.DescribeInstanceStatusOutput, error) {
+	if m.DescribeInstanceStatusFunc == nil {
+		return nil, unexpected("DescribeInstanceStatus")
+	}
+	return m.DescribeInstanceStatusFunc(ctx, in)
+}
+
+func (m *EC2) DescribeRegionsWithContext(ctx aws.Context, in *ec2.DescribeRegionsInput, _ ...request.Option) (*ec2.DescribeRegionsOutput, error) {
+	if m.DescribeRegionsFunc == nil {
+		return nil, unexpected("DescribeRegions")
+	}
+	return m.DescribeRegionsFunc(ctx, in)
+}
+
+func (m *EC2) TerminateInstancesWithContext(ctx aws.Context, in *ec2.TerminateInstancesInput, _ ...request.Option) (*ec2.TerminateInstancesOutput, error) {
+	if m.TerminateInstancesFunc == nil {
+		return nil, unexpected("TerminateInstances")
+	}
+	return m.TerminateInstancesFunc(ctx, in)
+}
+
+func (m *EC2) ModifyInstanceAttributeWithContext(ctx aws.Context, in *ec2.ModifyInstanceAttributeInput, _ ...request.Option) (*ec2.ModifyInstanceAttributeOutput, error) {
+	if m.ModifyInstanceAttributeFunc == nil {
+		return nil, unexpected("ModifyInstanceAttribute")
+	}
+	return m.ModifyInstanceAttributeFunc(ctx, in)
+}
+
+func (m *EC2) DescribeInstanceAttributeWithContext(ctx aws.Context, in *ec2.DescribeInstanceAttributeInput, _ ...request.Option) (*ec2.DescribeInstanceAttributeOutput, error) {
+	if m.DescribeInstanceAttributeFunc == nil {
+		return nil, unexpected("DescribeInstanceAttribute")
+	}
+	return m.DescribeInstanceAttributeFunc(ctx, in)
+}
+
+func (m *EC2) DescribeSecurityGroupsWithContext(ctx aws.Context, in *ec2.DescribeSecurityGroupsInput, _ ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error) {
+	if m.DescribeSecurityGroupsFunc == nil {
+		return nil, unexpected("DescribeSecurityGroups")
+	}
+	return m.DescribeSecurityGroupsFunc(ctx, in)
+}
+
+func (m *EC2) CreateSecurityGroupWithContext(ctx aws.Context, in *ec2.CreateSecurityGroupInput, _ ...request.Option) (*ec2.CreateSecurityGroupOutput, error) {
+	if m.CreateSecurityGroupFunc == nil {
+		return nil, unexpected("CreateSecurityGroup")
+	}
+	return m.CreateSecurityGroupFunc(ctx, in)
+}
+
+func (m *EC2) RevokeSecurityGroupEgressWithContext(ctx aws.Context, in *ec2.RevokeSecurityGroupEgressInput, _ ...request.Option) (*ec2.RevokeSecurityGroupEgressOutput, error) {
+	if m.RevokeSecurityGroupEgressFunc == nil {
+		return nil, unexpected("RevokeSecurityGroupEgress")
+	}
+	return m.RevokeSecurityGroupEgressFunc(ctx, in)
+}
+
+func (m *EC2) DescribeVolumesWithContext(ctx aws.Context, in *ec2.DescribeVolumesInput, _ ...request.Option) (*ec2.DescribeVolumesOutput, error) {
+	if m.DescribeVolumesFunc == nil {
+		return nil, unexpected("DescribeVolumes")
+	}
+	return m.DescribeVolumesFunc(ctx, in)
+}
+
+func (m *EC2) DetachVolumeWithContext(ctx aws.Context, in *ec2.DetachVolumeInput, _ ...request.Option) (*ec2.VolumeAttachment, error) {
+	if m.DetachVolumeFunc == nil {
+		return nil, unexpected("DetachVolume")
+	}
+	return m.DetachVolumeFunc(ctx, in)
+}
+
+func (m *EC2) AttachVolumeWithContext(ctx aws.Context, in *ec2.AttachVolumeInput, _ ...request.Option) (*ec2.VolumeAttachment, error) {
+	if m.AttachVolumeFunc == nil {
+		return nil, unexpected("AttachVolume")
+	}
+	return m.AttachVolumeFunc(ctx, in)
+}
+
+func (m *EC2) DescribeLaunchTemplateVersionsWithContext(ctx aws.Context, in *ec2.DescribeLaunchTemplateVersionsInput, _ ...request.Option) (*ec2.DescribeLaunchTemplateVersionsOutput, error) {
+	if m.DescribeLaunchTemplateVersionsFunc == nil {
+		return nil, unexpected("DescribeLaunchTemplateVersions")
+	}
+	return m.DescribeLaunchTemplateVersionsFunc(ctx, in)
+}
+
+// SimpleDB mocks chaosaws.SimpleDBAPI.
+type SimpleDB struct {
+	ListDomainsPagesFunc      func(aws.Context, *simpledb.ListDomainsInput, func(*simpledb.ListDomainsOutput, bool) bool) error
+	DomainMetadataFunc        func(aws.Context, *simpledb.DomainMetadataInput) (*simpledb.DomainMetadataOutput, error)
+	SelectPagesFunc           func(aws.Context, *simpledb.SelectInput, func(*simpledb.SelectOutput, bool) bool) error
+	DeleteDomainFunc          func(aws.Context, *simpledb.DeleteDomainInput) (*simpledb.DeleteDomainOutput, error)
+	BatchDeleteAttributesFunc func(aws.Context, *simpledb.BatchDeleteAttributesInput) (*simpledb.BatchDeleteAttributesOutput, error)
+}
+
+func (m *SimpleDB) ListDomainsPagesWithContext(ctx aws.Context, in *simpledb.ListDomainsInput, fn func(*simpledb.ListDomainsOutput, bool) bool, _ ...request.Option) error {
+	if m.ListDomainsPagesFunc == nil {
+		return unexpected("ListDomainsPages")
+	}
+	return m.ListDomainsPagesFunc(ctx, in, fn)
+}
+
+func (m *SimpleDB) DomainMetadataWithContext(ctx aws.Context, in *simpledb.DomainMetadataInput, _ ...request.Option) (*simpledb.DomainMetadataOutput, error) {
+	if m.DomainMetadataFunc == nil {
+		return nil, unexpected("DomainMetadata")
+	}
+	return m.DomainMetadataFunc(ctx, in)
+}
+
+func (m *SimpleDB) SelectPagesWithContext(ctx aws.Context, in *simpledb.SelectInput, fn func(*simpledb.SelectOutput, bool) bool, _ ...request.Option) error {
+	if m.SelectPagesFunc == nil {
+		return unexpected("SelectPages")
+	}
+	return m.SelectPagesFunc(ctx, in, fn)
+}
+
+func (m *SimpleDB) DeleteDomainWithContext(ctx aws.Context, in *simpledb.DeleteDomainInput, _ ...request.Option) (*simpledb.DeleteDomainOutput, error) {
+	if m.DeleteDomainFunc == nil {
+		return nil, unexpected("DeleteDomain")
+	}
+	return m.DeleteDomainFunc(ctx, in)
+}
+
+func (m *SimpleDB) BatchDeleteAttributesWithContext(ctx aws.Context, in *simpledb.BatchDeleteAttributesInput, _ ...request.Option) (*simpledb.BatchDeleteAttributesOutput, error) {
+	if m.BatchDeleteAttributesFunc == nil {
+		return nil, unexpected("BatchDeleteAttributes")
+	}
+	return m.BatchDeleteAttributesFunc(ctx, in)
+}
+
+// CloudTrail mocks chaosaws.CloudTrailAPI.
+type CloudTrail struct {
+	LookupEventsPagesFunc func(aws.Context, *cloudtrail.LookupEventsInput, func(*cloudtrail.LookupEventsOutput, bool) bool) error
+}
+
+func (m *CloudTrail) LookupEventsPagesWithContext(ctx aws.Context, in *cloudtrail.LookupEventsInput, fn func(*cloudtrail.LookupEventsOutput, bool) bool, _ ...request.Option) error {
+	if m.LookupEventsPagesFunc == nil {
+		return unexpected("LookupEventsPages")
+	}
+	return m.LookupEventsPagesFunc(ctx, in, fn)
+}
+
+// CloudWatch mocks chaosaws.CloudWatchAPI.
+type CloudWatch struct {
+	GetMetricStatisticsFunc func(aws.Context, *cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error)
+	DescribeAlarmsPagesFunc func(aws.Context, *cloudwatch.DescribeAlarmsInput, func(*cloudwatch.DescribeAlarmsOutput, bool) bool) error
+	GetMetricDataFunc       func(aws.Context, *cloudwatch.GetMetricDataInput) (*cloudwatch.GetMetricDataOutput, error)
+}
+
+func (m *CloudWatch) GetMetricStatisticsWithContext(ctx aws.Context, in *cloudwatch.GetMetricStatisticsInput, _ ...request.Option) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	if m.GetMetricStatisticsFunc == nil {
+		return nil, unexpected("GetMetricStatistics")
+	}
+	return m.GetMetricStatisticsFunc(ctx, in)
+}
+
+func (m *CloudWatch) DescribeAlarmsPagesWithContext(ctx aws.Context, in *cloudwatch.DescribeAlarmsInput, fn func(*cloudwatch.DescribeAlarmsOutput, bool) bool, _ ...request.Option) error {
+	if m.DescribeAlarmsPagesFunc == nil {
+		return unexpected("DescribeAlarmsPages")
+	}
+	return m.DescribeAlarmsPagesFunc(ctx, in, fn)
+}
+
+func (m *CloudWatch) GetMetricDataWithContext(ctx aws.Context, in *cloudwatch.GetMetricDataInput, _ ...request.Option) (*cloudwatch.GetMetricDataOutput, error) {
+	if m.GetMetricDataFunc == nil {
+		return nil, unexpected("GetMetricData")
+	}
+	return m.GetMetricDataFunc(ctx, in)
+}
+
+// CodeDeploy mocks chaosaws.CodeDeployAPI.
+type CodeDeploy struct {
+	ListDeploymentsPagesFunc func(aws.Context, *codedeploy.ListDeploymentsInput, func(*codedeploy.ListDeploymentsOutput, bool) bool) error
+	BatchGetDeploymentsFunc  func(aws.Context, *codedeploy.BatchGetDeploymentsInput) (*codedeploy.BatchGetDeploymentsOutput, error)
+	GetDeploymentGroupFunc   func(aws.Context, *codedeploy.GetDeploymentGroupInput) (*codedeploy.GetDeploymentGroupOutput, error)
+}
+
+func (m *CodeDeploy) ListDeploymentsPagesWithContext(ctx aws.Context, in *codedeploy.ListDeploymentsInput, fn func(*codedeploy.ListDeploymentsOutput, bool) bool, _ ...request.Option) error {
+	if m.ListDeploymentsPagesFunc == nil {
+		return unexpected("ListDeploymentsPages")
+	}
+	return m.ListDeploymentsPagesFunc(ctx, in, fn)
+}
+
+func (m *CodeDeploy) BatchGetDeploymentsWithContext(ctx aws.Context, in *codedeploy.BatchGetDeploymentsInput, _ ...request.Option) (*codedeploy.BatchGetDeploymentsOutput, error) {
+	if m.BatchGetDeploymentsFunc == nil {
+		return nil, unexpected("BatchGetDeployments")
+	}
+	return m.BatchGetDeploymentsFunc(ctx, in)
+}
+
+func (m *CodeDeploy) GetDeploymentGroupWithContext(ctx aws.Context, in *codedeploy.GetDeploymentGroupInput, _ ...request.Option) (*codedeploy.GetDeploymentGroupOutput, error) {
+	if m.GetDeploymentGroupFunc == nil {
+		return nil, unexpected("GetDeploymentGroup")
+	}
+	return m.GetDeploymentGroupFunc(ctx, in)
+}
+
+// ELB mocks chaosaws.ELBAPI.
+type ELB struct {
+	DescribeInstanceHealthFunc func(aws.Context, *elb.DescribeInstanceHealthInput) (*elb.DescribeInstanceHealthOutput, error)
+}
+
+func (m *ELB) DescribeInstanceHealthWithContext(ctx aws.Context, in *elb.DescribeInstanceHealthInput, _ ...request.Option) (*elb.DescribeInstanceHealthOutput, error) {
+	if m.DescribeInstanceHealthFunc == nil {
+		return nil, unexpected("DescribeInstanceHealth")
+	}
+	return m.DescribeInstanceHealthFunc(ctx, in)
+}
+
+// ELBV2 mocks chaosaws.ELBV2API.
+type ELBV2 struct {
+	DescribeTargetHealthFunc func(aws.Context, *elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error)
+}
+
+func (m *ELBV2) DescribeTargetHealthWithContext(ctx aws.Context, in *elbv2.DescribeTargetHealthInput, _ ...request.Option) (*elbv2.DescribeTargetHealthOutput, error) {
+	if m.DescribeTargetHealthFunc == nil {
+		return nil, unexpected("DescribeTargetHealth")
+	}
+	return m.DescribeTargetHealthFunc(ctx, in)
+}
+
+// EventBridge mocks chaosaws.EventBridgeAPI.
+type EventBridge struct {
+	PutEventsFunc func(aws.Context, *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error)
+}
+
+func (m *EventBridge) PutEventsWithContext(ctx aws.Context, in *eventbridge.PutEventsInput, _ ...request.Option) (*eventbridge.PutEventsOutput, error) {
+	if m.PutEventsFunc == nil {
+		return nil, unexpected("PutEvents")
+	}
+	return m.PutEventsFunc(ctx, in)
+}
+
+// Organizations mocks chaosaws.OrganizationsAPI.
+type Organizations struct {
+	ListAccountsPagesFunc func(aws.Context, *organizations.ListAccountsInput, func(*organizations.ListAccountsOutput, bool) bool) error
+}
+
+func (m *Organizations) ListAccountsPagesWithContext(ctx aws.Context, in *organizations.ListAccountsInput, fn func(*organizations.ListAccountsOutput, bool) bool, _ ...request.Option) error {
+	if m.ListAccountsPagesFunc == nil {
+		return unexpected("ListAccountsPages")
+	}
+	return m.ListAccountsPagesFunc(ctx, in, fn)
+}
+
+// Pricing mocks chaosaws.PricingAPI.
+type Pricing struct {
+	GetProductsFunc func(aws.Context, *pricing.GetProductsInput) (*pricing.GetProductsOutput, error)
+}
+
+func (m *Pricing) GetProductsWithContext(ctx aws.Context, in *pricing.GetProductsInput, _ ...request.Option) (*pricing.GetProductsOutput, error) {
+	if m.GetProductsFunc == nil {
+		return nil, unexpected("GetProducts")
+	}
+	return m.GetProductsFunc(ctx, in)
+}
+
+// S3 mocks chaosaws.S3API.
+type S3 struct {
+	PutObjectFunc func(aws.Context, *s3.PutObjectInput) (*s3.PutObjectOutput, error)
+}
+
+func (m *S3) PutObjectWithContext(ctx aws.Context, in *s3.PutObjectInput, _ ...request.Option) (*s3.PutObjectOutput, error) {
+	if m.PutObjectFunc == nil {
+		return nil, unexpected("PutObject")
+	}
+	return m.PutObjectFunc(ctx, in)
+}
+
+// SNS mocks chaosaws.SNSAPI.
+type SNS struct {
+	PublishFunc func(aws.Context, *sns.PublishInput) (*sns.PublishOutput, error)
+}
+
+func (m *SNS) PublishWithContext(ctx aws.Context, in *sns.PublishInput, _ ...request.Option) (*sns.PublishOutput, error) {
+	if m.PublishFunc == nil {
+		return nil, unexpected("Publish")
+	}
+	return m.PublishFunc(ctx, in)
+}
+
+// STS mocks chaosaws.STSAPI.
+type STS struct {
+	GetCallerIdentityFunc func(aws.Context, *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error)
+}
+
+func (m *STS) GetCallerIdentityWithContext(ctx aws.Context, in *sts.GetCallerIdentityInput, _ ...request.Option) (*sts.GetCallerIdentityOutput, error) {
+	if m.GetCallerIdentityFunc == nil {
+		return nil, unexpected("GetCallerIdentity")
+	}
+	return m.GetCallerIdentityFunc(ctx, in)
+}
@@ -0,0 +1,79 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// MetricMathBudget is a chaosmonkey.BudgetSource that evaluates a CloudWatch
+// metric math expression yielding the remaining error budget, e.g. a SEARCH
+// expression or Metrics Insights query over SLO metrics. As expressions
+// cannot refer to metrics defined elsewhere, combine them within the
+// expression, e.g.
+//
+//	1 - (1 - AVG(SEARCH('{SLO,Service} MetricName="Availability" Service="$group"', 'Average', 3600))) / 0.001
+//
+// The latest value of the expression within Lookback is used. $group and
+// $region in the expression are expanded for every chaos event.
+type MetricMathBudget struct {
+	// Client used to retrieve metrics in the region of the target (the
+	// region of the client if the target has none)
+	Client *Client
+
+	// Expression yielding the remaining error budget
+	Expression string
+
+	// Period of the expression (5 minutes if zero)
+	Period time.Duration
+
+	// How far to look back for a value (an hour if zero)
+	Lookback time.Duration
+}
+
+// ErrorBudget evaluates the expression for the chaos event.
+func (b *MetricMathBudget) ErrorBudget(ctx context.Context, t chaosmonkey.Target) (float64, error) {
+	svc, err := b.Client.forRegion(t.Region).cloudWatch()
+	if err != nil {
+		return 0, err
+	}
+	period := b.Period
+	if period == 0 {
+		period = 5 * time.Minute
+	}
+	lookback := b.Lookback
+	if lookback == 0 {
+		lookback = time.Hour
+	}
+	end := t.Time
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	expr := t.Expand(b.Expression)
+	out, err := svc.GetMetricDataWithContext(ctx, &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: []*cloudwatch.MetricDataQuery{{
+			Id:         aws.String("budget"),
+			Expression: aws.String(expr),
+			Period:     aws.Int64(int64(period / time.Second)),
+			ReturnData: aws.Bool(true),
+		}},
+		StartTime: aws.Time(end.Add(-lookback)),
+		EndTime:   aws.Time(end),
+		ScanBy:    aws.String(cloudwatch.ScanByTimestampDescending),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to evaluate expression %q: %s", expr, err)
+	}
+	for _, r := range out.MetricDataResults {
+		if len(r.Values) > 0 {
+			return aws.Float64Value(r.Values[0]), nil
+		}
+	}
+	return 0, fmt.Errorf("expression %q returned no data within %s", expr, lookback)
+}
@@ -0,0 +1,118 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// CapacityGuard is a Guard that refuses chaos events which could push an
+// auto scaling group below quorum. It assumes that a chaos event takes one
+// instance out of service, and refuses the event if the remaining instances
+// in service would fall below the minimum size of the group or the
+// configured thresholds. It also refuses events against groups that have
+// fewer instances in service than desired, as they are already recovering
+// or scaling.
+type CapacityGuard struct {
+	// Client used to retrieve groups in the region of the target
+	// (the region of the client if the target has none)
+	Client *Client
+
+	// Minimum number of instances that must remain in service
+	MinHealthy int
+
+	// Minimum fraction of the desired capacity that must remain in service,
+	// e.g. 0.75
+	MinHealthyRatio float64
+}
+
+// Check refuses chaos events against groups without spare capacity. It also
+// refuses them if the group cannot be retrieved.
+func (g *CapacityGuard) Check(t chaosmonkey.Target) error {
+	groups, err := g.Client.forRegion(t.Region).describeAutoScalingGroups(context.Background(), &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(t.AutoScalingGroupName)},
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get auto scaling group: %s", err)
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("failed to get auto scaling group: %s", ErrGroupNotFound)
+	}
+	asg := groups[0]
+
+	remaining := asg.InstancesInService - 1
+	switch {
+	case asg.InstancesInService < asg.DesiredCapacity:
+		return fmt.Errorf("group %s has only %d of %d desired instances in service",
+			asg.Name, asg.InstancesInService, asg.DesiredCapacity)
+	case remaining < asg.MinSize:
+		return fmt.Errorf("group %s would have %d instances in service, below its minimum size of %d",
+			asg.Name, remaining, asg.MinSize)
+	case remaining < g.MinHealthy:
+		return fmt.Errorf("group %s would have %d instances in service, below the minimum of %d",
+			asg.Name, remaining, g.MinHealthy)
+	}
+	if min := int(math.Ceil(g.MinHealthyRatio * float64(asg.DesiredCapacity))); remaining < min {
+		return fmt.Errorf("group %s would have %d instances in service, below %g%% of its desired capacity of %d",
+			asg.Name, remaining, g.MinHealthyRatio*100, asg.DesiredCapacity)
+	}
+	return nil
+}
+
+// DefaultMinInService is the number of instances in service GroupSizeGuard
+// requires unless configured otherwise.
+const DefaultMinInService = 2
+
+// GroupSizeGuard is a Guard that keeps chaos away from auto scaling groups
+// with too few instances in service, typically single-instance services,
+// which any chaos event would take fully offline. Unlike CapacityGuard, it
+// only looks at the instances currently in service, and it can be configured
+// to merely warn.
+type GroupSizeGuard struct {
+	// Client used to retrieve groups in the region of the target
+	// (the region of the client if the target has none)
+	Client *Client
+
+	// Minimum number of instances in service (DefaultMinInService if zero)
+	MinInService int
+
+	// Optional function called instead of refusing chaos events against
+	// groups that are too small, e.g. to print a warning
+	Warn func(err error)
+}
+
+// Check refuses chaos events against groups with fewer instances in service
+// than required, or passes the reason to Warn if set. It also refuses them if
+// the group cannot be retrieved.
+func (g *GroupSizeGuard) Check(t chaosmonkey.Target) error {
+	min := g.MinInService
+	if min == 0 {
+		min = DefaultMinInService
+	}
+	groups, err := g.Client.forRegion(t.Region).describeAutoScalingGroups(context.Background(), &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(t.AutoScalingGroupName)},
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get auto scaling group: %s", err)
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("failed to get auto scaling group: %s", ErrGroupNotFound)
+	}
+	asg := groups[0]
+
+	if asg.InstancesInService >= min {
+		return nil
+	}
+	err = fmt.Errorf("group %s has only %d instances in service, fewer than %d",
+		asg.Name, asg.InstancesInService, min)
+	if g.Warn != nil {
+		g.Warn(err)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// DefaultTrailWindow is how long after a chaos event TerminationRecord looks
+// for the termination of the instance unless configured otherwise.
+const DefaultTrailWindow = 15 * time.Minute
+
+// ErrTerminationNotFound is returned if CloudTrail has no record of the
+// termination of an instance.
+var ErrTerminationNotFound = errors.New("termination not found in CloudTrail")
+
+// TerminationRecord describes the TerminateInstances call recorded by
+// CloudTrail for an instance.
+type TerminationRecord struct {
+	EventID string    `json:"eventId" yaml:"eventId"`
+	Time    time.Time `json:"time" yaml:"time"`
+
+	// ARN of the identity that made the call
+	Caller string `json:"caller" yaml:"caller"`
+
+	SourceIP  string `json:"sourceIp" yaml:"sourceIp"`
+	UserAgent string `json:"userAgent" yaml:"userAgent"`
+	RequestID string `json:"requestId" yaml:"requestId"`
+
+	// Error of the call, if it failed
+	ErrorCode    string `json:"errorCode,omitempty" yaml:"errorCode,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty" yaml:"errorMessage,omitempty"`
+}
+
+// trailEvent holds the used fields of a CloudTrail event record.
+type trailEvent struct {
+	UserIdentity struct {
+		ARN string `json:"arn"`
+	} `json:"userIdentity"`
+	SourceIPAddress string `json:"sourceIPAddress"`
+	UserAgent       string `json:"userAgent"`
+	RequestID       string `json:"requestID"`
+	ErrorCode       string `json:"errorCode"`
+	ErrorMessage    string `json:"errorMessage"`
+}
+
+// TerminationRecord returns the first TerminateInstances call for the
+// instance of a chaos event that CloudTrail recorded within window
+// (DefaultTrailWindow if zero) after the event, which shows who actually
+// terminated the instance. It returns ErrTerminationNotFound if there is no
+// such call, e.g. because the strategy of the event does not terminate
+// instances. As CloudTrail delivers events with a delay of up to 15 minutes,
+// recent terminations may not be found yet.
+func (c *Client) TerminationRecord(ctx context.Context, ev chaosmonkey.Event, window time.Duration) (*TerminationRecord, error) {
+	if window == 0 {
+		window = DefaultTrailWindow
+	}
+	svc, err := c.cloudTrail()
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow for clocks of Chaos Monkey and AWS being slightly off
+	start := ev.TriggeredAt.Add(-time.Minute)
+	end := ev.TriggeredAt.Add(window)
+	var first *cloudtrail.Event
+	err = svc.LookupEventsPagesWithContext(ctx, &cloudtrail.LookupEventsInput{
+		LookupAttributes: []*cloudtrail.LookupAttribute{{
+			AttributeKey:   aws.String(cloudtrail.LookupAttributeKeyResourceName),
+			AttributeValue: aws.String(ev.InstanceID),
+		}},
+		StartTime: aws.Time(start),
+		EndTime:   aws.Time(end),
+	}, func(out *cloudtrail.LookupEventsOutput, last bool) bool {
+		for _, e := range out.Events {
+			if aws.StringValue(e.EventName) != "TerminateInstances" {
+				continue
+			}
+			if first == nil || aws.TimeValue(e.EventTime).Before(aws.TimeValue(first.EventTime)) {
+				first = e
+			}
+		}
+		return !last
+	})
+	if err != nil {
+		return nil, err
+	}
+	if first == nil {
+		return nil, ErrTerminationNotFound
+	}
+
+	rec := &TerminationRecord{
+		EventID: aws.StringValue(first.EventId),
+		Time:    aws.TimeValue(first.EventTime).UTC(),
+		Caller:  aws.StringValue(first.Username),
+	}
+	var te trailEvent
+	if err := json.Unmarshal([]byte(aws.StringValue(first.CloudTrailEvent)), &te); err == nil {
+		if te.UserIdentity.ARN != "" {
+			rec.Caller = te.UserIdentity.ARN
+		}
+		rec.SourceIP = te.SourceIPAddress
+		rec.UserAgent = te.UserAgent
+		rec.RequestID = te.RequestID
+		rec.ErrorCode = te.ErrorCode
+		rec.ErrorMessage = te.ErrorMessage
+	}
+	return rec, nil
+}
@@ -0,0 +1,179 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/pricing"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// DefaultReplacementTime is how long a replacement instance is assumed to be
+// billed before it serves traffic unless configured otherwise.
+const DefaultReplacementTime = 10 * time.Minute
+
+// CostEstimate is the approximate compute cost of replacing instances
+// terminated by chaos events.
+type CostEstimate struct {
+	InstanceType string `json:"instanceType" yaml:"instanceType"`
+
+	// On-demand price per instance hour in USD
+	HourlyPrice float64 `json:"hourlyPrice" yaml:"hourlyPrice"`
+
+	// Number of replaced instances
+	Replacements int `json:"replacements" yaml:"replacements"`
+
+	// Estimated cost in USD
+	Cost float64 `json:"cost" yaml:"cost"`
+}
+
+// CostEstimator estimates the cost of chaos events as the on-demand price of
+// replacement instances for the time they boot and warm up, during which
+// they are paid for without serving. Spot discounts, EBS volumes, and data
+// transfer are ignored.
+type CostEstimator struct {
+	// Client used to retrieve instance types and prices, which determines
+	// the region
+	Client *Client
+
+	// Optional hourly prices in USD by instance type, which take precedence
+	// over the AWS Price List API. As the API is not available in GovCloud,
+	// prices must be given there.
+	Prices map[string]float64
+
+	// Time a replacement instance is paid for before serving
+	// (DefaultReplacementTime if zero)
+	ReplacementTime time.Duration
+
+	mu     sync.Mutex
+	cached map[string]float64
+}
+
+// EstimateEvent estimates the cost of a triggered chaos event. The instance
+// type is taken from the terminated instance while EC2 still describes it,
+// and from the event's auto scaling group otherwise.
+func (e *CostEstimator) EstimateEvent(ctx context.Context, ev chaosmonkey.Event) (*CostEstimate, error) {
+	var instanceType string
+	if inst, err := e.Client.describeInstance(ctx, ev.InstanceID); err == nil {
+		instanceType = aws.StringValue(inst.InstanceType)
+	}
+	if instanceType == "" {
+		return e.EstimateTrigger(ctx, ev.AutoScalingGroupName, 1)
+	}
+	return e.estimate(ctx, instanceType, 1)
+}
+
+// EstimateTrigger estimates the cost of triggering count chaos events against
+// the given auto scaling group, e.g. before running a campaign. Groups with
+// several instance types are estimated with the first one.
+func (e *CostEstimator) EstimateTrigger(ctx context.Context, group string, count int) (*CostEstimate, error) {
+	g, err := e.Client.AutoScalingGroup(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.InstanceTypes) == 0 {
+		return nil, fmt.Errorf("instance type of group %s unknown", group)
+	}
+	return e.estimate(ctx, g.InstanceTypes[0], count)
+}
+
+func (e *CostEstimator) estimate(ctx context.Context, instanceType string, count int) (*CostEstimate, error) {
+	price, err := e.HourlyPrice(ctx, instanceType)
+	if err != nil {
+		return nil, err
+	}
+	d := e.ReplacementTime
+	if d == 0 {
+		d = DefaultReplacementTime
+	}
+	return &CostEstimate{
+		InstanceType: instanceType,
+		HourlyPrice:  price,
+		Replacements: count,
+		Cost:         price * d.Hours() * float64(count),
+	}, nil
+}
+
+// HourlyPrice returns the on-demand price per hour in USD of a Linux instance
+// of the given type in the client's region. Prices retrieved from the Price
+// List API are cached.
+func (e *CostEstimator) HourlyPrice(ctx context.Context, instanceType string) (float64, error) {
+	if p, ok := e.Prices[instanceType]; ok {
+		return p, nil
+	}
+	e.mu.Lock()
+	p, ok := e.cached[instanceType]
+	e.mu.Unlock()
+	if ok {
+		return p, nil
+	}
+
+	svc, err := e.Client.pricing()
+	if err != nil {
+		return 0, err
+	}
+	filter := func(field, value string) *pricing.Filter {
+		return &pricing.Filter{
+			Type:  aws.String(pricing.FilterTypeTermMatch),
+			Field: aws.String(field),
+			Value: aws.String(value),
+		}
+	}
+	out, err := svc.GetProductsWithContext(ctx, &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters: []*pricing.Filter{
+			filter("instanceType", instanceType),
+			filter("regionCode", e.Client.Region),
+			filter("operatingSystem", "Linux"),
+			filter("tenancy", "Shared"),
+			filter("preInstalledSw", "NA"),
+			filter("capacitystatus", "Used"),
+		},
+		MaxResults: aws.Int64(1),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get price of %s: %s", instanceType, err)
+	}
+	if len(out.PriceList) == 0 {
+		return 0, fmt.Errorf("no price found for %s in %s", instanceType, e.Client.Region)
+	}
+	p, ok = onDemandPrice(out.PriceList[0])
+	if !ok {
+		return 0, fmt.Errorf("no on-demand price found for %s in %s", instanceType, e.Client.Region)
+	}
+
+	e.mu.Lock()
+	if e.cached == nil {
+		e.cached = make(map[string]float64)
+	}
+	e.cached[instanceType] = p
+	e.mu.Unlock()
+	return p, nil
+}
+
+// onDemandPrice extracts the hourly USD price from a product of the Price
+// List API, which looks like:
+//
+//	{"terms": {"OnDemand": {"<offer>": {"priceDimensions": {"<rate>": {"pricePerUnit": {"USD": "0.0960000000"}}}}}}}
+func onDemandPrice(product aws.JSONValue) (float64, bool) {
+	terms, _ := product["terms"].(map[string]interface{})
+	offers, _ := terms["OnDemand"].(map[string]interface{})
+	for _, offer := range offers {
+		o, _ := offer.(map[string]interface{})
+		dimensions, _ := o["priceDimensions"].(map[string]interface{})
+		for _, dim := range dimensions {
+			d, _ := dim.(map[string]interface{})
+			prices, _ := d["pricePerUnit"].(map[string]interface{})
+			usd, _ := prices["USD"].(string)
+			if p, err := strconv.ParseFloat(usd, 64); err == nil {
+				return p, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,236 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// Kinds of deployments returned by ActiveDeployments.
+const (
+	DeploymentInstanceRefresh = "instance-refresh"
+	DeploymentCodeDeploy      = "codedeploy"
+)
+
+// maxBatchGetDeployments is the maximum number of deployments per
+// BatchGetDeployments call.
+const maxBatchGetDeployments = 25
+
+// Deployment is a deployment to an auto scaling group that is underway.
+type Deployment struct {
+	// Kind of deployment, e.g. DeploymentInstanceRefresh
+	Kind string `json:"kind" yaml:"kind"`
+
+	// ID of the instance refresh or CodeDeploy deployment
+	ID string `json:"id" yaml:"id"`
+
+	// Status of the deployment, e.g. "InProgress"
+	Status string `json:"status" yaml:"status"`
+
+	StartTime time.Time `json:"startTime" yaml:"startTime"`
+
+	// Application and deployment group of CodeDeploy deployments
+	Application     string `json:"application,omitempty" yaml:"application,omitempty"`
+	DeploymentGroup string `json:"deploymentGroup,omitempty" yaml:"deploymentGroup,omitempty"`
+}
+
+func (d Deployment) String() string {
+	if d.Kind == DeploymentCodeDeploy {
+		return fmt.Sprintf("CodeDeploy deployment %s of %s/%s (%s)", d.ID, d.Application, d.DeploymentGroup, d.Status)
+	}
+	return fmt.Sprintf("instance refresh %s (%s)", d.ID, d.Status)
+}
+
+// activeInstanceRefreshStatuses are the statuses of instance refreshes that
+// are still replacing instances. Refreshes with a bake time are "Baking"
+// after replacing all instances, which the SDK has no constant for.
+var activeInstanceRefreshStatuses = map[string]bool{
+	autoscaling.InstanceRefreshStatusPending:            true,
+	autoscaling.InstanceRefreshStatusInProgress:         true,
+	autoscaling.InstanceRefreshStatusCancelling:         true,
+	autoscaling.InstanceRefreshStatusRollbackInProgress: true,
+	"Baking": true,
+}
+
+// activeCodeDeployStatuses are the statuses of CodeDeploy deployments that
+// have not finished yet.
+var activeCodeDeployStatuses = []string{
+	codedeploy.DeploymentStatusCreated,
+	codedeploy.DeploymentStatusQueued,
+	codedeploy.DeploymentStatusInProgress,
+	codedeploy.DeploymentStatusBaking,
+	codedeploy.DeploymentStatusReady,
+}
+
+// ActiveDeployments returns the instance refreshes and CodeDeploy
+// deployments that are underway on the given auto scaling group. Failures
+// during a deployment are hard to tell from those caused by chaos.
+func (c *Client) ActiveDeployments(ctx context.Context, group string) ([]Deployment, error) {
+	refreshes, err := c.activeInstanceRefreshes(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	deployments, err := c.activeCodeDeployments(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	return append(refreshes, deployments...), nil
+}
+
+func (c *Client) activeInstanceRefreshes(ctx context.Context, group string) ([]Deployment, error) {
+	svc, err := c.autoScaling()
+	if err != nil {
+		return nil, err
+	}
+	// Refreshes are returned newest first and only one can be active at a
+	// time, so the first page suffices
+	out, err := svc.DescribeInstanceRefreshesWithContext(ctx, &autoscaling.DescribeInstanceRefreshesInput{
+		AutoScalingGroupName: aws.String(group),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instance refreshes: %s", err)
+	}
+	var deployments []Deployment
+	for _, r := range out.InstanceRefreshes {
+		if !activeInstanceRefreshStatuses[aws.StringValue(r.Status)] {
+			continue
+		}
+		deployments = append(deployments, Deployment{
+			Kind:      DeploymentInstanceRefresh,
+			ID:        aws.StringValue(r.InstanceRefreshId),
+			Status:    aws.StringValue(r.Status),
+			StartTime: aws.TimeValue(r.StartTime).UTC(),
+		})
+	}
+	return deployments, nil
+}
+
+func (c *Client) activeCodeDeployments(ctx context.Context, group string) ([]Deployment, error) {
+	svc, err := c.codeDeploy()
+	if err != nil {
+		return nil, err
+	}
+	var ids []*string
+	err = svc.ListDeploymentsPagesWithContext(ctx, &codedeploy.ListDeploymentsInput{
+		IncludeOnlyStatuses: aws.StringSlice(activeCodeDeployStatuses),
+	}, func(out *codedeploy.ListDeploymentsOutput, last bool) bool {
+		ids = append(ids, out.Deployments...)
+		return !last
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CodeDeploy deployments: %s", err)
+	}
+
+	// Auto scaling groups of deployment groups by "application/group"
+	groups := make(map[string][]string)
+	var deployments []Deployment
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxBatchGetDeployments {
+			n = maxBatchGetDeployments
+		}
+		out, err := svc.BatchGetDeploymentsWithContext(ctx, &codedeploy.BatchGetDeploymentsInput{
+			DeploymentIds: ids[:n],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get CodeDeploy deployments: %s", err)
+		}
+		ids = ids[n:]
+
+		for _, d := range out.DeploymentsInfo {
+			app, dg := aws.StringValue(d.ApplicationName), aws.StringValue(d.DeploymentGroupName)
+			var targets []string
+			if d.TargetInstances != nil {
+				targets = aws.StringValueSlice(d.TargetInstances.AutoScalingGroups)
+			}
+			key := app + "/" + dg
+			asgs, ok := groups[key]
+			if !ok {
+				out, err := svc.GetDeploymentGroupWithContext(ctx, &codedeploy.GetDeploymentGroupInput{
+					ApplicationName:     aws.String(app),
+					DeploymentGroupName: aws.String(dg),
+				})
+				if err != nil {
+					return nil, fmt.Errorf("failed to get CodeDeploy deployment group %s: %s", key, err)
+				}
+				if out.DeploymentGroupInfo != nil {
+					for _, g := range out.DeploymentGroupInfo.AutoScalingGroups {
+						asgs = append(asgs, aws.StringValue(g.Name))
+					}
+				}
+				groups[key] = asgs
+			}
+			if !contains(targets, group) && !contains(asgs, group) {
+				continue
+			}
+			start := d.StartTime
+			if start == nil {
+				start = d.CreateTime
+			}
+			deployments = append(deployments, Deployment{
+				Kind:            DeploymentCodeDeploy,
+				ID:              aws.StringValue(d.DeploymentId),
+				Status:          aws.StringValue(d.Status),
+				StartTime:       aws.TimeValue(start).UTC(),
+				Application:     app,
+				DeploymentGroup: dg,
+			})
+		}
+	}
+	return deployments, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// DeploymentGuard is a Guard that postpones chaos events against auto
+// scaling groups while a deployment is underway, as failures during a
+// deployment cannot be attributed to either.
+type DeploymentGuard struct {
+	// Client used to retrieve deployments in the region of the target
+	// (the region of the client if the target has none)
+	Client *Client
+
+	// Whether to only check instance refreshes, e.g. if CodeDeploy is not
+	// used or cannot be accessed
+	InstanceRefreshesOnly bool
+}
+
+// Check refuses chaos events against groups with active deployments. It
+// also refuses them if the deployments cannot be retrieved.
+func (g *DeploymentGuard) Check(t chaosmonkey.Target) error {
+	ctx := context.Background()
+	var (
+		deployments []Deployment
+		err         error
+	)
+	if g.InstanceRefreshesOnly {
+		deployments, err = g.Client.forRegion(t.Region).activeInstanceRefreshes(ctx, t.AutoScalingGroupName)
+	} else {
+		deployments, err = g.Client.forRegion(t.Region).ActiveDeployments(ctx, t.AutoScalingGroupName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check deployments: %s", err)
+	}
+	if len(deployments) == 0 {
+		return nil
+	}
+	var names []string
+	for _, d := range deployments {
+		names = append(names, d.String())
+	}
+	return fmt.Errorf("group %s is being deployed: %s", t.AutoScalingGroupName, strings.Join(names, ", "))
+}
@@ -0,0 +1,206 @@
+// Package ecs injects chaos into Amazon ECS clusters by stopping random tasks
+// of services or draining random container instances. Like Chaos Monkey, it
+// reports each chaos event as chaosmonkey.Event, so that ECS chaos shows up
+// in the same reports as EC2 chaos.
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+
+	chaosaws "github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+const (
+	// StrategyStopTask stops a running task of an ECS service, which is
+	// then replaced by the service scheduler.
+	StrategyStopTask chaosmonkey.Strategy = "StopEcsTask"
+
+	// StrategyDrainInstance drains an ECS container instance, which makes
+	// the service scheduler move its tasks to other container instances.
+	StrategyDrainInstance chaosmonkey.Strategy = "DrainEcsInstance"
+)
+
+// API contains the used operations of ECS.
+type API interface {
+	ListTasksPagesWithContext(aws.Context, *ecsapi.ListTasksInput, func(*ecsapi.ListTasksOutput, bool) bool, ...request.Option) error
+	StopTaskWithContext(aws.Context, *ecsapi.StopTaskInput, ...request.Option) (*ecsapi.StopTaskOutput, error)
+	ListContainerInstancesPagesWithContext(aws.Context, *ecsapi.ListContainerInstancesInput, func(*ecsapi.ListContainerInstancesOutput, bool) bool, ...request.Option) error
+	UpdateContainerInstancesStateWithContext(aws.Context, *ecsapi.UpdateContainerInstancesStateInput, ...request.Option) (*ecsapi.UpdateContainerInstancesStateOutput, error)
+}
+
+// Monkey injects chaos into ECS clusters. Chaos events are against services
+// or clusters, as named by the group of events, and subject to the Chaos
+// client of Client (see chaosaws.Client.Inject).
+type Monkey struct {
+	// Client used to create a session, which determines region and
+	// credentials, and to inject chaos
+	Client *chaosaws.Client
+
+	// Optional ECS client to use instead of one created from Client, e.g. a
+	// mock in tests
+	ECS API
+
+	// Optional source of randomness used to pick victims
+	Rand *rand.Rand
+}
+
+// StopRandomTask stops a random running task of the given service. The
+// returned event has the task ID as instance ID and the service name as
+// group name.
+func (m *Monkey) StopRandomTask(ctx context.Context, cluster, service string) (*chaosmonkey.Event, error) {
+	svc, err := m.ecs()
+	if err != nil {
+		return nil, err
+	}
+	var tasks []string
+	err = svc.ListTasksPagesWithContext(ctx, &ecsapi.ListTasksInput{
+		Cluster:       aws.String(cluster),
+		ServiceName:   aws.String(service),
+		DesiredStatus: aws.String(ecsapi.DesiredStatusRunning),
+	}, func(out *ecsapi.ListTasksOutput, last bool) bool {
+		tasks = append(tasks, aws.StringValueSlice(out.TaskArns)...)
+		return !last
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("service %s has no running tasks", service)
+	}
+
+	task := tasks[m.intn(len(tasks))]
+	return m.inject(ctx, service, StrategyStopTask, func(ctx context.Context) (*chaosmonkey.Event, error) {
+		_, err := svc.StopTaskWithContext(ctx, &ecsapi.StopTaskInput{
+			Cluster: aws.String(cluster),
+			Task:    aws.String(task),
+			Reason:  aws.String("Stopped by chaosmonkey"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to stop task %s: %s", task, err)
+		}
+		return m.event(resourceID(task), service, StrategyStopTask), nil
+	})
+}
+
+// DrainRandomContainerInstance drains a random active container instance of
+// the given cluster. The returned event has the container instance ID as
+// instance ID and the cluster name as group name. Call Activate to undo the
+// draining.
+func (m *Monkey) DrainRandomContainerInstance(ctx context.Context, cluster string) (*chaosmonkey.Event, error) {
+	svc, err := m.ecs()
+	if err != nil {
+		return nil, err
+	}
+	var instances []string
+	err = svc.ListContainerInstancesPagesWithContext(ctx, &ecsapi.ListContainerInstancesInput{
+		Cluster: aws.String(cluster),
+		Status:  aws.String(ecsapi.ContainerInstanceStatusActive),
+	}, func(out *ecsapi.ListContainerInstancesOutput, last bool) bool {
+		instances = append(instances, aws.StringValueSlice(out.ContainerInstanceArns)...)
+		return !last
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch len(instances) {
+	case 0:
+		return nil, fmt.Errorf("cluster %s has no active container instances", cluster)
+	case 1:
+		return nil, fmt.Errorf("cluster %s has only one active container instance", cluster)
+	}
+
+	instance := instances[m.intn(len(instances))]
+	return m.inject(ctx, cluster, StrategyDrainInstance, func(ctx context.Context) (*chaosmonkey.Event, error) {
+		if err := m.setState(ctx, cluster, instance, ecsapi.ContainerInstanceStatusDraining); err != nil {
+			return nil, fmt.Errorf("failed to drain container instance %s: %s", instance, err)
+		}
+		return m.event(resourceID(instance), cluster, StrategyDrainInstance), nil
+	})
+}
+
+// Activate returns a drained container instance to service.
+func (m *Monkey) Activate(ctx context.Context, cluster, instance string) error {
+	if err := m.setState(ctx, cluster, instance, ecsapi.ContainerInstanceStatusActive); err != nil {
+		return fmt.Errorf("failed to activate container instance %s: %s", instance, err)
+	}
+	return nil
+}
+
+func (m *Monkey) setState(ctx context.Context, cluster, instance, status string) error {
+	svc, err := m.ecs()
+	if err != nil {
+		return err
+	}
+	out, err := svc.UpdateContainerInstancesStateWithContext(ctx, &ecsapi.UpdateContainerInstancesStateInput{
+		Cluster:            aws.String(cluster),
+		ContainerInstances: []*string{aws.String(instance)},
+		Status:             aws.String(status),
+	})
+	if err != nil {
+		return err
+	}
+	if len(out.Failures) > 0 {
+		return fmt.Errorf("%s", aws.StringValue(out.Failures[0].Reason))
+	}
+	return nil
+}
+
+// inject causes a chaos event against the service or cluster via the Chaos
+// client of Client, which calls inject if the event passes its checks.
+func (m *Monkey) inject(ctx context.Context, group string, s chaosmonkey.Strategy, inject func(ctx context.Context) (*chaosmonkey.Event, error)) (*chaosmonkey.Event, error) {
+	if m.Client == nil {
+		return nil, chaosaws.ErrNoChaosClient
+	}
+	return m.Client.Inject(ctx, group, s, inject)
+}
+
+func (m *Monkey) event(id, group string, s chaosmonkey.Strategy) *chaosmonkey.Event {
+	var region string
+	if m.Client != nil {
+		region = m.Client.Region
+	}
+	return &chaosmonkey.Event{
+		InstanceID:           id,
+		AutoScalingGroupName: group,
+		Region:               region,
+		Strategy:             s,
+		TriggeredAt:          time.Now().UTC(),
+	}
+}
+
+func (m *Monkey) intn(n int) int {
+	if m.Rand != nil {
+		return m.Rand.Intn(n)
+	}
+	return rand.Intn(n)
+}
+
+func (m *Monkey) ecs() (API, error) {
+	if m.ECS != nil {
+		return m.ECS, nil
+	}
+	sess, err := m.Client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return ecsapi.New(sess), nil
+}
+
+// resourceID returns the last part of an ARN like
+// "arn:aws:ecs:us-east-1:123456789012:task/cluster/0123abcd", which is the
+// ID of the task or container instance. Other strings are returned as is.
+func resourceID(arn string) string {
+	if !strings.HasPrefix(arn, "arn:") {
+		return arn
+	}
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
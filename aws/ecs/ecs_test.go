@@ -0,0 +1,101 @@
+package ecs_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	ecsapi "github.com/aws/aws-sdk-go/service/ecs"
+
+	chaosaws "github.com/FlyLevin/chaosmonkey/aws"
+	"github.com/FlyLevin/chaosmonkey/aws/ecs"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+type fakeECS struct {
+	ecs.API
+	stopped []string
+	states  []string
+}
+
+func (f *fakeECS) ListTasksPagesWithContext(ctx aws.Context, in *ecsapi.ListTasksInput, fn func(*ecsapi.ListTasksOutput, bool) bool, _ ...request.Option) error {
+	fn(&ecsapi.ListTasksOutput{TaskArns: aws.StringSlice([]string{"arn:aws:ecs:us-east-1:123456789012:task/prod/0123abcd"})}, true)
+	return nil
+}
+
+func (f *fakeECS) StopTaskWithContext(ctx aws.Context, in *ecsapi.StopTaskInput, _ ...request.Option) (*ecsapi.StopTaskOutput, error) {
+	f.stopped = append(f.stopped, aws.StringValue(in.Task))
+	return &ecsapi.StopTaskOutput{}, nil
+}
+
+func (f *fakeECS) ListContainerInstancesPagesWithContext(ctx aws.Context, in *ecsapi.ListContainerInstancesInput, fn func(*ecsapi.ListContainerInstancesOutput, bool) bool, _ ...request.Option) error {
+	fn(&ecsapi.ListContainerInstancesOutput{ContainerInstanceArns: aws.StringSlice([]string{"ci-1", "ci-2"})}, true)
+	return nil
+}
+
+func (f *fakeECS) UpdateContainerInstancesStateWithContext(ctx aws.Context, in *ecsapi.UpdateContainerInstancesStateInput, _ ...request.Option) (*ecsapi.UpdateContainerInstancesStateOutput, error) {
+	f.states = append(f.states, aws.StringValue(in.Status))
+	return &ecsapi.UpdateContainerInstancesStateOutput{}, nil
+}
+
+// newMonkey returns a monkey using the fake, which checks chaos events
+// against the given guards.
+func newMonkey(t *testing.T, fake *fakeECS, guards ...chaosmonkey.Guard) *ecs.Monkey {
+	chaos, err := chaosmonkey.NewClient(&chaosmonkey.Config{Guards: guards})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ecs.Monkey{ECS: fake, Client: &chaosaws.Client{Chaos: chaos}}
+}
+
+func TestStopRandomTask(t *testing.T) {
+	fake := &fakeECS{}
+	monkey := newMonkey(t, fake)
+
+	ev, err := monkey.StopRandomTask(context.Background(), "prod", "payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.InstanceID != "0123abcd" || ev.AutoScalingGroupName != "payments" || ev.Strategy != ecs.StrategyStopTask {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+	if len(fake.stopped) != 1 {
+		t.Errorf("expected one stopped task, got %v", fake.stopped)
+	}
+}
+
+func TestDrainRandomContainerInstance(t *testing.T) {
+	fake := &fakeECS{}
+	monkey := newMonkey(t, fake)
+
+	ev, err := monkey.DrainRandomContainerInstance(context.Background(), "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := monkey.Activate(context.Background(), "prod", ev.InstanceID); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.states) != 2 || fake.states[0] != "DRAINING" || fake.states[1] != "ACTIVE" {
+		t.Errorf("expected DRAINING and ACTIVE, got %v", fake.states)
+	}
+}
+
+func TestStopRandomTaskRefused(t *testing.T) {
+	fake := &fakeECS{}
+	monkey := newMonkey(t, fake, chaosmonkey.GuardFunc(func(t chaosmonkey.Target) error {
+		if t.AutoScalingGroupName == "payments" && t.Strategy == ecs.StrategyStopTask {
+			return errors.New("frozen")
+		}
+		return nil
+	}))
+
+	_, err := monkey.StopRandomTask(context.Background(), "prod", "payments")
+	if _, ok := err.(*chaosmonkey.GuardError); !ok {
+		t.Fatalf("expected *GuardError, got %v", err)
+	}
+	if len(fake.stopped) != 0 {
+		t.Errorf("expected no stopped tasks, got %v", fake.stopped)
+	}
+}
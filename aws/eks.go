@@ -0,0 +1,53 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Tags by which EKS marks the auto scaling groups backing managed node
+// groups.
+const (
+	TagEKSCluster   = "eks:cluster-name"
+	TagEKSNodeGroup = "eks:nodegroup-name"
+)
+
+// NodeGroup returns the EKS cluster and managed node group backed by the
+// auto scaling group, if any.
+func (g *AutoScalingGroup) NodeGroup() (cluster, nodegroup string, ok bool) {
+	cluster, nodegroup = g.Tags[TagEKSCluster], g.Tags[TagEKSNodeGroup]
+	return cluster, nodegroup, cluster != "" && nodegroup != ""
+}
+
+// NodeGroups returns the auto scaling groups backing the managed node groups
+// of the given EKS cluster.
+func (c *Client) NodeGroups(ctx context.Context, cluster string) ([]AutoScalingGroup, error) {
+	return c.FilterAutoScalingGroups(ctx, GroupFilter{
+		Tags: map[string]string{TagEKSCluster: cluster, TagEKSNodeGroup: ""},
+	})
+}
+
+// NodeGroupAutoScalingGroup returns the name of the auto scaling group
+// backing the given managed node group, which allows targeting node groups
+// by name.
+func (c *Client) NodeGroupAutoScalingGroup(ctx context.Context, cluster, nodegroup string) (string, error) {
+	groups, err := c.FilterAutoScalingGroups(ctx, GroupFilter{
+		Tags: map[string]string{TagEKSCluster: cluster, TagEKSNodeGroup: nodegroup},
+	})
+	if err != nil {
+		return "", err
+	}
+	switch len(groups) {
+	case 0:
+		return "", fmt.Errorf("node group %s of EKS cluster %s not found", nodegroup, cluster)
+	case 1:
+		return groups[0].Name, nil
+	}
+	var names []string
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	return "", fmt.Errorf("node group %s of EKS cluster %s is backed by several auto scaling groups: %s",
+		nodegroup, cluster, strings.Join(names, ", "))
+}
@@ -0,0 +1,140 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// Tags by which auto scaling groups opt in to or out of chaos.
+const (
+	// Groups with chaos=true opt in, groups with chaos=false opt out
+	TagChaos = "chaos"
+
+	// Groups with this tag opt out, regardless of its value
+	TagChaosExempt = "chaos-exempt"
+)
+
+// DefaultExemptTags are the tags by which ExemptionGuard recognizes critical
+// groups and instances unless configured otherwise.
+var DefaultExemptTags = []string{"criticality=tier0"}
+
+// ChaosExempt reports whether an auto scaling group is exempt from chaos
+// according to its tags. A group is exempt if it has the tag chaos-exempt or
+// chaos=false. If optIn is true, groups without chaos=true are exempt too.
+func ChaosExempt(g *AutoScalingGroup, optIn bool) bool {
+	if _, ok := g.Tags[TagChaosExempt]; ok {
+		return true
+	}
+	v, ok := g.Tags[TagChaos]
+	switch {
+	case ok && strings.EqualFold(v, "false"):
+		return true
+	case optIn:
+		return !ok || !strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+// ChaosEligibility splits all auto scaling groups into the ones eligible for
+// chaos and the exempt ones, see ChaosExempt. This allows external
+// schedulers to follow the same opt-in conventions as Chaos Monkey.
+func (c *Client) ChaosEligibility(ctx context.Context, optIn bool) (eligible, exempt []AutoScalingGroup, err error) {
+	err = c.AutoScalingGroupsPages(ctx, func(groups []AutoScalingGroup) bool {
+		for _, g := range groups {
+			if ChaosExempt(&g, optIn) {
+				exempt = append(exempt, g)
+			} else {
+				eligible = append(eligible, g)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return eligible, exempt, nil
+}
+
+// ExemptionGuard is a Guard that refuses chaos events against auto scaling
+// groups that are exempt from chaos according to the tags of the group or any
+// of its instances, regardless of other options like allowed groups or
+// approvals. Besides the tags of ChaosExempt, it respects tags marking
+// critical groups, e.g. criticality=tier0.
+type ExemptionGuard struct {
+	// Client used to retrieve tags in the region of the target
+	// (the region of the client if the target has none)
+	Client *Client
+
+	// Tags marking groups and instances as exempt, given as key or
+	// key=value, where values are compared case-insensitively
+	// (DefaultExemptTags if nil)
+	Tags []string
+
+	// Whether groups without chaos=true are exempt too, see ChaosExempt
+	OptIn bool
+}
+
+// Check refuses chaos events against exempt groups. It also refuses them if
+// the tags cannot be retrieved.
+func (g *ExemptionGuard) Check(t chaosmonkey.Target) error {
+	ctx := context.Background()
+	client := g.Client.forRegion(t.Region)
+	groups, err := client.describeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(t.AutoScalingGroupName)},
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get tags of group %s: %s", t.AutoScalingGroupName, err)
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("failed to get tags of group %s: %s", t.AutoScalingGroupName, ErrGroupNotFound)
+	}
+	if ChaosExempt(&groups[0], g.OptIn) {
+		return fmt.Errorf("group %s is exempt from chaos by its tags", t.AutoScalingGroupName)
+	}
+	if tag := g.exemptTag(groups[0].Tags); tag != "" {
+		return fmt.Errorf("group %s is exempt from chaos by its tag %s", t.AutoScalingGroupName, tag)
+	}
+
+	instances, err := client.Instances(ctx, t.AutoScalingGroupName)
+	if err != nil {
+		return fmt.Errorf("failed to get tags of instances of group %s: %s", t.AutoScalingGroupName, err)
+	}
+	var exempt []string
+	for _, i := range instances {
+		if ChaosExempt(&AutoScalingGroup{Tags: i.Tags}, false) || g.exemptTag(i.Tags) != "" {
+			exempt = append(exempt, i.ID)
+		}
+	}
+	if len(exempt) > 0 {
+		sort.Strings(exempt)
+		return fmt.Errorf("group %s has instances exempt from chaos by their tags: %s",
+			t.AutoScalingGroupName, strings.Join(exempt, ", "))
+	}
+	return nil
+}
+
+// exemptTag returns the first of the configured exempt tags found in tags, or
+// an empty string if there is none.
+func (g *ExemptionGuard) exemptTag(tags map[string]string) string {
+	exemptTags := g.Tags
+	if exemptTags == nil {
+		exemptTags = DefaultExemptTags
+	}
+	for _, tag := range exemptTags {
+		key, value := tag, ""
+		if i := strings.Index(tag, "="); i >= 0 {
+			key, value = tag[:i], tag[i+1:]
+		}
+		if v, ok := tags[key]; ok && (value == "" || strings.EqualFold(v, value)) {
+			return tag
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// maxFilterValues is the maximum number of values of an EC2 filter.
+const maxFilterValues = 200
+
+// EnrichEvents sets the Instance of chaos events to the availability zone,
+// type, AMI, launch time, and tags of their instance, so that the client can
+// be used as chaosmonkey.EventEnricher. Events of other regions than the
+// client's and events whose instance EC2 does not describe anymore, which
+// happens about an hour after termination, are left as they are.
+func (c *Client) EnrichEvents(ctx context.Context, events []chaosmonkey.Event) error {
+	byID := make(map[string][]int)
+	var ids []*string
+	for i, ev := range events {
+		if ev.InstanceID == "" || (ev.Region != "" && c.Region != "" && ev.Region != c.Region) {
+			continue
+		}
+		if _, ok := byID[ev.InstanceID]; !ok {
+			ids = append(ids, aws.String(ev.InstanceID))
+		}
+		byID[ev.InstanceID] = append(byID[ev.InstanceID], i)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	svc, err := c.ec2()
+	if err != nil {
+		return err
+	}
+
+	// Filter rather than pass instance IDs, which fails if any instance is
+	// unknown
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxFilterValues {
+			n = maxFilterValues
+		}
+		err := svc.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{{
+				Name:   aws.String("instance-id"),
+				Values: ids[:n],
+			}},
+		}, func(out *ec2.DescribeInstancesOutput, last bool) bool {
+			for _, r := range out.Reservations {
+				for _, inst := range r.Instances {
+					details := newInstanceDetails(inst)
+					for _, i := range byID[aws.StringValue(inst.InstanceId)] {
+						events[i].Instance = details
+					}
+				}
+			}
+			return !last
+		})
+		if err != nil {
+			return err
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
+
+func newInstanceDetails(inst *ec2.Instance) *chaosmonkey.InstanceDetails {
+	details := &chaosmonkey.InstanceDetails{
+		InstanceType: aws.StringValue(inst.InstanceType),
+		ImageID:      aws.StringValue(inst.ImageId),
+		LaunchTime:   aws.TimeValue(inst.LaunchTime).UTC(),
+	}
+	if inst.Placement != nil {
+		details.AvailabilityZone = aws.StringValue(inst.Placement.AvailabilityZone)
+	}
+	if len(inst.Tags) > 0 {
+		details.Tags = make(map[string]string, len(inst.Tags))
+		for _, t := range inst.Tags {
+			details.Tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+		}
+	}
+	return details
+}
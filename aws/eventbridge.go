@@ -0,0 +1,124 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+
+	"github.com/FlyLevin/chaosmonkey/gameday"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// DefaultEventSource is the source of events emitted by EventBridgeEmitter
+// unless configured otherwise.
+const DefaultEventSource = "chaosmonkey"
+
+// Detail types of events emitted by EventBridgeEmitter, by notification
+// type.
+var detailTypes = map[string]string{
+	NotificationEvent:        "Chaos Event Triggered",
+	NotificationVerification: "Chaos Event Verified",
+	NotificationBlocked:      "Chaos Event Failed",
+	NotificationHalted:       "Chaos Experiment Halted",
+	NotificationGameDay:      "Chaos GameDay Scheduled",
+}
+
+// EventBridgeEmitter emits chaos lifecycle events onto an EventBridge bus,
+// so that rules can react to chaos in real time. Events have the following
+// detail types, and a Notification as JSON detail:
+//
+//	Chaos Event Triggered    a chaos event was triggered
+//	Chaos Event Verified     a termination was verified, see VerifyTermination
+//	Chaos Event Failed       a chaos event was refused, see the reason
+//	Chaos Experiment Halted  a campaign was aborted, see the reason
+//	Chaos GameDay Scheduled  a GameDay was scheduled, see gameday.Planner
+//
+// A rule matching all of them looks like:
+//
+//	{"source": ["chaosmonkey"], "detail-type": [{"prefix": "Chaos "}]}
+type EventBridgeEmitter struct {
+	// Client used to emit events, which determines the region
+	Client *Client
+
+	// Name or ARN of the event bus (the default bus if empty)
+	BusName string
+
+	// Source of the events (DefaultEventSource if empty)
+	Source string
+
+	// Optional function called with errors of events emitted by Subscribe,
+	// which would go unnoticed otherwise
+	OnError func(err error)
+}
+
+// Subscribe emits an event for every chaos event recorded or refused and
+// every experiment halted on the bus. It returns a function that stops
+// emitting.
+func (e *EventBridgeEmitter) Subscribe(bus *chaosmonkey.Bus) (stop func()) {
+	return subscribeNotifications(bus, func(n Notification) {
+		if err := e.Emit(context.Background(), n); err != nil && e.OnError != nil {
+			e.OnError(err)
+		}
+	})
+}
+
+// EmitVerification emits the result of VerifyTermination.
+func (e *EventBridgeEmitter) EmitVerification(ctx context.Context, res *VerificationResult) error {
+	return e.Emit(ctx, Notification{
+		Type:                 NotificationVerification,
+		Time:                 time.Now().UTC(),
+		AutoScalingGroupName: res.AutoScalingGroupName,
+		Verification:         res,
+	})
+}
+
+// Announce emits the announcement of a scheduled GameDay, which implements
+// gameday.Announcer.
+func (e *EventBridgeEmitter) Announce(ctx context.Context, gd *gameday.GameDay) error {
+	return e.Emit(ctx, gameDayNotification(gd))
+}
+
+// Emit emits a notification as event with the detail type of its type.
+func (e *EventBridgeEmitter) Emit(ctx context.Context, n Notification) error {
+	detailType, ok := detailTypes[n.Type]
+	if !ok {
+		return fmt.Errorf("unknown notification type %q", n.Type)
+	}
+	detail, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	svc, err := e.Client.eventBridge()
+	if err != nil {
+		return err
+	}
+	source := e.Source
+	if source == "" {
+		source = DefaultEventSource
+	}
+	entry := &eventbridge.PutEventsRequestEntry{
+		Source:     aws.String(source),
+		DetailType: aws.String(detailType),
+		Detail:     aws.String(string(detail)),
+	}
+	if e.BusName != "" {
+		entry.EventBusName = aws.String(e.BusName)
+	}
+	if !n.Time.IsZero() {
+		entry.Time = aws.Time(n.Time)
+	}
+	out, err := svc.PutEventsWithContext(ctx, &eventbridge.PutEventsInput{
+		Entries: []*eventbridge.PutEventsRequestEntry{entry},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to emit event to EventBridge: %s", err)
+	}
+	if aws.Int64Value(out.FailedEntryCount) > 0 && len(out.Entries) > 0 {
+		return fmt.Errorf("failed to emit event to EventBridge: %s", aws.StringValue(out.Entries[0].ErrorMessage))
+	}
+	return nil
+}
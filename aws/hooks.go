@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// TransitionInstanceTerminating is the transition of lifecycle hooks that
+// pause the termination of instances.
+const TransitionInstanceTerminating = "autoscaling:EC2_INSTANCE_TERMINATING"
+
+// LifecycleHook describes a lifecycle hook of an auto scaling group, which
+// pauses instances while they launch or terminate until the hook is
+// completed or times out.
+type LifecycleHook struct {
+	Name string `json:"name" yaml:"name"`
+
+	// Transition that the hook pauses, e.g. TransitionInstanceTerminating
+	Transition string `json:"transition" yaml:"transition"`
+
+	// Time after which the hook times out unless its heartbeat is recorded
+	HeartbeatTimeout time.Duration `json:"heartbeatTimeout" yaml:"heartbeatTimeout"`
+
+	// Maximum time an instance can remain paused by the hook
+	GlobalTimeout time.Duration `json:"globalTimeout" yaml:"globalTimeout"`
+
+	// Action taken when the hook times out, "CONTINUE" or "ABANDON"
+	DefaultResult string `json:"defaultResult" yaml:"defaultResult"`
+}
+
+// Terminating reports whether the hook delays the termination of instances.
+func (h *LifecycleHook) Terminating() bool {
+	return h.Transition == TransitionInstanceTerminating
+}
+
+// LifecycleHooks returns the lifecycle hooks of the given auto scaling group.
+// Hooks that pause termination delay the replacement of instances terminated
+// by chaos events by up to their heartbeat timeout, and so skew recovery
+// times.
+func (c *Client) LifecycleHooks(ctx context.Context, group string) ([]LifecycleHook, error) {
+	svc, err := c.autoScaling()
+	if err != nil {
+		return nil, err
+	}
+	out, err := svc.DescribeLifecycleHooksWithContext(ctx, &autoscaling.DescribeLifecycleHooksInput{
+		AutoScalingGroupName: aws.String(group),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var hooks []LifecycleHook
+	for _, h := range out.LifecycleHooks {
+		hooks = append(hooks, LifecycleHook{
+			Name:             aws.StringValue(h.LifecycleHookName),
+			Transition:       aws.StringValue(h.LifecycleTransition),
+			HeartbeatTimeout: time.Duration(aws.Int64Value(h.HeartbeatTimeout)) * time.Second,
+			GlobalTimeout:    time.Duration(aws.Int64Value(h.GlobalTimeout)) * time.Second,
+			DefaultResult:    aws.StringValue(h.DefaultResult),
+		})
+	}
+	return hooks, nil
+}
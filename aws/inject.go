@@ -0,0 +1,70 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// ErrNoChaosClient is returned by functions injecting chaos themselves, such
+// as BlockNetwork or FailZone, if Client.Chaos is not set.
+var ErrNoChaosClient = errors.New("injecting chaos requires Client.Chaos")
+
+// tagGroupName is the tag EC2 Auto Scaling adds to the instances of a group.
+const tagGroupName = "aws:autoscaling:groupName"
+
+// Inject causes a chaos event against the auto scaling group by calling
+// inject if the event passes the checks of Client.Chaos, like the kill switch
+// and guards, which also records the event. It returns ErrNoChaosClient if
+// Client.Chaos is not set. inject is not called in a dry run, for which the
+// returned event has DryRun set.
+func (c *Client) Inject(ctx context.Context, group string, strategy chaosmonkey.Strategy, inject func(ctx context.Context) (*chaosmonkey.Event, error)) (*chaosmonkey.Event, error) {
+	if c.Chaos == nil {
+		return nil, ErrNoChaosClient
+	}
+	return c.Chaos.Inject(ctx, group, strategy, c.Region, inject)
+}
+
+// InstanceGroup returns the name of the auto scaling group of an EC2
+// instance, or the ID of the instance if it is not part of a group, which
+// is the target of chaos injected into the instance.
+func (c *Client) InstanceGroup(ctx context.Context, instanceID string) (string, error) {
+	inst, err := c.describeInstance(ctx, instanceID)
+	if err != nil {
+		return "", err
+	}
+	return instanceGroup(inst), nil
+}
+
+// InstancesGroup is like InstanceGroup but returns the group of several
+// instances, which must all be part of it.
+func (c *Client) InstancesGroup(ctx context.Context, instanceIDs []string) (string, error) {
+	var group string
+	for _, id := range instanceIDs {
+		g, err := c.InstanceGroup(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		if group != "" && g != group {
+			return "", fmt.Errorf("instances of different groups given: %s and %s", group, g)
+		}
+		group = g
+	}
+	return group, nil
+}
+
+// instanceGroup returns the name of the auto scaling group of the instance,
+// or its ID if it is not part of a group.
+func instanceGroup(inst *ec2.Instance) string {
+	for _, t := range inst.Tags {
+		if aws.StringValue(t.Key) == tagGroupName {
+			return aws.StringValue(t.Value)
+		}
+	}
+	return aws.StringValue(inst.InstanceId)
+}
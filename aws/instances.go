@@ -0,0 +1,285 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// Instance describes an EC2 instance that belongs to an auto scaling group.
+type Instance struct {
+	ID                   string            `json:"id" yaml:"id"`
+	AutoScalingGroupName string            `json:"autoScalingGroupName" yaml:"autoScalingGroupName"`
+	LifecycleState       string            `json:"lifecycleState" yaml:"lifecycleState"`
+	AvailabilityZone     string            `json:"availabilityZone" yaml:"availabilityZone"`
+	InstanceType         string            `json:"instanceType" yaml:"instanceType"`
+	Lifecycle            string            `json:"lifecycle" yaml:"lifecycle"`
+	LaunchTime           time.Time         `json:"launchTime" yaml:"launchTime"`
+	ProtectedFromScaleIn bool              `json:"protectedFromScaleIn,omitempty" yaml:"protectedFromScaleIn,omitempty"`
+	SecurityGroups       []string          `json:"securityGroups,omitempty" yaml:"securityGroups,omitempty"`
+	Tags                 map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// Instance lifecycles, i.e. how instances are purchased.
+const (
+	LifecycleOnDemand  = "on-demand"
+	LifecycleSpot      = "spot"
+	LifecycleScheduled = "scheduled"
+)
+
+// Selector decides whether an instance may be a victim of chaos.
+type Selector func(i Instance) bool
+
+// ExcludeTag returns a selector that rejects instances with the given tag,
+// e.g. ExcludeTag("do-not-kill", "") or ExcludeTag("canary", "true"). An
+// empty value matches any value.
+func ExcludeTag(key, value string) Selector {
+	return func(i Instance) bool {
+		v, ok := i.Tags[key]
+		return !ok || (value != "" && v != value)
+	}
+}
+
+// RequireTag returns a selector that only accepts instances with the given
+// tag. An empty value matches any value.
+func RequireTag(key, value string) Selector {
+	return func(i Instance) bool {
+		v, ok := i.Tags[key]
+		return ok && (value == "" || v == value)
+	}
+}
+
+// ExcludeSpot returns a selector that rejects spot instances, which already
+// experience natural chaos through interruptions.
+func ExcludeSpot() Selector {
+	return func(i Instance) bool {
+		return i.Lifecycle != LifecycleSpot
+	}
+}
+
+// RequireSpot returns a selector that only accepts spot instances.
+func RequireSpot() Selector {
+	return func(i Instance) bool {
+		return i.Lifecycle == LifecycleSpot
+	}
+}
+
+// Instances returns the instances of the given auto scaling group together
+// with their launch time, lifecycle, and EC2 tags, which are not part of the
+// group description. It returns nil if the group does not exist or is empty.
+func (c *Client) Instances(ctx context.Context, group string) ([]Instance, error) {
+	asg, err := c.autoScaling()
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []Instance
+	err = asg.DescribeAutoScalingGroupsPagesWithContext(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(group)},
+	}, func(out *autoscaling.DescribeAutoScalingGroupsOutput, last bool) bool {
+		for _, g := range out.AutoScalingGroups {
+			for _, i := range g.Instances {
+				instances = append(instances, Instance{
+					ID:                   aws.StringValue(i.InstanceId),
+					AutoScalingGroupName: aws.StringValue(g.AutoScalingGroupName),
+					LifecycleState:       aws.StringValue(i.LifecycleState),
+					AvailabilityZone:     aws.StringValue(i.AvailabilityZone),
+					InstanceType:         aws.StringValue(i.InstanceType),
+					ProtectedFromScaleIn: aws.BoolValue(i.ProtectedFromScaleIn),
+					Tags:                 make(map[string]string),
+				})
+			}
+		}
+		return !last
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, nil
+	}
+
+	byID := make(map[string]*Instance, len(instances))
+	var ids []*string
+	for i := range instances {
+		byID[instances[i].ID] = &instances[i]
+		ids = append(ids, aws.String(instances[i].ID))
+	}
+	svc, err := c.ec2()
+	if err != nil {
+		return nil, err
+	}
+	err = svc.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: ids,
+	}, func(out *ec2.DescribeInstancesOutput, last bool) bool {
+		for _, r := range out.Reservations {
+			for _, i := range r.Instances {
+				inst, ok := byID[aws.StringValue(i.InstanceId)]
+				if !ok {
+					continue
+				}
+				inst.LaunchTime = aws.TimeValue(i.LaunchTime)
+				inst.Lifecycle = LifecycleOnDemand
+				if l := aws.StringValue(i.InstanceLifecycle); l != "" {
+					inst.Lifecycle = l
+				}
+				for _, g := range i.SecurityGroups {
+					inst.SecurityGroups = append(inst.SecurityGroups, aws.StringValue(g.GroupId))
+				}
+				if inst.InstanceType == "" {
+					inst.InstanceType = aws.StringValue(i.InstanceType)
+				}
+				for _, t := range i.Tags {
+					inst.Tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+				}
+			}
+		}
+		return !last
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instances: %s", err)
+	}
+	return instances, nil
+}
+
+// ErrInstanceNotFound is returned if an EC2 instance does not exist.
+var ErrInstanceNotFound = errors.New("instance not found")
+
+// InstanceStatus describes the state and health of an EC2 instance as
+// reported by EC2 status checks.
+type InstanceStatus struct {
+	ID               string `json:"id" yaml:"id"`
+	AvailabilityZone string `json:"availabilityZone" yaml:"availabilityZone"`
+
+	// State of the instance, e.g. "running" or "stopped"
+	State string `json:"state" yaml:"state"`
+
+	// Result of the system status check, e.g. "ok" or "impaired"
+	SystemStatus string `json:"systemStatus" yaml:"systemStatus"`
+
+	// Result of the instance status check, e.g. "ok" or "impaired"
+	InstanceStatus string `json:"instanceStatus" yaml:"instanceStatus"`
+
+	// Codes of scheduled events, e.g. "instance-retirement"
+	Events []string `json:"events,omitempty" yaml:"events,omitempty"`
+}
+
+// Healthy reports whether the instance is running and passes both status
+// checks.
+func (s *InstanceStatus) Healthy() bool {
+	return s.State == ec2.InstanceStateNameRunning &&
+		s.SystemStatus == ec2.SummaryStatusOk &&
+		s.InstanceStatus == ec2.SummaryStatusOk
+}
+
+// InstanceStatus returns the status of the given EC2 instance, or
+// ErrInstanceNotFound if there is no such instance.
+func (c *Client) InstanceStatus(ctx context.Context, id string) (*InstanceStatus, error) {
+	svc, err := c.ec2()
+	if err != nil {
+		return nil, err
+	}
+	out, err := svc.DescribeInstanceStatusWithContext(ctx, &ec2.DescribeInstanceStatusInput{
+		InstanceIds:         []*string{aws.String(id)},
+		IncludeAllInstances: aws.Bool(true),
+	})
+	if err, ok := err.(awserr.Error); ok && err.Code() == "InvalidInstanceID.NotFound" {
+		return nil, ErrInstanceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(out.InstanceStatuses) == 0 {
+		return nil, ErrInstanceNotFound
+	}
+
+	return newInstanceStatus(out.InstanceStatuses[0]), nil
+}
+
+// describeInstance returns the EC2 description of the given instance, or
+// ErrInstanceNotFound if there is no such instance.
+func (c *Client) describeInstance(ctx context.Context, id string) (*ec2.Instance, error) {
+	svc, err := c.ec2()
+	if err != nil {
+		return nil, err
+	}
+	var inst *ec2.Instance
+	err = svc.DescribeInstancesPagesWithContext(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []*string{aws.String(id)},
+	}, func(out *ec2.DescribeInstancesOutput, last bool) bool {
+		for _, r := range out.Reservations {
+			for _, i := range r.Instances {
+				if aws.StringValue(i.InstanceId) == id {
+					inst = i
+				}
+			}
+		}
+		return !last
+	})
+	if err, ok := err.(awserr.Error); ok && err.Code() == "InvalidInstanceID.NotFound" {
+		return nil, ErrInstanceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if inst == nil {
+		return nil, ErrInstanceNotFound
+	}
+	return inst, nil
+}
+
+// newInstanceStatus converts the status returned by EC2. Completed and
+// canceled events are ignored.
+func newInstanceStatus(st *ec2.InstanceStatus) *InstanceStatus {
+	status := &InstanceStatus{
+		ID:               aws.StringValue(st.InstanceId),
+		AvailabilityZone: aws.StringValue(st.AvailabilityZone),
+	}
+	if st.InstanceState != nil {
+		status.State = aws.StringValue(st.InstanceState.Name)
+	}
+	if st.SystemStatus != nil {
+		status.SystemStatus = aws.StringValue(st.SystemStatus.Status)
+	}
+	if st.InstanceStatus != nil {
+		status.InstanceStatus = aws.StringValue(st.InstanceStatus.Status)
+	}
+	for _, e := range st.Events {
+		desc := aws.StringValue(e.Description)
+		if strings.HasPrefix(desc, "[Completed]") || strings.HasPrefix(desc, "[Canceled]") {
+			continue
+		}
+		status.Events = append(status.Events, aws.StringValue(e.Code))
+	}
+	return status
+}
+
+// EligibleInstances returns the in-service instances of the given auto
+// scaling group that are accepted by all selectors.
+func (c *Client) EligibleInstances(ctx context.Context, group string, selectors ...Selector) ([]Instance, error) {
+	instances, err := c.Instances(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	var eligible []Instance
+outer:
+	for _, i := range instances {
+		if i.LifecycleState != autoscaling.LifecycleStateInService {
+			continue
+		}
+		for _, s := range selectors {
+			if !s(i) {
+				continue outer
+			}
+		}
+		eligible = append(eligible, i)
+	}
+	return eligible, nil
+}
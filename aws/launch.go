@@ -0,0 +1,89 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// setLaunchSpec sets the launch configuration or template of a group and the
+// instance types of its mixed instances policy, which are part of the group
+// description.
+func setLaunchSpec(group *AutoScalingGroup, g *autoscaling.Group) {
+	group.LaunchConfiguration = aws.StringValue(g.LaunchConfigurationName)
+	spec := g.LaunchTemplate
+	if p := g.MixedInstancesPolicy; p != nil && p.LaunchTemplate != nil {
+		spec = p.LaunchTemplate.LaunchTemplateSpecification
+		for _, o := range p.LaunchTemplate.Overrides {
+			if t := aws.StringValue(o.InstanceType); t != "" {
+				group.InstanceTypes = append(group.InstanceTypes, t)
+			}
+		}
+	}
+	if spec != nil {
+		group.LaunchTemplate = aws.StringValue(spec.LaunchTemplateName)
+		if group.LaunchTemplate == "" {
+			group.LaunchTemplate = aws.StringValue(spec.LaunchTemplateId)
+		}
+		group.LaunchTemplateVersion = aws.StringValue(spec.Version)
+	}
+}
+
+// launchDetails sets the AMI and, unless set by a mixed instances policy, the
+// instance type of a group from its launch configuration or template.
+func (c *Client) launchDetails(ctx context.Context, group *AutoScalingGroup) error {
+	var image, instanceType string
+	switch {
+	case group.LaunchConfiguration != "":
+		svc, err := c.autoScaling()
+		if err != nil {
+			return err
+		}
+		out, err := svc.DescribeLaunchConfigurationsWithContext(ctx, &autoscaling.DescribeLaunchConfigurationsInput{
+			LaunchConfigurationNames: []*string{aws.String(group.LaunchConfiguration)},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe launch configuration: %s", err)
+		}
+		if len(out.LaunchConfigurations) == 0 {
+			return nil
+		}
+		image = aws.StringValue(out.LaunchConfigurations[0].ImageId)
+		instanceType = aws.StringValue(out.LaunchConfigurations[0].InstanceType)
+	case group.LaunchTemplate != "":
+		svc, err := c.ec2()
+		if err != nil {
+			return err
+		}
+		version := group.LaunchTemplateVersion
+		if version == "" {
+			version = "$Default"
+		}
+		in := &ec2.DescribeLaunchTemplateVersionsInput{Versions: []*string{aws.String(version)}}
+		if strings.HasPrefix(group.LaunchTemplate, "lt-") {
+			in.LaunchTemplateId = aws.String(group.LaunchTemplate)
+		} else {
+			in.LaunchTemplateName = aws.String(group.LaunchTemplate)
+		}
+		out, err := svc.DescribeLaunchTemplateVersionsWithContext(ctx, in)
+		if err != nil {
+			return fmt.Errorf("failed to describe launch template: %s", err)
+		}
+		if len(out.LaunchTemplateVersions) == 0 || out.LaunchTemplateVersions[0].LaunchTemplateData == nil {
+			return nil
+		}
+		data := out.LaunchTemplateVersions[0].LaunchTemplateData
+		image = aws.StringValue(data.ImageId)
+		instanceType = aws.StringValue(data.InstanceType)
+	}
+
+	group.ImageID = image
+	if len(group.InstanceTypes) == 0 && instanceType != "" {
+		group.InstanceTypes = []string{instanceType}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+// LoadBalancer describes a classic load balancer or a target group of an
+// application or network load balancer attached to an auto scaling group.
+type LoadBalancer struct {
+	// Name of the classic load balancer, or ARN of the target group
+	Name string `json:"name" yaml:"name"`
+
+	// Whether this is a target group rather than a classic load balancer
+	TargetGroup bool `json:"targetGroup" yaml:"targetGroup"`
+
+	// Number of healthy and unhealthy hosts registered with the load
+	// balancer. They are only known after calling LoadBalancerHealth.
+	Healthy   int `json:"healthy" yaml:"healthy"`
+	Unhealthy int `json:"unhealthy" yaml:"unhealthy"`
+}
+
+// LoadBalancerHealth retrieves the number of healthy and unhealthy hosts of
+// all load balancers attached to the given group. Hosts that are neither
+// healthy nor unhealthy, e.g. draining ones, are not counted.
+func (c *Client) LoadBalancerHealth(ctx context.Context, g *AutoScalingGroup) error {
+	if len(g.LoadBalancers) == 0 {
+		return nil
+	}
+	lbs, err := c.elb()
+	if err != nil {
+		return err
+	}
+	tgs, err := c.elbv2()
+	if err != nil {
+		return err
+	}
+
+	for i := range g.LoadBalancers {
+		lb := &g.LoadBalancers[i]
+		lb.Healthy, lb.Unhealthy = 0, 0
+		if lb.TargetGroup {
+			out, err := tgs.DescribeTargetHealthWithContext(ctx, &elbv2.DescribeTargetHealthInput{
+				TargetGroupArn: aws.String(lb.Name),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to get health of target group %s: %s", lb.Name, err)
+			}
+			for _, t := range out.TargetHealthDescriptions {
+				if t.TargetHealth == nil {
+					continue
+				}
+				switch aws.StringValue(t.TargetHealth.State) {
+				case elbv2.TargetHealthStateEnumHealthy:
+					lb.Healthy++
+				case elbv2.TargetHealthStateEnumUnhealthy:
+					lb.Unhealthy++
+				}
+			}
+		} else {
+			out, err := lbs.DescribeInstanceHealthWithContext(ctx, &elb.DescribeInstanceHealthInput{
+				LoadBalancerName: aws.String(lb.Name),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to get health of load balancer %s: %s", lb.Name, err)
+			}
+			for _, s := range out.InstanceStates {
+				switch aws.StringValue(s.State) {
+				case "InService":
+					lb.Healthy++
+				case "OutOfService":
+					lb.Unhealthy++
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// Degraded reports whether AWS itself is degrading the instance, i.e. it
+// fails a status check or has scheduled events like retirement or
+// maintenance.
+func (s *InstanceStatus) Degraded() bool {
+	return s.SystemStatus == ec2.SummaryStatusImpaired ||
+		s.InstanceStatus == ec2.SummaryStatusImpaired ||
+		len(s.Events) > 0
+}
+
+// maxStatusIDs is the maximum number of instance IDs per request of
+// DescribeInstanceStatus.
+const maxStatusIDs = 100
+
+// DegradedInstances returns the status of all instances of an auto scaling
+// group that are degraded by AWS, see InstanceStatus.Degraded.
+func (c *Client) DegradedInstances(ctx context.Context, group string) ([]InstanceStatus, error) {
+	instances, err := c.Instances(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := c.ec2()
+	if err != nil {
+		return nil, err
+	}
+
+	var degraded []InstanceStatus
+	for start := 0; start < len(instances); start += maxStatusIDs {
+		var ids []*string
+		for i := start; i < len(instances) && i < start+maxStatusIDs; i++ {
+			ids = append(ids, aws.String(instances[i].ID))
+		}
+		out, err := svc.DescribeInstanceStatusWithContext(ctx, &ec2.DescribeInstanceStatusInput{
+			InstanceIds: ids,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, st := range out.InstanceStatuses {
+			if s := newInstanceStatus(st); s.Degraded() {
+				degraded = append(degraded, *s)
+			}
+		}
+	}
+	return degraded, nil
+}
+
+// MaintenanceGuard is a Guard that refuses chaos events against auto scaling
+// groups whose instances are already degraded by AWS, as double chaos is
+// rarely the goal.
+type MaintenanceGuard struct {
+	// Client used to retrieve instance status in the region of the target
+	// (the region of the client if the target has none)
+	Client *Client
+
+	// Optional function called instead of refusing chaos events, e.g. to
+	// only log a warning
+	Warn func(err error)
+}
+
+// Check refuses chaos events against groups with degraded instances. It also
+// refuses them if the instance status cannot be retrieved.
+func (g *MaintenanceGuard) Check(t chaosmonkey.Target) error {
+	degraded, err := g.Client.forRegion(t.Region).DegradedInstances(context.Background(), t.AutoScalingGroupName)
+	if err != nil {
+		err = fmt.Errorf("failed to get instance status: %s", err)
+	} else if len(degraded) > 0 {
+		var details []string
+		for _, s := range degraded {
+			reasons := append([]string{}, s.Events...)
+			if s.SystemStatus == ec2.SummaryStatusImpaired {
+				reasons = append(reasons, "system impaired")
+			}
+			if s.InstanceStatus == ec2.SummaryStatusImpaired {
+				reasons = append(reasons, "instance impaired")
+			}
+			details = append(details, fmt.Sprintf("%s (%s)", s.ID, strings.Join(reasons, ", ")))
+		}
+		err = fmt.Errorf("AWS is already degrading group %s: %s", t.AutoScalingGroupName, strings.Join(details, ", "))
+	}
+	if err != nil && g.Warn != nil {
+		g.Warn(err)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,150 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// Metric identifies a CloudWatch metric with an AutoScalingGroupName
+// dimension, e.g. CPUUtilization of AWS/EC2 or GroupInServiceInstances of
+// AWS/AutoScaling.
+type Metric struct {
+	// Namespace of the metric ("AWS/EC2" if empty)
+	Namespace string `json:"namespace" yaml:"namespace"`
+
+	// Name of the metric
+	Name string `json:"name" yaml:"name"`
+
+	// Statistic to retrieve ("Average" if empty)
+	Statistic string `json:"statistic" yaml:"statistic"`
+}
+
+// Datapoint is a value of a metric at a certain time.
+type Datapoint struct {
+	Time  time.Time `json:"time" yaml:"time"`
+	Value float64   `json:"value" yaml:"value"`
+}
+
+// MetricSeries holds the datapoints of a metric around a chaos event, along
+// with the mean values before, during, and after the event. The means are
+// zero for phases without datapoints.
+type MetricSeries struct {
+	Metric     Metric      `json:"metric" yaml:"metric"`
+	Datapoints []Datapoint `json:"datapoints" yaml:"datapoints"`
+	Before     float64     `json:"before" yaml:"before"`
+	During     float64     `json:"during" yaml:"during"`
+	After      float64     `json:"after" yaml:"after"`
+}
+
+// EventMetrics holds metrics of the auto scaling group affected by a chaos
+// event. With a window w and the event triggered at t, the phases are
+// [t-w, t) before, [t, t+w) during, and [t+w, t+2w) after the event.
+type EventMetrics struct {
+	Event  chaosmonkey.Event `json:"event" yaml:"event"`
+	Window time.Duration     `json:"window" yaml:"window"`
+	Series []MetricSeries    `json:"series" yaml:"series"`
+}
+
+// maxDatapoints is the maximum number of datapoints returned by a single
+// GetMetricStatistics request.
+const maxDatapoints = 1440
+
+// MetricsAround retrieves CloudWatch metrics of the auto scaling group
+// affected by the given chaos event for the phases before, during, and after
+// the event.
+func (c *Client) MetricsAround(ctx context.Context, e chaosmonkey.Event, metrics []Metric, window time.Duration) (*EventMetrics, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("window must be positive")
+	}
+	svc, err := c.cloudWatch()
+	if err != nil {
+		return nil, err
+	}
+
+	start := e.TriggeredAt.Add(-window)
+	end := e.TriggeredAt.Add(2 * window)
+	// Use the smallest period in full minutes that fits into one request
+	period := int64(60)
+	if n := int64(end.Sub(start)/time.Second) / maxDatapoints; n > period {
+		period = (n + 59) / 60 * 60
+	}
+
+	res := &EventMetrics{Event: e, Window: window}
+	for _, m := range metrics {
+		if m.Namespace == "" {
+			m.Namespace = "AWS/EC2"
+		}
+		if m.Statistic == "" {
+			m.Statistic = cloudwatch.StatisticAverage
+		}
+		out, err := svc.GetMetricStatisticsWithContext(ctx, &cloudwatch.GetMetricStatisticsInput{
+			Namespace:  aws.String(m.Namespace),
+			MetricName: aws.String(m.Name),
+			Dimensions: []*cloudwatch.Dimension{{
+				Name:  aws.String("AutoScalingGroupName"),
+				Value: aws.String(e.AutoScalingGroupName),
+			}},
+			StartTime:  aws.Time(start),
+			EndTime:    aws.Time(end),
+			Period:     aws.Int64(period),
+			Statistics: []*string{aws.String(m.Statistic)},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metric %s/%s: %s", m.Namespace, m.Name, err)
+		}
+
+		s := MetricSeries{Metric: m, Datapoints: []Datapoint{}}
+		for _, d := range out.Datapoints {
+			s.Datapoints = append(s.Datapoints, Datapoint{
+				Time:  aws.TimeValue(d.Timestamp),
+				Value: statistic(d, m.Statistic),
+			})
+		}
+		sort.Slice(s.Datapoints, func(i, j int) bool { return s.Datapoints[i].Time.Before(s.Datapoints[j].Time) })
+		s.Before = mean(s.Datapoints, start, e.TriggeredAt)
+		s.During = mean(s.Datapoints, e.TriggeredAt, e.TriggeredAt.Add(window))
+		s.After = mean(s.Datapoints, e.TriggeredAt.Add(window), end)
+		res.Series = append(res.Series, s)
+	}
+	return res, nil
+}
+
+// statistic returns the value of the given statistic of a datapoint.
+func statistic(d *cloudwatch.Datapoint, name string) float64 {
+	switch name {
+	case cloudwatch.StatisticSum:
+		return aws.Float64Value(d.Sum)
+	case cloudwatch.StatisticMinimum:
+		return aws.Float64Value(d.Minimum)
+	case cloudwatch.StatisticMaximum:
+		return aws.Float64Value(d.Maximum)
+	case cloudwatch.StatisticSampleCount:
+		return aws.Float64Value(d.SampleCount)
+	default:
+		return aws.Float64Value(d.Average)
+	}
+}
+
+// mean returns the mean value of the datapoints in [from, to), or zero if
+// there are none.
+func mean(points []Datapoint, from, to time.Time) float64 {
+	var sum float64
+	var n int
+	for _, p := range points {
+		if !p.Time.Before(from) && p.Time.Before(to) {
+			sum += p.Value
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
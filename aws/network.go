@@ -0,0 +1,212 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// DenyAllSecurityGroupName is the name of the security group BlockNetwork
+// assigns to instances. It is created in the VPC of the instance if needed,
+// with no ingress rules and its default egress rule revoked.
+const DenyAllSecurityGroupName = "chaosmonkey-deny-all"
+
+// DefaultBlockTimeout is how long BlockNetwork blocks the network of an
+// instance unless configured otherwise.
+const DefaultBlockTimeout = 10 * time.Minute
+
+// NetworkBlock records the security groups of an instance whose network was
+// blocked by BlockNetwork, so that they can be restored.
+type NetworkBlock struct {
+	InstanceID string
+
+	// Original security groups of the instance
+	SecurityGroups []string
+
+	// Time when the block is lifted automatically
+	Expires time.Time
+
+	client   *Client
+	timer    *time.Timer
+	mu       sync.Mutex
+	restored bool
+}
+
+// BlockNetwork cuts off all network traffic of an EC2 instance by replacing
+// its security groups with the deny-all security group of its VPC, like the
+// BlockAllNetworkTraffic strategy but without running anything on the
+// instance. Only the primary network interface is changed. Established
+// connections may survive, as security groups only track new ones.
+//
+// The original security groups are restored when Restore is called, or
+// after the given timeout (DefaultBlockTimeout if zero) at the latest, so
+// that an aborted experiment does not leave the instance isolated. The
+// timeout only works while the process is running.
+//
+// The block is a chaos event against the group of the instance, subject to
+// Client.Chaos (see Inject). In a dry run, nothing is changed and the
+// returned block is already restored.
+func (c *Client) BlockNetwork(ctx context.Context, instanceID string, timeout time.Duration) (*NetworkBlock, error) {
+	if timeout == 0 {
+		timeout = DefaultBlockTimeout
+	}
+	svc, err := c.ec2()
+	if err != nil {
+		return nil, err
+	}
+
+	inst, err := c.describeInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	vpc := aws.StringValue(inst.VpcId)
+	if vpc == "" {
+		return nil, fmt.Errorf("instance %s is not in a VPC", instanceID)
+	}
+
+	b := &NetworkBlock{
+		InstanceID: instanceID,
+		Expires:    time.Now().Add(timeout),
+		client:     c,
+	}
+	for _, g := range inst.SecurityGroups {
+		b.SecurityGroups = append(b.SecurityGroups, aws.StringValue(g.GroupId))
+	}
+	ev, err := c.Inject(ctx, instanceGroup(inst), chaosmonkey.StrategyBlockAllNetworkTraffic, func(ctx context.Context) (*chaosmonkey.Event, error) {
+		denyAll, err := c.denyAllSecurityGroup(ctx, vpc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deny-all security group: %s", err)
+		}
+		_, err = svc.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+			InstanceId: aws.String(instanceID),
+			Groups:     []*string{aws.String(denyAll)},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to block network of instance %s: %s", instanceID, err)
+		}
+		return &chaosmonkey.Event{InstanceID: instanceID}, nil
+	})
+	if ev == nil {
+		return nil, err
+	}
+	// Hold the lock so that an immediate timeout cannot restore the instance
+	// before the timer is set
+	b.mu.Lock()
+	if ev.DryRun {
+		b.restored = true
+	} else {
+		b.timer = time.AfterFunc(timeout, func() { b.Restore(context.Background()) })
+	}
+	b.mu.Unlock()
+	// The block is in effect even if it could not be audited
+	return b, err
+}
+
+// Restore restores the original security groups of the instance. It does
+// nothing if they have already been restored, and succeeds if the instance
+// no longer exists.
+func (b *NetworkBlock) Restore(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.restored {
+		return nil
+	}
+	svc, err := b.client.ec2()
+	if err != nil {
+		return err
+	}
+	_, err = svc.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId: aws.String(b.InstanceID),
+		Groups:     aws.StringSlice(b.SecurityGroups),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidInstanceID.NotFound" {
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore instance %s: %s", b.InstanceID, err)
+	}
+	b.restored = true
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	return nil
+}
+
+// Restored reports whether the original security groups have been
+// restored.
+func (b *NetworkBlock) Restored() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.restored
+}
+
+// denyAllSecurityGroup returns the ID of the deny-all security group in the
+// given VPC, creating it if it does not exist. Egress rules of an existing
+// group are revoked, e.g. if creating it failed midway, and ingress rules
+// are refused, as the group may not be used to block traffic then.
+func (c *Client) denyAllSecurityGroup(ctx context.Context, vpc string) (string, error) {
+	svc, err := c.ec2()
+	if err != nil {
+		return "", err
+	}
+	out, err := svc.DescribeSecurityGroupsWithContext(ctx, &ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("vpc-id"), Values: []*string{aws.String(vpc)}},
+			{Name: aws.String("group-name"), Values: []*string{aws.String(DenyAllSecurityGroupName)}},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(out.SecurityGroups) > 0 {
+		g := out.SecurityGroups[0]
+		id := aws.StringValue(g.GroupId)
+		if len(g.IpPermissions) > 0 {
+			return "", fmt.Errorf("security group %s has ingress rules", id)
+		}
+		if len(g.IpPermissionsEgress) > 0 {
+			if err := revokeEgress(ctx, svc, id, g.IpPermissionsEgress); err != nil {
+				return "", err
+			}
+		}
+		return id, nil
+	}
+
+	created, err := svc.CreateSecurityGroupWithContext(ctx, &ec2.CreateSecurityGroupInput{
+		GroupName:   aws.String(DenyAllSecurityGroupName),
+		Description: aws.String("Blocks all traffic of instances during chaos experiments"),
+		VpcId:       aws.String(vpc),
+	})
+	if err != nil {
+		return "", err
+	}
+	id := aws.StringValue(created.GroupId)
+	// New security groups allow all outbound traffic
+	err = revokeEgress(ctx, svc, id, []*ec2.IpPermission{{
+		IpProtocol: aws.String("-1"),
+		IpRanges:   []*ec2.IpRange{{CidrIp: aws.String("0.0.0.0/0")}},
+	}})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// revokeEgress revokes the given egress rules of a security group.
+func revokeEgress(ctx context.Context, svc EC2API, id string, rules []*ec2.IpPermission) error {
+	_, err := svc.RevokeSecurityGroupEgressWithContext(ctx, &ec2.RevokeSecurityGroupEgressInput{
+		GroupId:       aws.String(id),
+		IpPermissions: rules,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke egress of security group %s: %s", id, err)
+	}
+	return nil
+}
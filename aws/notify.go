@@ -0,0 +1,195 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+
+	"github.com/FlyLevin/chaosmonkey/gameday"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// Types of notifications published by SNSNotifier and EventBridgeEmitter.
+const (
+	NotificationEvent        = "event"
+	NotificationBlocked      = "blocked"
+	NotificationHalted       = "halted"
+	NotificationVerification = "verification"
+	NotificationGameDay      = "gameday"
+)
+
+// Notification is the JSON payload of messages published by SNSNotifier and
+// the detail of events emitted by EventBridgeEmitter.
+type Notification struct {
+	// Type of the notification, e.g. NotificationEvent
+	Type string `json:"type"`
+
+	// Time of the notification
+	Time time.Time `json:"time"`
+
+	// Name of the targeted auto scaling group
+	AutoScalingGroupName string `json:"autoScalingGroupName"`
+
+	// Triggered chaos event (NotificationEvent only)
+	Event *chaosmonkey.Event `json:"event,omitempty"`
+
+	// Why a chaos event was refused or an experiment was halted
+	// (NotificationBlocked and NotificationHalted only)
+	Reason string `json:"reason,omitempty"`
+
+	// Result of verifying a termination (NotificationVerification only)
+	Verification *VerificationResult `json:"verification,omitempty"`
+
+	// Scheduled GameDay (NotificationGameDay only)
+	GameDay *gameday.GameDay `json:"gameDay,omitempty"`
+}
+
+// SNSNotifier publishes chaos events and verification results to an SNS
+// topic, so that other systems can subscribe to them. Messages carry the
+// attributes "type" and "autoScalingGroupName", which subscriptions can
+// filter on.
+type SNSNotifier struct {
+	// Client used to publish, which determines the region
+	Client *Client
+
+	// ARN of the SNS topic
+	TopicARN string
+
+	// Optional function called with errors of notifications published by
+	// Subscribe, which would go unnoticed otherwise
+	OnError func(err error)
+}
+
+// notificationTopics are the bus topics turned into notifications.
+var notificationTopics = []chaosmonkey.Topic{
+	chaosmonkey.TopicEventRecorded,
+	chaosmonkey.TopicGuardBlocked,
+	chaosmonkey.TopicExperimentFinished,
+}
+
+// newNotification converts a bus message into a notification. It returns
+// false for messages that need no notification, i.e. experiments that
+// finished without error.
+func newNotification(m chaosmonkey.Message) (Notification, bool) {
+	n := Notification{
+		Time:                 m.Time,
+		AutoScalingGroupName: m.AutoScalingGroupName,
+	}
+	switch m.Topic {
+	case chaosmonkey.TopicEventRecorded:
+		n.Type = NotificationEvent
+		n.Event = m.Event
+		return n, true
+	case chaosmonkey.TopicGuardBlocked:
+		n.Type = NotificationBlocked
+	case chaosmonkey.TopicExperimentFinished:
+		if m.Err == nil {
+			return n, false
+		}
+		n.Type = NotificationHalted
+	default:
+		return n, false
+	}
+	if m.Err != nil {
+		n.Reason = m.Err.Error()
+	}
+	return n, true
+}
+
+// subscribeNotifications calls fn with a notification for every chaos event
+// recorded or refused and every experiment halted on the bus. It returns a
+// function that stops the subscription.
+func subscribeNotifications(bus *chaosmonkey.Bus, fn func(Notification)) (stop func()) {
+	var unsubs []func()
+	for _, topic := range notificationTopics {
+		unsubs = append(unsubs, bus.Subscribe(topic, func(m chaosmonkey.Message) {
+			if n, ok := newNotification(m); ok {
+				fn(n)
+			}
+		}))
+	}
+	return func() {
+		for _, unsub := range unsubs {
+			unsub()
+		}
+	}
+}
+
+// Subscribe publishes a notification for every chaos event recorded or
+// refused and every experiment halted on the bus. It returns a function
+// that stops publishing.
+func (n *SNSNotifier) Subscribe(bus *chaosmonkey.Bus) (stop func()) {
+	return subscribeNotifications(bus, func(notif Notification) {
+		if err := n.Publish(context.Background(), notif); err != nil && n.OnError != nil {
+			n.OnError(err)
+		}
+	})
+}
+
+// PublishVerification publishes the result of VerifyTermination.
+func (n *SNSNotifier) PublishVerification(ctx context.Context, res *VerificationResult) error {
+	return n.Publish(ctx, Notification{
+		Type:                 NotificationVerification,
+		Time:                 time.Now().UTC(),
+		AutoScalingGroupName: res.AutoScalingGroupName,
+		Verification:         res,
+	})
+}
+
+// Announce publishes the announcement of a scheduled GameDay, which
+// implements gameday.Announcer.
+func (n *SNSNotifier) Announce(ctx context.Context, gd *gameday.GameDay) error {
+	return n.Publish(ctx, gameDayNotification(gd))
+}
+
+// gameDayNotification returns the announcement of a scheduled GameDay.
+func gameDayNotification(gd *gameday.GameDay) Notification {
+	return Notification{
+		Type:    NotificationGameDay,
+		Time:    time.Now().UTC(),
+		GameDay: gd,
+	}
+}
+
+// Publish publishes a notification to the topic.
+func (n *SNSNotifier) Publish(ctx context.Context, notif Notification) error {
+	body, err := json.Marshal(notif)
+	if err != nil {
+		return err
+	}
+	svc, err := n.Client.sns()
+	if err != nil {
+		return err
+	}
+	attr := func(v string) *sns.MessageAttributeValue {
+		return &sns.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(v)}
+	}
+	in := &sns.PublishInput{
+		TopicArn: aws.String(n.TopicARN),
+		Subject:  aws.String(subject(notif)),
+		Message:  aws.String(string(body)),
+		MessageAttributes: map[string]*sns.MessageAttributeValue{
+			"type": attr(notif.Type),
+		},
+	}
+	// SNS rejects attributes with empty values
+	if notif.AutoScalingGroupName != "" {
+		in.MessageAttributes["autoScalingGroupName"] = attr(notif.AutoScalingGroupName)
+	}
+	if _, err := svc.PublishWithContext(ctx, in); err != nil {
+		return fmt.Errorf("failed to publish to SNS: %s", err)
+	}
+	return nil
+}
+
+// subject returns the subject of an SNS message.
+func subject(notif Notification) string {
+	if gd := notif.GameDay; gd != nil {
+		return fmt.Sprintf("Chaos Monkey GameDay %s: %s", gd.Name, gd.Start.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("Chaos Monkey %s: %s", notif.Type, notif.AutoScalingGroupName)
+}
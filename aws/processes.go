@@ -0,0 +1,88 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// Scaling processes of auto scaling groups that can be suspended.
+const (
+	ProcessLaunch            = "Launch"
+	ProcessTerminate         = "Terminate"
+	ProcessHealthCheck       = "HealthCheck"
+	ProcessReplaceUnhealthy  = "ReplaceUnhealthy"
+	ProcessAZRebalance       = "AZRebalance"
+	ProcessAlarmNotification = "AlarmNotification"
+	ProcessScheduledActions  = "ScheduledActions"
+	ProcessAddToLoadBalancer = "AddToLoadBalancer"
+)
+
+// SuspendProcesses suspends the given scaling processes of an auto scaling
+// group.
+func (c *Client) SuspendProcesses(ctx context.Context, group string, processes ...string) error {
+	svc, err := c.autoScaling()
+	if err != nil {
+		return err
+	}
+	_, err = svc.SuspendProcessesWithContext(ctx, &autoscaling.ScalingProcessQuery{
+		AutoScalingGroupName: aws.String(group),
+		ScalingProcesses:     aws.StringSlice(processes),
+	})
+	return err
+}
+
+// ResumeProcesses resumes the given scaling processes of an auto scaling
+// group.
+func (c *Client) ResumeProcesses(ctx context.Context, group string, processes ...string) error {
+	svc, err := c.autoScaling()
+	if err != nil {
+		return err
+	}
+	_, err = svc.ResumeProcessesWithContext(ctx, &autoscaling.ScalingProcessQuery{
+		AutoScalingGroupName: aws.String(group),
+		ScalingProcesses:     aws.StringSlice(processes),
+	})
+	return err
+}
+
+// WithSuspendedProcesses suspends the given scaling processes of an auto
+// scaling group, calls fn, and resumes the processes afterwards, even if fn
+// fails or panics, or ctx is canceled. Processes that were already suspended
+// stay suspended.
+func (c *Client) WithSuspendedProcesses(ctx context.Context, group string, processes []string, fn func() error) (err error) {
+	groups, err := c.describeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String(group)},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return ErrGroupNotFound
+	}
+	suspended := make(map[string]bool)
+	for _, p := range groups[0].SuspendedProcesses {
+		suspended[p] = true
+	}
+	var resume []string
+	for _, p := range processes {
+		if !suspended[p] {
+			resume = append(resume, p)
+		}
+	}
+	if len(resume) == 0 {
+		return fn()
+	}
+
+	if err := c.SuspendProcesses(ctx, group, resume...); err != nil {
+		return fmt.Errorf("failed to suspend processes: %s", err)
+	}
+	defer func() {
+		if rerr := c.ResumeProcesses(context.Background(), group, resume...); rerr != nil && err == nil {
+			err = fmt.Errorf("failed to resume processes: %s", rerr)
+		}
+	}()
+	return fn()
+}
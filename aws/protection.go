@@ -0,0 +1,113 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// InstanceProtection describes how an instance of an auto scaling group is
+// protected from being terminated.
+type InstanceProtection struct {
+	InstanceID string `json:"instanceId" yaml:"instanceId"`
+
+	// Whether EC2 termination protection is enabled, which makes
+	// terminating the instance fail
+	TerminationProtection bool `json:"terminationProtection" yaml:"terminationProtection"`
+
+	// Whether the group protects the instance from scale-in, which usually
+	// means that something else, e.g. an ECS capacity provider, manages its
+	// termination
+	ScaleInProtection bool `json:"scaleInProtection" yaml:"scaleInProtection"`
+}
+
+// ProtectedInstances returns the instances in service of the given auto
+// scaling group that have termination protection or scale-in protection
+// enabled. Termination protection is not part of the group description and
+// requires one EC2 call per instance.
+func (c *Client) ProtectedInstances(ctx context.Context, group string) ([]InstanceProtection, error) {
+	instances, err := c.Instances(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := c.ec2()
+	if err != nil {
+		return nil, err
+	}
+	var protected []InstanceProtection
+	for _, i := range instances {
+		if i.LifecycleState != autoscaling.LifecycleStateInService {
+			continue
+		}
+		out, err := svc.DescribeInstanceAttributeWithContext(ctx, &ec2.DescribeInstanceAttributeInput{
+			InstanceId: aws.String(i.ID),
+			Attribute:  aws.String(ec2.InstanceAttributeNameDisableApiTermination),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe termination protection of %s: %s", i.ID, err)
+		}
+		p := InstanceProtection{
+			InstanceID:        i.ID,
+			ScaleInProtection: i.ProtectedFromScaleIn,
+		}
+		if out.DisableApiTermination != nil {
+			p.TerminationProtection = aws.BoolValue(out.DisableApiTermination.Value)
+		}
+		if p.TerminationProtection || p.ScaleInProtection {
+			protected = append(protected, p)
+		}
+	}
+	return protected, nil
+}
+
+// ProtectionGuard is a Guard that refuses to shut down instances of auto
+// scaling groups with protected instances, as Chaos Monkey picks a random
+// instance. With termination protection, the chaos event fails; with
+// scale-in protection, it terminates an instance that something else expects
+// to manage. Other strategies are not affected by protection and always
+// allowed.
+type ProtectionGuard struct {
+	// Client used to retrieve instances in the region of the target
+	// (the region of the client if the target has none)
+	Client *Client
+
+	// Whether to allow chaos events against groups whose instances are only
+	// protected from scale-in
+	AllowScaleInProtection bool
+}
+
+// Check refuses chaos events that would shut down a protected instance. It
+// also refuses them if the instances cannot be retrieved.
+func (g *ProtectionGuard) Check(t chaosmonkey.Target) error {
+	if t.Strategy != chaosmonkey.StrategyShutdownInstance {
+		return nil
+	}
+	protected, err := g.Client.forRegion(t.Region).ProtectedInstances(context.Background(), t.AutoScalingGroupName)
+	if err != nil {
+		return fmt.Errorf("failed to check instance protection: %s", err)
+	}
+	var terminating, scaleIn []string
+	for _, p := range protected {
+		if p.TerminationProtection {
+			terminating = append(terminating, p.InstanceID)
+		}
+		if p.ScaleInProtection {
+			scaleIn = append(scaleIn, p.InstanceID)
+		}
+	}
+	switch {
+	case len(terminating) > 0:
+		return fmt.Errorf("group %s has instances with termination protection, which cannot be shut down: %s",
+			t.AutoScalingGroupName, strings.Join(terminating, ", "))
+	case len(scaleIn) > 0 && !g.AllowScaleInProtection:
+		return fmt.Errorf("group %s has instances protected from scale-in, whose termination may be managed elsewhere: %s",
+			t.AutoScalingGroupName, strings.Join(scaleIn, ", "))
+	}
+	return nil
+}
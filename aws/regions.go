@@ -0,0 +1,104 @@
+package aws
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// DefaultParallelism is the number of regions queried concurrently unless
+// configured otherwise.
+const DefaultParallelism = 4
+
+// AllRegions returns the names of all regions enabled for the account. The
+// client must have a region to send the request to.
+func (c *Client) AllRegions(ctx context.Context) ([]string, error) {
+	svc, err := c.ec2()
+	if err != nil {
+		return nil, err
+	}
+	out, err := svc.DescribeRegionsWithContext(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, err
+	}
+	var regions []string
+	for _, r := range out.Regions {
+		regions = append(regions, aws.StringValue(r.RegionName))
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// RegionGroups holds the auto scaling groups of a region, or the error that
+// occurred while retrieving them.
+type RegionGroups struct {
+	Region string
+	Groups []AutoScalingGroup
+	Err    error
+}
+
+// AutoScalingGroupsAllRegions returns the auto scaling groups of all enabled
+// regions, ordered by region. At most parallelism regions
+// (DefaultParallelism if zero) are queried concurrently. Errors in single
+// regions are reported per region; an error is only returned if the regions
+// cannot be determined.
+func (c *Client) AutoScalingGroupsAllRegions(ctx context.Context, parallelism int) ([]RegionGroups, error) {
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+	regions, err := c.AllRegions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]RegionGroups, len(regions))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func(i int, region string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			groups, err := c.inRegion(region).AutoScalingGroups(ctx)
+			results[i] = RegionGroups{Region: region, Groups: groups, Err: err}
+		}(i, region)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// forRegion returns c if the region is empty or the one of c, and a client
+// for the region otherwise, e.g. for the region of the target of a guard.
+func (c *Client) forRegion(region string) *Client {
+	if region == "" || region == c.Region {
+		return c
+	}
+	return c.inRegion(region)
+}
+
+// inRegion returns a new client like c, but for the given region, or the one
+// returned by ForRegion if set.
+func (c *Client) inRegion(region string) *Client {
+	if c.ForRegion != nil {
+		return c.ForRegion(region)
+	}
+	return &Client{
+		Region:           region,
+		Session:          c.Session,
+		Config:           c.Config,
+		Endpoints:        c.Endpoints,
+		UseFIPSEndpoint:  c.UseFIPSEndpoint,
+		RoleARN:          c.RoleARN,
+		ExternalID:       c.ExternalID,
+		MFASerial:        c.MFASerial,
+		MaxRetries:       c.MaxRetries,
+		MinThrottleDelay: c.MinThrottleDelay,
+		MaxThrottleDelay: c.MaxThrottleDelay,
+		Chaos:            c.Chaos,
+		parent:           c,
+	}
+}
@@ -0,0 +1,288 @@
+package aws
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudtrail"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/codedeploy"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/organizations"
+	"github.com/aws/aws-sdk-go/service/pricing"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/simpledb"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sts"
+)
+
+// The following interfaces contain the operations of AWS services used by
+// Client. They are implemented by the service clients of the AWS SDK and by
+// the mocks of package awsmock, which allows to test code using Client
+// without access to AWS.
+
+// AutoScalingAPI contains the used operations of Auto Scaling.
+type AutoScalingAPI interface {
+	DescribeAutoScalingGroupsPagesWithContext(aws.Context, *autoscaling.DescribeAutoScalingGroupsInput, func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool, ...request.Option) error
+	SuspendProcessesWithContext(aws.Context, *autoscaling.ScalingProcessQuery, ...request.Option) (*autoscaling.SuspendProcessesOutput, error)
+	ResumeProcessesWithContext(aws.Context, *autoscaling.ScalingProcessQuery, ...request.Option) (*autoscaling.ResumeProcessesOutput, error)
+	DescribeLaunchConfigurationsWithContext(aws.Context, *autoscaling.DescribeLaunchConfigurationsInput, ...request.Option) (*autoscaling.DescribeLaunchConfigurationsOutput, error)
+	DescribeLifecycleHooksWithContext(aws.Context, *autoscaling.DescribeLifecycleHooksInput, ...request.Option) (*autoscaling.DescribeLifecycleHooksOutput, error)
+	DescribeWarmPoolWithContext(aws.Context, *autoscaling.DescribeWarmPoolInput, ...request.Option) (*autoscaling.DescribeWarmPoolOutput, error)
+	DescribeInstanceRefreshesWithContext(aws.Context, *autoscaling.DescribeInstanceRefreshesInput, ...request.Option) (*autoscaling.DescribeInstanceRefreshesOutput, error)
+}
+
+// EC2API contains the used operations of EC2.
+type EC2API interface {
+	DescribeInstancesPagesWithContext(aws.Context, *ec2.DescribeInstancesInput, func(*ec2.DescribeInstancesOutput, bool) bool, ...request.Option) error
+	DescribeInstanceStatusWithContext(aws.Context, *ec2.DescribeInstanceStatusInput, ...request.Option) (*ec2.DescribeInstanceStatusOutput, error)
+	DescribeRegionsWithContext(aws.Context, *ec2.DescribeRegionsInput, ...request.Option) (*ec2.DescribeRegionsOutput, error)
+	TerminateInstancesWithContext(aws.Context, *ec2.TerminateInstancesInput, ...request.Option) (*ec2.TerminateInstancesOutput, error)
+	ModifyInstanceAttributeWithContext(aws.Context, *ec2.ModifyInstanceAttributeInput, ...request.Option) (*ec2.ModifyInstanceAttributeOutput, error)
+	DescribeInstanceAttributeWithContext(aws.Context, *ec2.DescribeInstanceAttributeInput, ...request.Option) (*ec2.DescribeInstanceAttributeOutput, error)
+	DescribeSecurityGroupsWithContext(aws.Context, *ec2.DescribeSecurityGroupsInput, ...request.Option) (*ec2.DescribeSecurityGroupsOutput, error)
+	CreateSecurityGroupWithContext(aws.Context, *ec2.CreateSecurityGroupInput, ...request.Option) (*ec2.CreateSecurityGroupOutput, error)
+	RevokeSecurityGroupEgressWithContext(aws.Context, *ec2.RevokeSecurityGroupEgressInput, ...request.Option) (*ec2.RevokeSecurityGroupEgressOutput, error)
+	DescribeVolumesWithContext(aws.Context, *ec2.DescribeVolumesInput, ...request.Option) (*ec2.DescribeVolumesOutput, error)
+	DetachVolumeWithContext(aws.Context, *ec2.DetachVolumeInput, ...request.Option) (*ec2.VolumeAttachment, error)
+	AttachVolumeWithContext(aws.Context, *ec2.AttachVolumeInput, ...request.Option) (*ec2.VolumeAttachment, error)
+	DescribeLaunchTemplateVersionsWithContext(aws.Context, *ec2.DescribeLaunchTemplateVersionsInput, ...request.Option) (*ec2.DescribeLaunchTemplateVersionsOutput, error)
+}
+
+// SimpleDBAPI contains the used operations of SimpleDB.
+type SimpleDBAPI interface {
+	ListDomainsPagesWithContext(aws.Context, *simpledb.ListDomainsInput, func(*simpledb.ListDomainsOutput, bool) bool, ...request.Option) error
+	DomainMetadataWithContext(aws.Context, *simpledb.DomainMetadataInput, ...request.Option) (*simpledb.DomainMetadataOutput, error)
+	SelectPagesWithContext(aws.Context, *simpledb.SelectInput, func(*simpledb.SelectOutput, bool) bool, ...request.Option) error
+	DeleteDomainWithContext(aws.Context, *simpledb.DeleteDomainInput, ...request.Option) (*simpledb.DeleteDomainOutput, error)
+	BatchDeleteAttributesWithContext(aws.Context, *simpledb.BatchDeleteAttributesInput, ...request.Option) (*simpledb.BatchDeleteAttributesOutput, error)
+}
+
+// CloudTrailAPI contains the used operations of CloudTrail.
+type CloudTrailAPI interface {
+	LookupEventsPagesWithContext(aws.Context, *cloudtrail.LookupEventsInput, func(*cloudtrail.LookupEventsOutput, bool) bool, ...request.Option) error
+}
+
+// CloudWatchAPI contains the used operations of CloudWatch.
+type CloudWatchAPI interface {
+	GetMetricStatisticsWithContext(aws.Context, *cloudwatch.GetMetricStatisticsInput, ...request.Option) (*cloudwatch.GetMetricStatisticsOutput, error)
+	DescribeAlarmsPagesWithContext(aws.Context, *cloudwatch.DescribeAlarmsInput, func(*cloudwatch.DescribeAlarmsOutput, bool) bool, ...request.Option) error
+	GetMetricDataWithContext(aws.Context, *cloudwatch.GetMetricDataInput, ...request.Option) (*cloudwatch.GetMetricDataOutput, error)
+}
+
+// CodeDeployAPI contains the used operations of CodeDeploy.
+type CodeDeployAPI interface {
+	ListDeploymentsPagesWithContext(aws.Context, *codedeploy.ListDeploymentsInput, func(*codedeploy.ListDeploymentsOutput, bool) bool, ...request.Option) error
+	BatchGetDeploymentsWithContext(aws.Context, *codedeploy.BatchGetDeploymentsInput, ...request.Option) (*codedeploy.BatchGetDeploymentsOutput, error)
+	GetDeploymentGroupWithContext(aws.Context, *codedeploy.GetDeploymentGroupInput, ...request.Option) (*codedeploy.GetDeploymentGroupOutput, error)
+}
+
+// ELBAPI contains the used operations of Elastic Load Balancing.
+type ELBAPI interface {
+	DescribeInstanceHealthWithContext(aws.Context, *elb.DescribeInstanceHealthInput, ...request.Option) (*elb.DescribeInstanceHealthOutput, error)
+}
+
+// ELBV2API contains the used operations of Elastic Load Balancing v2.
+type ELBV2API interface {
+	DescribeTargetHealthWithContext(aws.Context, *elbv2.DescribeTargetHealthInput, ...request.Option) (*elbv2.DescribeTargetHealthOutput, error)
+}
+
+// EventBridgeAPI contains the used operations of EventBridge.
+type EventBridgeAPI interface {
+	PutEventsWithContext(aws.Context, *eventbridge.PutEventsInput, ...request.Option) (*eventbridge.PutEventsOutput, error)
+}
+
+// OrganizationsAPI contains the used operations of AWS Organizations.
+type OrganizationsAPI interface {
+	ListAccountsPagesWithContext(aws.Context, *organizations.ListAccountsInput, func(*organizations.ListAccountsOutput, bool) bool, ...request.Option) error
+}
+
+// PricingAPI contains the used operations of the AWS Price List API.
+type PricingAPI interface {
+	GetProductsWithContext(aws.Context, *pricing.GetProductsInput, ...request.Option) (*pricing.GetProductsOutput, error)
+}
+
+// S3API contains the used operations of the Simple Storage Service.
+type S3API interface {
+	PutObjectWithContext(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error)
+}
+
+// SNSAPI contains the used operations of the Simple Notification Service.
+type SNSAPI interface {
+	PublishWithContext(aws.Context, *sns.PublishInput, ...request.Option) (*sns.PublishOutput, error)
+}
+
+// STSAPI contains the used operations of the Security Token Service.
+type STSAPI interface {
+	GetCallerIdentityWithContext(aws.Context, *sts.GetCallerIdentityInput, ...request.Option) (*sts.GetCallerIdentityOutput, error)
+}
+
+func (c *Client) autoScaling() (AutoScalingAPI, error) {
+	if c.AutoScaling != nil {
+		return c.AutoScaling, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return autoscaling.New(sess), nil
+}
+
+func (c *Client) ec2() (EC2API, error) {
+	if c.EC2 != nil {
+		return c.EC2, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return ec2.New(sess), nil
+}
+
+func (c *Client) simpleDB() (SimpleDBAPI, error) {
+	if c.SimpleDB != nil {
+		return c.SimpleDB, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return simpledb.New(sess), nil
+}
+
+func (c *Client) cloudTrail() (CloudTrailAPI, error) {
+	if c.CloudTrail != nil {
+		return c.CloudTrail, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return cloudtrail.New(sess), nil
+}
+
+func (c *Client) cloudWatch() (CloudWatchAPI, error) {
+	if c.CloudWatch != nil {
+		return c.CloudWatch, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return cloudwatch.New(sess), nil
+}
+
+func (c *Client) codeDeploy() (CodeDeployAPI, error) {
+	if c.CodeDeploy != nil {
+		return c.CodeDeploy, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return codedeploy.New(sess), nil
+}
+
+func (c *Client) elb() (ELBAPI, error) {
+	if c.ELB != nil {
+		return c.ELB, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return elb.New(sess), nil
+}
+
+func (c *Client) elbv2() (ELBV2API, error) {
+	if c.ELBV2 != nil {
+		return c.ELBV2, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return elbv2.New(sess), nil
+}
+
+func (c *Client) eventBridge() (EventBridgeAPI, error) {
+	if c.EventBridge != nil {
+		return c.EventBridge, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return eventbridge.New(sess), nil
+}
+
+func (c *Client) organizations() (OrganizationsAPI, error) {
+	if c.Organizations != nil {
+		return c.Organizations, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return organizations.New(sess), nil
+}
+
+// pricing returns a client of the Price List API, which is only available in
+// a few regions, regardless of the client's region.
+func (c *Client) pricing() (PricingAPI, error) {
+	if c.Pricing != nil {
+		return c.Pricing, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	region := "us-east-1"
+	switch p := partition(aws.StringValue(sess.Config.Region)); p {
+	case endpoints.AwsPartitionID:
+	case endpoints.AwsCnPartitionID:
+		region = "cn-northwest-1"
+	default:
+		return nil, fmt.Errorf("price list API not available in partition %s", p)
+	}
+	return pricing.New(sess, &aws.Config{Region: aws.String(region)}), nil
+}
+
+func (c *Client) s3() (S3API, error) {
+	if c.S3 != nil {
+		return c.S3, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(sess), nil
+}
+
+func (c *Client) sns() (SNSAPI, error) {
+	if c.SNS != nil {
+		return c.SNS, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return sns.New(sess), nil
+}
+
+func (c *Client) sts() (STSAPI, error) {
+	if c.STS != nil {
+		return c.STS, nil
+	}
+	sess, err := c.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return sts.New(sess), nil
+}
@@ -0,0 +1,222 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/simpledb"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// SimpleDBItem is an item of a SimpleDB domain, such as a chaos event
+// recorded by Chaos Monkey.
+type SimpleDBItem struct {
+	Name       string              `json:"name"`
+	Attributes map[string][]string `json:"attributes"`
+}
+
+// SimpleDBDomainItemCount returns the number of items in a SimpleDB domain.
+func (c *Client) SimpleDBDomainItemCount(ctx context.Context, domainName string) (int, error) {
+	svc, err := c.simpleDB()
+	if err != nil {
+		return 0, err
+	}
+	out, err := svc.DomainMetadataWithContext(ctx, &simpledb.DomainMetadataInput{
+		DomainName: aws.String(domainName),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(aws.Int64Value(out.ItemCount)), nil
+}
+
+// SimpleDBItems returns all items of a SimpleDB domain.
+func (c *Client) SimpleDBItems(ctx context.Context, domainName string) ([]SimpleDBItem, error) {
+	return c.selectSimpleDBItems(ctx, "select * from "+quoteSimpleDBName(domainName))
+}
+
+// ExportSimpleDBDomain writes all items of a SimpleDB domain to w as JSON
+// array and returns the number of exported items.
+func (c *Client) ExportSimpleDBDomain(ctx context.Context, domainName string, w io.Writer) (int, error) {
+	items, err := c.SimpleDBItems(ctx, domainName)
+	if err != nil {
+		return 0, err
+	}
+	if items == nil {
+		items = []SimpleDBItem{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(items); err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+// BackupAndDeleteSimpleDBDomain exports all items of a SimpleDB domain to w
+// like ExportSimpleDBDomain, and deletes the domain only if the export
+// succeeded.
+func (c *Client) BackupAndDeleteSimpleDBDomain(ctx context.Context, domainName string, w io.Writer) error {
+	if _, err := c.ExportSimpleDBDomain(ctx, domainName, w); err != nil {
+		return fmt.Errorf("failed to back up SimpleDB domain: %s", err)
+	}
+	return c.DeleteSimpleDBDomain(ctx, domainName)
+}
+
+// ChaosRecords returns the chaos events recorded by Chaos Monkey in the given
+// SimpleDB domain (usually SIMIAN_ARMY) since the given time, ordered by
+// time. This allows to inspect chaos events while the REST API is down. If
+// the client has a region, only events in that region are returned.
+func (c *Client) ChaosRecords(ctx context.Context, domainName string, since time.Time) ([]chaosmonkey.Event, error) {
+	expr := "select * from " + quoteSimpleDBName(domainName) + c.chaosRecordsWhere(">=", since)
+	items, err := c.selectSimpleDBItems(ctx, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []chaosmonkey.Event
+	for _, item := range items {
+		attr := func(name string) string {
+			if v := item.Attributes[name]; len(v) > 0 {
+				return v[0]
+			}
+			return ""
+		}
+		// Types are recorded as "NAME|class", e.g. "CHAOS|...ChaosMonkey$Type"
+		typ := func(name string) string {
+			return strings.SplitN(attr(name), "|", 2)[0]
+		}
+		eventTime, err := strconv.ParseInt(attr("eventTime"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid eventTime of item %s: %s", item.Name, err)
+		}
+		resp := chaosmonkey.APIResponse{
+			ChaosType:  attr("chaosType"),
+			EventID:    attr("id"),
+			EventTime:  eventTime,
+			EventType:  typ("eventType"),
+			GroupName:  attr("groupName"),
+			GroupType:  typ("groupType"),
+			MonkeyType: typ("monkeyType"),
+			Region:     attr("region"),
+		}
+		events = append(events, *resp.ToEvent())
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].TriggeredAt.Before(events[j].TriggeredAt) })
+	return events, nil
+}
+
+// DefaultPruneBatchSize is the number of records deleted per request by
+// PruneChaosRecords unless configured otherwise. It is also the maximum
+// allowed by SimpleDB.
+const DefaultPruneBatchSize = 25
+
+// PruneChaosRecords deletes the chaos events recorded by Chaos Monkey in the
+// given SimpleDB domain before the given time, batchSize records
+// (DefaultPruneBatchSize if zero) per request. Unlike deleting the domain,
+// this keeps recent records and does not require a restart of Chaos Monkey.
+// If the client has a region, only events in that region are deleted. If
+// dryRun is set, nothing is deleted. It returns the number of records
+// deleted, or that would be deleted in a dry run, also if an error occurs
+// midway.
+func (c *Client) PruneChaosRecords(ctx context.Context, domainName string, before time.Time, batchSize int, dryRun bool) (int, error) {
+	if batchSize == 0 {
+		batchSize = DefaultPruneBatchSize
+	}
+	if batchSize < 0 || batchSize > DefaultPruneBatchSize {
+		return 0, fmt.Errorf("batch size must be between 1 and %d", DefaultPruneBatchSize)
+	}
+	expr := "select itemName() from " + quoteSimpleDBName(domainName) + c.chaosRecordsWhere("<", before)
+	items, err := c.selectSimpleDBItems(ctx, expr)
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		return len(items), nil
+	}
+
+	svc, err := c.simpleDB()
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for len(items) > 0 {
+		n := len(items)
+		if n > batchSize {
+			n = batchSize
+		}
+		in := &simpledb.BatchDeleteAttributesInput{DomainName: aws.String(domainName)}
+		for _, item := range items[:n] {
+			in.Items = append(in.Items, &simpledb.DeletableItem{Name: aws.String(item.Name)})
+		}
+		if _, err := svc.BatchDeleteAttributesWithContext(ctx, in); err != nil {
+			return deleted, err
+		}
+		deleted += n
+		items = items[n:]
+	}
+	return deleted, nil
+}
+
+// chaosRecordsWhere returns the where clause of select expressions for
+// chaos events recorded by Chaos Monkey in the client's region, if any,
+// whose time compares to t with op, e.g. ">=".
+func (c *Client) chaosRecordsWhere(op string, t time.Time) string {
+	where := " where recordType = 'MonkeyEvent' and monkeyType like 'CHAOS%'" +
+		" and eventTime " + op + " " + quoteSimpleDBValue(strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10))
+	if c.Region != "" {
+		where += " and region = " + quoteSimpleDBValue(c.Region)
+	}
+	return where
+}
+
+// selectSimpleDBItems returns all items matching a SimpleDB select
+// expression.
+func (c *Client) selectSimpleDBItems(ctx context.Context, expr string) ([]SimpleDBItem, error) {
+	svc, err := c.simpleDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []SimpleDBItem
+	err = svc.SelectPagesWithContext(ctx, &simpledb.SelectInput{
+		SelectExpression: aws.String(expr),
+		ConsistentRead:   aws.Bool(true),
+	}, func(out *simpledb.SelectOutput, last bool) bool {
+		for _, i := range out.Items {
+			item := SimpleDBItem{
+				Name:       aws.StringValue(i.Name),
+				Attributes: make(map[string][]string),
+			}
+			for _, a := range i.Attributes {
+				name := aws.StringValue(a.Name)
+				item.Attributes[name] = append(item.Attributes[name], aws.StringValue(a.Value))
+			}
+			items = append(items, item)
+		}
+		return !last
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// quoteSimpleDBName quotes a domain or attribute name for use in select
+// expressions.
+func quoteSimpleDBName(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// quoteSimpleDBValue quotes a value for use in select expressions.
+func quoteSimpleDBValue(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
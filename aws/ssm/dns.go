@@ -0,0 +1,33 @@
+package ssm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// domainPattern matches valid domain names, which are safe to pass to the
+// shell.
+var domainPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+
+// FailDomains makes the given domains unresolvable on the instances for
+// duration d (DefaultDuration if zero) by pointing them to 0.0.0.0 in
+// /etc/hosts, after which the entries are removed again. Unlike the FailDns
+// strategy, which blocks all DNS traffic, it only affects the given domains
+// and works with any resolver that honors /etc/hosts.
+func (in *Injector) FailDomains(ctx context.Context, instanceIDs, domains []string, d time.Duration) (*Command, error) {
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no domains given")
+	}
+	for _, domain := range domains {
+		if !domainPattern.MatchString(domain) {
+			return nil, fmt.Errorf("invalid domain %q", domain)
+		}
+	}
+	script := strings.Replace(failDomainsScript, "$DOMAINS", strings.Join(domains, " "), -1)
+	return in.send(ctx, chaosmonkey.StrategyFailDNS, script, instanceIDs, d)
+}
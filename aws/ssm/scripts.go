@@ -0,0 +1,60 @@
+package ssm
+
+import chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+
+// scripts maps strategies to shell scripts, which are modeled after the
+// scripts of Chaos Monkey. $DURATION is replaced by the duration of chaos in
+// seconds.
+var scripts = map[chaosmonkey.Strategy]string{
+	chaosmonkey.StrategyBurnCPU: `
+for i in $(seq $(nproc)); do
+  timeout $DURATION sh -c 'while true; do :; done' &
+done
+wait
+`,
+	chaosmonkey.StrategyBurnIO: `
+end=$(($(date +%s) + $DURATION))
+while [ $(date +%s) -lt $end ]; do
+  dd if=/dev/urandom of=/var/tmp/chaosmonkey-burnio bs=1M count=1024 oflag=direct 2>/dev/null
+done
+rm -f /var/tmp/chaosmonkey-burnio
+`,
+	chaosmonkey.StrategyFailDNS: `
+for proto in udp tcp; do
+  iptables -I OUTPUT -p $proto --dport 53 -j DROP
+done
+sleep $DURATION
+for proto in udp tcp; do
+  iptables -D OUTPUT -p $proto --dport 53 -j DROP
+done
+`,
+	chaosmonkey.StrategyFillDisk: `
+dd if=/dev/zero of=/var/tmp/chaosmonkey-filldisk bs=1M 2>/dev/null
+sleep $DURATION
+rm -f /var/tmp/chaosmonkey-filldisk
+`,
+	chaosmonkey.StrategyKillProcesses: `
+end=$(($(date +%s) + $DURATION))
+while [ $(date +%s) -lt $end ]; do
+  pkill -KILL -f java
+  pkill -KILL -f python
+  sleep 1
+done
+`,
+	chaosmonkey.StrategyNetworkLatency: `
+dev=$(ip route show default | awk '{print $5; exit}')
+tc qdisc add dev $dev root netem delay 1000ms 500ms
+sleep $DURATION
+tc qdisc del dev $dev root netem
+`,
+}
+
+// failDomainsScript blackholes the domains in $DOMAINS by adding them to
+// /etc/hosts, marked so that only the added lines are removed afterwards.
+const failDomainsScript = `
+for d in $DOMAINS; do
+  echo "0.0.0.0 $d # chaosmonkey" >> /etc/hosts
+done
+sleep $DURATION
+sed -i '/ # chaosmonkey$/d' /etc/hosts
+`
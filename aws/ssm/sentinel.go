@@ -0,0 +1,57 @@
+package ssm
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	ssmapi "github.com/aws/aws-sdk-go/service/ssm"
+
+	chaosaws "github.com/FlyLevin/chaosmonkey/aws"
+)
+
+// ParameterSentinel is a stop sentinel of chaosmonkey.Client reporting an
+// emergency stop while a Systems Manager parameter is set to anything but a
+// false value like "0" or "false". As all processes of an account can read
+// the same parameter, it stops chaos org-wide, e.g. with
+//
+//	aws ssm put-parameter --name /chaosmonkey/stop --type String --overwrite --value "incident 1234"
+//
+// A parameter that does not exist does not stop chaos.
+type ParameterSentinel struct {
+	// Client used to create a session, which determines region and
+	// credentials
+	Client *chaosaws.Client
+
+	// Optional Systems Manager client to use instead of one created from
+	// Client, e.g. a mock in tests
+	SSM API
+
+	// Name of the parameter
+	Name string
+}
+
+// CheckStop checks the value of the parameter.
+func (s *ParameterSentinel) CheckStop(ctx context.Context) error {
+	svc, err := newAPI(s.Client, s.SSM)
+	if err != nil {
+		return err
+	}
+	out, err := svc.GetParameterWithContext(ctx, &ssmapi.GetParameterInput{
+		Name:           aws.String(s.Name),
+		WithDecryption: aws.Bool(true),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssmapi.ErrCodeParameterNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot check parameter %s: %s", s.Name, err)
+	}
+	v := aws.StringValue(out.Parameter.Value)
+	if stop, err := strconv.ParseBool(v); v == "" || (err == nil && !stop) {
+		return nil
+	}
+	return fmt.Errorf("parameter %s is set to %q", s.Name, v)
+}
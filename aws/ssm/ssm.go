@@ -0,0 +1,169 @@
+// Package ssm injects chaos into EC2 instances by sending shell scripts via
+// AWS Systems Manager Run Command. Unlike Chaos Monkey, it needs no SSH
+// access to instances, only the SSM agent and an instance profile allowing
+// Systems Manager to manage them.
+package ssm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	ssmapi "github.com/aws/aws-sdk-go/service/ssm"
+
+	chaosaws "github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// DefaultDuration is the duration of injected chaos unless configured
+// otherwise.
+const DefaultDuration = 5 * time.Minute
+
+// API contains the used operations of Systems Manager.
+type API interface {
+	SendCommandWithContext(aws.Context, *ssmapi.SendCommandInput, ...request.Option) (*ssmapi.SendCommandOutput, error)
+	GetCommandInvocationWithContext(aws.Context, *ssmapi.GetCommandInvocationInput, ...request.Option) (*ssmapi.GetCommandInvocationOutput, error)
+	GetParameterWithContext(aws.Context, *ssmapi.GetParameterInput, ...request.Option) (*ssmapi.GetParameterOutput, error)
+}
+
+// Injector injects chaos via Systems Manager.
+type Injector struct {
+	// Client used to create a session, which determines region and
+	// credentials, and to look up the groups of instances. Chaos is only
+	// injected via its Chaos client (see chaosaws.Client.Inject).
+	Client *chaosaws.Client
+
+	// Optional Systems Manager client to use instead of one created from
+	// Client, e.g. a mock in tests
+	SSM API
+}
+
+// Command is a chaos script sent to instances.
+type Command struct {
+	ID          string
+	Strategy    chaosmonkey.Strategy
+	InstanceIDs []string
+	Duration    time.Duration
+
+	// Whether the command was only simulated in a dry run and not sent
+	DryRun bool
+}
+
+// Strategies returns the chaos strategies supported by the injector.
+func Strategies() []chaosmonkey.Strategy {
+	var strategies []chaosmonkey.Strategy
+	for s := range scripts {
+		strategies = append(strategies, s)
+	}
+	sort.Slice(strategies, func(i, j int) bool { return strategies[i] < strategies[j] })
+	return strategies
+}
+
+// Inject runs the script of the given strategy on the instances for duration
+// d (DefaultDuration if zero), after which the script undoes its effects, if
+// possible. The instances must be part of the same auto scaling group, which
+// the chaos event is against.
+func (in *Injector) Inject(ctx context.Context, s chaosmonkey.Strategy, instanceIDs []string, d time.Duration) (*Command, error) {
+	script, ok := scripts[s]
+	if !ok {
+		return nil, fmt.Errorf("strategy %s is not supported via SSM", s)
+	}
+	return in.send(ctx, s, script, instanceIDs, d)
+}
+
+// send runs the script on the instances for duration d (DefaultDuration if
+// zero).
+func (in *Injector) send(ctx context.Context, s chaosmonkey.Strategy, script string, instanceIDs []string, d time.Duration) (*Command, error) {
+	if len(instanceIDs) == 0 {
+		return nil, fmt.Errorf("no instances given")
+	}
+	if d == 0 {
+		d = DefaultDuration
+	}
+	svc, err := in.ssm()
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Client == nil {
+		return nil, chaosaws.ErrNoChaosClient
+	}
+	group, err := in.Client.InstancesGroup(ctx, instanceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := &Command{
+		Strategy:    s,
+		InstanceIDs: instanceIDs,
+		Duration:    d,
+	}
+	seconds := int64(d / time.Second)
+	commands := strings.Replace(script, "$DURATION", fmt.Sprint(seconds), -1)
+	ev, err := in.Client.Inject(ctx, group, s, func(ctx context.Context) (*chaosmonkey.Event, error) {
+		out, err := svc.SendCommandWithContext(ctx, &ssmapi.SendCommandInput{
+			DocumentName: aws.String("AWS-RunShellScript"),
+			Comment:      aws.String("chaosmonkey " + string(s)),
+			InstanceIds:  aws.StringSlice(instanceIDs),
+			Parameters: map[string][]*string{
+				"commands":         {aws.String(commands)},
+				"executionTimeout": {aws.String(fmt.Sprint(seconds + 60))},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		cmd.ID = aws.StringValue(out.Command.CommandId)
+		return nil, nil
+	})
+	if ev == nil {
+		return nil, err
+	}
+	cmd.DryRun = ev.DryRun
+	return cmd, err
+}
+
+// Status returns the status of the command on each instance, e.g.
+// "InProgress", "Success", or "Failed". Commands of a dry run have no status.
+func (in *Injector) Status(ctx context.Context, cmd *Command) (map[string]string, error) {
+	if cmd.DryRun {
+		return map[string]string{}, nil
+	}
+	svc, err := in.ssm()
+	if err != nil {
+		return nil, err
+	}
+	status := make(map[string]string, len(cmd.InstanceIDs))
+	for _, id := range cmd.InstanceIDs {
+		out, err := svc.GetCommandInvocationWithContext(ctx, &ssmapi.GetCommandInvocationInput{
+			CommandId:  aws.String(cmd.ID),
+			InstanceId: aws.String(id),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status on instance %s: %s", id, err)
+		}
+		status[id] = aws.StringValue(out.Status)
+	}
+	return status, nil
+}
+
+func (in *Injector) ssm() (API, error) {
+	return newAPI(in.Client, in.SSM)
+}
+
+// newAPI returns svc if it is set, and a Systems Manager client created from
+// client otherwise.
+func newAPI(client *chaosaws.Client, svc API) (API, error) {
+	if svc != nil {
+		return svc, nil
+	}
+	sess, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return ssmapi.New(sess), nil
+}
@@ -0,0 +1,160 @@
+package ssm_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	ssmapi "github.com/aws/aws-sdk-go/service/ssm"
+
+	chaosaws "github.com/FlyLevin/chaosmonkey/aws"
+	"github.com/FlyLevin/chaosmonkey/aws/awsmock"
+	"github.com/FlyLevin/chaosmonkey/aws/ssm"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+type fakeSSM struct {
+	sent      *ssmapi.SendCommandInput
+	parameter *string
+}
+
+func (f *fakeSSM) SendCommandWithContext(ctx aws.Context, in *ssmapi.SendCommandInput, _ ...request.Option) (*ssmapi.SendCommandOutput, error) {
+	f.sent = in
+	return &ssmapi.SendCommandOutput{Command: &ssmapi.Command{CommandId: aws.String("cmd-1")}}, nil
+}
+
+func (f *fakeSSM) GetCommandInvocationWithContext(ctx aws.Context, in *ssmapi.GetCommandInvocationInput, _ ...request.Option) (*ssmapi.GetCommandInvocationOutput, error) {
+	return &ssmapi.GetCommandInvocationOutput{Status: aws.String(ssmapi.CommandInvocationStatusInProgress)}, nil
+}
+
+func (f *fakeSSM) GetParameterWithContext(ctx aws.Context, in *ssmapi.GetParameterInput, _ ...request.Option) (*ssmapi.GetParameterOutput, error) {
+	if f.parameter == nil {
+		return nil, awserr.New(ssmapi.ErrCodeParameterNotFound, "parameter not found", nil)
+	}
+	return &ssmapi.GetParameterOutput{Parameter: &ssmapi.Parameter{Value: f.parameter}}, nil
+}
+
+// awsClient returns a client whose instances are part of the given groups
+// by ID, and which checks injected chaos against the given guards.
+func awsClient(t *testing.T, groups map[string]string, guards ...chaosmonkey.Guard) *chaosaws.Client {
+	chaos, err := chaosmonkey.NewClient(&chaosmonkey.Config{Guards: guards})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &chaosaws.Client{
+		Chaos: chaos,
+		EC2: &awsmock.EC2{
+			DescribeInstancesPagesFunc: func(ctx aws.Context, in *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool) error {
+				id := aws.StringValue(in.InstanceIds[0])
+				fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: []*ec2.Instance{{
+					InstanceId: aws.String(id),
+					Tags:       []*ec2.Tag{{Key: aws.String("aws:autoscaling:groupName"), Value: aws.String(groups[id])}},
+				}}}}}, true)
+				return nil
+			},
+		},
+	}
+}
+
+func TestInject(t *testing.T) {
+	fake := &fakeSSM{}
+	injector := &ssm.Injector{SSM: fake, Client: awsClient(t, map[string]string{"i-1": "payments-api"})}
+
+	cmd, err := injector.Inject(context.Background(), chaosmonkey.StrategyBurnCPU, []string{"i-1"}, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.ID != "cmd-1" {
+		t.Errorf("expected command ID cmd-1, got %q", cmd.ID)
+	}
+	script := aws.StringValue(fake.sent.Parameters["commands"][0])
+	if !strings.Contains(script, "timeout 120 ") {
+		t.Errorf("expected script to run for 120 seconds, got:\n%s", script)
+	}
+
+	status, err := injector.Status(context.Background(), cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status["i-1"] != "InProgress" {
+		t.Errorf("expected status InProgress, got %q", status["i-1"])
+	}
+
+	if _, err := injector.Inject(context.Background(), chaosmonkey.StrategyShutdownInstance, []string{"i-1"}, 0); err == nil {
+		t.Error("expected error for unsupported strategy")
+	}
+}
+
+func TestInjectChecks(t *testing.T) {
+	fake := &fakeSSM{}
+	var refused []string
+	injector := &ssm.Injector{
+		SSM: fake,
+		Client: awsClient(t, map[string]string{"i-1": "payments-api", "i-2": "payments-api", "i-3": "search-api"},
+			chaosmonkey.GuardFunc(func(t chaosmonkey.Target) error {
+				refused = append(refused, t.AutoScalingGroupName)
+				return errors.New("frozen")
+			})),
+	}
+
+	if _, err := injector.Inject(context.Background(), chaosmonkey.StrategyBurnCPU, []string{"i-1", "i-3"}, 0); err == nil {
+		t.Error("expected error for instances of different groups")
+	}
+	_, err := injector.Inject(context.Background(), chaosmonkey.StrategyBurnCPU, []string{"i-1", "i-2"}, 0)
+	if _, ok := err.(*chaosmonkey.GuardError); !ok {
+		t.Fatalf("expected *GuardError, got %v", err)
+	}
+	if len(refused) != 1 || refused[0] != "payments-api" {
+		t.Errorf("expected guard to refuse payments-api, got %v", refused)
+	}
+	if fake.sent != nil {
+		t.Error("expected no command to be sent")
+	}
+	if _, err := (&ssm.Injector{SSM: fake}).Inject(context.Background(), chaosmonkey.StrategyBurnCPU, []string{"i-1"}, 0); err != chaosaws.ErrNoChaosClient {
+		t.Errorf("expected ErrNoChaosClient, got %v", err)
+	}
+}
+
+func TestFailDomains(t *testing.T) {
+	fake := &fakeSSM{}
+	injector := &ssm.Injector{SSM: fake, Client: awsClient(t, map[string]string{"i-1": "payments-api"})}
+
+	_, err := injector.FailDomains(context.Background(), []string{"i-1"}, []string{"db.internal", "api.example.com"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := aws.StringValue(fake.sent.Parameters["commands"][0])
+	if !strings.Contains(script, "for d in db.internal api.example.com;") || !strings.Contains(script, "sleep 60") {
+		t.Errorf("unexpected script:\n%s", script)
+	}
+
+	if _, err := injector.FailDomains(context.Background(), []string{"i-1"}, []string{"evil.com; rm -rf /"}, 0); err == nil {
+		t.Error("expected error for invalid domain")
+	}
+}
+
+func TestParameterSentinel(t *testing.T) {
+	fake := &fakeSSM{}
+	sentinel := &ssm.ParameterSentinel{SSM: fake, Name: "/chaosmonkey/stop"}
+	for _, tc := range []struct {
+		value *string
+		stop  bool
+	}{
+		{nil, false},
+		{aws.String(""), false},
+		{aws.String("false"), false},
+		{aws.String("incident 1234"), true},
+		{aws.String("1"), true},
+	} {
+		fake.parameter = tc.value
+		if err := sentinel.CheckStop(context.Background()); (err != nil) != tc.stop {
+			t.Errorf("value %q: expected stop %v, got %v", aws.StringValue(tc.value), tc.stop, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package aws
+
+import (
+	"fmt"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// Template generates starter campaigns for a common architecture, covering
+// the failure modes relevant to it, which helps teams new to chaos
+// engineering to get going. Generated campaigns are meant to be reviewed and
+// adapted before they are run.
+type Template struct {
+	// Name of the template, e.g. "web-tier"
+	Name string `json:"name" yaml:"name"`
+
+	// What kind of system the template is for
+	Description string `json:"description" yaml:"description"`
+
+	steps func(groups []AutoScalingGroup) []chaosmonkey.CampaignStep
+}
+
+// Templates are the known campaign templates.
+var Templates = []*Template{
+	{
+		Name:        "web-tier",
+		Description: "Web tier spread across availability zones behind a load balancer",
+		steps:       webTierSteps,
+	},
+	{
+		Name:        "app-database",
+		Description: "Application tier in front of a database like Aurora, which is not targeted itself",
+		steps:       appDatabaseSteps,
+	},
+	{
+		Name:        "queue-worker",
+		Description: "Workers consuming queues or streams and writing to S3 or DynamoDB",
+		steps:       queueWorkerSteps,
+	},
+}
+
+// LookupTemplate returns the template with the given name.
+func LookupTemplate(name string) (*Template, bool) {
+	for _, t := range Templates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Generate returns a campaign for the given auto scaling groups, e.g. those
+// selected by FilterAutoScalingGroups. Steps only target the groups they are
+// relevant to, e.g. those behind load balancers for the web tier.
+func (t *Template) Generate(name string, groups []AutoScalingGroup) (*chaosmonkey.Campaign, error) {
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no auto scaling groups to generate a campaign for")
+	}
+	steps := t.steps(groups)
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("none of the auto scaling groups fit template %s", t.Name)
+	}
+	if name == "" {
+		name = t.Name
+	}
+	cp := &chaosmonkey.Campaign{Name: name, Steps: steps}
+	if err := cp.Validate(); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// templateWait is how long generated steps wait for the system to recover.
+const templateWait = 10 * time.Minute
+
+// templateStep returns a step against the named groups, or nil if there are
+// none.
+func templateStep(name string, strategy chaosmonkey.Strategy, groups []string) []chaosmonkey.CampaignStep {
+	if len(groups) == 0 {
+		return nil
+	}
+	return []chaosmonkey.CampaignStep{{
+		Name:     name,
+		Groups:   groups,
+		Strategy: strategy,
+		Wait:     templateWait,
+	}}
+}
+
+// groupNames returns the names of the groups for which match returns true.
+func groupNames(groups []AutoScalingGroup, match func(g *AutoScalingGroup) bool) []string {
+	var names []string
+	for i := range groups {
+		if match(&groups[i]) {
+			names = append(names, groups[i].Name)
+		}
+	}
+	return names
+}
+
+func anyGroup(*AutoScalingGroup) bool { return true }
+
+func webTierSteps(groups []AutoScalingGroup) []chaosmonkey.CampaignStep {
+	balanced := func(g *AutoScalingGroup) bool { return len(g.LoadBalancers) > 0 }
+	// Only groups with spare capacity are expected to survive losing more
+	// than one instance
+	spare := func(g *AutoScalingGroup) bool { return balanced(g) && g.InstancesInService >= 3 }
+
+	var steps []chaosmonkey.CampaignStep
+	steps = append(steps, templateStep("lose an instance", chaosmonkey.StrategyShutdownInstance, groupNames(groups, balanced))...)
+	if lose := templateStep("lose capacity", chaosmonkey.StrategyShutdownInstance, groupNames(groups, spare)); lose != nil {
+		lose[0].Count = 2
+		lose[0].Interval = 2 * time.Minute
+		steps = append(steps, lose...)
+	}
+	steps = append(steps, templateStep("slow instances", chaosmonkey.StrategyNetworkLatency, groupNames(groups, balanced))...)
+	steps = append(steps, templateStep("saturated instances", chaosmonkey.StrategyBurnCPU, groupNames(groups, balanced))...)
+	steps = append(steps, templateStep("DNS failure", chaosmonkey.StrategyFailDNS, groupNames(groups, balanced))...)
+	return steps
+}
+
+func appDatabaseSteps(groups []AutoScalingGroup) []chaosmonkey.CampaignStep {
+	names := groupNames(groups, anyGroup)
+	var steps []chaosmonkey.CampaignStep
+	steps = append(steps, templateStep("slow database connections", chaosmonkey.StrategyNetworkLatency, names)...)
+	steps = append(steps, templateStep("dropped database connections", chaosmonkey.StrategyNetworkLoss, names)...)
+	steps = append(steps, templateStep("restarted connection pools", chaosmonkey.StrategyKillProcesses, names)...)
+	steps = append(steps, templateStep("lose an instance", chaosmonkey.StrategyShutdownInstance, names)...)
+	return steps
+}
+
+func queueWorkerSteps(groups []AutoScalingGroup) []chaosmonkey.CampaignStep {
+	names := groupNames(groups, anyGroup)
+	var steps []chaosmonkey.CampaignStep
+	steps = append(steps, templateStep("crashed workers", chaosmonkey.StrategyKillProcesses, names)...)
+	if lose := templateStep("lose workers", chaosmonkey.StrategyShutdownInstance, names); lose != nil {
+		lose[0].Count = 2
+		lose[0].Interval = 2 * time.Minute
+		steps = append(steps, lose...)
+	}
+	steps = append(steps, templateStep("full disks", chaosmonkey.StrategyFillDisk, names)...)
+	steps = append(steps, templateStep("S3 failure", chaosmonkey.StrategyFailS3, names)...)
+	steps = append(steps, templateStep("DynamoDB failure", chaosmonkey.StrategyFailDynamoDB, names)...)
+	return steps
+}
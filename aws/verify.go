@@ -0,0 +1,141 @@
+package aws
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// DefaultVerifyInterval is the time between polls of VerifyTermination unless
+// configured otherwise.
+const DefaultVerifyInterval = 10 * time.Second
+
+// VerificationResult describes the termination of instances of an auto
+// scaling group and their replacement after a chaos event.
+type VerificationResult struct {
+	AutoScalingGroupName string `json:"autoScalingGroupName" yaml:"autoScalingGroupName"`
+
+	// IDs of instances that were terminated or left the group
+	Terminated []string `json:"terminated" yaml:"terminated"`
+
+	// IDs of new instances in service
+	Replacements []string `json:"replacements" yaml:"replacements"`
+
+	// IDs of the replacements that were promoted from the warm pool of the
+	// group rather than launched, which makes them much faster to recover
+	WarmPoolReplacements []string `json:"warmPoolReplacements,omitempty" yaml:"warmPoolReplacements,omitempty"`
+
+	// Time when verification started
+	Started time.Time `json:"started" yaml:"started"`
+
+	// Time until the first instance was seen terminated, zero if none was
+	TerminatedAfter time.Duration `json:"terminatedAfter" yaml:"terminatedAfter"`
+
+	// Time until replacements for all terminated instances were in service,
+	// zero if they were not
+	ReplacedAfter time.Duration `json:"replacedAfter" yaml:"replacedAfter"`
+
+	// Lifecycle hooks of the group, which may have delayed termination or
+	// replacement
+	LifecycleHooks []LifecycleHook `json:"lifecycleHooks,omitempty" yaml:"lifecycleHooks,omitempty"`
+}
+
+// Replaced reports whether at least one instance was terminated and all
+// terminated instances were replaced.
+func (r *VerificationResult) Replaced() bool {
+	return len(r.Terminated) > 0 && len(r.Replacements) >= len(r.Terminated)
+}
+
+// VerifyTermination polls the instances of the given auto scaling group
+// until an instance of before, the instances of the group prior to the chaos
+// event, has been terminated and replaced by a new instance in service. It
+// polls every interval (DefaultVerifyInterval if zero). The result includes
+// the lifecycle hooks of the group, as they can delay termination
+// considerably, and tells replacements promoted from a warm pool from those
+// launched cold. If ctx is done first, the result so far is returned
+// together with ctx.Err().
+func (c *Client) VerifyTermination(ctx context.Context, group string, before []Instance, interval time.Duration) (*VerificationResult, error) {
+	if interval == 0 {
+		interval = DefaultVerifyInterval
+	}
+	known := make(map[string]bool, len(before))
+	for _, i := range before {
+		known[i.ID] = true
+	}
+	res := &VerificationResult{
+		AutoScalingGroupName: group,
+		Started:              time.Now().UTC(),
+	}
+	hooks, err := c.LifecycleHooks(ctx, group)
+	if err != nil {
+		return res, err
+	}
+	res.LifecycleHooks = hooks
+	terminated := make(map[string]bool)
+	replaced := make(map[string]bool)
+
+	// Instances seen in the warm pool, which keep their ID when promoted
+	warm := make(map[string]bool)
+	hasWarmPool := true
+
+	for {
+		if hasWarmPool {
+			var pool []Instance
+			pool, hasWarmPool, err = c.warmPool(ctx, group)
+			if err != nil {
+				return res, err
+			}
+			for _, i := range pool {
+				warm[i.ID] = true
+			}
+		}
+		instances, err := c.Instances(ctx, group)
+		if err != nil {
+			return res, err
+		}
+		present := make(map[string]bool, len(instances))
+		for _, i := range instances {
+			present[i.ID] = true
+			switch {
+			case known[i.ID] && isTerminating(i.LifecycleState) && !terminated[i.ID]:
+				terminated[i.ID] = true
+				res.Terminated = append(res.Terminated, i.ID)
+			case !known[i.ID] && i.LifecycleState == autoscaling.LifecycleStateInService && !replaced[i.ID]:
+				replaced[i.ID] = true
+				res.Replacements = append(res.Replacements, i.ID)
+				if warm[i.ID] {
+					res.WarmPoolReplacements = append(res.WarmPoolReplacements, i.ID)
+				}
+			}
+		}
+		for _, i := range before {
+			if !present[i.ID] && !terminated[i.ID] {
+				terminated[i.ID] = true
+				res.Terminated = append(res.Terminated, i.ID)
+			}
+		}
+
+		elapsed := time.Since(res.Started)
+		if len(res.Terminated) > 0 && res.TerminatedAfter == 0 {
+			res.TerminatedAfter = elapsed
+		}
+		if res.Replaced() {
+			res.ReplacedAfter = elapsed
+			return res, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return res, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// isTerminating reports whether the lifecycle state of an instance means
+// that it is being or has been terminated.
+func isTerminating(state string) bool {
+	return strings.HasPrefix(state, "Terminating") || state == "Terminated"
+}
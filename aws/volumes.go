@@ -0,0 +1,150 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// DefaultAttachInterval is the time between polls of AttachVolumes for
+// detached volumes to become available unless configured otherwise.
+const DefaultAttachInterval = 5 * time.Second
+
+// Volume is an EBS volume attached to an EC2 instance.
+type Volume struct {
+	ID         string `json:"id" yaml:"id"`
+	InstanceID string `json:"instanceId" yaml:"instanceId"`
+
+	// Device name of the attachment, e.g. "/dev/sdf"
+	Device string `json:"device" yaml:"device"`
+}
+
+// Volumes returns the EBS volumes attached to the given instance, except for
+// its root volume.
+func (c *Client) Volumes(ctx context.Context, instanceID string) ([]Volume, error) {
+	inst, err := c.describeInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return volumes(inst), nil
+}
+
+// volumes returns the EBS volumes attached to the instance, except for its
+// root volume.
+func volumes(inst *ec2.Instance) []Volume {
+	root := aws.StringValue(inst.RootDeviceName)
+	var volumes []Volume
+	for _, m := range inst.BlockDeviceMappings {
+		device := aws.StringValue(m.DeviceName)
+		if m.Ebs == nil || device == root {
+			continue
+		}
+		volumes = append(volumes, Volume{
+			ID:         aws.StringValue(m.Ebs.VolumeId),
+			InstanceID: aws.StringValue(inst.InstanceId),
+			Device:     device,
+		})
+	}
+	return volumes
+}
+
+// DetachVolumes detaches all non-root EBS volumes of the given instance, like
+// the DetachVolumes strategy but without running anything on the instance.
+// If force is set, volumes are detached even if the instance does not
+// release them, which risks data loss. It returns the detached volumes,
+// including those detached before an error, which can be reattached with
+// AttachVolumes.
+//
+// Detaching is a chaos event against the group of the instance, subject to
+// Client.Chaos (see Inject). In a dry run, no volumes are detached.
+func (c *Client) DetachVolumes(ctx context.Context, instanceID string, force bool) ([]Volume, error) {
+	inst, err := c.describeInstance(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := c.ec2()
+	if err != nil {
+		return nil, err
+	}
+	var detached []Volume
+	_, err = c.Inject(ctx, instanceGroup(inst), chaosmonkey.StrategyDetachVolumes, func(ctx context.Context) (*chaosmonkey.Event, error) {
+		for _, v := range volumes(inst) {
+			_, err := svc.DetachVolumeWithContext(ctx, &ec2.DetachVolumeInput{
+				VolumeId:   aws.String(v.ID),
+				InstanceId: aws.String(v.InstanceID),
+				Device:     aws.String(v.Device),
+				Force:      aws.Bool(force),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to detach volume %s: %s", v.ID, err)
+			}
+			detached = append(detached, v)
+		}
+		return &chaosmonkey.Event{InstanceID: instanceID}, nil
+	})
+	return detached, err
+}
+
+// AttachVolumes reattaches volumes detached by DetachVolumes to their
+// instances under their original device names. As detaching takes a while,
+// it polls every interval (DefaultAttachInterval if zero) until a volume is
+// available before attaching it. It tries to attach all volumes and returns
+// the first error.
+func (c *Client) AttachVolumes(ctx context.Context, volumes []Volume, interval time.Duration) error {
+	if interval == 0 {
+		interval = DefaultAttachInterval
+	}
+	svc, err := c.ec2()
+	if err != nil {
+		return err
+	}
+
+	var first error
+	for _, v := range volumes {
+		err := c.waitVolumeAvailable(ctx, v.ID, interval)
+		if err == nil {
+			_, err = svc.AttachVolumeWithContext(ctx, &ec2.AttachVolumeInput{
+				VolumeId:   aws.String(v.ID),
+				InstanceId: aws.String(v.InstanceID),
+				Device:     aws.String(v.Device),
+			})
+		}
+		if err != nil && first == nil {
+			first = fmt.Errorf("failed to attach volume %s: %s", v.ID, err)
+		}
+	}
+	return first
+}
+
+// waitVolumeAvailable polls the state of the given volume until it is
+// available or ctx is done.
+func (c *Client) waitVolumeAvailable(ctx context.Context, id string, interval time.Duration) error {
+	svc, err := c.ec2()
+	if err != nil {
+		return err
+	}
+	for {
+		out, err := svc.DescribeVolumesWithContext(ctx, &ec2.DescribeVolumesInput{
+			VolumeIds: []*string{aws.String(id)},
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.Volumes) == 0 {
+			return fmt.Errorf("volume %s not found", id)
+		}
+		if aws.StringValue(out.Volumes[0].State) == ec2.VolumeStateAvailable {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// WarmPoolInstances returns the pre-initialized instances in the warm pool of
+// the given auto scaling group, whose lifecycle states start with "Warmed:".
+// The group promotes them instead of launching new instances when it scales
+// out or replaces instances, which shortens recovery considerably. It
+// returns nil if the group has no warm pool.
+func (c *Client) WarmPoolInstances(ctx context.Context, group string) ([]Instance, error) {
+	instances, _, err := c.warmPool(ctx, group)
+	return instances, err
+}
+
+// warmPool returns the instances in the warm pool of the given group and
+// whether the group has a warm pool at all.
+func (c *Client) warmPool(ctx context.Context, group string) ([]Instance, bool, error) {
+	svc, err := c.autoScaling()
+	if err != nil {
+		return nil, false, err
+	}
+	var (
+		instances  []Instance
+		configured bool
+	)
+	in := &autoscaling.DescribeWarmPoolInput{AutoScalingGroupName: aws.String(group)}
+	for {
+		out, err := svc.DescribeWarmPoolWithContext(ctx, in)
+		if err != nil {
+			return nil, false, err
+		}
+		configured = configured || out.WarmPoolConfiguration != nil
+		for _, i := range out.Instances {
+			instances = append(instances, Instance{
+				ID:                   aws.StringValue(i.InstanceId),
+				AutoScalingGroupName: group,
+				LifecycleState:       aws.StringValue(i.LifecycleState),
+				AvailabilityZone:     aws.StringValue(i.AvailabilityZone),
+				InstanceType:         aws.StringValue(i.InstanceType),
+			})
+		}
+		if aws.StringValue(out.NextToken) == "" {
+			return instances, configured, nil
+		}
+		in.NextToken = out.NextToken
+	}
+}
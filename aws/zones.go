@@ -0,0 +1,162 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// InstancesByZone returns the instances of the given auto scaling group
+// grouped by availability zone.
+func (c *Client) InstancesByZone(ctx context.Context, group string) (map[string][]Instance, error) {
+	instances, err := c.Instances(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	zones := make(map[string][]Instance)
+	for _, i := range instances {
+		zones[i.AvailabilityZone] = append(zones[i.AvailabilityZone], i)
+	}
+	return zones, nil
+}
+
+// zoneInstances returns the in-service instances of the given group in the
+// given zone. It refuses to return all in-service instances of the group, as
+// failing them would take down the group rather than a zone.
+func (c *Client) zoneInstances(ctx context.Context, group, zone string) ([]Instance, error) {
+	zones, err := c.InstancesByZone(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	var victims []Instance
+	others := 0
+	for z, instances := range zones {
+		for _, i := range instances {
+			if i.LifecycleState != autoscaling.LifecycleStateInService {
+				continue
+			}
+			if z == zone {
+				victims = append(victims, i)
+			} else {
+				others++
+			}
+		}
+	}
+	switch {
+	case len(victims) == 0:
+		return nil, fmt.Errorf("group %s has no instances in service in %s", group, zone)
+	case others == 0:
+		return nil, fmt.Errorf("all instances in service of group %s are in %s", group, zone)
+	}
+	return victims, nil
+}
+
+// FailZone terminates all instances in service of an auto scaling group in
+// the given availability zone, simulating the failure of the zone. It
+// returns the IDs of terminated instances.
+//
+// The failure is a chaos event against the group, subject to Client.Chaos
+// (see Inject). In a dry run, no instances are terminated.
+func (c *Client) FailZone(ctx context.Context, group, zone string) ([]string, error) {
+	victims, err := c.zoneInstances(ctx, group, zone)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := c.ec2()
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, i := range victims {
+		ids = append(ids, i.ID)
+	}
+	ev, err := c.Inject(ctx, group, chaosmonkey.StrategyShutdownInstance, func(ctx context.Context) (*chaosmonkey.Event, error) {
+		_, err := svc.TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{
+			InstanceIds: aws.StringSlice(ids),
+		})
+		return nil, err
+	})
+	if ev == nil || ev.DryRun {
+		return nil, err
+	}
+	return ids, err
+}
+
+// ZoneIsolation records the security groups of instances isolated by
+// IsolateZone, so that they can be restored.
+type ZoneIsolation struct {
+	AutoScalingGroupName string
+	AvailabilityZone     string
+
+	// Original security groups by instance ID
+	SecurityGroups map[string][]string
+}
+
+// IsolateZone cuts off the network of all instances in service of an auto
+// scaling group in the given availability zone by replacing their security
+// groups with the given one, which should allow no traffic. Only the primary
+// network interface of instances is changed. Call RestoreZone to undo the
+// isolation; instances isolated before an error are included in the
+// returned isolation.
+//
+// The isolation is a chaos event against the group, subject to Client.Chaos
+// (see Inject). In a dry run, no instances are isolated.
+func (c *Client) IsolateZone(ctx context.Context, group, zone, securityGroupID string) (*ZoneIsolation, error) {
+	victims, err := c.zoneInstances(ctx, group, zone)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := c.ec2()
+	if err != nil {
+		return nil, err
+	}
+
+	iso := &ZoneIsolation{
+		AutoScalingGroupName: group,
+		AvailabilityZone:     zone,
+		SecurityGroups:       make(map[string][]string),
+	}
+	_, err = c.Inject(ctx, group, chaosmonkey.StrategyBlockAllNetworkTraffic, func(ctx context.Context) (*chaosmonkey.Event, error) {
+		for _, i := range victims {
+			_, err := svc.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+				InstanceId: aws.String(i.ID),
+				Groups:     []*string{aws.String(securityGroupID)},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to isolate instance %s: %s", i.ID, err)
+			}
+			iso.SecurityGroups[i.ID] = i.SecurityGroups
+		}
+		return nil, nil
+	})
+	if err != nil && len(iso.SecurityGroups) == 0 {
+		return nil, err
+	}
+	return iso, err
+}
+
+// RestoreZone restores the security groups of instances isolated by
+// IsolateZone. It tries to restore all instances and returns the first error.
+func (c *Client) RestoreZone(ctx context.Context, iso *ZoneIsolation) error {
+	svc, err := c.ec2()
+	if err != nil {
+		return err
+	}
+
+	var first error
+	for id, groups := range iso.SecurityGroups {
+		_, err := svc.ModifyInstanceAttributeWithContext(ctx, &ec2.ModifyInstanceAttributeInput{
+			InstanceId: aws.String(id),
+			Groups:     aws.StringSlice(groups),
+		})
+		if err != nil && first == nil {
+			first = fmt.Errorf("failed to restore instance %s: %s", id, err)
+		}
+	}
+	return first
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// chaosmonkeyDir returns the path of the given file in ~/.chaosmonkey, or
+// an empty string if the home directory is unknown.
+func chaosmonkeyDir(name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".chaosmonkey", name)
+}
+
+// callerIdentity returns the ARN of the AWS identity of the user, which
+// names requesters and approvers.
+func callerIdentity(region string) string {
+	arn, err := aws.NewClient(region).CallerIdentity(context.Background())
+	if err != nil {
+		abort("cannot determine AWS identity: %s", err)
+	}
+	return arn
+}
+
+func runApprove(args []string) {
+	fs := newFlagSet("approve")
+	var (
+		group    = fs.String("group", "", "Name of auto scaling group to approve chaos against")
+		strategy = fs.String("strategy", string(chaosmonkey.StrategyShutdownInstance), "Chaos strategy to approve, see 'chaosmonkey strategies'")
+		approver = fs.String("approver", "", "Name of the approver in the approvers file (default the ARN of your AWS identity)")
+		key      = fs.String("key", chaosmonkeyDir("approval.key"), "File holding your private approval key")
+		generate = fs.Bool("generate-key", false, "Generate a private approval key in --key and print the line to add to the approvers file")
+		region   = fs.String("region", "", "Name of AWS region to determine your AWS identity in")
+		ttl      = fs.Duration("ttl", chaosmonkey.DefaultApprovalTTL, "Time until the approval expires")
+		daemon   = fs.String("daemon", os.Getenv("CHAOSMONKEY_DAEMON"), "Grant the approval to chaos events triggered by the daemon at this URL, started with 'chaosmonkey serve', instead of printing a token")
+	)
+	parseFlags(fs, args)
+
+	if *key == "" {
+		exit(exitUsage, "approve requires --key")
+	}
+	if *approver == "" {
+		*approver = callerIdentity(*region)
+	}
+	if *generate {
+		pub, err := generateApprovalKey(*key)
+		if err != nil {
+			abort("failed to generate key: %s", err)
+		}
+		fmt.Fprintf(os.Stderr, "Stored private key in %s. Add this line to the approvers file of requesters:\n", *key)
+		fmt.Printf("%s %s\n", *approver, base64.StdEncoding.EncodeToString(pub))
+		return
+	}
+
+	if *group == "" {
+		exit(exitUsage, "approve requires --group")
+	}
+	s, err := chaosmonkey.ParseStrategy(*strategy)
+	if err != nil {
+		abort("%s (see 'chaosmonkey strategies')", err)
+	}
+	priv, err := readApprovalKey(*key)
+	if err != nil {
+		abort("%s (create one with --generate-key)", err)
+	}
+
+	if *daemon != "" {
+		var ap chaosmonkey.Approval
+		req := map[string]string{"group": *group, "strategy": string(s), "ttl": ttl.String()}
+		err := sendDaemon(*daemon, "/approvals/", req, &ap, func(req *http.Request) error {
+			return chaosmonkey.SignApprovalRequest(req, priv, *approver)
+		})
+		if err != nil {
+			abort("failed to grant approval: %s", err)
+		}
+		fmt.Fprintf(os.Stderr, "Approved %s against %s by %s until %s via the daemon (approval %s).\n",
+			ap.Strategy, ap.Group, ap.Approver, ap.Expires.Local().Format(time.RFC3339), ap.ID)
+		return
+	}
+
+	ap, err := (&chaosmonkey.Approvals{}).Approve(priv, *approver, *group, s, *ttl)
+	if err != nil {
+		exit(exitUsage, "%s", err)
+	}
+	fmt.Fprintf(os.Stderr, "Approved %s against %s by %s until %s (approval %s).\n",
+		ap.Strategy, ap.Group, ap.Approver, ap.Expires.Local().Format(time.RFC3339), ap.ID)
+	fmt.Println(ap.Token)
+}
+
+// generateApprovalKey stores a new private approval key in the file, which
+// must not exist, and returns the public key.
+func generateApprovalKey(path string) (ed25519.PublicKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	_, err = fmt.Fprintln(f, base64.StdEncoding.EncodeToString(priv))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return pub, err
+}
+
+// readApprovalKey reads a private approval key stored by
+// generateApprovalKey.
+func readApprovalKey(path string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid approval key in %s", path)
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+// readApprovers reads the public keys of approvers from a file with lines of
+// names and base64-encoded keys, as printed by approve --generate-key. Empty
+// lines and lines starting with # are ignored.
+func readApprovers(path string) (map[string]ed25519.PublicKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	approvers := make(map[string]ed25519.PublicKey)
+	in := bufio.NewScanner(f)
+	for n := 1; in.Scan(); n++ {
+		line := strings.TrimSpace(in.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected name and key", path, n)
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s:%d: invalid key", path, n)
+		}
+		approvers[fields[0]] = ed25519.PublicKey(key)
+	}
+	return approvers, in.Err()
+}
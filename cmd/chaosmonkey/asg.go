@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+)
+
+func runASG(args []string) {
+	if len(args) > 0 && args[0] == "instances" {
+		runASGInstances(args[1:])
+		return
+	}
+
+	fs := newFlagSet("asg")
+	cf := addAWSFlags(fs)
+	addOutputFlag(fs)
+	prefix := fs.String("prefix", "", "Only list groups whose name starts with this prefix")
+	pattern := fs.String("match", "", "Only list groups whose name matches this regular expression")
+	var tags tagFilters
+	fs.Var(&tags, "tag", "Only list groups with this tag, given as key or key=value (repeatable)")
+	if len(args) == 0 || args[0] != "list" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	parseFlags(fs, args[1:])
+
+	filter := aws.GroupFilter{NamePrefix: *prefix, Tags: tags.toMap()}
+	if *pattern != "" {
+		re, err := regexp.Compile(*pattern)
+		if err != nil {
+			exit(exitUsage, "invalid --match: %s", err)
+		}
+		filter.NamePattern = re
+	}
+	groups, err := aws.NewClient(cf.region).FilterAutoScalingGroups(context.Background(), filter)
+	if err != nil {
+		abort("failed to get auto scaling groups: %s", err)
+	}
+	listAutoScalingGroups(groups)
+}
+
+func runASGInstances(args []string) {
+	fs := newFlagSet("asg")
+	cf := addAWSFlags(fs)
+	addOutputFlag(fs)
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fs.Usage()
+		os.Exit(2)
+	}
+	group := args[0]
+	parseFlags(fs, args[1:])
+
+	instances, err := aws.NewClient(cf.region).Instances(context.Background(), group)
+	if err != nil {
+		abort("failed to get instances: %s", err)
+	}
+	if instances == nil {
+		exit(exitGroupNotFound, "auto scaling group %q not found or empty", group)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	listInstances(instances)
+}
+
+// addAWSFlags adds the options needed by commands that only talk to AWS.
+func addAWSFlags(fs *flag.FlagSet) *clientFlags {
+	var f clientFlags
+	fs.StringVar(&f.profile, "profile", "", "Name of profile in configuration file (or use CHAOSMONKEY_PROFILE)")
+	fs.StringVar(&f.region, "region", "", "Name of AWS region")
+	clientFlagSets[fs] = &f
+	return &f
+}
+
+// tagFilters is a repeatable flag of tags given as key or key=value.
+type tagFilters []string
+
+func (t *tagFilters) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *tagFilters) Set(v string) error {
+	if v == "" || strings.HasPrefix(v, "=") {
+		return fmt.Errorf("tag key must not be empty")
+	}
+	*t = append(*t, v)
+	return nil
+}
+
+// toMap returns the filters as map from keys to values, which are empty if
+// any value matches.
+func (t tagFilters) toMap() map[string]string {
+	m := make(map[string]string, len(t))
+	for _, f := range t {
+		kv := strings.SplitN(f, "=", 2)
+		m[kv[0]] = ""
+		if len(kv) == 2 {
+			m[kv[0]] = kv[1]
+		}
+	}
+	return m
+}
+
+// match reports whether the given tags satisfy all filters.
+func (t tagFilters) match(tags map[string]string) bool {
+	for _, f := range t {
+		kv := strings.SplitN(f, "=", 2)
+		v, ok := tags[kv[0]]
+		if !ok || (len(kv) == 2 && v != kv[1]) {
+			return false
+		}
+	}
+	return true
+}
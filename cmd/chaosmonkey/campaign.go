@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ryanuber/columnize"
+	"gopkg.in/yaml.v2"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+	"github.com/FlyLevin/chaosmonkey/policy"
+)
+
+// campaignFile describes a campaign file:
+//
+//	name: checkout resilience
+//	maxFailures: 1
+//	healthCheck: https://checkout.example.com/health
+//	steps:
+//	  - name: kill one instance each
+//	    groups: [checkout-api, checkout-worker]
+//	    regions: [us-east-1, eu-west-1]
+//	    strategy: ShutdownInstance
+//	    interval: 15m
+//	    wait: 5m
+//	  - name: burn CPU
+//	    groups: [checkout-api]
+//	    strategy: BurnCpu
+//	    count: 3
+//	    interval: 1m
+//	guards:
+//	  - name: spare capacity
+//	    rego: |
+//	      package chaosmonkey
+//	      deny[msg] {
+//	        input.state.instancesInService < 3
+//	        msg := sprintf("%s has too few instances", [input.group])
+//	      }
+//	probes:
+//	  - name: error rate
+//	    url: https://metrics.example.com/checkout/errors
+//	    rego: |
+//	      package chaosmonkey
+//	      deny[msg] {
+//	        input.body.errorRate > 0.01
+//	        msg := "error rate above 1%"
+//	      }
+type campaignFile struct {
+	chaosmonkey.Campaign `yaml:",inline"`
+
+	// URL that must respond with a 2xx status code before each step
+	HealthCheck string `yaml:"healthCheck"`
+
+	// Rego policies refusing chaos events of the campaign, see policy.Guard
+	Guards []planPolicy `yaml:"guards"`
+
+	// Rego policies on responses of URLs that halt the campaign, see
+	// policy.Probe
+	Probes []planPolicy `yaml:"probes"`
+
+	guards []*policy.Guard
+	probes []*policy.Probe
+}
+
+// planPolicy is a guard or probe of a campaign file. Its policy runs in a
+// sandbox, as campaign files are often shared more widely than the options
+// of the operator.
+type planPolicy struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url,omitempty"`
+	Rego string `yaml:"rego"`
+}
+
+func runCampaign(args []string) {
+	if len(args) > 0 && args[0] == "template" {
+		runCampaignTemplate(args[1:])
+		return
+	}
+
+	fs := newFlagSet("campaign")
+	cf := addTriggerFlags(fs)
+	var (
+		results = fs.String("results", "", "Write results to this file (JSON, or YAML if it ends in .yaml)")
+		listen  = fs.String("listen", "", "Serve a kill switch at this address, e.g. localhost:8081 (POST /halt)")
+		yes     = fs.Bool("yes", false, "Do not ask for confirmation (required if not run in a terminal)")
+		tui     = fs.Bool("tui", false, "Show the progress of steps and the status of probes in a terminal view, with a hotkey to abort")
+	)
+	if len(args) < 2 || args[0] != "run" || strings.HasPrefix(args[1], "-") {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	path := args[1]
+	parseFlags(fs, args[2:])
+
+	campaign, err := loadCampaign(path)
+	if err != nil {
+		abort("%s", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Campaign %q has %d step(s):\n\n", campaign.Name, len(campaign.Steps))
+	for i, step := range campaign.Steps {
+		targets := strings.Join(step.Groups, ", ")
+		if len(step.Regions) > 0 {
+			targets += " in " + strings.Join(step.Regions, ", ")
+		}
+		fmt.Fprintf(os.Stderr, "  %d. %s: %s against %s\n", i+1, step.Name, strategyName(step.Strategy), targets)
+	}
+	fmt.Fprintln(os.Stderr)
+	// Campaigns related to open incidents caused by chaos are paused, also
+	// while running
+	var links *chaosmonkey.IncidentLinks
+	if cf.incidentLinks != "" {
+		links = &chaosmonkey.IncidentLinks{Path: cf.incidentLinks}
+		if err := links.CheckCampaign(&campaign.Campaign); err != nil {
+			exit(exitRefused, "%s", err)
+		}
+	}
+	if !*yes && !cf.dryRun {
+		if !isTerminal(os.Stdin) {
+			exit(exitUsage, "refusing to run campaign without confirmation (pass --yes in non-interactive use)")
+		}
+		confirm()
+	}
+	if *tui && (!isTerminal(os.Stdout) || !isTerminal(os.Stdin)) {
+		exit(exitUsage, "--tui must be run in a terminal")
+	}
+
+	for _, g := range campaign.guards {
+		g.State = cf.groupState
+		cf.guards = append(cf.guards, g)
+	}
+	for _, p := range campaign.probes {
+		cf.outageCheckers = append(cf.outageCheckers, p)
+	}
+	cf.bus = chaosmonkey.NewBus()
+	cf.halt = &chaosmonkey.HaltSwitch{}
+	if *listen != "" {
+		serveHaltSwitch(*listen, cf.halt)
+	}
+	finished := make(chan struct{})
+	monitorDone := make(chan struct{})
+	if *tui {
+		monitor := newCampaignMonitor(&campaign.Campaign, cf.halt)
+		monitor.Subscribe(cf.bus)
+		campaign.OnStep = monitor.OnStep
+		cf.observeProbe = monitor.ObserveProbe
+		if check := campaign.Check; check != nil {
+			campaign.Check = func() error {
+				err := check()
+				monitor.ObserveProbe("health check "+campaign.HealthCheck, err)
+				return err
+			}
+		}
+		go func() {
+			monitor.Run(time.Second, finished)
+			close(monitorDone)
+		}()
+	} else {
+		campaign.OnStep = func(i int, step chaosmonkey.CampaignStep) {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s\n", i+1, len(campaign.Steps), step.Name)
+		}
+		cf.bus.Subscribe(chaosmonkey.TopicEventRecorded, func(m chaosmonkey.Message) {
+			fmt.Fprintf(os.Stderr, "  triggered %s against %s\n", strategyName(m.Strategy), regionalGroup(m))
+		})
+		cf.bus.Subscribe(chaosmonkey.TopicGuardBlocked, func(m chaosmonkey.Message) {
+			fmt.Fprintf(os.Stderr, "  refused %s against %s: %s\n", strategyName(m.Strategy), regionalGroup(m), m.Err)
+		})
+		close(monitorDone)
+	}
+
+	if check := campaign.Check; links != nil {
+		campaign.Check = func() error {
+			if err := links.CheckCampaign(&campaign.Campaign); err != nil {
+				return err
+			}
+			if check != nil {
+				return check()
+			}
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	result, runErr := cf.newClient().RunCampaign(ctx, &campaign.Campaign)
+	close(finished)
+	<-monitorDone
+	if *results != "" && result != nil {
+		if err := writeResults(*results, result); err != nil {
+			abort("failed to write results: %s", err)
+		}
+	}
+	if runErr != nil {
+		abort("%s", runErr)
+	}
+	fmt.Fprintf(os.Stderr, "Campaign finished after %s\n", result.Finished.Sub(result.Started).Round(time.Second))
+}
+
+func runCampaignTemplate(args []string) {
+	fs := newFlagSet("campaign")
+	cf := addAWSFlags(fs)
+	name := fs.String("name", "", "Name of the generated campaign (the template name by default)")
+	prefix := fs.String("prefix", "", "Only target groups whose name starts with this prefix")
+	pattern := fs.String("match", "", "Only target groups whose name matches this regular expression")
+	var tags tagFilters
+	fs.Var(&tags, "tag", "Only target groups with this tag, given as key or key=value (repeatable)")
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		parseFlags(fs, args)
+		lines := []string{"Template|Description"}
+		for _, t := range aws.Templates {
+			lines = append(lines, fmt.Sprintf("%s|%s", t.Name, t.Description))
+		}
+		fmt.Println(columnize.SimpleFormat(lines))
+		return
+	}
+	template, ok := aws.LookupTemplate(args[0])
+	if !ok {
+		exit(exitUsage, "unknown template %q (see 'chaosmonkey campaign template')", args[0])
+	}
+	parseFlags(fs, args[1:])
+	if *prefix == "" && *pattern == "" && len(tags) == 0 {
+		exit(exitUsage, "select the groups of the system with --prefix, --match, or --tag")
+	}
+
+	filter := aws.GroupFilter{NamePrefix: *prefix, Tags: tags.toMap()}
+	if *pattern != "" {
+		re, err := regexp.Compile(*pattern)
+		if err != nil {
+			exit(exitUsage, "invalid --match: %s", err)
+		}
+		filter.NamePattern = re
+	}
+	groups, err := aws.NewClient(cf.region).FilterAutoScalingGroups(context.Background(), filter)
+	if err != nil {
+		abort("failed to get auto scaling groups: %s", err)
+	}
+	campaign, err := template.Generate(*name, groups)
+	if err != nil {
+		abort("%s", err)
+	}
+	data, err := yaml.Marshal(newTemplateFile(campaign))
+	if err != nil {
+		abort("%s", err)
+	}
+	fmt.Printf("# Generated from template %s. Review before running.\n%s", template.Name, data)
+}
+
+// templateFile is a campaign file generated from a template, which spells
+// out durations like hand-written campaign files do.
+type templateFile struct {
+	Name  string         `yaml:"name"`
+	Steps []templateStep `yaml:"steps"`
+}
+
+type templateStep struct {
+	Name     string               `yaml:"name"`
+	Groups   []string             `yaml:"groups,flow"`
+	Strategy chaosmonkey.Strategy `yaml:"strategy"`
+	Count    int                  `yaml:"count,omitempty"`
+	Interval string               `yaml:"interval,omitempty"`
+	Wait     string               `yaml:"wait,omitempty"`
+}
+
+func newTemplateFile(cp *chaosmonkey.Campaign) *templateFile {
+	f := &templateFile{Name: cp.Name}
+	for _, s := range cp.Steps {
+		step := templateStep{Name: s.Name, Groups: s.Groups, Strategy: s.Strategy, Count: s.Count}
+		if s.Interval > 0 {
+			step.Interval = shortDuration(s.Interval)
+		}
+		if s.Wait > 0 {
+			step.Wait = shortDuration(s.Wait)
+		}
+		f.Steps = append(f.Steps, step)
+	}
+	return f
+}
+
+// shortDuration formats d without zero units, e.g. "10m" instead of
+// "10m0s".
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = s[:len(s)-2]
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = s[:len(s)-2]
+	}
+	return s
+}
+
+// regionalGroup names the group of a message along with its region, if any.
+func regionalGroup(m chaosmonkey.Message) string {
+	if m.Region == "" {
+		return m.AutoScalingGroupName
+	}
+	return fmt.Sprintf("%s in %s", m.AutoScalingGroupName, m.Region)
+}
+
+// loadCampaign reads and validates a campaign file.
+func loadCampaign(path string) (*campaignFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var campaign campaignFile
+	if err := yaml.UnmarshalStrict(data, &campaign); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+	if campaign.Name == "" {
+		campaign.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := campaign.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid campaign %s: %s", path, err)
+	}
+	for i, g := range campaign.Guards {
+		if g.Name == "" {
+			g.Name = fmt.Sprintf("guard %d", i+1)
+		}
+		if g.URL != "" {
+			return nil, fmt.Errorf("invalid campaign %s: %s: guards have no URL", path, g.Name)
+		}
+		guard, err := policy.NewSandboxedGuard(context.Background(), "", map[string]string{g.Name + ".rego": g.Rego})
+		if err != nil {
+			return nil, fmt.Errorf("invalid campaign %s: %s: %s", path, g.Name, err)
+		}
+		campaign.guards = append(campaign.guards, guard)
+	}
+	for i, p := range campaign.Probes {
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("probe %d", i+1)
+		}
+		if p.URL == "" {
+			return nil, fmt.Errorf("invalid campaign %s: %s: probes need a URL", path, p.Name)
+		}
+		probe, err := policy.NewProbe(context.Background(), p.URL, "", map[string]string{p.Name + ".rego": p.Rego})
+		if err != nil {
+			return nil, fmt.Errorf("invalid campaign %s: %s: %s", path, p.Name, err)
+		}
+		campaign.probes = append(campaign.probes, probe)
+	}
+	if url := campaign.HealthCheck; url != "" {
+		client := &http.Client{Timeout: 10 * time.Second}
+		campaign.Check = func() error {
+			resp, err := client.Get(url)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return fmt.Errorf("health check returned %s", resp.Status)
+			}
+			return nil
+		}
+	}
+	return &campaign, nil
+}
+
+func writeResults(path string, result *chaosmonkey.CampaignResult) error {
+	var (
+		data []byte
+		err  error
+	)
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		data, err = yaml.Marshal(result)
+	} else {
+		data, err = json.MarshalIndent(result, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func readResults(path string) (*chaosmonkey.CampaignResult, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result chaosmonkey.CampaignResult
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		err = yaml.Unmarshal(data, &result)
+	} else {
+		err = json.Unmarshal(data, &result)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid campaign results %s: %s", path, err)
+	}
+	return &result, nil
+}
+
+// strategyName returns the name of a strategy, or the default strategy of
+// Chaos Monkey if s is empty.
+func strategyName(s chaosmonkey.Strategy) string {
+	if s == "" {
+		return "default strategy"
+	}
+	return string(s)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func runCompletion(args []string) {
+	fs := newFlagSet("completion")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var names, descs []string
+	for _, cmd := range commands {
+		names = append(names, cmd.Name)
+		descs = append(descs, fmt.Sprintf("'%s:%s'", cmd.Name, cmd.Summary))
+	}
+
+	switch fs.Arg(0) {
+	case "bash":
+		fmt.Printf(bashCompletion, strings.Join(names, " "))
+	case "zsh":
+		fmt.Printf(zshCompletion, strings.Join(descs, " "))
+	case "fish":
+		fmt.Print(fishCompletion)
+		for _, cmd := range commands {
+			fmt.Printf("complete -c chaosmonkey -n __fish_use_subcommand -a %s -d '%s'\n", cmd.Name, cmd.Summary)
+		}
+	default:
+		abort("unknown shell %q (must be bash, zsh, or fish)", fs.Arg(0))
+	}
+}
+
+// runComplete is invoked by the completion scripts to complete dynamic
+// values. It prints nothing if the values cannot be determined, e.g. when no
+// AWS credentials are available.
+func runComplete(args []string) {
+	fs := newFlagSet("__complete")
+	cf := addClientFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		os.Exit(2)
+	}
+	applyProfile(fs, cf)
+
+	switch fs.Arg(0) {
+	case "strategies":
+		for _, s := range chaosmonkey.KnownStrategies() {
+			fmt.Println(s)
+		}
+	case "groups":
+		groups, err := aws.NewClient(cf.region).AutoScalingGroups(context.Background())
+		if err != nil {
+			os.Exit(1)
+		}
+		for _, g := range groups {
+			fmt.Println(g.Name)
+		}
+	}
+}
+
+// Shell snippet printing the flags of the command in $cmd, which are parsed
+// from its usage message.
+const shellCommandFlags = `chaosmonkey "$cmd" -h 2>&1 | sed -n 's/^  \(-[a-z-]*\).*/-\1/p'`
+
+const bashCompletion = `_chaosmonkey() {
+    local cur prev cmd
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+    if [ "$COMP_CWORD" -eq 1 ]; then
+        COMPREPLY=($(compgen -W "%s" -- "$cur"))
+        return
+    fi
+    cmd="${COMP_WORDS[1]}"
+    case "$prev" in
+    -strategy|--strategy)
+        COMPREPLY=($(compgen -W "$(chaosmonkey __complete strategies 2>/dev/null)" -- "$cur"))
+        return ;;
+    -group|--group)
+        COMPREPLY=($(compgen -W "$(chaosmonkey __complete groups 2>/dev/null)" -- "$cur"))
+        return ;;
+    -output|--output)
+        COMPREPLY=($(compgen -W "table json yaml" -- "$cur"))
+        return ;;
+    esac
+    if [ "$cmd" = completion ]; then
+        COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur"))
+        return
+    fi
+    COMPREPLY=($(compgen -W "$(` + shellCommandFlags + `)" -- "$cur"))
+}
+complete -F _chaosmonkey chaosmonkey
+`
+
+const zshCompletion = `_chaosmonkey() {
+    local -a items
+    local cmd=${words[2]}
+    if (( CURRENT == 2 )); then
+        items=(%s)
+        _describe 'command' items
+        return
+    fi
+    case ${words[CURRENT-1]} in
+    -strategy|--strategy)
+        items=(${(f)"$(chaosmonkey __complete strategies 2>/dev/null)"}) ;;
+    -group|--group)
+        items=(${(f)"$(chaosmonkey __complete groups 2>/dev/null)"}) ;;
+    -output|--output)
+        items=(table json yaml) ;;
+    *)
+        if [[ $cmd == completion ]]; then
+            items=(bash zsh fish)
+        else
+            items=(${(f)"$(` + shellCommandFlags + `)"})
+        fi ;;
+    esac
+    compadd -a items
+}
+compdef _chaosmonkey chaosmonkey
+`
+
+const fishCompletion = `complete -c chaosmonkey -f
+complete -c chaosmonkey -l strategy -x -a '(chaosmonkey __complete strategies 2>/dev/null)'
+complete -c chaosmonkey -l group -x -a '(chaosmonkey __complete groups 2>/dev/null)'
+complete -c chaosmonkey -l output -x -a 'table json yaml'
+complete -c chaosmonkey -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'
+`
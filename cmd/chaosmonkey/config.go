@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configFile describes the configuration file of the chaosmonkey tool, which
+// is located at ~/.chaosmonkey/config.yaml by default:
+//
+//	profiles:
+//	  default:
+//	    endpoint: http://staging.example.com:8080
+//	  prod:
+//	    endpoint: http://prod.example.com:8080
+//	    username: admin
+//	    password: secret
+//	    region: eu-west-1
+//	    output: json
+//	    denyGroups:
+//	      - payments-*
+type configFile struct {
+	Profiles map[string]profile `yaml:"profiles"`
+}
+
+// profile holds default values for command-line options.
+type profile struct {
+	Endpoint string `yaml:"endpoint"`
+	Region   string `yaml:"region"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Output   string `yaml:"output"`
+
+	// Patterns of groups chaos events may and must not target
+	AllowGroups []string `yaml:"allowGroups"`
+	DenyGroups  []string `yaml:"denyGroups"`
+}
+
+// configPath returns the path of the configuration file, which may be
+// overridden with CHAOSMONKEY_CONFIG.
+func configPath() string {
+	if p := os.Getenv("CHAOSMONKEY_CONFIG"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".chaosmonkey", "config.yaml")
+}
+
+// loadProfile returns the named profile from the configuration file. If name
+// is empty, the profile "default" is returned if it exists. A missing
+// configuration file is only an error if a profile was named explicitly.
+func loadProfile(name string) (*profile, error) {
+	path := configPath()
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && name == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg configFile
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+
+	if name == "" {
+		if p, ok := cfg.Profiles["default"]; ok {
+			return &p, nil
+		}
+		return nil, nil
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q not found in %s", name, path)
+	}
+	return &p, nil
+}
+
+// applyProfile sets flags that were neither given on the command line nor
+// via environment variables to the values of the selected profile.
+func applyProfile(fs *flag.FlagSet, cf *clientFlags) {
+	name := cf.profile
+	if name == "" {
+		name = os.Getenv("CHAOSMONKEY_PROFILE")
+	}
+	p, err := loadProfile(name)
+	if err != nil {
+		abort("%s", err)
+	}
+	if p == nil {
+		return
+	}
+
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	defaults := []struct {
+		flag, env, value string
+	}{
+		{"endpoint", "CHAOSMONKEY_ENDPOINT", p.Endpoint},
+		{"region", "", p.Region},
+		{"username", "CHAOSMONKEY_USERNAME", p.Username},
+		{"password", "CHAOSMONKEY_PASSWORD", p.Password},
+		{"output", "", p.Output},
+	}
+	for _, d := range defaults {
+		if d.value == "" || given[d.flag] || fs.Lookup(d.flag) == nil {
+			continue
+		}
+		if d.env != "" && os.Getenv(d.env) != "" {
+			continue
+		}
+		if err := fs.Set(d.flag, d.value); err != nil {
+			abort("invalid value %q for %s in profile: %s", d.value, d.flag, err)
+		}
+	}
+
+	// Denied groups of the profile add to the ones given on the command
+	// line, so that they cannot be bypassed by accident.
+	patterns := []struct {
+		flag     string
+		values   []string
+		additive bool
+	}{
+		{"allow-group", p.AllowGroups, false},
+		{"deny-group", p.DenyGroups, true},
+	}
+	for _, d := range patterns {
+		if fs.Lookup(d.flag) == nil || (given[d.flag] && !d.additive) {
+			continue
+		}
+		for _, v := range d.values {
+			if err := fs.Set(d.flag, v); err != nil {
+				abort("invalid value %q for %s in profile: %s", v, d.flag, err)
+			}
+		}
+	}
+}
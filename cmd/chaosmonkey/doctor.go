@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// doctorGroup is the name of a group that does not exist. Triggering a chaos
+// event against it reveals whether Chaos Monkey is leashed or on-demand
+// termination is disabled without breaking anything.
+const doctorGroup = "chaosmonkey-doctor-nonexistent-group"
+
+func runDoctor(args []string) {
+	fs := newFlagSet("doctor")
+	cf := addClientFlags(fs)
+	domain := fs.String("domain", "SIMIAN_ARMY", "Name of SimpleDB domain used by Chaos Monkey")
+	parseFlags(fs, args)
+
+	client := cf.newClient()
+	failed := false
+	check := func(name string, err error, fix string) bool {
+		if err != nil {
+			failed = true
+			fmt.Printf("[FAIL] %s: %s\n       Fix: %s\n", name, err, fix)
+			return false
+		}
+		fmt.Printf("[ OK ] %s\n", name)
+		return true
+	}
+
+	// Chaos Monkey API
+	info, err := client.ServerInfo()
+	endpoint := cf.endpoint
+	if endpoint == "" {
+		endpoint = "the endpoint"
+	}
+	switch e := err.(type) {
+	case nil:
+		check("Endpoint is reachable", nil, "")
+		check("Authentication", nil, "")
+		if !info.Capabilities.Events {
+			err = fmt.Errorf("%s not found", chaosmonkey.APIPath)
+		}
+		if check("API path", err, "Make sure "+endpoint+" points to SimianArmy with the REST API enabled, without any path") {
+			version := info.Version
+			if version == "" {
+				version = "unknown version"
+			}
+			fmt.Printf("       Detected %s (%s), chaos strategies supported: %s, since filter: %s\n",
+				info.Flavor, version, info.Capabilities.Strategies, info.Capabilities.SinceFilter)
+			checkTermination(client, check)
+		}
+	case *chaosmonkey.APIError:
+		check("Endpoint is reachable", nil, "")
+		if e.StatusCode == 401 || e.StatusCode == 403 {
+			check("Authentication", err, "Pass valid credentials with --username and --password (or CHAOSMONKEY_USERNAME and CHAOSMONKEY_PASSWORD)")
+		} else {
+			check("API", err, "Check the logs of Chaos Monkey")
+		}
+	default:
+		check("Endpoint is reachable", err, "Pass the address and port of Chaos Monkey with --endpoint (or CHAOSMONKEY_ENDPOINT)")
+	}
+
+	// AWS
+	awsClient := aws.NewClient(cf.region)
+	arn, err := awsClient.CallerIdentity(context.Background())
+	if check("AWS credentials", err, "Set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY (and AWS_ROLE to assume a role) or configure a profile in ~/.aws") {
+		fmt.Printf("       Using %s\n", arn)
+		_, err := awsClient.SimpleDBDomainItemCount(context.Background(), *domain)
+		check("SimpleDB recorder access", err, fmt.Sprintf("Grant sdb:DomainMetadata and sdb:Select on domain %s, and check --region and --domain", *domain))
+	}
+
+	if failed {
+		os.Exit(exitError)
+	}
+}
+
+// checkTermination triggers a chaos event against a group that does not
+// exist to find out whether Chaos Monkey would accept on-demand events.
+func checkTermination(client *chaosmonkey.Client, check func(string, error, string) bool) {
+	_, err := client.TriggerEvent(doctorGroup, "")
+	e, ok := err.(*chaosmonkey.APIError)
+	if err == nil || !ok {
+		// Either the server accepted the event anyway or the result is
+		// inconclusive, e.g. in a dry run.
+		return
+	}
+	msg := strings.ToLower(e.Message)
+	switch {
+	case strings.Contains(msg, "leash"):
+		check("Chaos Monkey is unleashed", err, "Set simianarmy.chaos.leashed = false")
+	case strings.Contains(msg, "not enabled"):
+		check("Chaos Monkey is unleashed", nil, "")
+		check("On-demand termination is enabled", err, "Set simianarmy.chaos.terminateOndemand.enabled = true")
+	case strings.Contains(msg, "not found") || strings.Contains(msg, "no auto scaling group"):
+		check("Chaos Monkey is unleashed", nil, "")
+		check("On-demand termination is enabled", nil, "")
+	default:
+		fmt.Printf("[ ?? ] Could not determine leash status: %s\n", err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// Exit codes of the chaosmonkey tool. They are part of its interface and must
+// not change.
+const (
+	exitError         = 1 // unspecified error
+	exitUsage         = 2 // invalid command-line usage
+	exitAuth          = 3 // authentication with Chaos Monkey failed
+	exitLeashed       = 4 // Chaos Monkey is leashed or on-demand termination is disabled
+	exitGroupNotFound = 5 // auto scaling group does not exist
+	exitNetwork       = 6 // Chaos Monkey could not be reached
+	exitRefused       = 7 // chaos event was refused by a guard or halted by an outage
+)
+
+// exitReasons names the exit codes in JSON error output.
+var exitReasons = map[int]string{
+	exitError:         "error",
+	exitUsage:         "usage",
+	exitAuth:          "auth",
+	exitLeashed:       "leashed",
+	exitGroupNotFound: "group_not_found",
+	exitNetwork:       "network",
+	exitRefused:       "refused",
+}
+
+// errorFormat is the format used to print errors, see --error-format.
+var errorFormat = "text"
+
+// exitCode determines the exit code for an error returned by the client
+// library or the aws package.
+func exitCode(err error) int {
+	switch e := err.(type) {
+	case *chaosmonkey.APIError:
+		msg := strings.ToLower(e.Message)
+		switch {
+		case e.StatusCode == 401 || (e.StatusCode == 403 && !strings.Contains(msg, "leash")):
+			return exitAuth
+		case strings.Contains(msg, "leash") || strings.Contains(msg, "not enabled"):
+			return exitLeashed
+		case strings.Contains(msg, "not found") || strings.Contains(msg, "no auto scaling group"):
+			return exitGroupNotFound
+		}
+	case *chaosmonkey.GuardError, *chaosmonkey.OutageError, *chaosmonkey.StopError:
+		return exitRefused
+	case *url.Error, net.Error:
+		return exitNetwork
+	}
+	if err == aws.ErrGroupNotFound {
+		return exitGroupNotFound
+	}
+	return exitError
+}
+
+// fail prints err and exits with the exit code matching its cause.
+func fail(err error) {
+	exit(exitCode(err), "%s", err)
+}
+
+func abort(format string, a ...interface{}) {
+	exit(exitError, format, a...)
+}
+
+// exit prints an error message in the chosen format and exits with code.
+func exit(code int, format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if errorFormat == "json" {
+		json.NewEncoder(os.Stderr).Encode(struct {
+			Error  string `json:"error"`
+			Code   int    `json:"code"`
+			Reason string `json:"reason"`
+		}{msg, code, exitReasons[code]})
+	} else {
+		fmt.Fprintf(os.Stderr, "error: %s\n", msg)
+	}
+	closePlugins()
+	os.Exit(code)
+}
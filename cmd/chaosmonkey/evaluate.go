@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ryanuber/columnize"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func runEvaluate(args []string) {
+	fs := newFlagSet("evaluate")
+	cf := addTriggerFlags(fs)
+	addOutputFlag(fs)
+	var (
+		group    = fs.String("group", "", "Name of auto scaling group")
+		strategy = fs.String("strategy", "", "Chaos strategy to use, see 'chaosmonkey strategies'")
+	)
+	parseFlags(fs, args)
+
+	if *group == "" {
+		exit(exitUsage, "evaluate requires --group")
+	}
+	var s chaosmonkey.Strategy
+	if *strategy != "" {
+		var err error
+		if s, err = chaosmonkey.ParseStrategy(*strategy); err != nil {
+			abort("%s (see 'chaosmonkey strategies')", err)
+		}
+	}
+
+	ev := cf.newClient().Evaluate(*group, s)
+	if outputFormat != "table" {
+		printStructured(ev)
+	} else {
+		lines := []string{"Check|Result|Reason"}
+		for _, c := range ev.Checks {
+			result := "pass"
+			if !c.Passed {
+				result = "FAIL"
+			}
+			lines = append(lines, fmt.Sprintf("%s|%s|%s", c.Name, result, c.Reason))
+		}
+		fmt.Println(columnize.SimpleFormat(lines))
+	}
+	if !ev.Allowed {
+		fmt.Fprintf(os.Stderr, "%s against %s would be refused\n", strategyName(s), *group)
+		os.Exit(exitRefused)
+	}
+	fmt.Fprintf(os.Stderr, "%s against %s would be triggered\n", strategyName(s), *group)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func runEvents(args []string) {
+	fs := newFlagSet("events")
+	cf := addClientFlags(fs)
+	addOutputFlag(fs)
+	since := fs.Duration("since", 0, "Only list events of this period, e.g. 24h (all events by default)")
+	watch := fs.Bool("watch", false, "Keep running and print new events as they occur")
+	interval := fs.Duration("interval", 5*time.Second, "Time to wait between polls in watch mode")
+	domain := fs.String("simpledb", "", "Read events from this SimpleDB domain (e.g. SIMIAN_ARMY) instead of the API")
+	fs.BoolVar(&cf.enrich, "enrich", false, "Add availability zone, type, AMI, launch time, and tags of instances to events")
+	fs.StringVar(&cf.pluginEnricher, "plugin-enricher", "", "Add instance details to events via the plugin with this name instead of AWS")
+	parseFlags(fs, args)
+
+	if *watch && *interval <= 0 {
+		abort("interval must be positive")
+	}
+
+	if *domain != "" {
+		if *watch {
+			exit(exitUsage, "--watch cannot be combined with --simpledb")
+		}
+		var from time.Time
+		if *since > 0 {
+			from = time.Now().Add(-*since)
+		}
+		client := aws.NewClient(cf.region)
+		events, err := client.ChaosRecords(context.Background(), *domain, from)
+		if err != nil {
+			abort("failed to read events from SimpleDB: %s", err)
+		}
+		var enricher chaosmonkey.EventEnricher
+		if cf.enrich {
+			enricher = client
+		}
+		if cf.pluginEnricher != "" {
+			enricher = cf.startPlugin(cf.pluginEnricher)
+		}
+		if enricher != nil {
+			if err := enricher.EnrichEvents(context.Background(), events); err != nil {
+				abort("failed to enrich events: %s", err)
+			}
+		}
+		printEvents(events...)
+		return
+	}
+
+	client := cf.newClient()
+	if *watch {
+		from := time.Now()
+		if *since > 0 {
+			from = from.Add(-*since)
+		}
+		watchEvents(client, from, *interval)
+		return
+	}
+
+	var (
+		events []chaosmonkey.Event
+		err    error
+	)
+	if *since > 0 {
+		events, err = client.EventsSince(time.Now().Add(-*since))
+	} else {
+		events, err = client.Events()
+	}
+	if err != nil {
+		fail(err)
+	}
+	printEvents(events...)
+}
+
+// watchEvents prints the events since the given time and then new events
+// as they occur, forever. In watch mode, structured output is streamed as
+// one JSON object per line or one YAML document per event.
+func watchEvents(client *chaosmonkey.Client, since time.Time, interval time.Duration) {
+	first := true
+	for u := range client.Watch(context.Background(), since, interval) {
+		if u.Err != nil && first {
+			fail(u.Err)
+		}
+		first = false
+		if u.Err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to get events: %s\n", u.Err)
+			continue
+		}
+		if len(u.Events) > 0 || addHeader {
+			streamEvents(u.Events)
+		}
+	}
+}
+
+func streamEvents(events []chaosmonkey.Event) {
+	switch outputFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range events {
+			if err := enc.Encode(e); err != nil {
+				abort("%s", err)
+			}
+		}
+	case "yaml":
+		for _, e := range events {
+			out, err := yaml.Marshal(e)
+			if err != nil {
+				abort("%s", err)
+			}
+			fmt.Printf("---\n%s", out)
+		}
+	default:
+		printEvents(events...)
+	}
+}
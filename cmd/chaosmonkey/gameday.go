@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	"github.com/FlyLevin/chaosmonkey/gameday"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func runGameDay(args []string) {
+	fs := newFlagSet("gameday")
+	cf := addAWSFlags(fs)
+	var (
+		participants participantFlags
+		blackouts    blackoutFlags
+		services     patternFlags
+	)
+	fs.Var(&participants, "participant", "Participant given as <name>[:<timezone>[:<hours>]], e.g. alice:Europe/Berlin:08-16 (repeatable, local time zone and working hours 09-17 by default)")
+	fs.Var(&blackouts, "blackout", "Period without GameDays given as <start>/<end> in RFC 3339 format or as dates, e.g. 2018-12-24/2018-12-27 (repeatable)")
+	fs.Var(&services, "service", "Auto scaling group of a service to target (repeatable)")
+	var (
+		days      = fs.Int("days", 14, "Number of days from now in which to find slots")
+		duration  = fs.Duration("duration", 2*time.Hour, "Length of the GameDay")
+		slots     = fs.Int("slots", 5, "Number of slots to propose")
+		schedule  = fs.Bool("schedule", false, "Schedule the GameDay in the first slot: write the plan, suppress chaos against the services, and announce it")
+		name      = fs.String("name", "", "Name of the GameDay (required by --schedule)")
+		organizer = fs.String("organizer", os.Getenv("USER"), "Organizer of the GameDay, who owns the suppression")
+		strategy  = fs.String("strategy", "", "Chaos strategy to use against the services (Chaos Monkey's default if empty)")
+		plan      = fs.String("plan", "", "Write the plan to this campaign file for 'chaosmonkey campaign run' (<name>.yaml by default)")
+		daemon    = fs.String("daemon", os.Getenv("CHAOSMONKEY_DAEMON"), "Suppress chaos triggered by the daemon at this URL during the GameDay (requires CHAOSMONKEY_DAEMON_SECRET)")
+		snsTopic  = fs.String("notify-sns", "", "Announce the GameDay to the SNS topic with this ARN")
+		eventBus  = fs.String("notify-eventbridge", "", "Announce the GameDay onto the EventBridge bus with this name (\"default\" for the default bus)")
+	)
+	parseFlags(fs, args)
+
+	if len(participants) == 0 {
+		exit(exitUsage, "gameday requires at least one --participant")
+	}
+	if *days <= 0 || *slots <= 0 {
+		exit(exitUsage, "--days and --slots must be positive")
+	}
+	from := time.Now()
+	to := from.Add(time.Duration(*days) * 24 * time.Hour)
+
+	if !*schedule {
+		proposed := gameday.ProposeSlots(from, to, *duration, participants, blackouts, *slots)
+		if len(proposed) == 0 {
+			abort("no slot of %s within %d days suits all participants", *duration, *days)
+		}
+		for _, s := range proposed {
+			fmt.Printf("%s - %s\n", s.Start.Local().Format("Mon 2006-01-02 15:04"), s.End.Local().Format("15:04 MST"))
+		}
+		return
+	}
+
+	if *name == "" || len(services) == 0 {
+		exit(exitUsage, "--schedule requires --name and --service")
+	}
+	var s chaosmonkey.Strategy
+	if *strategy != "" {
+		var err error
+		if s, err = chaosmonkey.ParseStrategy(*strategy); err != nil {
+			abort("%s (see 'chaosmonkey strategies')", err)
+		}
+	}
+	if *plan == "" {
+		*plan = *name + ".yaml"
+	}
+	if _, err := os.Stat(*plan); err == nil {
+		exit(exitUsage, "plan %s already exists", *plan)
+	}
+
+	var planner gameday.Planner
+	if *daemon != "" {
+		secret := daemonSecret()
+		if len(secret) == 0 {
+			exit(exitUsage, "--daemon requires CHAOSMONKEY_DAEMON_SECRET")
+		}
+		planner.Suppressor = &daemonFreezer{daemon: *daemon, secret: string(secret)}
+	}
+	if *snsTopic != "" {
+		planner.Announcers = append(planner.Announcers, &aws.SNSNotifier{
+			Client:   aws.NewClient(cf.region),
+			TopicARN: *snsTopic,
+		})
+	}
+	if *eventBus != "" {
+		planner.Announcers = append(planner.Announcers, &aws.EventBridgeEmitter{
+			Client:  aws.NewClient(cf.region),
+			BusName: *eventBus,
+		})
+	}
+
+	gd, err := planner.Schedule(context.Background(), &gameday.Request{
+		Name:         *name,
+		Organizer:    *organizer,
+		Services:     services,
+		Strategy:     s,
+		Participants: participants,
+		Blackouts:    blackouts,
+		From:         from,
+		To:           to,
+		Duration:     *duration,
+	})
+	if err != nil {
+		abort("%s", err)
+	}
+	data, err := yaml.Marshal(newTemplateFile(&gd.Plan))
+	if err != nil {
+		abort("%s", err)
+	}
+	header := fmt.Sprintf("# Plan of GameDay %s from %s to %s. Review before running.\n",
+		gd.Name, gd.Start.Format(time.RFC3339), gd.End.Format(time.RFC3339))
+	if err := ioutil.WriteFile(*plan, append([]byte(header), data...), 0644); err != nil {
+		abort("failed to write plan: %s", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Scheduled GameDay %s from %s to %s, with the plan in %s.\n",
+		gd.Name, gd.Start.Local().Format("Mon 2006-01-02 15:04"), gd.End.Local().Format("15:04 MST"), *plan)
+	if gd.Suppression != nil {
+		fmt.Fprintf(os.Stderr, "Chaos triggered by the daemon is suppressed by freeze %s during the GameDay.\n", gd.Suppression.ID)
+	}
+	if n := len(planner.Announcers); n > 0 {
+		fmt.Fprintf(os.Stderr, "Announced to %d channel(s).\n", n)
+	}
+}
+
+// daemonFreezer suppresses chaos with the freezes of a daemon.
+type daemonFreezer struct {
+	daemon, secret string
+}
+
+func (f *daemonFreezer) FreezeBetween(groups []string, reason, owner string, start, end time.Time) (*chaosmonkey.Freeze, error) {
+	var fr chaosmonkey.Freeze
+	req := map[string]interface{}{
+		"groups":   groups,
+		"reason":   reason,
+		"owner":    owner,
+		"start":    start.Format(time.RFC3339),
+		"duration": end.Sub(start).String(),
+	}
+	if err := postDaemon(f.daemon, "/freezes/", f.secret, req, &fr); err != nil {
+		return nil, err
+	}
+	return &fr, nil
+}
+
+func (f *daemonFreezer) Unfreeze(id string) error {
+	req, err := http.NewRequest("DELETE", strings.TrimSuffix(f.daemon, "/")+"/freezes/"+id, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+f.secret)
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("daemon returned %s", resp.Status)
+	}
+	return nil
+}
+
+// participantFlags is a repeatable flag of GameDay participants given as
+// <name>[:<timezone>[:<hours>]].
+type participantFlags []gameday.Participant
+
+func (p *participantFlags) String() string {
+	var names []string
+	for _, pt := range *p {
+		names = append(names, pt.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (p *participantFlags) Set(v string) error {
+	parts := strings.Split(v, ":")
+	if parts[0] == "" || len(parts) > 3 {
+		return fmt.Errorf("invalid participant %q, expected <name>[:<timezone>[:<hours>]]", v)
+	}
+	pt := gameday.Participant{Name: parts[0], Location: time.Local}
+	if len(parts) > 1 {
+		loc, err := time.LoadLocation(parts[1])
+		if err != nil {
+			return err
+		}
+		pt.Location = loc
+	}
+	if len(parts) > 2 {
+		hours := strings.Split(parts[2], "-")
+		if len(hours) != 2 {
+			return fmt.Errorf("invalid working hours %q, expected e.g. 09-17", parts[2])
+		}
+		start, err1 := strconv.Atoi(hours[0])
+		end, err2 := strconv.Atoi(hours[1])
+		if err1 != nil || err2 != nil || start < 0 || end > 24 || end <= start {
+			return fmt.Errorf("invalid working hours %q, expected e.g. 09-17", parts[2])
+		}
+		pt.WorkStart, pt.WorkEnd = start, end
+	}
+	*p = append(*p, pt)
+	return nil
+}
+
+// blackoutFlags is a repeatable flag of periods without GameDays given as
+// <start>/<end>.
+type blackoutFlags []gameday.Blackout
+
+func (b *blackoutFlags) String() string {
+	return fmt.Sprintf("%d blackout(s)", len(*b))
+}
+
+func (b *blackoutFlags) Set(v string) error {
+	parts := strings.Split(v, "/")
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid blackout %q, expected <start>/<end>", v)
+	}
+	var times [2]time.Time
+	for i, s := range parts {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			if t, err = time.ParseInLocation("2006-01-02", s, time.Local); err != nil {
+				return fmt.Errorf("invalid blackout %q, expected times in RFC 3339 format or dates", v)
+			}
+			// Dates include the whole day
+			if i == 1 {
+				t = t.AddDate(0, 0, 1)
+			}
+		}
+		times[i] = t
+	}
+	if !times[1].After(times[0]) {
+		return fmt.Errorf("invalid blackout %q, end must be after start", v)
+	}
+	*b = append(*b, gameday.Blackout{Start: times[0], End: times[1]})
+	return nil
+}
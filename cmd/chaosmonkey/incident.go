@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ryanuber/columnize"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func runIncident(args []string) {
+	fs := newFlagSet("incident")
+	path := addIncidentLinksFlag(fs)
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	switch args[0] {
+	case "link":
+		runIncidentLink(fs, path, args[1:])
+	case "resolve":
+		incident := fs.String("incident", "", "ID of the resolved incident, which resumes chaos against its groups")
+		parseFlags(fs, args[1:])
+		if *incident == "" {
+			exit(exitUsage, "incident resolve requires --incident")
+		}
+		if err := (&chaosmonkey.IncidentLinks{Path: *path}).Resolve(*incident); err != nil {
+			abort("%s", err)
+		}
+		fmt.Fprintf(os.Stderr, "Resolved incident %s, chaos against its groups is resumed.\n", *incident)
+	case "list":
+		addOutputFlag(fs)
+		parseFlags(fs, args[1:])
+		links, err := (&chaosmonkey.IncidentLinks{Path: *path}).Links()
+		if err != nil {
+			abort("%s", err)
+		}
+		listIncidentLinks(links)
+	default:
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+}
+
+func runIncidentLink(fs *flag.FlagSet, path *string, args []string) {
+	var (
+		incident = fs.String("incident", "", "ID of the incident in the incident management system")
+		group    = fs.String("group", "", "Name of auto scaling group of the chaos event that caused the incident")
+		instance = fs.String("instance", "", "ID of the instance of the chaos event that caused the incident")
+		strategy = fs.String("strategy", "", "Chaos strategy of the chaos event that caused the incident")
+		at       = fs.String("time", "", "Time of the chaos event that caused the incident, e.g. 2018-04-02T10:00:00Z")
+		campaign = fs.String("campaign", "", "Campaign file of the campaign that caused the incident, pausing chaos against all its groups")
+		owner    = fs.String("owner", os.Getenv("USER"), "Who links the incident")
+	)
+	parseFlags(fs, args)
+
+	if *incident == "" || (*group == "") == (*campaign == "") {
+		exit(exitUsage, "incident link requires --incident and either --group or --campaign")
+	}
+	link := chaosmonkey.IncidentLink{
+		IncidentID:           *incident,
+		AutoScalingGroupName: *group,
+		InstanceID:           *instance,
+		Owner:                *owner,
+	}
+	if *strategy != "" {
+		s, err := chaosmonkey.ParseStrategy(*strategy)
+		if err != nil {
+			abort("%s (see 'chaosmonkey strategies')", err)
+		}
+		link.Strategy = s
+	}
+	if *at != "" {
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			exit(exitUsage, "invalid --time: %s", err)
+		}
+		link.TriggeredAt = t
+	}
+	if *campaign != "" {
+		cp, err := loadCampaign(*campaign)
+		if err != nil {
+			abort("%s", err)
+		}
+		link.Campaign = cp.Name
+		seen := make(map[string]bool)
+		for _, step := range cp.Steps {
+			for _, g := range step.Groups {
+				if !seen[g] {
+					seen[g] = true
+					link.Groups = append(link.Groups, g)
+				}
+			}
+		}
+	}
+
+	l, err := (&chaosmonkey.IncidentLinks{Path: *path}).Link(link)
+	if err != nil {
+		abort("%s", err)
+	}
+	fmt.Fprintf(os.Stderr, "Linked incident %s to %s. Chaos against %s is paused until 'chaosmonkey incident resolve --incident %s'.\n",
+		l.IncidentID, l.Cause(), strings.Join(l.Groups, ", "), l.IncidentID)
+}
+
+// addIncidentLinksFlag adds the --incident-links flag to commands that read
+// incident links without triggering chaos events.
+func addIncidentLinksFlag(fs *flag.FlagSet) *string {
+	return fs.String("incident-links", chaosmonkeyDir("incidents.json"), "File of chaos events and campaigns linked to incidents")
+}
+
+func listIncidentLinks(links []chaosmonkey.IncidentLink) {
+	if outputFormat != "table" {
+		if links == nil {
+			links = []chaosmonkey.IncidentLink{}
+		}
+		printStructured(links)
+		return
+	}
+
+	lines := []string{"IncidentID|Cause|Groups|Owner|LinkedAt|ResolvedAt"}
+	for _, l := range links {
+		resolved := "-"
+		if !l.ResolvedAt.IsZero() {
+			resolved = l.ResolvedAt.Format(time.RFC3339)
+		}
+		lines = append(lines, fmt.Sprintf("%s|%s|%s|%s|%s|%s",
+			l.IncidentID,
+			l.Cause(),
+			strings.Join(l.Groups, ", "),
+			l.Owner,
+			l.LinkedAt.Format(time.RFC3339),
+			resolved,
+		))
+	}
+	fmt.Println(columnize.SimpleFormat(lines))
+}
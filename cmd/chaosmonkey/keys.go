@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Keys read by readKeys that have no printable character.
+const (
+	keyBackspace = 127
+	keyCtrlH     = 8 // sent for backspace by some terminals
+	keyEscape    = 27
+)
+
+// rawMode switches the terminal of stdin to deliver single key presses
+// without echoing them, while keeping Ctrl-C working, and returns a function
+// that restores the previous mode. It fails where stty is not available.
+func rawMode() (restore func(), err error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(state)) }, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// readKeys reads stdin byte by byte, which are single key presses in raw
+// mode, and closes the returned channel at the end of input. Reads from
+// stdin cannot be interrupted, so the reader stops with the next key pressed
+// after closed is closed, or lives until the process exits.
+func readKeys(closed <-chan struct{}) <-chan byte {
+	keys := make(chan byte)
+	go func() {
+		defer close(keys)
+		in := bufio.NewReader(os.Stdin)
+		for {
+			key, err := in.ReadByte()
+			if err != nil {
+				return
+			}
+			select {
+			case keys <- key:
+			case <-closed:
+				return
+			}
+		}
+	}()
+	return keys
+}
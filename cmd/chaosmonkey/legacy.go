@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// legacyMain implements the flag-only interface of chaosmonkey v0.5, which
+// predates subcommands. It is kept so that existing scripts continue to work.
+func legacyMain(args []string) {
+	fs := flag.NewFlagSet("chaosmonkey", flag.ExitOnError)
+	cf := addTriggerFlags(fs)
+	fs.StringVar(&errorFormat, "error-format", "text", "Format of error messages: text or json")
+	var (
+		group    = fs.String("group", "", "Name of auto scaling group, see -list-groups")
+		strategy = fs.String("strategy", "", "Chaos strategy to use, see -list-strategies")
+
+		count       = fs.Int("count", 1, "Number of times to trigger chaos event")
+		interval    = fs.Duration("interval", 5*time.Second, "Time to wait between chaos events")
+		probability = fs.Float64("probability", 1.0, "Probability of chaos events")
+
+		listStrategies = fs.Bool("list-strategies", false, "List chaos strategies")
+		listGroups     = fs.Bool("list-groups", false, "List auto scaling groups")
+		wipeState      = fs.String("wipe-state", "", "Wipe state of Chaos Monkey by deleting given SimpleDB domain")
+		showVersion    = fs.Bool("version", false, "Show program version")
+	)
+	fs.Parse(args)
+
+	if fs.NArg() > 0 {
+		exit(exitUsage, "program expects no arguments, but %d given", fs.NArg())
+	}
+	applyProfile(fs, cf)
+
+	switch {
+	case *listStrategies:
+		runStrategies(nil)
+		return
+	case *listGroups:
+		groups, err := aws.NewClient(cf.region).AutoScalingGroups(context.Background())
+		if err != nil {
+			abort("failed to get auto scaling groups: %s", err)
+		}
+		listAutoScalingGroups(groups)
+		return
+	case *wipeState != "":
+		if err := aws.NewClient(cf.region).DeleteSimpleDBDomain(context.Background(), *wipeState); err != nil {
+			abort("failed to wipe state: %s", err)
+		}
+		return
+	case *showVersion:
+		runVersion(nil)
+		return
+	}
+
+	client := cf.newClient()
+
+	if *group != "" {
+		triggerEvents(client, triggerOptions{
+			group:       *group,
+			strategy:    chaosmonkey.Strategy(*strategy),
+			count:       *count,
+			interval:    *interval,
+			probability: *probability,
+		})
+	} else {
+		events, err := client.Events()
+		if err != nil {
+			fail(err)
+		}
+		printEvents(events...)
+	}
+}
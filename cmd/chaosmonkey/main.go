@@ -0,0 +1,820 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	"github.com/FlyLevin/chaosmonkey/aws/ssm"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+	"github.com/FlyLevin/chaosmonkey/plugin"
+	"github.com/FlyLevin/chaosmonkey/policy"
+)
+
+// command is a subcommand of the chaosmonkey tool.
+type command struct {
+	Name    string
+	Args    string
+	Summary string
+	Run     func(args []string)
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"trigger", "[--group <name>] [--strategy <name>] [--yes]", "Trigger chaos events", runTrigger},
+		{"simulate", "--group <name> [--strategy <name>] [--protect-tag <key>[=<value>]]", "Show possible victims of a chaos event without triggering it", runSimulate},
+		{"evaluate", "--group <name> [--strategy <name>]", "Show which guards would refuse a chaos event without triggering it", runEvaluate},
+		{"campaign", "run <file> [--results <file>] [--listen <addr>] [--yes] | template [<name>] [--tag <key>[=<value>]]", "Run a chaos campaign defined in YAML, or generate one from a template", runCampaign},
+		{"approve", "--group <name> [--strategy <name>] [--ttl <duration>] | --generate-key", "Approve chaos against a protected group for someone else", runApprove},
+		{"serve", "[--listen <addr>] [--token-dir <dir>]", "Trigger chaos events for holders of scoped tokens", runServe},
+		{"gameday", "--participant <name>[:<timezone>[:<hours>]] [--blackout <start>/<end>] [--schedule --name <name> --service <group>]", "Propose GameDay slots, or schedule a GameDay with its plan, suppression, and announcements", runGameDay},
+		{"token", "--daemon <url> --group <name> [--strategy <name>] [--ttl <duration>]", "Mint a token for one chaos event with the daemon", runToken},
+		{"incident", "link --incident <id> (--group <name> [--instance <id>] | --campaign <file>) | resolve --incident <id> | list", "Link chaos to an incident it caused, pausing chaos against the affected groups until it is resolved", runIncident},
+		{"schedule", "<cron expression> --group <name> [--strategy <name>] [--shadow <file>] [--listen <addr>]", "Trigger chaos events on a schedule", runSchedule},
+		{"events", "[--since <duration>] [--watch]", "List past chaos events", runEvents},
+		{"report", "[--since <duration>] [--format markdown|html] [--results <files>]", "Summarize past chaos events", runReport},
+		{"asg", "list [--prefix <prefix>] [--match <regexp>] [--tag <key>[=<value>]] | instances <group>", "List auto scaling groups", runASG},
+		{"wipe", "--region <name> [--domain <name>] [--backup <file>]", "Wipe state of Chaos Monkey in SimpleDB", runWipe},
+		{"prune", "--region <name> --retention <duration> [--domain <name>] [--batch-size <n>]", "Delete old chaos events from SimpleDB", runPrune},
+		{"strategies", "", "List chaos strategies", runStrategies},
+		{"tui", "[--interval <duration>]", "Show a live dashboard of chaos events and groups", runTUI},
+		{"doctor", "", "Check setup of Chaos Monkey and AWS", runDoctor},
+		{"completion", "bash|zsh|fish", "Print shell completion script", runCompletion},
+		{"version", "", "Show program version", runVersion},
+	}
+}
+
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	case "-version", "--version":
+		runVersion(nil)
+		return
+	case "__complete":
+		runComplete(args[1:])
+		return
+	}
+
+	// Support the flag-only interface of previous versions
+	if strings.HasPrefix(args[0], "-") {
+		legacyMain(args)
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.Name == args[0] {
+			cmd.Run(args[1:])
+			closePlugins()
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "error: unknown command %q\n\n", args[0])
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: chaosmonkey <command> [options]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'chaosmonkey <command> -h' for the options of a command.\n")
+}
+
+// newFlagSet returns a flag set for the given command that prints a usage
+// message tailored to it.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&errorFormat, "error-format", "text", "Format of error messages: text or json")
+	fs.Usage = func() {
+		for _, cmd := range commands {
+			if cmd.Name == name {
+				fmt.Fprintf(os.Stderr, "Usage: chaosmonkey %s %s\n\n%s.\n\nOptions:\n", cmd.Name, cmd.Args, cmd.Summary)
+			}
+		}
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses the arguments of a command, which expects no positional
+// arguments.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		exit(exitUsage, "%s expects no arguments, but %d given", fs.Name(), fs.NArg())
+	}
+	if f := errorFormat; f != "text" && f != "json" {
+		errorFormat = "text"
+		exit(exitUsage, "unknown error format %q (must be text or json)", f)
+	}
+	if cf, ok := clientFlagSets[fs]; ok {
+		applyProfile(fs, cf)
+	}
+	if fs.Lookup("output") != nil {
+		checkOutputFormat()
+	}
+}
+
+// clientFlags holds the options to connect to the Chaos Monkey API and, for
+// commands triggering chaos events, the options guarding them.
+type clientFlags struct {
+	profile  string
+	endpoint string
+	region   string
+	username string
+	password string
+
+	// Whether the options of addTriggerFlags were added
+	triggers bool
+
+	dryRun   bool
+	alarms   string
+	health   bool
+	protect  bool
+	deploys  bool
+	exempt   bool
+	capacity string
+	minSize  int
+	sizeWarn bool
+	snsTopic string
+	eventBus string
+
+	// Outage checkers halting chaos
+	haltAlarms string
+	haltProbe  string
+
+	// Emergency stop sentinels
+	stopFile  string
+	stopParam string
+
+	// Blast radius limits
+	maxGroupEvents int
+	groupWindow    time.Duration
+	maxTotalEvents int
+	totalWindow    time.Duration
+	overrideLimits string
+
+	// Cap of chaos events in flight per service across regions
+	maxInFlight int
+	inFlight    time.Duration
+
+	// Cooldown periods per strategy
+	cooldowns cooldownFlags
+
+	// Sources of error budgets
+	budgetPrometheus string
+	budgetQuery      string
+	budgetExpression string
+	minBudget        float64
+
+	// Tags marking groups and instances as exempt from chaos
+	exemptTags tagFlags
+
+	// Patterns of allowed and denied groups
+	allowGroups patternFlags
+	denyGroups  patternFlags
+
+	// Protected groups, approvers, and presented approval
+	requireApproval patternFlags
+	approvers       string
+	approvalDir     string
+	approval        string
+
+	// Rego policies admitting chaos events
+	policy      string
+	policyQuery string
+
+	// Allowed chaos windows
+	windows  windowFlags
+	holidays dateFlags
+	windowTZ string
+
+	// Audit log
+	reason    string
+	auditFile string
+	auditS3   string
+
+	// Optional bus passed to the client
+	bus *chaosmonkey.Bus
+
+	// Approvals guard of --require-approval, set by newClient
+	approvals *chaosmonkey.Approvals
+
+	// Optional kill switch passed to the client
+	halt *chaosmonkey.HaltSwitch
+
+	// Additional guards and outage checkers passed to the client, e.g. those
+	// of a campaign
+	guards         []chaosmonkey.Guard
+	outageCheckers []chaosmonkey.OutageChecker
+
+	// Optional function told the result of every outage check
+	observeProbe func(name string, err error)
+
+	// Whether the client enriches events with instance details
+	enrich bool
+
+	// File of chaos events and campaigns linked to incidents
+	incidentLinks string
+
+	// Plugins and their roles
+	pluginDir      string
+	pluginEnricher string
+	pluginNotifier nameFlags
+	pluginProbes   nameFlags
+	pluginBackend  string
+}
+
+// clientFlagSets maps flag sets to the client flags added to them.
+var clientFlagSets = make(map[*flag.FlagSet]*clientFlags)
+
+func addClientFlags(fs *flag.FlagSet) *clientFlags {
+	var f clientFlags
+	fs.StringVar(&f.profile, "profile", "", "Name of profile in configuration file (or use CHAOSMONKEY_PROFILE)")
+	fs.StringVar(&f.endpoint, "endpoint", "", "Address and port of Chaos Monkey API server")
+	fs.StringVar(&f.region, "region", "", "Name of AWS region (ignored by vanilla Chaos Monkey)")
+	fs.StringVar(&f.username, "username", "", "Username for HTTP basic authentication")
+	fs.StringVar(&f.password, "password", "", "Password for HTTP basic authentication")
+	fs.StringVar(&f.pluginDir, "plugin-dir", chaosmonkeyDir("plugins"), fmt.Sprintf("Directory of plugin executables, named %s<name>", plugin.Prefix))
+	clientFlagSets[fs] = &f
+	return &f
+}
+
+// addTriggerFlags adds the client flags and the options of commands
+// triggering chaos events, like guards, notifications and the audit log.
+func addTriggerFlags(fs *flag.FlagSet) *clientFlags {
+	f := addClientFlags(fs)
+	f.triggers = true
+	fs.BoolVar(&f.dryRun, "dry-run", false, "Print requests and check guards without triggering chaos events")
+	fs.StringVar(&f.alarms, "check-alarms", "", "Refuse chaos events while CloudWatch alarms with this name prefix are in ALARM state (\"*\" for all alarms)")
+	fs.BoolVar(&f.health, "check-maintenance", false, "Refuse chaos events against groups whose instances have failed status checks or scheduled events")
+	fs.BoolVar(&f.protect, "check-protection", false, "Refuse to shut down instances of groups with instances protected from termination or scale-in")
+	fs.BoolVar(&f.exempt, "check-exemptions", false, fmt.Sprintf("Refuse chaos events against groups exempt from chaos by tags of the group or its instances, e.g. %s=true or --exempt-tag", aws.TagChaosExempt))
+	fs.Var(&f.exemptTags, "exempt-tag", fmt.Sprintf("Tag marking groups and instances as exempt for --check-exemptions, given as <key> or <key>=<value> (repeatable, default %s)", strings.Join(aws.DefaultExemptTags, ",")))
+	fs.BoolVar(&f.deploys, "check-deployments", false, "Refuse chaos events against groups with instance refreshes or CodeDeploy deployments underway")
+	fs.StringVar(&f.snsTopic, "notify-sns", "", "Publish triggered and refused chaos events to the SNS topic with this ARN")
+	fs.StringVar(&f.eventBus, "notify-eventbridge", "", "Emit chaos lifecycle events onto the EventBridge bus with this name (\"default\" for the default bus)")
+	fs.StringVar(&f.capacity, "min-healthy", "", "Refuse chaos events that would leave fewer instances in service than this number or percentage of desired capacity, e.g. 3 or 75%")
+	fs.IntVar(&f.minSize, "min-group-size", 0, fmt.Sprintf("Refuse chaos events against groups with fewer instances in service than this number, e.g. %d", aws.DefaultMinInService))
+	fs.BoolVar(&f.sizeWarn, "min-group-size-warn", false, "Only warn about groups below --min-group-size instead of refusing chaos events")
+	fs.StringVar(&f.haltAlarms, "halt-on-alarms", "", "Halt chaos, including running campaigns, while any CloudWatch alarm with this name prefix is in ALARM state (\"*\" for all alarms)")
+	fs.StringVar(&f.haltProbe, "halt-on-probe", "", "Halt chaos, including running campaigns, while this URL does not respond with a 2xx status code")
+	fs.StringVar(&f.stopFile, "stop-file", "", "Halt chaos, including running campaigns, while this file exists")
+	fs.StringVar(&f.stopParam, "stop-parameter", "", "Halt chaos, including running campaigns, while this SSM parameter is set to anything but false")
+	fs.IntVar(&f.maxGroupEvents, "max-group-events", 0, "Refuse chaos events against groups that already had this many within --group-window")
+	fs.DurationVar(&f.groupWindow, "group-window", chaosmonkey.DefaultGroupWindow, "Window of --max-group-events")
+	fs.IntVar(&f.maxTotalEvents, "max-total-events", 0, "Refuse chaos events once this many were triggered within --total-window")
+	fs.DurationVar(&f.totalWindow, "total-window", chaosmonkey.DefaultTotalWindow, "Window of --max-total-events")
+	fs.StringVar(&f.overrideLimits, "override-limits", "", "Exceed --max-group-events and --max-total-events, stating the reason")
+	fs.IntVar(&f.maxInFlight, "max-in-flight", 0, "Refuse chaos events against groups, across all regions, that already have this many in flight, e.g. 1")
+	fs.DurationVar(&f.inFlight, "in-flight", chaosmonkey.DefaultInFlight, "How long chaos events are in flight for --max-in-flight")
+	fs.Var(&f.cooldowns, "cooldown", "Refuse chaos events against groups that had one within this period, given as <duration> or <strategy>=<duration>, e.g. NetworkLatency=6h (repeatable)")
+	fs.StringVar(&f.budgetPrometheus, "error-budget-prometheus", "", "Refuse chaos events while --error-budget-query, evaluated by the Prometheus server at this URL, yields an exhausted error budget")
+	fs.StringVar(&f.budgetQuery, "error-budget-query", "", "PromQL query yielding the remaining fraction of the error budget of the service of $group in $region")
+	fs.StringVar(&f.budgetExpression, "error-budget-expression", "", "Refuse chaos events while this CloudWatch metric math expression yields an exhausted error budget, given as remaining fraction like --error-budget-query")
+	fs.Float64Var(&f.minBudget, "min-error-budget", 0, "Fraction of the error budget that must remain for chaos events, e.g. 0.1")
+	fs.Var(&f.allowGroups, "allow-group", "Refuse chaos events against groups not matching this shell pattern or /regexp/ (repeatable)")
+	fs.Var(&f.denyGroups, "deny-group", "Refuse chaos events against groups matching this shell pattern or /regexp/, even if allowed (repeatable)")
+	fs.Var(&f.requireApproval, "require-approval", "Refuse destructive chaos events against groups matching this shell pattern or /regexp/ without approval by a second person (repeatable)")
+	fs.StringVar(&f.approvers, "approvers", chaosmonkeyDir("approvers"), "File listing approvers and their public keys for --require-approval, as printed by 'chaosmonkey approve --generate-key'")
+	fs.StringVar(&f.approvalDir, "approval-dir", chaosmonkeyDir("approvals"), "Directory recording used approvals for --require-approval")
+	fs.StringVar(&f.approval, "approval", "", "Approval token granted by 'chaosmonkey approve'")
+	fs.StringVar(&f.policy, "policy", "", "Refuse chaos events denied by the Rego policies in this file or directory")
+	fs.StringVar(&f.policyQuery, "policy-query", policy.DefaultQuery, "Rego query yielding the messages of denied chaos events")
+	fs.Var(&f.windows, "allowed-hours", "Refuse chaos events outside of this window, e.g. \"MON-FRI 09:00-16:00\" (repeatable)")
+	fs.Var(&f.holidays, "holiday", "Refuse chaos events on this date, e.g. 2018-12-25 (repeatable)")
+	fs.StringVar(&f.windowTZ, "allowed-timezone", "Local", "Time zone of --allowed-hours and --holiday, e.g. Europe/Berlin")
+	fs.StringVar(&f.reason, "reason", "", "Why chaos events are triggered, as recorded in the audit log")
+	fs.StringVar(&f.auditFile, "audit-file", "", "Append an audit record of every chaos event to this file as JSON lines (requires --reason)")
+	fs.StringVar(&f.auditS3, "audit-s3", "", "Store an audit record of every chaos event in this S3 bucket, given as bucket or bucket/prefix (requires --reason)")
+	fs.StringVar(&f.incidentLinks, "incident-links", chaosmonkeyDir("incidents.json"), "Refuse chaos events against groups of open incidents linked to chaos in this file by 'chaosmonkey incident link'")
+	fs.Var(&f.pluginNotifier, "plugin", "Send triggered and refused chaos events to the plugin with this name (repeatable)")
+	fs.Var(&f.pluginProbes, "plugin-probe", "Halt chaos, including running campaigns, while the plugin with this name reports an outage (repeatable)")
+	fs.StringVar(&f.pluginBackend, "plugin-backend", "", "Have the plugin with this name cause chaos events instead of Chaos Monkey")
+	return f
+}
+
+func (f *clientFlags) newClient() *chaosmonkey.Client {
+	config := &chaosmonkey.Config{
+		Endpoint:   f.endpoint,
+		Region:     f.region,
+		Username:   f.username,
+		Password:   f.password,
+		UserAgent:  fmt.Sprintf("chaosmonkey Go client %s", Version),
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		DryRun:     f.dryRun,
+		Bus:        f.bus,
+		HaltSwitch: f.halt,
+	}
+	if f.enrich {
+		config.EnrichEvents = aws.NewClient(f.region)
+	}
+	if f.pluginEnricher != "" {
+		config.EnrichEvents = f.startPlugin(f.pluginEnricher)
+	}
+	// CHAOSMONKEY_STOP is checked regardless of flags, so that on-callers can
+	// rely on it
+	config.StopSentinels = append(config.StopSentinels, &chaosmonkey.EnvSentinel{})
+	var client *chaosmonkey.Client
+	if f.triggers {
+		// Count chaos events of other processes as well, except in dry runs,
+		// which are not recorded by Chaos Monkey
+		var history func(since time.Time) ([]chaosmonkey.Event, error)
+		if !f.dryRun {
+			history = func(since time.Time) ([]chaosmonkey.Event, error) {
+				return client.EventsSince(since)
+			}
+		}
+		f.addGuards(config, history)
+	}
+	if f.dryRun {
+		if config.Bus == nil {
+			config.Bus = chaosmonkey.NewBus()
+		}
+		config.Bus.Subscribe(chaosmonkey.TopicTriggerRequested, func(m chaosmonkey.Message) {
+			body, _ := json.Marshal(m.Request)
+			fmt.Fprintf(os.Stderr, "Dry run: would send POST %s%s %s\n", config.Endpoint, chaosmonkey.APIPath, body)
+		})
+	}
+	var err error
+	client, err = chaosmonkey.NewClient(config)
+	if err != nil {
+		abort("%s", err)
+	}
+	return client
+}
+
+// addGuards adds the guards, outage checkers, stop sentinels, notifiers, and
+// audit sink given by the options of addTriggerFlags to the configuration.
+// history, if not nil, returns the chaos events triggered since a given time,
+// which limits and cooldowns count besides the events recorded on the bus.
+func (f *clientFlags) addGuards(config *chaosmonkey.Config, history func(since time.Time) ([]chaosmonkey.Event, error)) {
+	config.AllowGroups = f.allowGroups
+	config.DenyGroups = f.denyGroups
+	if len(f.requireApproval) > 0 || f.policy != "" || f.auditFile != "" || f.auditS3 != "" {
+		config.Actor = f.actor()
+	}
+	if f.alarms != "" {
+		prefix := f.alarms
+		if prefix == "*" {
+			prefix = ""
+		}
+		config.Guards = append(config.Guards, &aws.AlarmGuard{
+			Client:     aws.NewClient(f.region),
+			NamePrefix: prefix,
+		})
+	}
+	if f.health {
+		config.Guards = append(config.Guards, &aws.MaintenanceGuard{Client: aws.NewClient(f.region)})
+	}
+	if f.protect {
+		config.Guards = append(config.Guards, &aws.ProtectionGuard{Client: aws.NewClient(f.region)})
+	}
+	if f.exempt {
+		config.Guards = append(config.Guards, &aws.ExemptionGuard{Client: aws.NewClient(f.region), Tags: f.exemptTags})
+	} else if len(f.exemptTags) > 0 {
+		exit(exitUsage, "--exempt-tag requires --check-exemptions")
+	}
+	if f.deploys {
+		config.Guards = append(config.Guards, &aws.DeploymentGuard{Client: aws.NewClient(f.region)})
+	}
+	if f.capacity != "" {
+		guard := &aws.CapacityGuard{Client: aws.NewClient(f.region)}
+		var err error
+		if strings.HasSuffix(f.capacity, "%") {
+			var percent float64
+			percent, err = strconv.ParseFloat(strings.TrimSuffix(f.capacity, "%"), 64)
+			guard.MinHealthyRatio = percent / 100
+		} else {
+			guard.MinHealthy, err = strconv.Atoi(f.capacity)
+		}
+		if err != nil {
+			exit(exitUsage, "invalid minimum of healthy instances %q", f.capacity)
+		}
+		config.Guards = append(config.Guards, guard)
+	}
+	if f.minSize > 0 {
+		guard := &aws.GroupSizeGuard{Client: aws.NewClient(f.region), MinInService: f.minSize}
+		if f.sizeWarn {
+			guard.Warn = func(err error) {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+			}
+		}
+		config.Guards = append(config.Guards, guard)
+	}
+	if f.haltAlarms != "" {
+		prefix := f.haltAlarms
+		if prefix == "*" {
+			prefix = ""
+		}
+		config.OutageCheckers = append(config.OutageCheckers, &aws.AlarmOutageChecker{
+			Client:     aws.NewClient(f.region),
+			NamePrefix: prefix,
+		})
+	}
+	if f.haltProbe != "" {
+		config.OutageCheckers = append(config.OutageCheckers, &chaosmonkey.HTTPProbe{URL: f.haltProbe})
+	}
+	for _, name := range f.pluginProbes {
+		config.OutageCheckers = append(config.OutageCheckers, f.startPlugin(name))
+	}
+	config.OutageCheckers = append(config.OutageCheckers, f.outageCheckers...)
+	if f.observeProbe != nil {
+		for i, o := range config.OutageCheckers {
+			config.OutageCheckers[i] = observedProbe(probeName(o), o, f.observeProbe)
+		}
+	}
+	if f.stopFile != "" {
+		config.StopSentinels = append(config.StopSentinels, &chaosmonkey.FileSentinel{Path: f.stopFile})
+	}
+	if f.stopParam != "" {
+		config.StopSentinels = append(config.StopSentinels, &ssm.ParameterSentinel{
+			Client: aws.NewClient(f.region),
+			Name:   f.stopParam,
+		})
+	}
+	if f.snsTopic != "" {
+		if config.Bus == nil {
+			config.Bus = chaosmonkey.NewBus()
+		}
+		notifier := &aws.SNSNotifier{
+			Client:   aws.NewClient(f.region),
+			TopicARN: f.snsTopic,
+			OnError: func(err error) {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+			},
+		}
+		notifier.Subscribe(config.Bus)
+	}
+	if f.eventBus != "" {
+		if config.Bus == nil {
+			config.Bus = chaosmonkey.NewBus()
+		}
+		emitter := &aws.EventBridgeEmitter{
+			Client:  aws.NewClient(f.region),
+			BusName: f.eventBus,
+			OnError: func(err error) {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+			},
+		}
+		emitter.Subscribe(config.Bus)
+	}
+	for _, name := range f.pluginNotifier {
+		if config.Bus == nil {
+			config.Bus = chaosmonkey.NewBus()
+		}
+		f.startPlugin(name).Subscribe(config.Bus, func(err error) {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", err)
+		}, pluginTopics...)
+	}
+	if f.pluginBackend != "" {
+		config.Backend = f.startPlugin(f.pluginBackend)
+	}
+	if len(f.requireApproval) > 0 {
+		if f.approvers == "" || f.approvalDir == "" {
+			exit(exitUsage, "--require-approval requires --approvers and --approval-dir")
+		}
+		approvers, err := readApprovers(f.approvers)
+		if err != nil {
+			exit(exitUsage, "cannot read approvers: %s", err)
+		}
+		approvals := &chaosmonkey.Approvals{
+			Approvers: approvers,
+			Protected: f.requireApproval,
+			Requester: config.Actor,
+			Used:      &chaosmonkey.TokenDir{Path: f.approvalDir},
+		}
+		if f.approval != "" {
+			if _, err := approvals.Present(f.approval); err != nil {
+				exit(exitUsage, "%s", err)
+			}
+		}
+		if config.Bus == nil {
+			config.Bus = chaosmonkey.NewBus()
+		}
+		approvals.Subscribe(config.Bus)
+		config.Guards = append(config.Guards, approvals)
+		f.approvals = approvals
+	}
+	config.Audit = f.auditSink(config)
+	if windows := f.windowPolicy(); windows != nil {
+		config.Guards = append(config.Guards, windows)
+	}
+	if f.policy != "" {
+		config.Guards = append(config.Guards, f.policyGuard())
+	}
+	if f.incidentLinks != "" {
+		config.Guards = append(config.Guards, &chaosmonkey.IncidentLinks{Path: f.incidentLinks})
+	}
+	config.Guards = append(config.Guards, f.guards...)
+	if (f.budgetPrometheus == "") != (f.budgetQuery == "") {
+		exit(exitUsage, "--error-budget-prometheus and --error-budget-query must be given together")
+	}
+	if f.budgetPrometheus != "" {
+		config.Guards = append(config.Guards, &chaosmonkey.ErrorBudgetGuard{
+			Source:       &chaosmonkey.PrometheusBudget{URL: f.budgetPrometheus, Query: f.budgetQuery},
+			MinRemaining: f.minBudget,
+		})
+	}
+	if f.budgetExpression != "" {
+		config.Guards = append(config.Guards, &chaosmonkey.ErrorBudgetGuard{
+			Source:       &aws.MetricMathBudget{Client: aws.NewClient(f.region), Expression: f.budgetExpression},
+			MinRemaining: f.minBudget,
+		})
+	}
+	if f.maxGroupEvents > 0 || f.maxTotalEvents > 0 {
+		if config.Bus == nil {
+			config.Bus = chaosmonkey.NewBus()
+		}
+		limiter := &chaosmonkey.BlastRadiusLimiter{
+			MaxPerGroup: f.maxGroupEvents,
+			GroupWindow: f.groupWindow,
+			MaxTotal:    f.maxTotalEvents,
+			TotalWindow: f.totalWindow,
+			History:     history,
+			OnOverride: func(t chaosmonkey.Target, reason string) {
+				fmt.Fprintf(os.Stderr, "warning: exceeding blast radius limits for %s: %s\n", t.AutoScalingGroupName, reason)
+			},
+		}
+		if f.overrideLimits != "" {
+			if err := limiter.Override(f.overrideLimits); err != nil {
+				exit(exitUsage, "%s", err)
+			}
+		}
+		limiter.Subscribe(config.Bus)
+		config.Guards = append(config.Guards, limiter)
+	}
+	if f.maxInFlight > 0 {
+		if config.Bus == nil {
+			config.Bus = chaosmonkey.NewBus()
+		}
+		inFlight := &chaosmonkey.InFlightLimiter{
+			MaxInFlight: f.maxInFlight,
+			InFlight:    f.inFlight,
+			History:     history,
+		}
+		inFlight.Subscribe(config.Bus)
+		config.Guards = append(config.Guards, inFlight)
+	}
+	if f.cooldowns.Default > 0 || len(f.cooldowns.Strategies) > 0 {
+		if config.Bus == nil {
+			config.Bus = chaosmonkey.NewBus()
+		}
+		cooldowns := &chaosmonkey.Cooldowns{
+			Strategies: f.cooldowns.Strategies,
+			Default:    f.cooldowns.Default,
+			History:    history,
+		}
+		cooldowns.Subscribe(config.Bus)
+		config.Guards = append(config.Guards, cooldowns)
+	}
+}
+
+// actor returns who triggers chaos events: the ARN of the AWS identity of the
+// user, or the user authenticated with the Chaos Monkey API if the identity
+// cannot be determined. Unlike $USER, neither can be chosen freely.
+func (f *clientFlags) actor() string {
+	arn, err := aws.NewClient(f.region).CallerIdentity(context.Background())
+	if err == nil {
+		return arn
+	}
+	if f.username != "" {
+		return f.username
+	}
+	if user := os.Getenv("CHAOSMONKEY_USERNAME"); user != "" {
+		return user
+	}
+	abort("cannot determine AWS identity: %s", err)
+	return ""
+}
+
+// auditSink returns the sinks of the audit log, or nil if chaos events are
+// not audited. It also sets the role and reason of config.
+func (f *clientFlags) auditSink(config *chaosmonkey.Config) chaosmonkey.AuditSink {
+	var sinks []chaosmonkey.AuditSink
+	if f.auditFile != "" {
+		file, err := os.OpenFile(f.auditFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			abort("%s", err)
+		}
+		sinks = append(sinks, &chaosmonkey.AuditWriter{W: file})
+	}
+	if f.auditS3 != "" {
+		bucket, prefix := f.auditS3, ""
+		if i := strings.Index(bucket, "/"); i >= 0 {
+			bucket, prefix = bucket[:i], bucket[i+1:]
+			if prefix != "" && !strings.HasSuffix(prefix, "/") {
+				prefix += "/"
+			}
+		}
+		client := aws.NewClient(f.region)
+		sinks = append(sinks, &aws.S3AuditSink{Client: client, Bucket: bucket, Prefix: prefix})
+		// Credentials are needed to store records anyway, so the identity
+		// using them is recorded as role
+		role, err := client.CallerIdentity(context.Background())
+		if err != nil {
+			abort("cannot determine AWS identity for audit log: %s", err)
+		}
+		config.Role = role
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+	if f.reason == "" {
+		exit(exitUsage, "--audit-file and --audit-s3 require --reason")
+	}
+	config.Reason = f.reason
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+	return chaosmonkey.AuditSinkFunc(func(ctx context.Context, r chaosmonkey.AuditRecord) error {
+		for _, s := range sinks {
+			if err := s.WriteAudit(ctx, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// policyGuard returns the guard evaluating the Rego policies, which are
+// passed the live state of the targeted group.
+func (f *clientFlags) policyGuard() *policy.Guard {
+	guard, err := policy.LoadGuard(context.Background(), f.policyQuery, f.policy)
+	if err != nil {
+		exit(exitUsage, "%s", err)
+	}
+	guard.State = f.groupState
+	return guard
+}
+
+// groupState returns the live state of the targeted auto scaling group for
+// policies.
+func (f *clientFlags) groupState(ctx context.Context, t chaosmonkey.Target) (interface{}, error) {
+	region := t.Region
+	if region == "" {
+		region = f.region
+	}
+	return aws.NewClient(region).AutoScalingGroup(ctx, t.AutoScalingGroupName)
+}
+
+// windowPolicy returns the policy restricting chaos to the allowed windows,
+// or nil if chaos is allowed at any time.
+func (f *clientFlags) windowPolicy() *chaosmonkey.WindowPolicy {
+	if len(f.windows) == 0 && len(f.holidays) == 0 {
+		return nil
+	}
+	loc, err := time.LoadLocation(f.windowTZ)
+	if err != nil {
+		exit(exitUsage, "%s", err)
+	}
+	return &chaosmonkey.WindowPolicy{
+		Windows:  f.windows,
+		Holidays: f.holidays,
+		Location: loc,
+	}
+}
+
+// patternFlags is a repeatable flag of group name patterns.
+type patternFlags []string
+
+func (p *patternFlags) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patternFlags) Set(v string) error {
+	if v == "" {
+		return fmt.Errorf("pattern must not be empty")
+	}
+	*p = append(*p, v)
+	return nil
+}
+
+// tagFlags is a repeatable flag of tags given as <key> or <key>=<value>.
+type tagFlags []string
+
+func (t *tagFlags) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *tagFlags) Set(v string) error {
+	if v == "" || strings.HasPrefix(v, "=") {
+		return fmt.Errorf("tag key must not be empty")
+	}
+	*t = append(*t, v)
+	return nil
+}
+
+// windowFlags is a repeatable flag of allowed chaos windows.
+type windowFlags []chaosmonkey.Window
+
+func (w *windowFlags) String() string {
+	return fmt.Sprintf("%d window(s)", len(*w))
+}
+
+func (w *windowFlags) Set(v string) error {
+	window, err := chaosmonkey.ParseWindow(v)
+	if err != nil {
+		return err
+	}
+	*w = append(*w, *window)
+	return nil
+}
+
+// cooldownFlags is a repeatable flag of cooldown periods, given either per
+// strategy as <strategy>=<duration> or as default <duration>.
+type cooldownFlags struct {
+	Strategies map[chaosmonkey.Strategy]time.Duration
+	Default    time.Duration
+}
+
+func (c *cooldownFlags) String() string {
+	var cooldowns []string
+	if c.Default > 0 {
+		cooldowns = append(cooldowns, c.Default.String())
+	}
+	for s, d := range c.Strategies {
+		cooldowns = append(cooldowns, fmt.Sprintf("%s=%s", s, d))
+	}
+	return strings.Join(cooldowns, ",")
+}
+
+func (c *cooldownFlags) Set(v string) error {
+	name, value := "", v
+	if i := strings.Index(v, "="); i >= 0 {
+		name, value = v[:i], v[i+1:]
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid cooldown %q, expected a positive duration", v)
+	}
+	if name == "" {
+		c.Default = d
+		return nil
+	}
+	strategy, err := chaosmonkey.ParseStrategy(name)
+	if err != nil {
+		return err
+	}
+	if c.Strategies == nil {
+		c.Strategies = make(map[chaosmonkey.Strategy]time.Duration)
+	}
+	c.Strategies[strategy] = d
+	return nil
+}
+
+// dateFlags is a repeatable flag of dates given as YYYY-MM-DD.
+type dateFlags []time.Time
+
+func (d *dateFlags) String() string {
+	var dates []string
+	for _, t := range *d {
+		dates = append(dates, t.Format("2006-01-02"))
+	}
+	return strings.Join(dates, ",")
+}
+
+func (d *dateFlags) Set(v string) error {
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", v)
+	}
+	*d = append(*d, t)
+	return nil
+}
+
+func runStrategies(args []string) {
+	fs := newFlagSet("strategies")
+	parseFlags(fs, args)
+
+	for _, s := range chaosmonkey.KnownStrategies() {
+		fmt.Println(s)
+	}
+}
+
+func runVersion(args []string) {
+	fs := newFlagSet("version")
+	parseFlags(fs, args)
+
+	fmt.Printf("chaosmonkey %s %s/%s %s\n", Version,
+		runtime.GOOS, runtime.GOARCH, runtime.Version())
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ryanuber/columnize"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+	"github.com/FlyLevin/chaosmonkey/plugin"
+	"github.com/FlyLevin/chaosmonkey/policy"
+)
+
+// probeName names an outage checker for display.
+func probeName(o chaosmonkey.OutageChecker) string {
+	switch o := o.(type) {
+	case *chaosmonkey.HTTPProbe:
+		return o.URL
+	case *policy.Probe:
+		return o.URL
+	case *aws.AlarmOutageChecker:
+		if o.NamePrefix == "" {
+			return "all alarms"
+		}
+		return fmt.Sprintf("alarms %s*", o.NamePrefix)
+	case *plugin.Plugin:
+		return "plugin " + o.Name
+	}
+	return fmt.Sprintf("%T", o)
+}
+
+// observedProbe returns an outage checker that tells observe the result of
+// every check by o.
+func observedProbe(name string, o chaosmonkey.OutageChecker, observe func(name string, err error)) chaosmonkey.OutageChecker {
+	return chaosmonkey.OutageCheckerFunc(func(ctx context.Context) error {
+		err := o.CheckOutage(ctx)
+		observe(name, err)
+		return err
+	})
+}
+
+// campaignMonitor is a terminal view of a running campaign, showing the
+// progress of its steps and the status of its probes, from which operators
+// can abort the campaign.
+type campaignMonitor struct {
+	campaign *chaosmonkey.Campaign
+	halt     *chaosmonkey.HaltSwitch
+	started  time.Time
+
+	mu      sync.Mutex
+	current int // index of the running step, -1 before the first
+	steps   []stepProgress
+	probes  []probeStatus
+	log     []string
+	status  string
+	done    bool
+}
+
+type stepProgress struct {
+	triggered int
+	refused   int
+}
+
+type probeStatus struct {
+	name    string
+	err     error
+	checked time.Time
+}
+
+// monitorLogLines is the number of recent chaos events shown.
+const monitorLogLines = 8
+
+func newCampaignMonitor(campaign *chaosmonkey.Campaign, halt *chaosmonkey.HaltSwitch) *campaignMonitor {
+	return &campaignMonitor{
+		campaign: campaign,
+		halt:     halt,
+		started:  time.Now(),
+		current:  -1,
+		steps:    make([]stepProgress, len(campaign.Steps)),
+	}
+}
+
+// Subscribe tracks the chaos events triggered and refused on the bus.
+func (m *campaignMonitor) Subscribe(bus *chaosmonkey.Bus) {
+	bus.Subscribe(chaosmonkey.TopicEventRecorded, func(msg chaosmonkey.Message) {
+		m.record(msg, false)
+	})
+	bus.Subscribe(chaosmonkey.TopicGuardBlocked, func(msg chaosmonkey.Message) {
+		m.record(msg, true)
+	})
+}
+
+func (m *campaignMonitor) record(msg chaosmonkey.Message, refused bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	line := fmt.Sprintf("%s triggered %s against %s", msg.Time.Local().Format("15:04:05"), strategyName(msg.Strategy), regionalGroup(msg))
+	if refused {
+		line = fmt.Sprintf("%s refused %s against %s: %s", msg.Time.Local().Format("15:04:05"), strategyName(msg.Strategy), regionalGroup(msg), msg.Err)
+	}
+	m.log = append(m.log, line)
+	if len(m.log) > monitorLogLines {
+		m.log = m.log[len(m.log)-monitorLogLines:]
+	}
+	if m.current < 0 {
+		return
+	}
+	if refused {
+		m.steps[m.current].refused++
+	} else {
+		m.steps[m.current].triggered++
+	}
+}
+
+// OnStep tracks the running step; use it as Campaign.OnStep.
+func (m *campaignMonitor) OnStep(i int, step chaosmonkey.CampaignStep) {
+	m.mu.Lock()
+	m.current = i
+	m.mu.Unlock()
+}
+
+// ObserveProbe tracks the result of a probe, i.e. an outage checker or the
+// health check of the campaign.
+func (m *campaignMonitor) ObserveProbe(name string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.probes {
+		if m.probes[i].name == name {
+			m.probes[i].err, m.probes[i].checked = err, time.Now()
+			return
+		}
+	}
+	m.probes = append(m.probes, probeStatus{name: name, err: err, checked: time.Now()})
+}
+
+// Run renders the view every interval and handles commands entered by the
+// user until finished is closed.
+func (m *campaignMonitor) Run(interval time.Duration, finished <-chan struct{}) {
+	// Reads from stdin cannot be interrupted, so the reader stops with the
+	// next line entered after the monitor finished, or lives until the
+	// process exits, which it does right after campaigns
+	lines, closed := make(chan string), make(chan struct{})
+	defer close(closed)
+	go func() {
+		in := bufio.NewScanner(os.Stdin)
+		for in.Scan() {
+			select {
+			case lines <- in.Text():
+			case <-closed:
+				return
+			}
+		}
+	}()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.render()
+		select {
+		case <-finished:
+			m.mu.Lock()
+			m.done = true
+			m.mu.Unlock()
+			m.render()
+			fmt.Println()
+			return
+		case <-ticker.C:
+		case line := <-lines:
+			m.handle(line)
+		}
+	}
+}
+
+// handle processes a command entered by the user.
+func (m *campaignMonitor) handle(line string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch strings.TrimSpace(line) {
+	case "":
+	case "a", "abort":
+		m.halt.Halt()
+		m.status = "Aborting campaign"
+	default:
+		m.status = fmt.Sprintf("Unknown command %q", strings.TrimSpace(line))
+	}
+}
+
+func (m *campaignMonitor) render() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	state := "running"
+	if m.done {
+		state = "finished"
+	}
+	fmt.Fprintf(&b, "Campaign %s - %s for %s\n\n", m.campaign.Name, state, time.Since(m.started).Round(time.Second))
+
+	lines := []string{"#|Step|Strategy|Progress|Refused|"}
+	for i, step := range m.campaign.Steps {
+		p := m.steps[i]
+		marker := ""
+		switch {
+		case i == m.current && !m.done:
+			marker = "<- running"
+		case i > m.current:
+			marker = "pending"
+		}
+		lines = append(lines, fmt.Sprintf("%d|%s|%s|%d/%d|%d|%s", i+1, step.Name, strategyName(step.Strategy),
+			p.triggered, stepEvents(step), p.refused, marker))
+	}
+	b.WriteString(columnize.SimpleFormat(lines))
+
+	b.WriteString("\n\nProbes:\n\n")
+	if len(m.probes) == 0 {
+		b.WriteString("(none checked yet)")
+	} else {
+		lines = []string{"Probe|Status|Checked"}
+		for _, p := range m.probes {
+			status := "OK"
+			if p.err != nil {
+				status = "FAILING: " + p.err.Error()
+			}
+			lines = append(lines, fmt.Sprintf("%s|%s|%s", p.name, status, p.checked.Format("15:04:05")))
+		}
+		b.WriteString(columnize.SimpleFormat(lines))
+	}
+
+	b.WriteString("\n\nRecent chaos events:\n\n")
+	for _, line := range m.log {
+		b.WriteString(line + "\n")
+	}
+
+	fmt.Fprintf(&b, "\n%s\n", m.status)
+	if !m.done {
+		b.WriteString("Commands: a to abort, Enter to refresh\n> ")
+	}
+	fmt.Print(b.String())
+}
+
+// stepEvents returns the number of chaos events a step triggers if none
+// fail.
+func stepEvents(step chaosmonkey.CampaignStep) int {
+	count := step.Count
+	if count == 0 {
+		count = 1
+	}
+	regions := len(step.Regions)
+	if regions == 0 {
+		regions = 1
+	}
+	return count * len(step.Groups) * regions
+}
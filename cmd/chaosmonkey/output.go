@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ryanuber/columnize"
+	"gopkg.in/yaml.v2"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func listAutoScalingGroups(groups []aws.AutoScalingGroup) {
+	if outputFormat != "table" {
+		if groups == nil {
+			groups = []aws.AutoScalingGroup{}
+		}
+		printStructured(groups)
+		return
+	}
+
+	lines := []string{"AutoScalingGroupName|Instances|Desired|Min|Max"}
+	for _, g := range groups {
+		lines = append(lines, fmt.Sprintf("%s|%d|%d|%d|%d",
+			g.Name,
+			g.InstancesInService,
+			g.DesiredCapacity,
+			g.MinSize,
+			g.MaxSize,
+		))
+	}
+	fmt.Println(columnize.SimpleFormat(lines))
+}
+
+func listInstances(instances []aws.Instance) {
+	if outputFormat != "table" {
+		printStructured(instances)
+		return
+	}
+
+	lines := []string{"InstanceID|AvailabilityZone|State|Type|Lifecycle|Launched"}
+	for _, i := range instances {
+		launched := "-"
+		if !i.LaunchTime.IsZero() {
+			launched = i.LaunchTime.UTC().Format(time.RFC3339)
+		}
+		lines = append(lines, fmt.Sprintf("%s|%s|%s|%s|%s|%s",
+			i.ID,
+			i.AvailabilityZone,
+			i.LifecycleState,
+			i.InstanceType,
+			i.Lifecycle,
+			launched,
+		))
+	}
+	fmt.Println(columnize.SimpleFormat(lines))
+}
+
+// outputFormat is the format used to print results, see addOutputFlag.
+var outputFormat = "table"
+
+// addOutputFlag adds the --output flag to commands that print results.
+func addOutputFlag(fs *flag.FlagSet) {
+	fs.StringVar(&outputFormat, "output", "table", "Output format: table, json, or yaml")
+}
+
+func checkOutputFormat() {
+	switch outputFormat {
+	case "table", "json", "yaml":
+	default:
+		abort("unknown output format %q (must be table, json, or yaml)", outputFormat)
+	}
+}
+
+// printStructured prints v in the chosen structured output format.
+func printStructured(v interface{}) {
+	switch outputFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			abort("%s", err)
+		}
+	case "yaml":
+		out, err := yaml.Marshal(v)
+		if err != nil {
+			abort("%s", err)
+		}
+		os.Stdout.Write(out)
+	}
+}
+
+var addHeader = true
+
+func printEvents(event ...chaosmonkey.Event) {
+	if outputFormat != "table" {
+		if event == nil {
+			event = []chaosmonkey.Event{}
+		}
+		printStructured(event)
+		return
+	}
+
+	var enriched bool
+	for _, e := range event {
+		if e.Instance != nil {
+			enriched = true
+		}
+	}
+
+	var lines []string
+	if addHeader {
+		header := "InstanceID|AutoScalingGroupName|Region|Strategy|TriggeredAt"
+		if enriched {
+			header += "|AvailabilityZone|InstanceType|ImageID"
+		}
+		lines = append(lines, header)
+		addHeader = false
+	}
+	for _, e := range event {
+		line := fmt.Sprintf("%s|%s|%s|%s|%s",
+			e.InstanceID,
+			e.AutoScalingGroupName,
+			e.Region,
+			e.Strategy,
+			e.TriggeredAt.Format(time.RFC3339),
+		)
+		if enriched {
+			var i chaosmonkey.InstanceDetails
+			if e.Instance != nil {
+				i = *e.Instance
+			}
+			line += fmt.Sprintf("|%s|%s|%s", i.AvailabilityZone, i.InstanceType, i.ImageID)
+		}
+		lines = append(lines, line)
+	}
+	fmt.Println(columnize.SimpleFormat(lines))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ryanuber/columnize"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+)
+
+// isTerminal reports whether f is connected to a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks the user whether to proceed and aborts unless the answer is
+// yes.
+func confirm() {
+	fmt.Fprint(os.Stderr, "Proceed? [y/N] ")
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+	default:
+		abort("aborted by user")
+	}
+}
+
+// pickGroup lets the user interactively select one of the auto scaling groups
+// in the given region. Typing text narrows down the list using fuzzy search;
+// typing a number selects the group with that number.
+func pickGroup(region string) string {
+	groups, err := aws.NewClient(region).AutoScalingGroups(context.Background())
+	if err != nil {
+		abort("failed to get auto scaling groups: %s", err)
+	}
+	if len(groups) == 0 {
+		abort("no auto scaling groups found")
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	matches := groups
+	for {
+		printGroupChoices(os.Stderr, matches)
+		fmt.Fprint(os.Stderr, "Enter number to select, text to search, or nothing to list all: ")
+
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			abort("no auto scaling group selected")
+		}
+		line = strings.TrimSpace(line)
+
+		if n, err := strconv.Atoi(line); err == nil {
+			if n < 1 || n > len(matches) {
+				fmt.Fprintf(os.Stderr, "Invalid selection %d\n\n", n)
+				continue
+			}
+			return matches[n-1].Name
+		}
+
+		var found []aws.AutoScalingGroup
+		for _, g := range groups {
+			if fuzzyMatch(line, g.Name) {
+				found = append(found, g)
+			}
+		}
+		if len(found) == 0 {
+			fmt.Fprintf(os.Stderr, "No auto scaling group matches %q\n\n", line)
+			continue
+		}
+		matches = found
+	}
+}
+
+func printGroupChoices(w io.Writer, groups []aws.AutoScalingGroup) {
+	lines := []string{"#|AutoScalingGroupName|Instances|Desired|Warning"}
+	for i, g := range groups {
+		warning := ""
+		if g.InstancesInService <= 1 {
+			warning = "only one instance or less in service"
+		}
+		lines = append(lines, fmt.Sprintf("%d|%s|%d|%d|%s",
+			i+1, g.Name, g.InstancesInService, g.DesiredCapacity, warning))
+	}
+	fmt.Fprintf(w, "%s\n\n", columnize.SimpleFormat(lines))
+}
+
+// fuzzyMatch reports whether all characters of pattern appear in s in the
+// same order, ignoring case.
+func fuzzyMatch(pattern, s string) bool {
+	s = strings.ToLower(s)
+	for _, r := range strings.ToLower(pattern) {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
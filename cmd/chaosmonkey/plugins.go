@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+	"github.com/FlyLevin/chaosmonkey/plugin"
+)
+
+// pluginTopics are the topics forwarded to plugins given by --plugin.
+var pluginTopics = []chaosmonkey.Topic{
+	chaosmonkey.TopicEventRecorded,
+	chaosmonkey.TopicGuardBlocked,
+	chaosmonkey.TopicTriggerFailed,
+	chaosmonkey.TopicExperimentFinished,
+}
+
+// pluginCloseTimeout is how long closePlugins waits for plugins to exit.
+const pluginCloseTimeout = 5 * time.Second
+
+// plugins are the running plugins by name, started once by startPlugin and
+// closed by closePlugins.
+var plugins = make(map[string]*plugin.Plugin)
+
+// startPlugin starts the plugin with the given name found in --plugin-dir,
+// unless it is already running.
+func (f *clientFlags) startPlugin(name string) *plugin.Plugin {
+	if p, ok := plugins[name]; ok {
+		return p
+	}
+	path, err := plugin.Find(f.pluginDir, name)
+	if err != nil {
+		exit(exitUsage, "%s", err)
+	}
+	p, err := plugin.Start(path)
+	if err != nil {
+		abort("%s", err)
+	}
+	plugins[name] = p
+	return p
+}
+
+// closePlugins closes all running plugins, waiting for them to exit for at
+// most pluginCloseTimeout.
+func closePlugins() {
+	if len(plugins) == 0 {
+		return
+	}
+	done := make(chan struct{})
+	running := plugins
+	plugins = make(map[string]*plugin.Plugin)
+	go func() {
+		defer close(done)
+		for _, p := range running {
+			if err := p.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: plugin %s: %s\n", p.Name, err)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(pluginCloseTimeout):
+		fmt.Fprintf(os.Stderr, "warning: plugins did not exit within %s\n", pluginCloseTimeout)
+	}
+}
+
+// nameFlags is a repeatable flag of names.
+type nameFlags []string
+
+func (n *nameFlags) String() string {
+	return strings.Join(*n, ",")
+}
+
+func (n *nameFlags) Set(v string) error {
+	if v == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	*n = append(*n, v)
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+)
+
+func runPrune(args []string) {
+	fs := newFlagSet("prune")
+	cf := addAWSFlags(fs)
+	var (
+		domain    = fs.String("domain", "SIMIAN_ARMY", "Name of SimpleDB domain used by Chaos Monkey")
+		retention = fs.Duration("retention", 0, "Keep chaos events of this period, e.g. 2160h for 90 days")
+		batchSize = fs.Int("batch-size", aws.DefaultPruneBatchSize, "Number of records to delete per request (at most 25)")
+		dryRun    = fs.Bool("dry-run", false, "Only show the number of records that would be deleted")
+		yes       = fs.Bool("yes", false, "Do not ask for confirmation (required if not run in a terminal)")
+	)
+	parseFlags(fs, args)
+
+	if cf.region == "" {
+		exit(exitUsage, "prune requires --region")
+	}
+	if *retention <= 0 {
+		exit(exitUsage, "prune requires a positive --retention")
+	}
+	if *batchSize < 1 || *batchSize > aws.DefaultPruneBatchSize {
+		exit(exitUsage, "batch size must be between 1 and %d", aws.DefaultPruneBatchSize)
+	}
+	client := aws.NewClient(cf.region)
+	before := time.Now().Add(-*retention)
+
+	count, err := client.PruneChaosRecords(context.Background(), *domain, before, *batchSize, true)
+	if err != nil {
+		abort("failed to get records of SimpleDB domain %q: %s", *domain, err)
+	}
+	fmt.Fprintf(os.Stderr, "SimpleDB domain %s in %s has %d chaos event(s) before %s.\n",
+		*domain, cf.region, count, before.Format(time.RFC3339))
+	if *dryRun || count == 0 {
+		return
+	}
+
+	if !*yes {
+		if !isTerminal(os.Stdin) {
+			exit(exitUsage, "refusing to prune records without confirmation (pass --yes in non-interactive use)")
+		}
+		confirm()
+	}
+
+	deleted, err := client.PruneChaosRecords(context.Background(), *domain, before, *batchSize, false)
+	if err != nil {
+		abort("failed to prune records after deleting %d: %s", deleted, err)
+	}
+	fmt.Fprintf(os.Stderr, "Deleted %d chaos event(s) from SimpleDB domain %s.\n", deleted, *domain)
+}
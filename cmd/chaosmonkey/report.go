@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func runReport(args []string) {
+	fs := newFlagSet("report")
+	cf := addClientFlags(fs)
+	var (
+		since      = fs.Duration("since", 30*24*time.Hour, "Period to report on")
+		format     = fs.String("format", "markdown", "Report format: markdown or html")
+		gap        = fs.Duration("gap", 7*24*time.Hour, "Report groups without chaos for at least this long")
+		strategies = fs.String("strategies", "", "Suggest these comma-separated strategies if never used against a group (all by default)")
+		results    = fs.String("results", "", "Suggest intervals from these comma-separated campaign results files")
+		incidents  = addIncidentLinksFlag(fs)
+	)
+	parseFlags(fs, args)
+
+	suggested := chaosmonkey.Strategies
+	if *strategies != "" {
+		suggested = nil
+		for _, name := range strings.Split(*strategies, ",") {
+			s, err := chaosmonkey.ParseStrategy(name)
+			if err != nil {
+				abort("%s", err)
+			}
+			suggested = append(suggested, s)
+		}
+	}
+	var campaigns []chaosmonkey.CampaignResult
+	if *results != "" {
+		for _, path := range strings.Split(*results, ",") {
+			result, err := readResults(path)
+			if err != nil {
+				abort("%s", err)
+			}
+			campaigns = append(campaigns, *result)
+		}
+	}
+
+	now := time.Now().UTC()
+	events, err := cf.newClient().EventsSince(now.Add(-*since))
+	if err != nil {
+		fail(err)
+	}
+	summary := chaosmonkey.Summarize(events, now, *gap)
+	summary.Suggest(events, suggested, campaigns)
+	if *incidents != "" {
+		links, err := (&chaosmonkey.IncidentLinks{Path: *incidents}).Links()
+		if err != nil {
+			abort("%s", err)
+		}
+		// Report incidents linked in the period and those still open
+		var recent []chaosmonkey.IncidentLink
+		for _, l := range links {
+			if l.ResolvedAt.IsZero() || l.LinkedAt.After(now.Add(-*since)) {
+				recent = append(recent, l)
+			}
+		}
+		summary.Annotate(recent)
+	}
+	data := struct {
+		Generated time.Time
+		Since     time.Time
+		*chaosmonkey.Summary
+	}{now, now.Add(-*since), summary}
+
+	switch *format {
+	case "markdown":
+		err = markdownReport.Execute(os.Stdout, data)
+	case "html":
+		err = htmlReport.Execute(os.Stdout, data)
+	default:
+		abort("unknown report format %q (must be markdown or html)", *format)
+	}
+	if err != nil {
+		abort("%s", err)
+	}
+}
+
+var reportFuncs = map[string]interface{}{
+	"date": func(t time.Time) string { return t.Format("2006-01-02 15:04 MST") },
+	"duration": func(d time.Duration) string {
+		hours := int(d.Hours())
+		if hours < 24 {
+			return fmt.Sprintf("%dh", hours)
+		}
+		return fmt.Sprintf("%dd %dh", hours/24, hours%24)
+	},
+	"bar": func(n int) string { return strings.Repeat("█", n) },
+}
+
+var markdownReport = template.Must(template.New("markdown").Funcs(reportFuncs).Parse(`# Chaos Monkey Report
+
+Generated {{date .Generated}} for events since {{date .Since}}.
+{{if not .Events}}
+No chaos events were triggered in this period.
+{{else}}
+{{.Events}} chaos events were triggered between {{date .First}} and {{date .Last}}.
+
+## Events per auto scaling group
+
+| Group | Events |
+|-------|-------:|
+{{range .ByGroup}}| {{.Name}} | {{.Events}} |
+{{end}}
+## Events per strategy
+
+| Strategy | Events |
+|----------|-------:|
+{{range .ByStrategy}}| {{.Name}} | {{.Events}} |
+{{end}}{{if .ByRegion}}
+## Events per region
+
+| Region | Events |
+|--------|-------:|
+{{range .ByRegion}}| {{.Name}} | {{.Events}} |
+{{end}}{{end}}
+## Timeline
+
+| Day | Events | |
+|-----|-------:|-|
+{{range .Timeline}}| {{.Name}} | {{.Events}} | {{bar .Events}} |
+{{end}}{{if .Gaps}}
+## Gaps
+
+| Group | Without chaos from | Until | For |
+|-------|--------------------|-------|-----|
+{{range .Gaps}}| {{.AutoScalingGroupName}} | {{date .Start}} | {{date .End}} | {{duration .Duration}} |
+{{end}}{{end}}{{end}}{{if .Incidents}}
+## Incidents caused by chaos
+
+| Incident | Caused by | Linked | Resolved |
+|----------|-----------|--------|----------|
+{{range .Incidents}}| {{.IncidentID}} | {{.Cause}} | {{date .LinkedAt}} | {{if .ResolvedAt.IsZero}}open, chaos paused{{else}}{{date .ResolvedAt}}{{end}} |
+{{end}}{{end}}{{if .Suggestions}}
+## Suggestions
+
+| Group | Strategy | Interval | Reason |
+|-------|----------|----------|--------|
+{{range .Suggestions}}| {{.AutoScalingGroupName}} | {{.Strategy}} | {{if .Interval}}{{.Interval}}{{end}} | {{.Reason}} |
+{{end}}{{end}}`))
+
+var htmlReport = htmltemplate.Must(htmltemplate.New("html").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Chaos Monkey Report</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+td.n { text-align: right; }
+.bar { display: inline-block; height: 0.8em; background: #c0392b; }
+</style>
+</head>
+<body>
+<h1>Chaos Monkey Report</h1>
+<p>Generated {{date .Generated}} for events since {{date .Since}}.</p>
+{{if not .Events}}
+<p>No chaos events were triggered in this period.</p>
+{{else}}
+<p>{{.Events}} chaos events were triggered between {{date .First}} and {{date .Last}}.</p>
+<h2>Events per auto scaling group</h2>
+<table><tr><th>Group</th><th>Events</th></tr>
+{{range .ByGroup}}<tr><td>{{.Name}}</td><td class="n">{{.Events}}</td></tr>
+{{end}}</table>
+<h2>Events per strategy</h2>
+<table><tr><th>Strategy</th><th>Events</th></tr>
+{{range .ByStrategy}}<tr><td>{{.Name}}</td><td class="n">{{.Events}}</td></tr>
+{{end}}</table>
+{{if .ByRegion}}<h2>Events per region</h2>
+<table><tr><th>Region</th><th>Events</th></tr>
+{{range .ByRegion}}<tr><td>{{.Name}}</td><td class="n">{{.Events}}</td></tr>
+{{end}}</table>
+{{end}}<h2>Timeline</h2>
+<table><tr><th>Day</th><th>Events</th><th></th></tr>
+{{range .Timeline}}<tr><td>{{.Name}}</td><td class="n">{{.Events}}</td><td><span class="bar" style="width: {{.Events}}em"></span></td></tr>
+{{end}}</table>
+{{if .Gaps}}<h2>Gaps</h2>
+<table><tr><th>Group</th><th>Without chaos from</th><th>Until</th><th>For</th></tr>
+{{range .Gaps}}<tr><td>{{.AutoScalingGroupName}}</td><td>{{date .Start}}</td><td>{{date .End}}</td><td>{{duration .Duration}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+{{if .Incidents}}<h2>Incidents caused by chaos</h2>
+<table><tr><th>Incident</th><th>Caused by</th><th>Linked</th><th>Resolved</th></tr>
+{{range .Incidents}}<tr><td>{{.IncidentID}}</td><td>{{.Cause}}</td><td>{{date .LinkedAt}}</td><td>{{if .ResolvedAt.IsZero}}open, chaos paused{{else}}{{date .ResolvedAt}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Suggestions}}<h2>Suggestions</h2>
+<table><tr><th>Group</th><th>Strategy</th><th>Interval</th><th>Reason</th></tr>
+{{range .Suggestions}}<tr><td>{{.AutoScalingGroupName}}</td><td>{{.Strategy}}</td><td>{{if .Interval}}{{.Interval}}{{end}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
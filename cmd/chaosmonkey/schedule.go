@@ -0,0 +1,156 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func runSchedule(args []string) {
+	fs := newFlagSet("schedule")
+	cf := addTriggerFlags(fs)
+	var (
+		group    = fs.String("group", "", "Name of auto scaling group")
+		strategy = fs.String("strategy", "", "Chaos strategy to use, see 'chaosmonkey strategies'")
+		timezone = fs.String("timezone", "Local", "Time zone of the schedule, e.g. Europe/Berlin")
+		shadow   = fs.String("shadow", "", "Only append what would have been triggered to this file (implies --dry-run)")
+		listen   = fs.String("listen", "", "Serve a kill switch at this address, e.g. localhost:8081 (POST /halt and /resume)")
+		regions  = fs.String("regions", "", "Target the group in each of these comma-separated regions, e.g. us-east-1,eu-west-1 (see --max-in-flight)")
+	)
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fs.Parse(args)
+		abort("schedule requires a cron expression")
+	}
+	expr := args[0]
+	parseFlags(fs, args[1:])
+
+	if *group == "" {
+		abort("schedule requires --group")
+	}
+	sched, err := chaosmonkey.ParseSchedule(expr)
+	if err != nil {
+		abort("%s", err)
+	}
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		abort("%s", err)
+	}
+	var fanOut []string
+	if *regions != "" {
+		for _, r := range strings.Split(*regions, ",") {
+			if r = strings.TrimSpace(r); r == "" {
+				abort("invalid regions %q", *regions)
+			}
+			fanOut = append(fanOut, r)
+		}
+	}
+	var s chaosmonkey.Strategy
+	if *strategy != "" {
+		if s, err = chaosmonkey.ParseStrategy(*strategy); err != nil {
+			abort("%s (see 'chaosmonkey strategies')", err)
+		}
+	}
+
+	if *shadow != "" {
+		f, err := os.OpenFile(*shadow, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			abort("%s", err)
+		}
+		defer f.Close()
+		cf.dryRun = true
+		cf.bus = chaosmonkey.NewBus()
+		chaosmonkey.RecordDecisions(cf.bus, f)
+		log.Printf("Running in shadow mode, recording decisions to %s", *shadow)
+	}
+
+	cf.halt = &chaosmonkey.HaltSwitch{}
+	if *listen != "" {
+		serveHaltSwitch(*listen, cf.halt)
+	}
+
+	runScheduler(cf.newClient(), sched, loc, cf.windowPolicy(), cf.halt, *group, fanOut, s)
+}
+
+// runScheduler triggers a chaos event whenever the schedule is due until
+// the process receives SIGINT or SIGTERM, one in each of the given regions
+// if any. Times outside of the windows allowed by the optional policy are
+// skipped, and so are all times while the kill switch is halted. Failed
+// events are logged, but do not stop the scheduler.
+func runScheduler(client *chaosmonkey.Client, sched *chaosmonkey.Schedule, loc *time.Location, policy *chaosmonkey.WindowPolicy, halt *chaosmonkey.HaltSwitch, group string, regions []string, strategy chaosmonkey.Strategy) {
+	if len(regions) == 0 {
+		regions = []string{""}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	log.Printf("Scheduling chaos against %s at %q (%s)", group, sched, loc)
+	for {
+		next := nextAllowed(sched, policy, time.Now().In(loc))
+		if next.IsZero() {
+			abort("schedule %q never fires within the allowed windows", sched)
+		}
+		log.Printf("Next chaos event at %s", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case sig := <-signals:
+			timer.Stop()
+			log.Printf("Received %s, shutting down", sig)
+			return
+		case <-halt.Halted():
+			timer.Stop()
+			log.Printf("Chaos halted, waiting to resume")
+			select {
+			case sig := <-signals:
+				log.Printf("Received %s, shutting down", sig)
+				return
+			case <-halt.Resumed():
+				log.Printf("Chaos resumed")
+			}
+			continue
+		case <-timer.C:
+		}
+
+		for _, region := range regions {
+			event, err := client.TriggerEventInRegion(group, strategy, region)
+			if err != nil {
+				log.Printf("Failed to trigger chaos event: %s", err)
+				continue
+			}
+			log.Printf("Triggered %s against instance %s of %s", event.Strategy, event.InstanceID, event.AutoScalingGroupName)
+		}
+	}
+}
+
+// nextAllowed returns the first time after t matching the schedule and
+// allowed by the policy, or the zero time if there is none within a year.
+func nextAllowed(sched *chaosmonkey.Schedule, policy *chaosmonkey.WindowPolicy, t time.Time) time.Time {
+	limit := t.AddDate(1, 0, 0)
+	for next := sched.Next(t); !next.IsZero() && next.Before(limit); next = sched.Next(next) {
+		if policy == nil || policy.Allows(next) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// serveHaltSwitch serves the kill switch via HTTP in the background.
+func serveHaltSwitch(addr string, halt *chaosmonkey.HaltSwitch) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		abort("%s", err)
+	}
+	log.Printf("Serving kill switch at http://%s", ln.Addr())
+	go func() {
+		if err := http.Serve(ln, halt); err != nil {
+			log.Printf("Kill switch stopped: %s", err)
+		}
+	}()
+}
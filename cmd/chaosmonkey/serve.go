@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// daemonSecret returns the secret required to mint scoped tokens.
+func daemonSecret() []byte {
+	return []byte(os.Getenv("CHAOSMONKEY_DAEMON_SECRET"))
+}
+
+// tokenDir returns the default directory recording used scoped tokens.
+func tokenDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".chaosmonkey", "tokens")
+}
+
+func runServe(args []string) {
+	fs := newFlagSet("serve")
+	cf := addTriggerFlags(fs)
+	var (
+		listen = fs.String("listen", "localhost:8090", "Address to serve the daemon at")
+		used   = fs.String("token-dir", tokenDir(), "Directory recording used tokens, which may be shared by several daemons")
+		maxTTL = fs.Duration("max-token-ttl", chaosmonkey.DefaultMaxTokenTTL, "Longest validity of tokens")
+	)
+	parseFlags(fs, args)
+
+	secret := daemonSecret()
+	if len(secret) == 0 {
+		exit(exitUsage, "serve requires CHAOSMONKEY_DAEMON_SECRET")
+	}
+	if *used == "" {
+		exit(exitUsage, "serve requires --token-dir")
+	}
+	key := []byte(os.Getenv("CHAOSMONKEY_TOKEN_KEY"))
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			abort("%s", err)
+		}
+		log.Printf("CHAOSMONKEY_TOKEN_KEY not set, tokens will be invalid after a restart")
+	}
+
+	// Freezes, e.g. those of GameDays, suppress chaos triggered by the
+	// daemon until it is restarted
+	freezer := &chaosmonkey.Freezer{}
+	cf.guards = append(cf.guards, freezer)
+	daemon := &chaosmonkey.Daemon{
+		Client: cf.newClient(),
+		Tokens: &chaosmonkey.TokenIssuer{
+			Key:    key,
+			Used:   &chaosmonkey.TokenDir{Path: *used},
+			MaxTTL: *maxTTL,
+		},
+		AdminSecret: secret,
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", daemon)
+	freezer.Authorize = daemon.AuthorizeAdmin
+	mux.Handle("/freezes", http.StripPrefix("/freezes", freezer))
+	mux.Handle("/freezes/", http.StripPrefix("/freezes", freezer))
+	// Incident management systems link incidents to the chaos that caused
+	// them, pausing chaos against the affected groups
+	if cf.incidentLinks != "" {
+		links := &chaosmonkey.IncidentLinks{Path: cf.incidentLinks, Authorize: daemon.AuthorizeAdmin}
+		mux.Handle("/incidents", http.StripPrefix("/incidents", links))
+		mux.Handle("/incidents/", http.StripPrefix("/incidents", links))
+	}
+	// Approvers grant approvals to chaos events triggered by the daemon with
+	// requests signed by their approval keys
+	if approvals := cf.approvals; approvals != nil {
+		approvals.Authenticate = approvals.VerifyRequest
+		mux.Handle("/approvals", http.StripPrefix("/approvals", approvals))
+		mux.Handle("/approvals/", http.StripPrefix("/approvals", approvals))
+	}
+	log.Printf("Serving daemon at http://%s", *listen)
+	if err := http.ListenAndServe(*listen, mux); err != nil {
+		abort("%s", err)
+	}
+}
+
+func runToken(args []string) {
+	fs := newFlagSet("token")
+	var (
+		daemon   = fs.String("daemon", os.Getenv("CHAOSMONKEY_DAEMON"), "URL of the daemon started with 'chaosmonkey serve'")
+		group    = fs.String("group", "", "Name of auto scaling group the token allows chaos against")
+		strategy = fs.String("strategy", string(chaosmonkey.StrategyShutdownInstance), "Chaos strategy the token allows, see 'chaosmonkey strategies'")
+		ttl      = fs.Duration("ttl", chaosmonkey.DefaultTokenTTL, "Time until the token expires")
+	)
+	parseFlags(fs, args)
+
+	if *daemon == "" || *group == "" {
+		exit(exitUsage, "token requires --daemon and --group")
+	}
+	s, err := chaosmonkey.ParseStrategy(*strategy)
+	if err != nil {
+		abort("%s (see 'chaosmonkey strategies')", err)
+	}
+	secret := daemonSecret()
+	if len(secret) == 0 {
+		exit(exitUsage, "token requires CHAOSMONKEY_DAEMON_SECRET")
+	}
+
+	var token chaosmonkey.ScopedToken
+	req := map[string]string{"group": *group, "strategy": string(s), "ttl": ttl.String()}
+	if err := postDaemon(*daemon, "/tokens", string(secret), req, &token); err != nil {
+		abort("failed to mint token: %s", err)
+	}
+	fmt.Fprintf(os.Stderr, "Token %s allows %s against %s until %s.\n",
+		token.ID, token.Strategy, token.Group, token.Expires.Local().Format(time.RFC3339))
+	fmt.Println(token.Token)
+}
+
+// triggerViaDaemon triggers a chaos event with the daemon, presenting a
+// scoped token for it.
+func triggerViaDaemon(daemon, token string, opts triggerOptions, region string) (*chaosmonkey.Event, error) {
+	var ev chaosmonkey.Event
+	req := map[string]string{"group": opts.group, "strategy": string(opts.strategy), "region": region}
+	if err := postDaemon(daemon, "/events", token, req, &ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// postDaemon sends a request to the daemon, authorized by the given bearer
+// token, and decodes the response into out.
+func postDaemon(daemon, path, credential string, in, out interface{}) error {
+	return sendDaemon(daemon, path, in, out, func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+credential)
+		return nil
+	})
+}
+
+// sendDaemon sends a request to the daemon, authorized by authorize, and
+// decodes the response into out.
+func sendDaemon(daemon, path string, in, out interface{}, authorize func(req *http.Request) error) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(daemon, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := authorize(req); err != nil {
+		return err
+	}
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Message != "" {
+			return fmt.Errorf("%s (%s)", e.Message, resp.Status)
+		}
+		return fmt.Errorf("daemon returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ryanuber/columnize"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func runSimulate(args []string) {
+	fs := newFlagSet("simulate")
+	cf := addTriggerFlags(fs)
+	var (
+		group    = fs.String("group", "", "Name of auto scaling group")
+		strategy = fs.String("strategy", "", "Chaos strategy to use, see 'chaosmonkey strategies'")
+		protect  tagFilters
+	)
+	fs.Var(&protect, "protect-tag", "Mark instances with this tag, given as key or key=value, as protected (repeatable)")
+	parseFlags(fs, args)
+
+	if *group == "" {
+		exit(exitUsage, "simulate requires --group")
+	}
+	var s chaosmonkey.Strategy
+	if *strategy != "" {
+		var err error
+		if s, err = chaosmonkey.ParseStrategy(*strategy); err != nil {
+			abort("%s (see 'chaosmonkey strategies')", err)
+		}
+	}
+
+	client := aws.NewClient(cf.region)
+	instances, err := client.Instances(context.Background(), *group)
+	if err != nil {
+		abort("failed to get instances: %s", err)
+	}
+	if instances == nil {
+		exit(exitGroupNotFound, "auto scaling group %q not found or empty", *group)
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+
+	var candidates int
+	for _, i := range instances {
+		if i.LifecycleState == "InService" {
+			candidates++
+		}
+	}
+
+	lines := []string{"InstanceID|AvailabilityZone|State|Chance|Note"}
+	protected := 0
+	for _, i := range instances {
+		chance, note := "-", "not in service"
+		if i.LifecycleState == "InService" {
+			chance = fmt.Sprintf("%.0f%%", 100/float64(candidates))
+			note = ""
+			for _, t := range protect {
+				if tagFilters([]string{t}).match(i.Tags) {
+					note = "protected by tag " + t
+					protected++
+					break
+				}
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s|%s|%s|%s|%s", i.ID, i.AvailabilityZone, i.LifecycleState, chance, note))
+	}
+	fmt.Println(columnize.SimpleFormat(lines))
+	fmt.Println()
+
+	strategyInfo := strategyName(s)
+	if info, ok := chaosmonkey.LookupStrategy(s); ok && info.Description != "" {
+		strategyInfo += " (" + strings.TrimSuffix(info.Description, ".") + ")"
+	}
+	fmt.Printf("Strategy: %s\n", strategyInfo)
+	switch {
+	case candidates == 0:
+		fmt.Println("Victim:   none, no instance is in service")
+	default:
+		fmt.Printf("Victim:   picked by Chaos Monkey uniformly at random among %d instance(s) in service\n", candidates)
+	}
+	if candidates == 1 {
+		fmt.Println("Warning:  the only instance in service would be affected")
+	}
+	if protected > 0 {
+		fmt.Printf("Warning:  %d protected instance(s) may be picked, as Chaos Monkey ignores instance tags\n", protected)
+	}
+	hooks, err := client.LifecycleHooks(context.Background(), *group)
+	if err != nil {
+		fmt.Printf("Hooks:    unknown, %s\n", err)
+	}
+	for _, h := range hooks {
+		fmt.Printf("Hooks:    %s pauses %s for up to %s\n", h.Name, strings.TrimPrefix(h.Transition, "autoscaling:"), h.HeartbeatTimeout)
+	}
+
+	// Run the chaos event through the guards without triggering it
+	cf.dryRun = true
+	_, err = cf.newClient().TriggerEvent(*group, s)
+	switch err.(type) {
+	case nil:
+		fmt.Println("Guards:   chaos event would be allowed")
+	case *chaosmonkey.GuardError:
+		fmt.Printf("Guards:   %s\n", err)
+		os.Exit(exitRefused)
+	default:
+		fail(err)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// triggerOptions configures a series of chaos events.
+type triggerOptions struct {
+	group       string
+	strategy    chaosmonkey.Strategy
+	count       int
+	interval    time.Duration
+	probability float64
+}
+
+func runTrigger(args []string) {
+	fs := newFlagSet("trigger")
+	cf := addTriggerFlags(fs)
+	addOutputFlag(fs)
+	var (
+		group       = fs.String("group", "", "Name of auto scaling group (prompts for one if omitted)")
+		nodegroup   = fs.String("nodegroup", "", "EKS managed node group to target instead of --group, as <cluster>/<nodegroup>")
+		strategy    = fs.String("strategy", "", "Chaos strategy to use, see 'chaosmonkey strategies'")
+		count       = fs.Int("count", 1, "Number of times to trigger chaos event")
+		interval    = fs.Duration("interval", 5*time.Second, "Time to wait between chaos events")
+		probability = fs.Float64("probability", 1.0, "Probability of chaos events")
+		yes         = fs.Bool("yes", false, "Do not ask for confirmation (required if not run in a terminal)")
+		daemon      = fs.String("daemon", os.Getenv("CHAOSMONKEY_DAEMON"), "Trigger via the daemon at this URL, started with 'chaosmonkey serve', instead of Chaos Monkey")
+		token       = fs.String("token", os.Getenv("CHAOSMONKEY_TOKEN"), "Token for the chaos event minted with 'chaosmonkey token', required with --daemon")
+	)
+	parseFlags(fs, args)
+
+	if *nodegroup != "" {
+		if *group != "" {
+			exit(exitUsage, "--group and --nodegroup are mutually exclusive")
+		}
+		parts := strings.SplitN(*nodegroup, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			exit(exitUsage, "invalid node group %q, expected <cluster>/<nodegroup>", *nodegroup)
+		}
+		name, err := aws.NewClient(cf.region).NodeGroupAutoScalingGroup(context.Background(), parts[0], parts[1])
+		if err != nil {
+			abort("failed to get node group: %s", err)
+		}
+		*group = name
+	}
+	if *group == "" {
+		if !isTerminal(os.Stdin) {
+			abort("trigger requires --group")
+		}
+		*group = pickGroup(cf.region)
+	}
+	opts := triggerOptions{
+		group:       *group,
+		count:       *count,
+		interval:    *interval,
+		probability: *probability,
+	}
+	if *strategy != "" {
+		s, err := chaosmonkey.ParseStrategy(*strategy)
+		if err != nil {
+			abort("%s (see 'chaosmonkey strategies')", err)
+		}
+		opts.strategy = s
+	}
+
+	if !*yes && !cf.dryRun {
+		confirmTrigger(cf.region, opts)
+	}
+
+	if *daemon != "" {
+		if *token == "" {
+			exit(exitUsage, "--daemon requires --token")
+		}
+		if opts.count != 1 {
+			exit(exitUsage, "--daemon allows a single chaos event per token")
+		}
+		ev, err := triggerViaDaemon(*daemon, *token, opts, cf.region)
+		if err != nil {
+			abort("failed to trigger chaos event: %s", err)
+		}
+		printEvents(*ev)
+		return
+	}
+	triggerEvents(cf.newClient(), opts)
+}
+
+// confirmTrigger describes the chaos events about to be triggered and asks
+// the user for confirmation, aborting if it is not given.
+func confirmTrigger(region string, opts triggerOptions) {
+	if !isTerminal(os.Stdin) {
+		exit(exitUsage, "refusing to trigger chaos events without confirmation (pass --yes in non-interactive use)")
+	}
+
+	instances := "unknown number of instances"
+	g, err := aws.NewClient(region).AutoScalingGroup(context.Background(), opts.group)
+	switch {
+	case err == aws.ErrGroupNotFound:
+		exit(exitGroupNotFound, "auto scaling group %q not found", opts.group)
+	case err == nil:
+		instances = fmt.Sprintf("%d instance(s) in service", g.InstancesInService)
+		if cluster, nodegroup, ok := g.NodeGroup(); ok {
+			instances = fmt.Sprintf("EKS node group %s/%s, %s", cluster, nodegroup, instances)
+		}
+	}
+
+	strategy := opts.strategy
+	if strategy == "" {
+		strategy = chaosmonkey.StrategyShutdownInstance
+	}
+	severity := "degrades instances"
+	switch {
+	case strategy.IsDestructive():
+		severity = "destructive"
+	case strategy.IsNetwork():
+		severity = "disrupts network"
+	}
+
+	fmt.Fprintf(os.Stderr, "About to trigger chaos:\n\n")
+	fmt.Fprintf(os.Stderr, "  Group:     %s (%s)\n", opts.group, instances)
+	if g != nil {
+		if g.ImageID != "" {
+			fmt.Fprintf(os.Stderr, "  Image:     %s (%s)\n", g.ImageID, strings.Join(g.InstanceTypes, ", "))
+		}
+		if g.WarmPoolState != "" {
+			fmt.Fprintf(os.Stderr, "  Warm pool: %d instances (%s)\n", g.WarmPoolSize, strings.ToLower(g.WarmPoolState))
+		}
+		for _, lb := range g.LoadBalancers {
+			fmt.Fprintf(os.Stderr, "  Balancer:  %s (%d healthy, %d unhealthy)\n", lb.Name, lb.Healthy, lb.Unhealthy)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "  Strategy:  %s (%s)\n", strategy, severity)
+	fmt.Fprintf(os.Stderr, "  Events:    %d\n\n", opts.count)
+	confirm()
+}
+
+// triggerEvents triggers the same chaos event count times, skipping each with
+// the configured probability, and prints the triggered events. Tables are
+// printed as events are triggered, other formats once all are done.
+func triggerEvents(client *chaosmonkey.Client, opts triggerOptions) {
+	rnd := rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
+	skipped := 0
+	var events []chaosmonkey.Event
+	for i := 1; i <= opts.count; i++ {
+		if rnd.Float64() > opts.probability {
+			skipped++
+		} else {
+			event, err := client.TriggerEvent(opts.group, opts.strategy)
+			if err != nil {
+				// The event was triggered if only its audit record failed
+				if event != nil {
+					if outputFormat == "table" {
+						printEvents(*event)
+					} else {
+						events = append(events, *event)
+					}
+				}
+				if outputFormat != "table" && len(events) > 0 {
+					printEvents(events...)
+				}
+				fail(err)
+			}
+			if outputFormat == "table" {
+				printEvents(*event)
+			} else {
+				events = append(events, *event)
+			}
+		}
+		if i < opts.count {
+			time.Sleep(opts.interval)
+		}
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Skipped %d chaos event(s) with probability of %f\n", skipped, opts.probability)
+	}
+	if outputFormat != "table" {
+		printEvents(events...)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ryanuber/columnize"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// dashboard holds the state of the terminal dashboard, which only the loop
+// of runTUI changes. Slow work like triggering chaos events and listing
+// groups runs in the background and reports back to the loop.
+type dashboard struct {
+	client *chaosmonkey.Client
+	aws    *aws.Client
+	since  time.Duration
+
+	events  []chaosmonkey.Event
+	groups  []aws.AutoScalingGroup
+	updated time.Time
+	status  string
+
+	// Group number typed so far and selected strategy (index into
+	// strategies, where 0 is Chaos Monkey's default)
+	input      string
+	strategy   int
+	strategies []chaosmonkey.Strategy
+
+	// Chaos event awaiting confirmation, if any
+	pending *triggerOptions
+
+	// Number of chaos events being triggered, and whether groups are being
+	// listed
+	triggering int
+	listing    bool
+
+	// Whether the user was warned that quitting abandons chaos events being
+	// triggered
+	warned bool
+
+	// Results of background work
+	results chan string
+	listed  chan groupsResult
+}
+
+// groupsResult is the result of listing auto scaling groups.
+type groupsResult struct {
+	groups []aws.AutoScalingGroup
+	err    error
+}
+
+func runTUI(args []string) {
+	fs := newFlagSet("tui")
+	cf := addTriggerFlags(fs)
+	var (
+		interval = fs.Duration("interval", 5*time.Second, "Time between polls for new events")
+		since    = fs.Duration("since", 24*time.Hour, "Period of events to show")
+	)
+	parseFlags(fs, args)
+
+	if !isTerminal(os.Stdout) || !isTerminal(os.Stdin) {
+		exit(exitUsage, "tui must be run in a terminal")
+	}
+	if *interval <= 0 {
+		exit(exitUsage, "interval must be positive")
+	}
+
+	d := &dashboard{
+		client:     cf.newClient(),
+		aws:        aws.NewClient(cf.region),
+		since:      *since,
+		strategies: append([]chaosmonkey.Strategy{""}, chaosmonkey.KnownStrategies()...),
+		results:    make(chan string),
+		listed:     make(chan groupsResult),
+	}
+
+	// Without raw mode, keys take effect once Enter is pressed
+	if restore, err := rawMode(); err != nil {
+		d.status = fmt.Sprintf("Press Enter after keys (cannot read single keys: %s)", err)
+	} else {
+		defer restore()
+	}
+	closed := make(chan struct{})
+	defer close(closed)
+	keys := readKeys(closed)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := d.client.Watch(ctx, time.Now().Add(-d.since), *interval)
+
+	// Groups change less often than events and are more expensive to list
+	ticker := time.NewTicker(6 * *interval)
+	defer ticker.Stop()
+	d.listGroups()
+	for {
+		d.render()
+		select {
+		case u := <-updates:
+			if u.Err != nil {
+				d.status = fmt.Sprintf("Failed to get events: %s", u.Err)
+				break
+			}
+			d.addEvents(u.Events)
+		case r := <-d.listed:
+			d.listing = false
+			if r.err != nil {
+				d.status = fmt.Sprintf("Failed to get auto scaling groups: %s", r.err)
+				break
+			}
+			d.groups = r.groups
+		case status := <-d.results:
+			d.triggering--
+			d.status = status
+		case <-ticker.C:
+			d.listGroups()
+		case key, ok := <-keys:
+			if !ok || !d.handle(key) {
+				fmt.Print("\033[H\033[2J")
+				return
+			}
+		case <-signals:
+			fmt.Print("\033[H\033[2J")
+			return
+		}
+	}
+}
+
+// addEvents adds new events and drops those older than the period shown.
+func (d *dashboard) addEvents(events []chaosmonkey.Event) {
+	d.updated = time.Now()
+	start := d.updated.Add(-d.since)
+	recent := events
+	for _, e := range d.events {
+		if e.TriggeredAt.After(start) {
+			recent = append(recent, e)
+		}
+	}
+	sort.SliceStable(recent, func(i, j int) bool { return recent[i].TriggeredAt.After(recent[j].TriggeredAt) })
+	d.events = recent
+}
+
+// listGroups lists auto scaling groups in the background, unless already
+// listing them.
+func (d *dashboard) listGroups() {
+	if d.listing {
+		return
+	}
+	d.listing = true
+	go func() {
+		groups, err := d.aws.AutoScalingGroups(context.Background())
+		d.listed <- groupsResult{groups, err}
+	}()
+}
+
+// trigger triggers a chaos event in the background.
+func (d *dashboard) trigger(opts *triggerOptions) {
+	d.triggering++
+	d.status = fmt.Sprintf("Triggering %s against %s", strategyName(opts.strategy), opts.group)
+	go func() {
+		ev, err := d.client.TriggerEvent(opts.group, opts.strategy)
+		if err != nil {
+			d.results <- fmt.Sprintf("Failed to trigger chaos event against %s: %s", opts.group, err)
+			return
+		}
+		d.results <- fmt.Sprintf("Triggered %s against instance %s of %s", strategyName(ev.Strategy), ev.InstanceID, ev.AutoScalingGroupName)
+	}()
+}
+
+// handle processes a key pressed by the user and reports whether the
+// dashboard should keep running.
+func (d *dashboard) handle(key byte) bool {
+	if d.pending != nil {
+		opts := d.pending
+		d.pending = nil
+		if key == 'y' || key == 'Y' {
+			d.trigger(opts)
+		} else {
+			d.status = "Canceled"
+		}
+		return true
+	}
+
+	switch {
+	case key >= '0' && key <= '9':
+		d.input += string(key)
+	case key == keyBackspace || key == keyCtrlH:
+		if d.input != "" {
+			d.input = d.input[:len(d.input)-1]
+		}
+	case key == keyEscape:
+		d.input = ""
+	case key == 's':
+		d.strategy = (d.strategy + 1) % len(d.strategies)
+	case key == 'S':
+		d.strategy = (d.strategy + len(d.strategies) - 1) % len(d.strategies)
+	case key == 't':
+		n, err := strconv.Atoi(d.input)
+		if err != nil || n < 1 || n > len(d.groups) {
+			d.status = "Type the number of a group before pressing t"
+			return true
+		}
+		d.input = ""
+		d.pending = &triggerOptions{group: d.groups[n-1].Name, strategy: d.strategies[d.strategy], count: 1}
+		d.status = fmt.Sprintf("Trigger %s against %s? Press y to confirm", strategyName(d.pending.strategy), d.pending.group)
+	case key == 'r':
+		d.listGroups()
+	case key == 'q':
+		if d.triggering > 0 && !d.warned {
+			d.warned = true
+			d.status = "Chaos events are still being triggered, press q again to quit anyway"
+			return true
+		}
+		return false
+	case key == '\n' || key == '\r':
+	default:
+		d.status = fmt.Sprintf("Unknown key %q", key)
+	}
+	return true
+}
+
+func (d *dashboard) render() {
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	updated := "loading"
+	if !d.updated.IsZero() {
+		updated = "last change " + d.updated.Format("15:04:05")
+	}
+	fmt.Fprintf(&b, "Chaos Monkey dashboard - %d event(s) in the last %s - %s\n\n",
+		len(d.events), d.since, updated)
+
+	perGroup := make(map[string]int)
+	for _, e := range d.events {
+		perGroup[e.AutoScalingGroupName]++
+	}
+	lines := []string{"#|AutoScalingGroupName|InService|Desired|Events|"}
+	for i, g := range d.groups {
+		health := ""
+		if g.InstancesInService < g.DesiredCapacity {
+			health = "<- degraded"
+		}
+		lines = append(lines, fmt.Sprintf("%d|%s|%d|%d|%d %s|%s", i+1, g.Name,
+			g.InstancesInService, g.DesiredCapacity, perGroup[g.Name], strings.Repeat("#", perGroup[g.Name]), health))
+	}
+	b.WriteString(columnize.SimpleFormat(lines))
+	b.WriteString("\n\nRecent events:\n\n")
+
+	lines = []string{"TriggeredAt|AutoScalingGroupName|Strategy|InstanceID"}
+	for i, e := range d.events {
+		if i == 10 {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s|%s|%s|%s", e.TriggeredAt.Local().Format("2006-01-02 15:04:05"),
+			e.AutoScalingGroupName, strategyName(e.Strategy), e.InstanceID))
+	}
+	b.WriteString(columnize.SimpleFormat(lines))
+
+	fmt.Fprintf(&b, "\n\n%s\n", d.status)
+	if d.triggering > 0 {
+		fmt.Fprintf(&b, "Triggering %d chaos event(s)...\n", d.triggering)
+	}
+	b.WriteString("Keys: <#> t to trigger against group #, s/S to change strategy, r to refresh groups, q to quit\n")
+	fmt.Fprintf(&b, "Group: %s_  Strategy: %s", d.input, strategyName(d.strategies[d.strategy]))
+	fmt.Print(b.String())
+}
@@ -2,4 +2,4 @@ package main
 
 // Version is the current version of the chaosmonkey tool. A ".dev" suffix
 // denotes that the version is currently being developed.
-const Version = "v0.5.4"
+const Version = "v0.6.0.dev"
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/FlyLevin/chaosmonkey/aws"
+)
+
+func runWipe(args []string) {
+	fs := newFlagSet("wipe")
+	cf := addAWSFlags(fs)
+	var (
+		domain = fs.String("domain", "SIMIAN_ARMY", "Name of SimpleDB domain used by Chaos Monkey")
+		backup = fs.String("backup", "", "Export all records to this JSON file before wiping")
+		dryRun = fs.Bool("dry-run", false, "Only show the number of records that would be deleted")
+		yes    = fs.Bool("yes", false, "Do not ask for confirmation (required if not run in a terminal)")
+	)
+	parseFlags(fs, args)
+
+	if cf.region == "" {
+		exit(exitUsage, "wipe requires --region")
+	}
+	client := aws.NewClient(cf.region)
+
+	count, err := client.SimpleDBDomainItemCount(context.Background(), *domain)
+	if err != nil {
+		abort("failed to get records of SimpleDB domain %q: %s", *domain, err)
+	}
+	fmt.Fprintf(os.Stderr, "SimpleDB domain %s in %s has %d record(s).\n", *domain, cf.region, count)
+	if *dryRun {
+		return
+	}
+
+	if !*yes {
+		if !isTerminal(os.Stdin) {
+			exit(exitUsage, "refusing to wipe state without confirmation (pass --yes in non-interactive use)")
+		}
+		fmt.Fprintf(os.Stderr, "All records will be deleted, which requires a restart of Chaos Monkey.\n")
+		confirm()
+	}
+
+	if *backup != "" {
+		if err := backupSimpleDBDomain(client, *domain, *backup); err != nil {
+			abort("failed to back up records: %s", err)
+		}
+	}
+	if err := client.DeleteSimpleDBDomain(context.Background(), *domain); err != nil {
+		abort("failed to wipe state: %s", err)
+	}
+	fmt.Fprintf(os.Stderr, "Deleted SimpleDB domain %s. Restart Chaos Monkey now.\n", *domain)
+}
+
+// backupSimpleDBDomain writes all items of a SimpleDB domain to a new JSON
+// file. It refuses to overwrite existing files.
+func backupSimpleDBDomain(client *aws.Client, domain, path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	n, err := client.ExportSimpleDBDomain(context.Background(), domain, f)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d record(s) to %s.\n", n, path)
+	return nil
+}
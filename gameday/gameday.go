@@ -0,0 +1,240 @@
+// Package gameday helps to schedule GameDay events, during which chaos is
+// injected deliberately while all participants are available.
+package gameday
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// Participant describes someone who needs to attend a GameDay.
+type Participant struct {
+	// Name of the participant
+	Name string
+
+	// Time zone of the participant (UTC if nil)
+	Location *time.Location
+
+	// Working hours of the participant, in hours of the day in their time
+	// zone (09-17 if both are zero)
+	WorkStart, WorkEnd int
+
+	// Whether the participant also works on weekends
+	Weekends bool
+}
+
+// Blackout is a period during which no GameDay may take place, e.g. a
+// release freeze or a holiday.
+type Blackout struct {
+	Start, End time.Time
+	Reason     string
+}
+
+// Slot is a proposed time for a GameDay.
+type Slot struct {
+	Start, End time.Time
+}
+
+// slotStep is the granularity of proposed slots.
+const slotStep = 30 * time.Minute
+
+// ProposeSlots returns up to limit non-overlapping slots of the given
+// duration between from and to, during which all participants are within
+// their working hours and no blackout applies. Slots are returned in
+// chronological order.
+func ProposeSlots(from, to time.Time, duration time.Duration, participants []Participant, blackouts []Blackout, limit int) []Slot {
+	var slots []Slot
+	start := from.Truncate(slotStep)
+	if start.Before(from) {
+		start = start.Add(slotStep)
+	}
+	for ; !start.Add(duration).After(to) && len(slots) < limit; start = start.Add(slotStep) {
+		end := start.Add(duration)
+		if !everyoneAvailable(start, end, participants) || blackedOut(start, end, blackouts) {
+			continue
+		}
+		slots = append(slots, Slot{Start: start, End: end})
+		start = end.Add(-slotStep)
+	}
+	return slots
+}
+
+func everyoneAvailable(start, end time.Time, participants []Participant) bool {
+	for _, p := range participants {
+		if !p.available(start, end) {
+			return false
+		}
+	}
+	return true
+}
+
+// available reports whether the participant works during the whole period.
+// Periods spanning more than one working day are never available.
+func (p Participant) available(start, end time.Time) bool {
+	loc := p.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	workStart, workEnd := p.WorkStart, p.WorkEnd
+	if workStart == 0 && workEnd == 0 {
+		workStart, workEnd = 9, 17
+	}
+
+	s := start.In(loc)
+	if !p.Weekends && (s.Weekday() == time.Saturday || s.Weekday() == time.Sunday) {
+		return false
+	}
+	dayStart := time.Date(s.Year(), s.Month(), s.Day(), workStart, 0, 0, 0, loc)
+	dayEnd := time.Date(s.Year(), s.Month(), s.Day(), workEnd, 0, 0, 0, loc)
+	return !s.Before(dayStart) && !end.In(loc).After(dayEnd)
+}
+
+func blackedOut(start, end time.Time, blackouts []Blackout) bool {
+	for _, b := range blackouts {
+		if start.Before(b.End) && b.Start.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// Request describes a GameDay to schedule.
+type Request struct {
+	// Name of the GameDay
+	Name string
+
+	// Who organizes the GameDay, which owns its suppressions
+	Organizer string
+
+	// Auto scaling groups of the services to target
+	Services []string
+
+	// Chaos strategy to use against the services (Chaos Monkey's default if
+	// empty)
+	Strategy chaosmonkey.Strategy
+
+	// People who need to attend
+	Participants []Participant
+
+	// Periods during which the GameDay may not take place
+	Blackouts []Blackout
+
+	// Period in which to find a slot
+	From, To time.Time
+
+	// Length of the GameDay
+	Duration time.Duration
+}
+
+// GameDay is a scheduled GameDay.
+type GameDay struct {
+	Name      string    `json:"name"`
+	Organizer string    `json:"organizer"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+
+	// Auto scaling groups of the targeted services
+	Services []string `json:"services"`
+
+	// Names of the participants
+	Participants []string `json:"participants,omitempty"`
+
+	// Campaign to run during the GameDay, with one step per service
+	Plan chaosmonkey.Campaign `json:"plan"`
+
+	// Freeze suppressing other chaos against the services during the
+	// GameDay, if any
+	Suppression *chaosmonkey.Freeze `json:"suppression,omitempty"`
+}
+
+// Suppressor suppresses chaos against groups for a period, e.g. a
+// chaosmonkey.Freezer.
+type Suppressor interface {
+	FreezeBetween(groups []string, reason, owner string, start, end time.Time) (*chaosmonkey.Freeze, error)
+	Unfreeze(id string) error
+}
+
+// Announcer announces a scheduled GameDay, e.g. aws.SNSNotifier or
+// aws.EventBridgeEmitter.
+type Announcer interface {
+	Announce(ctx context.Context, gd *GameDay) error
+}
+
+// Planner schedules GameDays.
+type Planner struct {
+	// Optional suppressor of chaos against the targeted services during the
+	// GameDay, so that scheduled chaos does not interfere with it. The plan
+	// must be run by a client that does not use it.
+	Suppressor Suppressor
+
+	// Announcers to announce scheduled GameDays with
+	Announcers []Announcer
+}
+
+// Schedule schedules a GameDay in one operation: it picks the first slot
+// proposed for the request, creates the plan, suppresses chaos against the
+// services during the slot, and announces the GameDay. If an announcement
+// fails, the suppression is removed again and an error is returned, although
+// earlier announcements cannot be taken back.
+func (p *Planner) Schedule(ctx context.Context, req *Request) (*GameDay, error) {
+	switch {
+	case req.Name == "":
+		return nil, fmt.Errorf("GameDay needs a name")
+	case req.Organizer == "":
+		return nil, fmt.Errorf("GameDay needs an organizer")
+	case len(req.Services) == 0:
+		return nil, fmt.Errorf("GameDay needs at least one service to target")
+	case req.Duration <= 0:
+		return nil, fmt.Errorf("GameDay needs a positive duration")
+	}
+
+	slots := ProposeSlots(req.From, req.To, req.Duration, req.Participants, req.Blackouts, 1)
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("no slot of %s between %s and %s suits all participants",
+			req.Duration, req.From.Format(time.RFC3339), req.To.Format(time.RFC3339))
+	}
+	gd := &GameDay{
+		Name:      req.Name,
+		Organizer: req.Organizer,
+		Start:     slots[0].Start,
+		End:       slots[0].End,
+		Services:  req.Services,
+		Plan:      chaosmonkey.Campaign{Name: req.Name},
+	}
+	for _, pt := range req.Participants {
+		gd.Participants = append(gd.Participants, pt.Name)
+	}
+	for _, s := range req.Services {
+		gd.Plan.Steps = append(gd.Plan.Steps, chaosmonkey.CampaignStep{
+			Name:     s,
+			Groups:   []string{s},
+			Strategy: req.Strategy,
+		})
+	}
+	if err := gd.Plan.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid GameDay plan: %s", err)
+	}
+
+	if p.Suppressor != nil {
+		reason := fmt.Sprintf("GameDay %s", req.Name)
+		fr, err := p.Suppressor.FreezeBetween(req.Services, reason, req.Organizer, gd.Start, gd.End)
+		if err != nil {
+			return nil, fmt.Errorf("failed to suppress chaos during GameDay: %s", err)
+		}
+		gd.Suppression = fr
+	}
+	for _, a := range p.Announcers {
+		if err := a.Announce(ctx, gd); err != nil {
+			if gd.Suppression != nil {
+				if uerr := p.Suppressor.Unfreeze(gd.Suppression.ID); uerr != nil {
+					err = fmt.Errorf("%s (and failed to remove suppression %s: %s)", err, gd.Suppression.ID, uerr)
+				}
+			}
+			return nil, fmt.Errorf("failed to announce GameDay: %s", err)
+		}
+	}
+	return gd, nil
+}
@@ -0,0 +1,144 @@
+package gameday_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/FlyLevin/chaosmonkey/gameday"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestProposeSlots(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+	newYork := time.FixedZone("EDT", -4*60*60)
+	participants := []gameday.Participant{
+		{Name: "alice", Location: berlin},
+		{Name: "bob", Location: newYork},
+	}
+	blackouts := []gameday.Blackout{{
+		Start:  time.Date(2018, 4, 3, 14, 0, 0, 0, time.UTC),
+		End:    time.Date(2018, 4, 3, 14, 30, 0, 0, time.UTC),
+		Reason: "release",
+	}}
+
+	from := time.Date(2018, 3, 31, 0, 0, 0, 0, time.UTC) // Saturday
+	to := time.Date(2018, 4, 4, 0, 0, 0, 0, time.UTC)
+	slots := gameday.ProposeSlots(from, to, 2*time.Hour, participants, blackouts, 5)
+
+	// Working hours only overlap from 13:00 to 15:00 UTC, and Tuesday is
+	// blacked out.
+	expected := []gameday.Slot{{
+		Start: time.Date(2018, 4, 2, 13, 0, 0, 0, time.UTC),
+		End:   time.Date(2018, 4, 2, 15, 0, 0, 0, time.UTC),
+	}}
+	if diff := cmp.Diff(expected, slots); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+type fakeSuppressor struct {
+	freezes []chaosmonkey.Freeze
+}
+
+func (s *fakeSuppressor) FreezeBetween(groups []string, reason, owner string, start, end time.Time) (*chaosmonkey.Freeze, error) {
+	fr := chaosmonkey.Freeze{ID: "freeze-1", Groups: groups, Reason: reason, Owner: owner, Start: start, Expires: end}
+	s.freezes = append(s.freezes, fr)
+	return &fr, nil
+}
+
+func (s *fakeSuppressor) Unfreeze(id string) error {
+	s.freezes = nil
+	return nil
+}
+
+type announcerFunc func(ctx context.Context, gd *gameday.GameDay) error
+
+func (f announcerFunc) Announce(ctx context.Context, gd *gameday.GameDay) error { return f(ctx, gd) }
+
+func TestSchedule(t *testing.T) {
+	var announced []*gameday.GameDay
+	suppressor := &fakeSuppressor{}
+	planner := &gameday.Planner{
+		Suppressor: suppressor,
+		Announcers: []gameday.Announcer{announcerFunc(func(ctx context.Context, gd *gameday.GameDay) error {
+			announced = append(announced, gd)
+			return nil
+		})},
+	}
+	req := &gameday.Request{
+		Name:         "payments",
+		Organizer:    "alice",
+		Services:     []string{"payments-api", "payments-worker"},
+		Strategy:     "burncpu",
+		Participants: []gameday.Participant{{Name: "alice"}, {Name: "bob"}},
+		From:         time.Date(2018, 4, 2, 12, 0, 0, 0, time.UTC), // Monday
+		To:           time.Date(2018, 4, 3, 0, 0, 0, 0, time.UTC),
+		Duration:     2 * time.Hour,
+	}
+
+	gd, err := planner.Schedule(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2018, 4, 2, 12, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	expected := &gameday.GameDay{
+		Name:         "payments",
+		Organizer:    "alice",
+		Start:        start,
+		End:          end,
+		Services:     []string{"payments-api", "payments-worker"},
+		Participants: []string{"alice", "bob"},
+		Plan: chaosmonkey.Campaign{
+			Name: "payments",
+			Steps: []chaosmonkey.CampaignStep{
+				{Name: "payments-api", Groups: []string{"payments-api"}, Strategy: chaosmonkey.StrategyBurnCPU},
+				{Name: "payments-worker", Groups: []string{"payments-worker"}, Strategy: chaosmonkey.StrategyBurnCPU},
+			},
+		},
+		Suppression: &chaosmonkey.Freeze{
+			ID:      "freeze-1",
+			Groups:  []string{"payments-api", "payments-worker"},
+			Reason:  "GameDay payments",
+			Owner:   "alice",
+			Start:   start,
+			Expires: end,
+		},
+	}
+	if diff := cmp.Diff(expected, gd); diff != "" {
+		t.Fatal(diff)
+	}
+	if len(announced) != 1 || announced[0] != gd {
+		t.Errorf("expected GameDay to be announced once, got %d announcements", len(announced))
+	}
+}
+
+func TestScheduleFailedAnnouncement(t *testing.T) {
+	suppressor := &fakeSuppressor{}
+	planner := &gameday.Planner{
+		Suppressor: suppressor,
+		Announcers: []gameday.Announcer{announcerFunc(func(ctx context.Context, gd *gameday.GameDay) error {
+			return errors.New("topic not found")
+		})},
+	}
+	req := &gameday.Request{
+		Name:      "payments",
+		Organizer: "alice",
+		Services:  []string{"payments-api"},
+		From:      time.Date(2018, 4, 2, 12, 0, 0, 0, time.UTC),
+		To:        time.Date(2018, 4, 3, 0, 0, 0, 0, time.UTC),
+		Duration:  time.Hour,
+	}
+
+	if _, err := planner.Schedule(context.Background(), req); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(suppressor.freezes) != 0 {
+		t.Errorf("expected suppression to be removed, got %v", suppressor.freezes)
+	}
+}
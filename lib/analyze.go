@@ -0,0 +1,124 @@
+package chaosmonkey
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Suggestion is a machine-readable recommendation derived from past chaos
+// events or campaign results.
+type Suggestion struct {
+	// Name of auto scaling group the suggestion applies to
+	AutoScalingGroupName string
+
+	// Chaos strategy the suggestion refers to
+	Strategy Strategy
+
+	// Suggested minimum time between chaos events against the group, if the
+	// suggestion is to increase it
+	Interval time.Duration
+
+	// Human-readable explanation of the suggestion
+	Reason string
+}
+
+// SuggestCoverage inspects past chaos events and suggests, for each auto
+// scaling group found in the events, the given strategies that have never
+// been used against it. Suggestions are sorted by group name and keep the
+// order of strategies.
+func SuggestCoverage(events []Event, strategies []Strategy) []Suggestion {
+	tested := make(map[string]map[Strategy]bool)
+	for _, e := range events {
+		if tested[e.AutoScalingGroupName] == nil {
+			tested[e.AutoScalingGroupName] = make(map[Strategy]bool)
+		}
+		tested[e.AutoScalingGroupName][e.Strategy] = true
+	}
+
+	groups := make([]string, 0, len(tested))
+	for g := range tested {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+
+	var suggestions []Suggestion
+	for _, g := range groups {
+		for _, s := range strategies {
+			if tested[g][s] {
+				continue
+			}
+			suggestions = append(suggestions, Suggestion{
+				AutoScalingGroupName: g,
+				Strategy:             s,
+				Reason:               fmt.Sprintf("strategy %s has never been used against group %s", s, g),
+			})
+		}
+	}
+	return suggestions
+}
+
+// minSuggestedInterval is the least interval suggested by SuggestIntervals.
+const minSuggestedInterval = time.Minute
+
+// SuggestIntervals inspects the results of campaigns and suggests increasing
+// the interval between chaos events for auto scaling groups whose campaign
+// failed a check, i.e. the groups targeted by the last step with events
+// before the check failed. The suggested interval is twice the shortest time
+// the group was given to recover, from one event to the next or to the
+// failed check, but at least a minute. Suggestions are sorted by group name,
+// with one suggestion per group and the longest interval if several
+// campaigns failed.
+func SuggestIntervals(results []CampaignResult) []Suggestion {
+	byGroup := make(map[string]Suggestion)
+	for _, r := range results {
+		if !r.CheckFailed {
+			continue
+		}
+		var last []Event
+		times := make(map[string][]time.Time)
+		for _, step := range r.Steps {
+			if len(step.Events) > 0 {
+				last = step.Events
+			}
+			for _, e := range step.Events {
+				times[e.AutoScalingGroupName] = append(times[e.AutoScalingGroupName], e.TriggeredAt)
+			}
+		}
+
+		for _, e := range last {
+			g := e.AutoScalingGroupName
+			ts := append(append([]time.Time{}, times[g]...), r.Finished)
+			sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+			recovery := ts[len(ts)-1].Sub(ts[0])
+			for i := 1; i < len(ts); i++ {
+				if d := ts[i].Sub(ts[i-1]); d < recovery {
+					recovery = d
+				}
+			}
+			interval := (2 * recovery).Round(time.Second)
+			if interval < minSuggestedInterval {
+				interval = minSuggestedInterval
+			}
+			if interval <= byGroup[g].Interval {
+				continue
+			}
+			byGroup[g] = Suggestion{
+				AutoScalingGroupName: g,
+				Strategy:             e.Strategy,
+				Interval:             interval,
+				Reason: fmt.Sprintf("campaign %s failed a check after chaos against group %s, which had %s to recover; allow at least %s between chaos events",
+					r.Name, g, recovery.Round(time.Second), interval),
+			}
+		}
+	}
+
+	var suggestions []Suggestion
+	for _, s := range byGroup {
+		suggestions = append(suggestions, s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].AutoScalingGroupName < suggestions[j].AutoScalingGroupName
+	})
+	return suggestions
+}
@@ -0,0 +1,83 @@
+package chaosmonkey_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestSuggestCoverage(t *testing.T) {
+	events := []chaosmonkey.Event{
+		{AutoScalingGroupName: "b", Strategy: chaosmonkey.StrategyShutdownInstance},
+		{AutoScalingGroupName: "a", Strategy: chaosmonkey.StrategyBurnCPU},
+		{AutoScalingGroupName: "a", Strategy: chaosmonkey.StrategyShutdownInstance},
+	}
+	strategies := []chaosmonkey.Strategy{
+		chaosmonkey.StrategyShutdownInstance,
+		chaosmonkey.StrategyBurnCPU,
+	}
+
+	got := chaosmonkey.SuggestCoverage(events, strategies)
+
+	expected := []chaosmonkey.Suggestion{
+		{
+			AutoScalingGroupName: "b",
+			Strategy:             chaosmonkey.StrategyBurnCPU,
+			Reason:               "strategy BurnCpu has never been used against group b",
+		},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestSuggestIntervals(t *testing.T) {
+	at := func(min int) time.Time { return time.Date(2018, 4, 1, 10, min, 0, 0, time.UTC) }
+	results := []chaosmonkey.CampaignResult{
+		{
+			Name:     "healthy",
+			Finished: at(30),
+			Steps: []chaosmonkey.StepResult{
+				{Events: []chaosmonkey.Event{{AutoScalingGroupName: "c", TriggeredAt: at(0)}}},
+			},
+		},
+		{
+			Name:        "fragile",
+			Finished:    at(10),
+			CheckFailed: true,
+			Steps: []chaosmonkey.StepResult{
+				{Events: []chaosmonkey.Event{
+					{AutoScalingGroupName: "a", Strategy: chaosmonkey.StrategyShutdownInstance, TriggeredAt: at(0)},
+					{AutoScalingGroupName: "c", Strategy: chaosmonkey.StrategyShutdownInstance, TriggeredAt: at(1)},
+				}},
+				{Events: []chaosmonkey.Event{
+					{AutoScalingGroupName: "b", Strategy: chaosmonkey.StrategyBurnCPU, TriggeredAt: at(2)},
+					{AutoScalingGroupName: "a", Strategy: chaosmonkey.StrategyBurnCPU, TriggeredAt: at(5)},
+				}},
+			},
+		},
+	}
+
+	got := chaosmonkey.SuggestIntervals(results)
+
+	expected := []chaosmonkey.Suggestion{
+		{
+			AutoScalingGroupName: "a",
+			Strategy:             chaosmonkey.StrategyBurnCPU,
+			Interval:             10 * time.Minute,
+			Reason:               "campaign fragile failed a check after chaos against group a, which had 5m0s to recover; allow at least 10m0s between chaos events",
+		},
+		{
+			AutoScalingGroupName: "b",
+			Strategy:             chaosmonkey.StrategyBurnCPU,
+			Interval:             16 * time.Minute,
+			Reason:               "campaign fragile failed a check after chaos against group b, which had 8m0s to recover; allow at least 16m0s between chaos events",
+		},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
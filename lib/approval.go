@@ -0,0 +1,596 @@
+package chaosmonkey
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultApprovalTTL is how long an approval is valid unless configured
+// otherwise.
+const DefaultApprovalTTL = time.Hour
+
+// MaxRequestSkew is how far the time of a request signed by
+// SignApprovalRequest may differ from the time it is verified at.
+const MaxRequestSkew = 5 * time.Minute
+
+// Headers of requests signed by SignApprovalRequest
+const (
+	headerApprover  = "X-Chaosmonkey-Approver"
+	headerTime      = "X-Chaosmonkey-Time"
+	headerSignature = "X-Chaosmonkey-Signature"
+)
+
+// Approval allows one chaos event with a destructive strategy against a
+// protected group, granted by a second person.
+type Approval struct {
+	// Short ID of the approval, which identifies it without revealing the
+	// token
+	ID string `json:"id"`
+
+	// Token proving the approval, which is handed to the person triggering
+	// the chaos event (empty for approvals granted via HTTP)
+	Token string `json:"token,omitempty"`
+
+	// Name of the approved auto scaling group
+	Group string `json:"group"`
+
+	// Approved chaos strategy
+	Strategy Strategy `json:"strategy"`
+
+	// Who approved the chaos event
+	Approver string `json:"approver"`
+
+	// Time when the approval expires
+	Expires time.Time `json:"expires"`
+}
+
+// approvalClaims is the signed payload of an approval token.
+type approvalClaims struct {
+	Group    string   `json:"g"`
+	Strategy Strategy `json:"s"`
+	Approver string   `json:"a"`
+	Expires  int64    `json:"e"`
+	Nonce    string   `json:"n"`
+}
+
+// Approvals is a Guard enforcing the two-person rule: chaos events with
+// destructive strategies against protected groups are refused unless someone
+// other than the requester approved them. Every approval allows a single
+// chaos event and expires after a TTL.
+//
+// Approvers sign approval tokens with their own Ed25519 private keys, e.g.
+// with the approve command of the chaosmonkey tool, and hand them to the
+// requester, who presents them to the process triggering the chaos event.
+// Tokens are verified with the public keys of Approvers, so nobody can
+// approve on behalf of an approver without holding the approver's key.
+// Approvals also implements http.Handler, which allows approvers
+// authenticated by Authenticate, e.g. VerifyRequest, to grant approvals in a
+// long-running process via HTTP:
+//
+//	GET    /         lists approvals not used yet
+//	POST   /         grants an approval by the user from {"group", "strategy", "ttl"}
+//	DELETE /<id>     revokes an approval
+//
+// Check reserves an approval, which is used up once the chaos event was
+// triggered. If the chaos event is refused by another check or fails, the
+// approval can be used again, which Approvals learns from the buses it
+// subscribed to.
+type Approvals struct {
+	// Public keys verifying the approval tokens of the people allowed to
+	// approve chaos events, by name. Approvers who only grant approvals via
+	// HTTP need no key.
+	Approvers map[string]ed25519.PublicKey
+
+	// Names of protected auto scaling groups, given as shell patterns or as
+	// regular expressions enclosed in slashes like Config.DenyGroups
+	Protected []string
+
+	// Who triggers chaos events, whose own approvals do not count, unless
+	// the target names the requester (i.e. Config.Actor is set). It must be
+	// an authenticated identity, such as the AWS identity of the requester,
+	// with approvers named alike. If neither names the requester, chaos
+	// events against protected groups are refused.
+	Requester string
+
+	// How long approvals are valid (DefaultApprovalTTL if zero)
+	TTL time.Duration
+
+	// Record of used and revoked approvals (kept in memory if nil, which
+	// forgets them on restart). Share it, e.g. as TokenDir, between all
+	// processes that approval tokens may be presented to.
+	Used UsedTokens
+
+	// Function authenticating requests to the HTTP API, which returns the
+	// name of the user, e.g. VerifyRequest. Only approvers may use the API,
+	// which refuses all requests if Authenticate is nil.
+	Authenticate func(r *http.Request) (user string, err error)
+
+	// Clock used to tell the current time (SystemClock if nil)
+	Clock Clock
+
+	once       sync.Once
+	mu         sync.Mutex
+	approvals  []Approval
+	pending    map[uint64]Approval // reserved approvals by attempt
+	subscribed int
+}
+
+// Approve signs an approval of one chaos event with the given strategy
+// against the given group by the approver, whose private key is given, and
+// returns it, including the token to hand to the requester.
+func (a *Approvals) Approve(key ed25519.PrivateKey, approver, group string, strategy Strategy, ttl time.Duration) (*Approval, error) {
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("approval needs the private key of the approver")
+	}
+	claims, err := a.claims(approver, group, strategy, ttl)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	sig := ed25519.Sign(key, payload)
+	enc := base64.RawURLEncoding
+	return approval(enc.EncodeToString(payload)+"."+enc.EncodeToString(sig), sig, claims), nil
+}
+
+// claims returns the claims of a new approval, validating its parameters.
+func (a *Approvals) claims(approver, group string, strategy Strategy, ttl time.Duration) (approvalClaims, error) {
+	if ttl == 0 {
+		ttl = a.TTL
+	}
+	if ttl == 0 {
+		ttl = DefaultApprovalTTL
+	}
+	switch {
+	case group == "":
+		return approvalClaims{}, fmt.Errorf("approval needs a group")
+	case strategy == "":
+		return approvalClaims{}, fmt.Errorf("approval needs a strategy")
+	case approver == "":
+		return approvalClaims{}, fmt.Errorf("approval needs an approver")
+	case ttl < 0:
+		return approvalClaims{}, fmt.Errorf("approval needs a positive TTL")
+	}
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return approvalClaims{}, err
+	}
+	return approvalClaims{
+		Group:    group,
+		Strategy: strategy,
+		Approver: approver,
+		Expires:  a.now().Add(ttl).Unix(),
+		Nonce:    hex.EncodeToString(nonce),
+	}, nil
+}
+
+// approval returns the approval with the given token, signature, and
+// claims.
+func approval(token string, sig []byte, claims approvalClaims) *Approval {
+	return &Approval{
+		ID:       hex.EncodeToString(sig[:6]),
+		Token:    token,
+		Group:    claims.Group,
+		Strategy: claims.Strategy,
+		Approver: claims.Approver,
+		Expires:  time.Unix(claims.Expires, 0).UTC(),
+	}
+}
+
+// Present verifies an approval token signed by an approver and makes the
+// approval available to the guard. Approvals used or revoked before are
+// rejected.
+func (a *Approvals) Present(token string) (*Approval, error) {
+	ap, err := a.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	used, err := a.used().IsUsed(ap.ID)
+	if err != nil {
+		return nil, err
+	}
+	if used {
+		return nil, fmt.Errorf("approval %s was already used or revoked", ap.ID)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, existing := range a.approvals {
+		if existing.ID == ap.ID {
+			return ap, nil
+		}
+	}
+	a.approvals = append(a.approvals, *ap)
+	return ap, nil
+}
+
+// Revoke removes the approval with the given ID, which cannot be presented
+// again.
+func (a *Approvals) Revoke(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, ap := range a.approvals {
+		if ap.ID == id {
+			if err := a.used().Use(id, ap.Expires); err != nil && err != ErrTokenUsed {
+				return fmt.Errorf("failed to record revocation of approval %s: %s", id, err)
+			}
+			a.approvals = append(a.approvals[:i], a.approvals[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("approval %q not found", id)
+}
+
+// Active returns all approvals that have neither expired nor been used yet,
+// without their tokens.
+func (a *Approvals) Active() []Approval {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expire()
+	active := make([]Approval, 0, len(a.approvals))
+	for _, ap := range a.approvals {
+		ap.Token = ""
+		active = append(active, ap)
+	}
+	return active
+}
+
+// Subscribe tracks the outcome of the chaos events allowed by approvals on
+// the bus: approvals are used up by chaos events that were triggered, and
+// can be used again if the chaos event was refused or failed. It returns a
+// function that stops tracking.
+func (a *Approvals) Subscribe(bus *Bus) (stop func()) {
+	a.mu.Lock()
+	a.subscribed++
+	a.mu.Unlock()
+	stops := []func(){
+		bus.Subscribe(TopicEventRecorded, func(m Message) {
+			a.mu.Lock()
+			delete(a.pending, m.Attempt)
+			a.mu.Unlock()
+		}),
+	}
+	for _, topic := range []Topic{TopicGuardBlocked, TopicTriggerFailed} {
+		stops = append(stops, bus.Subscribe(topic, func(m Message) {
+			a.mu.Lock()
+			defer a.mu.Unlock()
+			ap, ok := a.pending[m.Attempt]
+			if !ok {
+				return
+			}
+			delete(a.pending, m.Attempt)
+			if err := a.used().Release(ap.ID); err == nil {
+				a.approvals = append(a.approvals, ap)
+			}
+		}))
+	}
+	return func() {
+		for _, stop := range stops {
+			stop()
+		}
+		a.mu.Lock()
+		a.subscribed--
+		a.mu.Unlock()
+	}
+}
+
+// Check refuses chaos events with destructive strategies against protected
+// groups unless a matching approval by someone other than the requester was
+// presented, which is reserved for the chaos event. They are also refused if
+// the requester is unknown.
+func (a *Approvals) Check(t Target) error {
+	return a.check(t, true)
+}
+
+// Evaluate is like Check but does not reserve the approval.
+func (a *Approvals) Evaluate(t Target) error {
+	return a.check(t, false)
+}
+
+func (a *Approvals) check(t Target, use bool) error {
+	strategy := t.Strategy
+	if strategy == "" {
+		strategy = StrategyShutdownInstance
+	}
+	if !strategy.IsDestructive() {
+		return nil
+	}
+	patterns, err := compileGroupPatterns(a.Protected)
+	if err != nil {
+		return err
+	}
+	protected := false
+	for _, p := range patterns {
+		if p.match(t.AutoScalingGroupName) {
+			protected = true
+			break
+		}
+	}
+	if !protected {
+		return nil
+	}
+	requester := t.Requester
+	if requester == "" {
+		requester = a.Requester
+	}
+	// Without knowing the requester, an approver could approve their own
+	// chaos events
+	if requester == "" {
+		return fmt.Errorf("%s against protected group %s needs approval by a second person, but the requester is unknown",
+			strategy, t.AutoScalingGroupName)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expire()
+	for i := 0; i < len(a.approvals); i++ {
+		ap := a.approvals[i]
+		if ap.Group != t.AutoScalingGroupName || ap.Strategy != strategy {
+			continue
+		}
+		if ap.Approver == requester {
+			continue
+		}
+		if !use {
+			return nil
+		}
+		// Record the use first, which fails if another process used the
+		// approval already
+		err := a.used().Use(ap.ID, ap.Expires)
+		if err != nil && err != ErrTokenUsed {
+			return fmt.Errorf("failed to record use of approval %s: %s", ap.ID, err)
+		}
+		a.approvals = append(a.approvals[:i], a.approvals[i+1:]...)
+		if err == ErrTokenUsed {
+			i--
+			continue
+		}
+		if a.subscribed > 0 && t.Attempt != 0 {
+			if a.pending == nil {
+				a.pending = make(map[uint64]Approval)
+			}
+			a.pending[t.Attempt] = ap
+		}
+		return nil
+	}
+	return fmt.Errorf("%s against protected group %s needs approval by a second person",
+		strategy, t.AutoScalingGroupName)
+}
+
+// verify checks the signature and expiry of an approval token.
+func (a *Approvals) verify(token string) (*Approval, error) {
+	invalid := fmt.Errorf("invalid approval token")
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, invalid
+	}
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, invalid
+	}
+	sig, err := enc.DecodeString(parts[1])
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return nil, invalid
+	}
+	var claims approvalClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, invalid
+	}
+	key, ok := a.Approvers[claims.Approver]
+	if !ok {
+		return nil, fmt.Errorf("approval by %q, who is not an approver", claims.Approver)
+	}
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, payload, sig) {
+		return nil, invalid
+	}
+	ap := approval(token, sig, claims)
+	if !a.now().Before(ap.Expires) {
+		return nil, fmt.Errorf("approval %s expired at %s", ap.ID, ap.Expires.Format(time.RFC3339))
+	}
+	return ap, nil
+}
+
+// grant grants an approval by an approver authenticated by the HTTP API,
+// which has no token.
+func (a *Approvals) grant(approver, group string, strategy Strategy, ttl time.Duration) (*Approval, error) {
+	claims, err := a.claims(approver, group, strategy, ttl)
+	if err != nil {
+		return nil, err
+	}
+	ap := &Approval{
+		ID:       claims.Nonce,
+		Group:    claims.Group,
+		Strategy: claims.Strategy,
+		Approver: claims.Approver,
+		Expires:  time.Unix(claims.Expires, 0).UTC(),
+	}
+	a.mu.Lock()
+	a.approvals = append(a.approvals, *ap)
+	a.mu.Unlock()
+	return ap, nil
+}
+
+func (a *Approvals) used() UsedTokens {
+	a.once.Do(func() {
+		if a.Used == nil {
+			a.Used = &memoryTokens{}
+		}
+	})
+	return a.Used
+}
+
+// expire removes expired approvals. It must be called with a.mu held.
+func (a *Approvals) expire() {
+	now := a.now()
+	active := a.approvals[:0]
+	for _, ap := range a.approvals {
+		if now.Before(ap.Expires) {
+			active = append(active, ap)
+		}
+	}
+	a.approvals = active
+}
+
+func (a *Approvals) now() time.Time {
+	return clockNow(a.Clock)
+}
+
+// SignApprovalRequest authenticates a request to the HTTP API of Approvals
+// as the approver, whose private key is given, by signing its method, URI,
+// body, and the current time, which VerifyRequest checks. The body is read
+// and replaced.
+func SignApprovalRequest(r *http.Request, key ed25519.PrivateKey, approver string) error {
+	if len(key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("signing requests needs the private key of the approver")
+	}
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	sig := ed25519.Sign(key, requestPayload(r.Method, r.URL.RequestURI(), approver, now, body))
+	r.Header.Set(headerApprover, approver)
+	r.Header.Set(headerTime, now)
+	r.Header.Set(headerSignature, base64.StdEncoding.EncodeToString(sig))
+	return nil
+}
+
+// VerifyRequest authenticates a request signed by SignApprovalRequest with
+// the key of an approver and returns the name of the approver. Use it as
+// Authenticate. Requests signed more than MaxRequestSkew ago and replayed
+// requests are rejected.
+func (a *Approvals) VerifyRequest(r *http.Request) (string, error) {
+	approver := r.Header.Get(headerApprover)
+	if approver == "" {
+		return "", fmt.Errorf("request is not signed")
+	}
+	key, ok := a.Approvers[approver]
+	if !ok || len(key) != ed25519.PublicKeySize {
+		return "", fmt.Errorf("no key of approver %q", approver)
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Header.Get(headerSignature))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return "", fmt.Errorf("invalid request signature")
+	}
+	now := r.Header.Get(headerTime)
+	unix, err := strconv.ParseInt(now, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid request time %q", now)
+	}
+	signed := time.Unix(unix, 0)
+	if skew := a.now().Sub(signed); skew > MaxRequestSkew || skew < -MaxRequestSkew {
+		return "", fmt.Errorf("request was signed at %s, too far from now", signed.UTC().Format(time.RFC3339))
+	}
+	body, err := readBody(r)
+	if err != nil {
+		return "", err
+	}
+	// Behind http.StripPrefix, only RequestURI holds the signed path
+	uri := r.RequestURI
+	if uri == "" {
+		uri = r.URL.RequestURI()
+	}
+	if !ed25519.Verify(key, requestPayload(r.Method, uri, approver, now, body), sig) {
+		return "", fmt.Errorf("invalid request signature")
+	}
+	if err := a.used().Use(hex.EncodeToString(sig[:16]), signed.Add(MaxRequestSkew)); err != nil {
+		if err == ErrTokenUsed {
+			return "", fmt.Errorf("request was replayed")
+		}
+		return "", err
+	}
+	return approver, nil
+}
+
+// requestPayload returns the signed payload of a request to the HTTP API.
+func requestPayload(method, uri, approver, signedAt string, body []byte) []byte {
+	hash := sha256.Sum256(body)
+	return []byte(strings.Join([]string{method, uri, approver, signedAt, hex.EncodeToString(hash[:])}, "\n"))
+}
+
+// readBody reads the body of a request, if any, and replaces it with a
+// reader of the same content.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// approvalRequest is the body of a POST request to grant an approval.
+type approvalRequest struct {
+	Group    string   `json:"group"`
+	Strategy Strategy `json:"strategy"`
+	TTL      string   `json:"ttl"`
+}
+
+// ServeHTTP implements the HTTP API of the approvals.
+func (a *Approvals) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.Authenticate == nil {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{"authentication is not configured"})
+		return
+	}
+	user, err := a.Authenticate(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{err.Error()})
+		return
+	}
+	if _, ok := a.Approvers[user]; !ok {
+		writeJSON(w, http.StatusForbidden, errorResponse{fmt.Sprintf("%s is not an approver", user)})
+		return
+	}
+
+	id := strings.Trim(r.URL.Path, "/")
+	switch {
+	case r.Method == "GET" && id == "":
+		writeJSON(w, http.StatusOK, a.Active())
+	case r.Method == "POST" && id == "":
+		var req approvalRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+		var ttl time.Duration
+		if req.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(req.TTL); err != nil {
+				writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+				return
+			}
+		}
+		ap, err := a.grant(user, req.Group, req.Strategy, ttl)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, ap)
+	case r.Method == "DELETE" && id != "":
+		if err := a.Revoke(id); err != nil {
+			writeJSON(w, http.StatusNotFound, errorResponse{err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
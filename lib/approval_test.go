@@ -0,0 +1,385 @@
+package chaosmonkey_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// approverKeys returns the private keys of the given approvers and the
+// allowlist of their public keys.
+func approverKeys(t *testing.T, names ...string) (map[string]ed25519.PrivateKey, map[string]ed25519.PublicKey) {
+	private := make(map[string]ed25519.PrivateKey)
+	public := make(map[string]ed25519.PublicKey)
+	for _, name := range names {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		private[name], public[name] = priv, pub
+	}
+	return private, public
+}
+
+func TestApprovals(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)}
+	keys, approvers := approverKeys(t, "alice", "bob")
+	approvals := &chaosmonkey.Approvals{
+		Approvers: approvers,
+		Protected: []string{"payments-*"},
+		Requester: "alice",
+		Clock:     clock,
+	}
+	bus := chaosmonkey.NewBus()
+	approvals.Subscribe(bus)
+	refuse := errors.New("refused by the next guard")
+	var next error
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun: true,
+		Clock:  clock,
+		Bus:    bus,
+		Guards: []chaosmonkey.Guard{approvals, chaosmonkey.GuardFunc(func(t chaosmonkey.Target) error { return next })},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigger := func(group string, strategy chaosmonkey.Strategy) error {
+		_, err := client.TriggerEvent(group, strategy)
+		return err
+	}
+
+	if err := trigger("search-api", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatalf("unprotected group was refused: %s", err)
+	}
+	if err := trigger("payments-api", chaosmonkey.StrategyBurnCPU); err != nil {
+		t.Fatalf("non-destructive strategy was refused: %s", err)
+	}
+	err = trigger("payments-api", "")
+	if want := "chaos event refused: ShutdownInstance against protected group payments-api needs approval by a second person"; err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+
+	// Approvals are signed elsewhere and presented as tokens
+	grantor := &chaosmonkey.Approvals{Clock: clock}
+	own, err := grantor.Approve(keys["alice"], "alice", "payments-api", chaosmonkey.StrategyShutdownInstance, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := approvals.Present(own.Token); err != nil {
+		t.Fatal(err)
+	}
+	if err := trigger("payments-api", chaosmonkey.StrategyShutdownInstance); err == nil {
+		t.Fatal("own approval was accepted")
+	}
+
+	// The requester cannot approve in the name of someone else
+	forged, err := grantor.Approve(keys["alice"], "bob", "payments-api", chaosmonkey.StrategyShutdownInstance, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := approvals.Present(forged.Token); err == nil {
+		t.Fatal("approval signed with the key of another approver was accepted")
+	}
+	_, unknown := approverKeys(t, "mallory")
+	stranger, err := grantor.Approve(keys["bob"], "mallory", "payments-api", chaosmonkey.StrategyShutdownInstance, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := approvals.Present(stranger.Token); err == nil || !strings.Contains(err.Error(), "not an approver") {
+		t.Fatalf("got error %v, want approval by unknown approver to be rejected", err)
+	}
+	if _, err := (&chaosmonkey.Approvals{Approvers: unknown}).Present(own.Token); err == nil {
+		t.Fatal("approval by unknown approver was accepted")
+	}
+
+	bobs, err := grantor.Approve(keys["bob"], "bob", "payments-api", chaosmonkey.StrategyShutdownInstance, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := approvals.Present(bobs.Token); err != nil {
+		t.Fatal(err)
+	}
+	// The approval is only used up by a chaos event that was triggered
+	next = refuse
+	if err := trigger("payments-api", chaosmonkey.StrategyShutdownInstance); err == nil || !strings.Contains(err.Error(), refuse.Error()) {
+		t.Fatalf("got error %v, want refusal by the next guard", err)
+	}
+	next = nil
+	if err := trigger("payments-api", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatalf("approved event was refused: %s", err)
+	}
+	if err := trigger("payments-api", chaosmonkey.StrategyShutdownInstance); err == nil {
+		t.Fatal("approval was used twice")
+	}
+	if _, err := approvals.Present(bobs.Token); err == nil {
+		t.Fatal("used approval was presented again")
+	}
+
+	if _, err := approvals.Present(bobs.Token[:len(bobs.Token)-2]); err == nil {
+		t.Fatal("tampered token was accepted")
+	}
+	expiring, err := grantor.Approve(keys["bob"], "bob", "payments-api", chaosmonkey.StrategyFillDisk, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	if _, err := approvals.Present(expiring.Token); err == nil {
+		t.Fatal("expired token was accepted")
+	}
+}
+
+func TestApprovalsTriggerFailed(t *testing.T) {
+	fail := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"groupName": "payments-api", "chaosType": "ShutdownInstance"}`))
+	}))
+	defer ts.Close()
+
+	keys, approvers := approverKeys(t, "bob")
+	approvals := &chaosmonkey.Approvals{Approvers: approvers, Protected: []string{"payments-api"}, Requester: "alice"}
+	bus := chaosmonkey.NewBus()
+	approvals.Subscribe(bus)
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint: ts.URL,
+		Bus:      bus,
+		Guards:   []chaosmonkey.Guard{approvals},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ap, err := approvals.Approve(keys["bob"], "bob", "payments-api", chaosmonkey.StrategyShutdownInstance, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := approvals.Present(ap.Token); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.TriggerEvent("payments-api", chaosmonkey.StrategyShutdownInstance); err == nil {
+		t.Fatal("expected request to fail")
+	}
+	if n := len(approvals.Active()); n != 1 {
+		t.Fatalf("got %d active approvals, want the approval back after the failure", n)
+	}
+	fail = false
+	if _, err := client.TriggerEvent("payments-api", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(approvals.Active()); n != 0 {
+		t.Fatalf("got %d active approvals, want none", n)
+	}
+}
+
+func TestApprovalsUsed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaosmonkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	keys, approvers := approverKeys(t, "bob")
+	target := chaosmonkey.Target{AutoScalingGroupName: "payments-api", Strategy: chaosmonkey.StrategyShutdownInstance, Requester: "alice"}
+
+	// Separate instances, like processes or restarts, share used approvals
+	first := &chaosmonkey.Approvals{Approvers: approvers, Protected: []string{"payments-api"}, Used: &chaosmonkey.TokenDir{Path: dir}}
+	second := &chaosmonkey.Approvals{Approvers: approvers, Protected: []string{"payments-api"}, Used: &chaosmonkey.TokenDir{Path: dir}}
+	ap, err := first.Approve(keys["bob"], "bob", "payments-api", chaosmonkey.StrategyShutdownInstance, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []*chaosmonkey.Approvals{first, second} {
+		if _, err := a.Present(ap.Token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Own approvals would count if the requester were unknown
+	anonymous := target
+	anonymous.Requester = ""
+	if err := first.Check(anonymous); err == nil || !strings.Contains(err.Error(), "requester is unknown") {
+		t.Fatalf("got error %v, want refusal without requester", err)
+	}
+	if err := first.Check(target); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Check(target); err == nil {
+		t.Fatal("approval was used by two processes")
+	}
+	if _, err := second.Present(ap.Token); err == nil {
+		t.Fatal("used approval was presented again")
+	}
+
+	revoked, err := first.Approve(keys["bob"], "bob", "payments-api", chaosmonkey.StrategyShutdownInstance, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Present(revoked.Token); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Revoke(revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Present(revoked.Token); err == nil {
+		t.Fatal("revoked approval was presented again")
+	}
+}
+
+func TestApprovalsHTTP(t *testing.T) {
+	_, approvers := approverKeys(t, "bob")
+	approvers["carol"] = nil
+	approvals := &chaosmonkey.Approvals{
+		Approvers: approvers,
+		Protected: []string{"payments-api"},
+		Requester: "alice",
+		Authenticate: func(r *http.Request) (string, error) {
+			user, password, ok := r.BasicAuth()
+			if !ok || password != user+"-password" {
+				return "", errors.New("invalid credentials")
+			}
+			return user, nil
+		},
+	}
+	ts := httptest.NewServer(approvals)
+	defer ts.Close()
+
+	grant := func(user, password string) (*http.Response, *chaosmonkey.Approval) {
+		body := `{"group": "payments-api", "strategy": "ShutdownInstance", "ttl": "30m"}`
+		req, err := http.NewRequest("POST", ts.URL, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user != "" {
+			req.SetBasicAuth(user, password)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var ap chaosmonkey.Approval
+		json.NewDecoder(resp.Body).Decode(&ap)
+		return resp, &ap
+	}
+
+	for _, test := range []struct {
+		user, password string
+		status         int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"carol", "guess", http.StatusUnauthorized},
+		{"alice", "alice-password", http.StatusForbidden},
+	} {
+		if resp, _ := grant(test.user, test.password); resp.StatusCode != test.status {
+			t.Errorf("%s: got %s, want %d", test.user, resp.Status, test.status)
+		}
+	}
+	if n := len(approvals.Active()); n != 0 {
+		t.Fatalf("expected no active approvals, got %d", n)
+	}
+
+	// Approvers without keys can approve via HTTP
+	resp, ap := grant("carol", "carol-password")
+	if resp.StatusCode != http.StatusOK || ap.Approver != "carol" {
+		t.Fatalf("unexpected response: %s %+v", resp.Status, ap)
+	}
+	active := approvals.Active()
+	if len(active) != 1 || active[0].ID != ap.ID {
+		t.Fatalf("unexpected active approvals: %+v", active)
+	}
+	target := chaosmonkey.Target{AutoScalingGroupName: "payments-api", Strategy: chaosmonkey.StrategyShutdownInstance, Requester: "alice"}
+	if err := approvals.Check(target); err != nil {
+		t.Fatalf("approved event was refused: %s", err)
+	}
+	if n := len(approvals.Active()); n != 0 {
+		t.Fatalf("expected no active approvals, got %d", n)
+	}
+
+	unauthenticated := httptest.NewServer(&chaosmonkey.Approvals{Approvers: approvers})
+	defer unauthenticated.Close()
+	resp, err := http.Get(unauthenticated.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("got %s, want API without authentication to refuse requests", resp.Status)
+	}
+}
+
+func TestApprovalsSignedRequests(t *testing.T) {
+	keys, approvers := approverKeys(t, "alice", "bob")
+	approvals := &chaosmonkey.Approvals{Approvers: approvers, Protected: []string{"payments-api"}, Requester: "alice"}
+	approvals.Authenticate = approvals.VerifyRequest
+	mux := http.NewServeMux()
+	mux.Handle("/approvals/", http.StripPrefix("/approvals", approvals))
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	newRequest := func(body string) *http.Request {
+		req, err := http.NewRequest("POST", ts.URL+"/approvals/", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	send := func(req *http.Request) int {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	body := `{"group": "payments-api", "strategy": "ShutdownInstance"}`
+
+	req := newRequest(body)
+	if err := chaosmonkey.SignApprovalRequest(req, keys["bob"], "bob"); err != nil {
+		t.Fatal(err)
+	}
+	signed := req.Header
+	if status := send(req); status != http.StatusOK {
+		t.Fatalf("got status %d, want signed request to be accepted", status)
+	}
+	if n := len(approvals.Active()); n != 1 {
+		t.Fatalf("expected one active approval, got %d", n)
+	}
+
+	replayed := newRequest(body)
+	replayed.Header = signed
+	if status := send(replayed); status != http.StatusUnauthorized {
+		t.Errorf("got status %d, want replayed request to be refused", status)
+	}
+	tampered := newRequest(`{"group": "orders-api", "strategy": "ShutdownInstance"}`)
+	if err := chaosmonkey.SignApprovalRequest(tampered, keys["bob"], "bob"); err != nil {
+		t.Fatal(err)
+	}
+	tampered.Body = ioutil.NopCloser(strings.NewReader(`{"group": "search-api", "strategy": "ShutdownInstance"}`))
+	if status := send(tampered); status != http.StatusUnauthorized {
+		t.Errorf("got status %d, want tampered request to be refused", status)
+	}
+	forged := newRequest(body)
+	if err := chaosmonkey.SignApprovalRequest(forged, keys["alice"], "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if status := send(forged); status != http.StatusUnauthorized {
+		t.Errorf("got status %d, want request signed with another key to be refused", status)
+	}
+	if status := send(newRequest(body)); status != http.StatusUnauthorized {
+		t.Errorf("got status %d, want unsigned request to be refused", status)
+	}
+	if n := len(approvals.Active()); n != 1 {
+		t.Errorf("expected one active approval, got %d", n)
+	}
+}
@@ -0,0 +1,182 @@
+package chaosmonkey
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditOutcome is the outcome of an audited call of TriggerEvent.
+type AuditOutcome string
+
+// Audit outcomes
+const (
+	// The chaos event was triggered
+	AuditTriggered AuditOutcome = "triggered"
+
+	// The chaos event was only simulated in a dry run
+	AuditSimulated AuditOutcome = "simulated"
+
+	// The chaos event was refused by a guard, an outage checker, a stop
+	// sentinel, or the kill switch
+	AuditRefused AuditOutcome = "refused"
+
+	// Triggering the chaos event failed
+	AuditFailed AuditOutcome = "failed"
+)
+
+// AuditRecord records a call of TriggerEvent in the audit log.
+type AuditRecord struct {
+	// Time of the call
+	Time time.Time `json:"time"`
+
+	// Who triggered the chaos event
+	Actor string `json:"actor"`
+
+	// Role of the actor, e.g. the ARN of an AWS identity
+	Role string `json:"role,omitempty"`
+
+	// User authenticated with the Chaos Monkey API (Config.Username)
+	User string `json:"user,omitempty"`
+
+	// Name of the targeted auto scaling group
+	AutoScalingGroupName string `json:"autoScalingGroupName"`
+
+	// Chaos strategy
+	Strategy Strategy `json:"strategy"`
+
+	// AWS region of the auto scaling group
+	Region string `json:"region,omitempty"`
+
+	// Why the chaos event was triggered
+	Reason string `json:"reason"`
+
+	// Whether the client was configured for a dry run
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Outcome of the call
+	Outcome AuditOutcome `json:"outcome"`
+
+	// Error returned by the call if it was refused or failed
+	Error string `json:"error,omitempty"`
+
+	// ID of the terminated instance if known
+	InstanceID string `json:"instanceId,omitempty"`
+}
+
+// AuditSink stores audit records. Configure an audit sink via Config.Audit to
+// have every call of TriggerEvent recorded, independently of the events
+// recorded by Chaos Monkey itself.
+type AuditSink interface {
+	WriteAudit(ctx context.Context, r AuditRecord) error
+}
+
+// AuditSinkFunc is an adapter to use an ordinary function as an AuditSink.
+type AuditSinkFunc func(ctx context.Context, r AuditRecord) error
+
+// WriteAudit calls f(ctx, r).
+func (f AuditSinkFunc) WriteAudit(ctx context.Context, r AuditRecord) error {
+	return f(ctx, r)
+}
+
+// AuditError is returned by TriggerEvent if the audit record of an otherwise
+// successful call could not be written. The chaos event is returned along
+// with the error, as it was triggered nevertheless.
+type AuditError struct {
+	// The error returned by the audit sink
+	Err error
+}
+
+func (e *AuditError) Error() string {
+	return fmt.Sprintf("cannot write audit record: %s", e.Err)
+}
+
+// audit writes the audit record of a call of TriggerEvent returning ev and
+// err, and returns the error to return from the call instead of err.
+func (c *Client) audit(ctx context.Context, r AuditRecord, ev *Event, err error) error {
+	if c.config.Audit == nil {
+		return err
+	}
+	r.Actor = c.config.Actor
+	r.Role = c.config.Role
+	r.User = c.config.Username
+	r.Reason = c.config.Reason
+	r.DryRun = c.config.DryRun
+	switch err.(type) {
+	case nil:
+		r.Outcome = AuditTriggered
+		if ev != nil {
+			if ev.DryRun {
+				r.Outcome = AuditSimulated
+			}
+			r.InstanceID = ev.InstanceID
+		}
+	case *GuardError, *OutageError, *StopError:
+		r.Outcome = AuditRefused
+	default:
+		r.Outcome = AuditFailed
+		if err == ErrHalted {
+			r.Outcome = AuditRefused
+		}
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if werr := c.config.Audit.WriteAudit(ctx, r); werr != nil && err == nil {
+		return &AuditError{Err: werr}
+	}
+	return err
+}
+
+// AuditWriter is an AuditSink writing audit records as JSON lines, e.g. to
+// a file opened for appending.
+type AuditWriter struct {
+	W io.Writer
+
+	mu sync.Mutex
+}
+
+// WriteAudit writes r as a line of JSON.
+func (w *AuditWriter) WriteAudit(ctx context.Context, r AuditRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.W.Write(append(line, '\n'))
+	return err
+}
+
+// DefaultAuditQuery is the statement used by SQLAuditSink unless configured
+// otherwise.
+const DefaultAuditQuery = `INSERT INTO chaosmonkey_audit
+	(time, actor, role, api_user, auto_scaling_group, strategy, region, reason, dry_run, outcome, error, instance_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// SQLAuditSink is an AuditSink inserting audit records into a database.
+type SQLAuditSink struct {
+	DB *sql.DB
+
+	// Statement inserting a record, which is passed the fields of the
+	// record in the order of DefaultAuditQuery (DefaultAuditQuery if
+	// empty). Drivers using other placeholders than "?", e.g. $1 for
+	// PostgreSQL, need a custom statement.
+	Query string
+}
+
+// WriteAudit inserts r into the database.
+func (s *SQLAuditSink) WriteAudit(ctx context.Context, r AuditRecord) error {
+	query := s.Query
+	if query == "" {
+		query = DefaultAuditQuery
+	}
+	_, err := s.DB.ExecContext(ctx, query,
+		r.Time, r.Actor, r.Role, r.User, r.AutoScalingGroupName, string(r.Strategy), r.Region,
+		r.Reason, r.DryRun, string(r.Outcome), r.Error, r.InstanceID)
+	return err
+}
@@ -0,0 +1,104 @@
+package chaosmonkey_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestAudit(t *testing.T) {
+	now := time.Date(2018, 12, 24, 10, 30, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Region:   "eu-west-1",
+		Username: "alice",
+		DryRun:   true,
+		Clock:    &fakeClock{now},
+		Audit:    &chaosmonkey.AuditWriter{W: &buf},
+		Actor:    "arn:aws:iam::111111111111:user/alice",
+		Role:     "arn:aws:iam::111111111111:user/alice",
+		Reason:   "game day",
+		Guards: []chaosmonkey.Guard{
+			chaosmonkey.GuardFunc(func(t chaosmonkey.Target) error {
+				if t.AutoScalingGroupName == "b" {
+					return errors.New("group b is frozen")
+				}
+				return nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.TriggerEvent("a", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.TriggerEvent("b", chaosmonkey.StrategyShutdownInstance); err == nil {
+		t.Fatal("expected guard to refuse chaos event")
+	}
+
+	var records []chaosmonkey.AuditRecord
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r chaosmonkey.AuditRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	base := chaosmonkey.AuditRecord{
+		Time:     now,
+		Actor:    "arn:aws:iam::111111111111:user/alice",
+		Role:     "arn:aws:iam::111111111111:user/alice",
+		User:     "alice",
+		Strategy: chaosmonkey.StrategyShutdownInstance,
+		Region:   "eu-west-1",
+		Reason:   "game day",
+		DryRun:   true,
+	}
+	simulated, refused := base, base
+	simulated.AutoScalingGroupName = "a"
+	simulated.Outcome = chaosmonkey.AuditSimulated
+	refused.AutoScalingGroupName = "b"
+	refused.Outcome = chaosmonkey.AuditRefused
+	refused.Error = "chaos event refused: group b is frozen"
+	if diff := cmp.Diff([]chaosmonkey.AuditRecord{simulated, refused}, records); diff != "" {
+		t.Errorf("unexpected audit records (-want +got):\n%s", diff)
+	}
+}
+
+func TestAuditError(t *testing.T) {
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun: true,
+		Audit: chaosmonkey.AuditSinkFunc(func(ctx context.Context, r chaosmonkey.AuditRecord) error {
+			return errors.New("disk full")
+		}),
+		Reason: "game day",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev, err := client.TriggerEvent("a", chaosmonkey.StrategyShutdownInstance)
+	if _, ok := err.(*chaosmonkey.AuditError); !ok {
+		t.Fatalf("expected *AuditError, got %v", err)
+	}
+	if ev == nil {
+		t.Error("expected triggered event to be returned")
+	}
+}
+
+func TestAuditNeedsReason(t *testing.T) {
+	_, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Audit: &chaosmonkey.AuditWriter{W: &bytes.Buffer{}},
+	})
+	if err == nil {
+		t.Error("expected error for audit log without reason")
+	}
+}
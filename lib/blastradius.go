@@ -0,0 +1,155 @@
+package chaosmonkey
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Windows over which BlastRadiusLimiter counts chaos events unless configured
+// otherwise.
+const (
+	DefaultGroupWindow = time.Hour
+	DefaultTotalWindow = 24 * time.Hour
+)
+
+// BlastRadiusLimiter is a Guard that limits how much chaos is caused, both
+// per auto scaling group and in total, within sliding windows. As TriggerEvent
+// checks guards, one limiter configured for a client covers single events,
+// campaigns, and schedules alike.
+//
+// The limiter counts the chaos events recorded on the buses it subscribed to,
+// including simulated ones. To count events triggered by other processes as
+// well, set History instead, e.g. to Client.EventsSince.
+type BlastRadiusLimiter struct {
+	// Maximum number of chaos events per auto scaling group within
+	// GroupWindow (unlimited if zero)
+	MaxPerGroup int
+
+	// Window of MaxPerGroup (DefaultGroupWindow if zero)
+	GroupWindow time.Duration
+
+	// Maximum number of chaos events in total within TotalWindow (unlimited
+	// if zero)
+	MaxTotal int
+
+	// Window of MaxTotal (DefaultTotalWindow if zero)
+	TotalWindow time.Duration
+
+	// Optional source of chaos events triggered since the given time. If
+	// set, events are counted from it instead of from the buses.
+	History func(since time.Time) ([]Event, error)
+
+	// Optional function called whenever an override lets a chaos event
+	// exceed the limits, e.g. to audit overrides
+	OnOverride func(t Target, reason string)
+
+	// Clock used to tell the current time (SystemClock if nil)
+	Clock Clock
+
+	history  eventHistory
+	mu       sync.Mutex
+	override string
+}
+
+// Subscribe counts the chaos events recorded on the bus. It returns a
+// function that stops counting.
+func (l *BlastRadiusLimiter) Subscribe(bus *Bus) (stop func()) {
+	return l.history.subscribe(bus, nil)
+}
+
+// Override lets chaos events exceed the limits until ClearOverride is
+// called. Overriding needs an explicit reason, which is passed to
+// OnOverride.
+func (l *BlastRadiusLimiter) Override(reason string) error {
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("override needs a reason")
+	}
+	l.mu.Lock()
+	l.override = reason
+	l.mu.Unlock()
+	return nil
+}
+
+// ClearOverride enforces the limits again.
+func (l *BlastRadiusLimiter) ClearOverride() {
+	l.mu.Lock()
+	l.override = ""
+	l.mu.Unlock()
+}
+
+// Check refuses chaos events that would exceed the limits, unless they are
+// overridden. It also refuses them if History fails.
+func (l *BlastRadiusLimiter) Check(t Target) error {
+	return l.check(t, l.OnOverride)
+}
+
+// Evaluate is like Check but does not call OnOverride.
+func (l *BlastRadiusLimiter) Evaluate(t Target) error {
+	return l.check(t, nil)
+}
+
+func (l *BlastRadiusLimiter) check(t Target, onOverride func(Target, string)) error {
+	if l.MaxPerGroup <= 0 && l.MaxTotal <= 0 {
+		return nil
+	}
+	groupWindow := l.GroupWindow
+	if groupWindow == 0 {
+		groupWindow = DefaultGroupWindow
+	}
+	totalWindow := l.TotalWindow
+	if totalWindow == 0 {
+		totalWindow = DefaultTotalWindow
+	}
+	now := t.Time
+	if now.IsZero() {
+		now = clockNow(l.Clock)
+	}
+	groupSince, totalSince := now.Add(-groupWindow), now.Add(-totalWindow)
+
+	since := earliest(groupSince, totalSince)
+	events, err := l.history.since(l.History, since, since)
+	if err != nil {
+		return fmt.Errorf("failed to get recent chaos events: %s", err)
+	}
+	var inGroup, total int
+	for _, ev := range events {
+		if ev.AutoScalingGroupName == t.AutoScalingGroupName && ev.TriggeredAt.After(groupSince) {
+			inGroup++
+		}
+		if ev.TriggeredAt.After(totalSince) {
+			total++
+		}
+	}
+
+	var exceeded error
+	switch {
+	case l.MaxPerGroup > 0 && inGroup >= l.MaxPerGroup:
+		exceeded = fmt.Errorf("group %s already had %d chaos event(s) within %s (limit %d)",
+			t.AutoScalingGroupName, inGroup, groupWindow, l.MaxPerGroup)
+	case l.MaxTotal > 0 && total >= l.MaxTotal:
+		exceeded = fmt.Errorf("already %d chaos event(s) within %s (limit %d)",
+			total, totalWindow, l.MaxTotal)
+	default:
+		return nil
+	}
+
+	l.mu.Lock()
+	reason := l.override
+	l.mu.Unlock()
+	if reason == "" {
+		return exceeded
+	}
+	if onOverride != nil {
+		onOverride(t, reason)
+	}
+	return nil
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,74 @@
+package chaosmonkey_test
+
+import (
+	"testing"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestBlastRadiusLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)}
+	bus := chaosmonkey.NewBus()
+	limiter := &chaosmonkey.BlastRadiusLimiter{
+		MaxPerGroup: 2,
+		MaxTotal:    3,
+		Clock:       clock,
+	}
+	limiter.Subscribe(bus)
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun: true,
+		Clock:  clock,
+		Bus:    bus,
+		Guards: []chaosmonkey.Guard{limiter},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigger := func(group string) error {
+		_, err := client.TriggerEvent(group, chaosmonkey.StrategyShutdownInstance)
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := trigger("payments-api"); err != nil {
+			t.Fatal(err)
+		}
+		clock.now = clock.now.Add(time.Minute)
+	}
+	err = trigger("payments-api")
+	if want := "chaos event refused: group payments-api already had 2 chaos event(s) within 1h0m0s (limit 2)"; err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+	if err := trigger("search-api"); err != nil {
+		t.Fatal(err)
+	}
+	err = trigger("search-api")
+	if want := "chaos event refused: already 3 chaos event(s) within 24h0m0s (limit 3)"; err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+
+	if err := limiter.Override(" "); err == nil {
+		t.Fatal("override without reason was accepted")
+	}
+	var overridden []string
+	limiter.OnOverride = func(t chaosmonkey.Target, reason string) {
+		overridden = append(overridden, t.AutoScalingGroupName+": "+reason)
+	}
+	if err := limiter.Override("game day"); err != nil {
+		t.Fatal(err)
+	}
+	if err := trigger("search-api"); err != nil {
+		t.Fatalf("overridden event was refused: %s", err)
+	}
+	if len(overridden) != 1 || overridden[0] != "search-api: game day" {
+		t.Fatalf("unexpected overrides: %v", overridden)
+	}
+	limiter.ClearOverride()
+
+	clock.now = clock.now.Add(24 * time.Hour)
+	if err := trigger("payments-api"); err != nil {
+		t.Fatalf("limits did not expire: %s", err)
+	}
+}
@@ -0,0 +1,129 @@
+package chaosmonkey
+
+import (
+	"sync"
+	"time"
+)
+
+// Topic identifies a kind of message published on a Bus.
+type Topic string
+
+// These are the topics published by the library.
+const (
+	// TopicTriggerRequested is published before a chaos event is triggered.
+	TopicTriggerRequested Topic = "trigger.requested"
+
+	// TopicGuardBlocked is published when a chaos event was refused before
+	// being triggered.
+	TopicGuardBlocked Topic = "guard.blocked"
+
+	// TopicEventRecorded is published after Chaos Monkey accepted a chaos
+	// event, or after a dry run produced a simulated one.
+	TopicEventRecorded Topic = "event.recorded"
+
+	// TopicTriggerFailed is published when triggering a chaos event failed
+	// after it passed all checks, e.g. because Chaos Monkey could not be
+	// reached.
+	TopicTriggerFailed Topic = "trigger.failed"
+
+	// TopicExperimentFinished is published when a series of chaos events
+	// run as one experiment has finished. Err is set if the experiment was
+	// aborted.
+	TopicExperimentFinished Topic = "experiment.finished"
+)
+
+// Message is a notification published on a Bus.
+type Message struct {
+	// Topic of the message
+	Topic Topic
+
+	// Time when the message was published
+	Time time.Time
+
+	// Name of the targeted auto scaling group, if any
+	AutoScalingGroupName string
+
+	// Chaos strategy involved, if any
+	Strategy Strategy
+
+	// AWS region involved, if any
+	Region string
+
+	// ID of the call of TriggerEvent the message is about, if any, which
+	// tells apart concurrent calls against the same group
+	Attempt uint64
+
+	// Request about to be sent to the API (TopicTriggerRequested only)
+	Request *APIRequest
+
+	// Recorded chaos event (TopicEventRecorded only)
+	Event *Event
+
+	// Reason why chaos was refused or failed, if any
+	Err error
+}
+
+// Handler is a function that receives messages from a Bus.
+type Handler func(Message)
+
+// Bus is a simple publish/subscribe bus used to notify other components
+// about what the client is doing. Handlers are called synchronously in the
+// order they subscribed. A nil *Bus discards all messages, and the zero value
+// is ready to use.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[Topic][]*subscription
+}
+
+type subscription struct {
+	handler Handler
+}
+
+// NewBus returns a new, empty Bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[Topic][]*subscription)}
+}
+
+// Subscribe registers a handler for the given topic. It returns a function
+// that removes the subscription again.
+func (b *Bus) Subscribe(topic Topic, h Handler) (unsubscribe func()) {
+	sub := &subscription{handler: h}
+
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = make(map[Topic][]*subscription)
+	}
+	b.subs[topic] = append(b.subs[topic], sub)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subs := b.subs[topic]
+		for i, s := range subs {
+			if s == sub {
+				b.subs[topic] = append(subs[:i:i], subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish delivers a message to all handlers subscribed to its topic. The
+// message time is set to the current time if it is zero.
+func (b *Bus) Publish(m Message) {
+	if b == nil {
+		return
+	}
+	if m.Time.IsZero() {
+		m.Time = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	subs := b.subs[m.Topic]
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		s.handler(m)
+	}
+}
@@ -0,0 +1,58 @@
+package chaosmonkey_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestBus(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	bus := chaosmonkey.NewBus()
+	var topics []chaosmonkey.Topic
+	record := func(m chaosmonkey.Message) { topics = append(topics, m.Topic) }
+	bus.Subscribe(chaosmonkey.TopicTriggerRequested, record)
+	unsubscribe := bus.Subscribe(chaosmonkey.TopicEventRecorded, record)
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint: ts.URL,
+		DryRun:   true,
+		Bus:      bus,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.TriggerEvent("SomeAutoScalingGroup", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatal(err)
+	}
+	unsubscribe()
+	if _, err := client.TriggerEvent("SomeAutoScalingGroup", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []chaosmonkey.Topic{
+		chaosmonkey.TopicTriggerRequested,
+		chaosmonkey.TopicEventRecorded,
+		chaosmonkey.TopicTriggerRequested,
+	}
+	if diff := cmp.Diff(expected, topics); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestBusZeroValue(t *testing.T) {
+	var bus chaosmonkey.Bus
+	var got []chaosmonkey.Topic
+	bus.Subscribe(chaosmonkey.TopicGuardBlocked, func(m chaosmonkey.Message) { got = append(got, m.Topic) })
+	bus.Publish(chaosmonkey.Message{Topic: chaosmonkey.TopicGuardBlocked})
+	if diff := cmp.Diff([]chaosmonkey.Topic{chaosmonkey.TopicGuardBlocked}, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,129 @@
+package chaosmonkey
+
+import (
+	"sync"
+	"time"
+)
+
+// eventsCache caches the results of event queries for a limited time and
+// deduplicates concurrent identical queries. Queries are bucketed by the TTL,
+// so that callers polling with a moving since time, e.g. "the last hour",
+// share one fetch whose events are filtered for each of them.
+type eventsCache struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	entries map[int64]cacheEntry
+	calls   map[int64]*cacheCall
+
+	// Incremented by invalidate, so that fetches started before are not
+	// cached
+	generation uint64
+}
+
+type cacheEntry struct {
+	events  []Event
+	expires time.Time
+}
+
+// cacheCall is a query in flight that other callers can wait for.
+type cacheCall struct {
+	done   chan struct{}
+	events []Event
+	err    error
+}
+
+func newEventsCache(ttl time.Duration, clock Clock) *eventsCache {
+	return &eventsCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[int64]cacheEntry),
+		calls:   make(map[int64]*cacheCall),
+	}
+}
+
+// bucket returns the start of the bucket of the since time, in milliseconds.
+func (c *eventsCache) bucket(since int64) int64 {
+	size := int64(c.ttl / time.Millisecond)
+	if size <= 0 || since <= 0 {
+		return since
+	}
+	return since - since%size
+}
+
+// get returns the events since the given time, in milliseconds, from any
+// cached query since an earlier time, or by calling fetch with the start of
+// its bucket. Concurrent callers in the same bucket share a single fetch.
+func (c *eventsCache) get(since int64, fetch func(since int64) ([]Event, error)) ([]Event, error) {
+	key := c.bucket(since)
+	c.mu.Lock()
+	now := c.clock.Now()
+	for k, e := range c.entries {
+		// Entries of earlier buckets include all events of later ones
+		if k <= since && now.Before(e.expires) {
+			c.mu.Unlock()
+			return eventsSince(e.events, since), nil
+		}
+	}
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return eventsSince(call.events, since), call.err
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.calls[key] = call
+	generation := c.generation
+	c.mu.Unlock()
+
+	call.events, call.err = fetch(key)
+
+	c.mu.Lock()
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+	if call.err == nil && c.generation == generation {
+		c.prune()
+		c.entries[key] = cacheEntry{
+			events:  call.events,
+			expires: c.clock.Now().Add(c.ttl),
+		}
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return eventsSince(call.events, since), call.err
+}
+
+// invalidate drops all cached entries, e.g. after a new event was triggered.
+// Fetches in flight are neither cached nor shared with later callers, as they
+// may miss the new event.
+func (c *eventsCache) invalidate() {
+	c.mu.Lock()
+	c.generation++
+	c.entries = make(map[int64]cacheEntry)
+	c.calls = make(map[int64]*cacheCall)
+	c.mu.Unlock()
+}
+
+// prune removes expired entries. It must be called with c.mu held.
+func (c *eventsCache) prune() {
+	now := c.clock.Now()
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// eventsSince returns a copy of the events triggered since the given time,
+// in milliseconds.
+func eventsSince(events []Event, since int64) []Event {
+	var filtered []Event
+	for _, ev := range events {
+		if toMillis(ev.TriggeredAt) >= since {
+			filtered = append(filtered, ev)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,221 @@
+package chaosmonkey
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Campaign is an ordered plan of chaos events, typically loaded from a file.
+type Campaign struct {
+	// Name of the campaign
+	Name string `json:"name" yaml:"name"`
+
+	// Steps to run one after another
+	Steps []CampaignStep `json:"steps" yaml:"steps"`
+
+	// Number of failed chaos events tolerated before the campaign is
+	// aborted (zero aborts on the first failure)
+	MaxFailures int `json:"maxFailures,omitempty" yaml:"maxFailures,omitempty"`
+
+	// Optional check run before each step to verify that the system still
+	// behaves as expected. If it returns an error, the campaign is aborted.
+	Check func() error `json:"-" yaml:"-"`
+
+	// Optional function called before each step, e.g. to report progress
+	OnStep func(index int, step CampaignStep) `json:"-" yaml:"-"`
+}
+
+// CampaignStep is a step of a campaign, which triggers the same chaos event
+// against one or more auto scaling groups.
+type CampaignStep struct {
+	// Name of the step
+	Name string `json:"name" yaml:"name"`
+
+	// Auto scaling groups to target
+	Groups []string `json:"groups" yaml:"groups"`
+
+	// AWS regions in which to target each group (the client's region if
+	// empty). Use an InFlightLimiter to keep chaos from hitting a group in
+	// several regions at once.
+	Regions []string `json:"regions,omitempty" yaml:"regions,omitempty"`
+
+	// Chaos strategy to use
+	Strategy Strategy `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+
+	// Number of chaos events per group (1 if zero)
+	Count int `json:"count,omitempty" yaml:"count,omitempty"`
+
+	// Time to wait between chaos events of the step
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"`
+
+	// Time to wait after the step, e.g. to let the system recover
+	Wait time.Duration `json:"wait,omitempty" yaml:"wait,omitempty"`
+}
+
+// CampaignResult describes what happened during a campaign.
+type CampaignResult struct {
+	Name     string       `json:"name" yaml:"name"`
+	Started  time.Time    `json:"started" yaml:"started"`
+	Finished time.Time    `json:"finished" yaml:"finished"`
+	Steps    []StepResult `json:"steps" yaml:"steps"`
+
+	// Why the campaign was aborted, if it was
+	Aborted string `json:"aborted,omitempty" yaml:"aborted,omitempty"`
+
+	// Whether the campaign was aborted because the check or an outage
+	// checker found the system misbehaving after chaos
+	CheckFailed bool `json:"checkFailed,omitempty" yaml:"checkFailed,omitempty"`
+}
+
+// StepResult describes what happened during a step of a campaign.
+type StepResult struct {
+	Name   string   `json:"name" yaml:"name"`
+	Events []Event  `json:"events" yaml:"events"`
+	Errors []string `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// Validate checks the campaign for errors and normalizes strategy names.
+func (cp *Campaign) Validate() error {
+	if len(cp.Steps) == 0 {
+		return fmt.Errorf("campaign needs at least one step")
+	}
+	for i := range cp.Steps {
+		step := &cp.Steps[i]
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step %d", i+1)
+		}
+		if len(step.Groups) == 0 {
+			return fmt.Errorf("%s: needs at least one auto scaling group", step.Name)
+		}
+		for _, region := range step.Regions {
+			if region == "" {
+				return fmt.Errorf("%s: regions must not be empty", step.Name)
+			}
+		}
+		if step.Count < 0 || step.Interval < 0 || step.Wait < 0 {
+			return fmt.Errorf("%s: count, interval, and wait must not be negative", step.Name)
+		}
+		if step.Strategy != "" {
+			s, err := ParseStrategy(string(step.Strategy))
+			if err != nil {
+				return fmt.Errorf("%s: %s", step.Name, err)
+			}
+			step.Strategy = s
+		}
+	}
+	return nil
+}
+
+// RunCampaign runs the steps of a campaign in order until all are done, the
+// context is canceled, or an abort condition is met, including outages
+// detected by the configured outage checkers and chaos being halted. It returns the result in any
+// case, along with an error if the campaign was aborted.
+func (c *Client) RunCampaign(ctx context.Context, cp *Campaign) (*CampaignResult, error) {
+	if err := cp.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.haltable(ctx)
+	defer cancel()
+
+	result := &CampaignResult{Name: cp.Name, Started: c.config.Clock.Now().UTC()}
+	defer func() {
+		result.Finished = c.config.Clock.Now().UTC()
+		m := Message{
+			Topic: TopicExperimentFinished,
+			Time:  result.Finished,
+		}
+		if result.Aborted != "" {
+			m.Err = fmt.Errorf("campaign %s aborted: %s", cp.Name, result.Aborted)
+		}
+		c.config.Bus.Publish(m)
+	}()
+	abort := func(format string, a ...interface{}) (*CampaignResult, error) {
+		result.Aborted = fmt.Sprintf(format, a...)
+		return result, fmt.Errorf("campaign aborted: %s", result.Aborted)
+	}
+
+	failures := 0
+	for i, step := range cp.Steps {
+		if err := c.checkStop(ctx); err != nil {
+			return abort("%s", err)
+		}
+		if err := c.checkOutages(ctx); err != nil {
+			result.CheckFailed = true
+			return abort("%s", err)
+		}
+		if cp.Check != nil {
+			if err := cp.Check(); err != nil {
+				result.CheckFailed = true
+				return abort("check failed before %s: %s", step.Name, err)
+			}
+		}
+		if cp.OnStep != nil {
+			cp.OnStep(i, step)
+		}
+
+		count := step.Count
+		if count == 0 {
+			count = 1
+		}
+		result.Steps = append(result.Steps, StepResult{Name: step.Name, Events: []Event{}})
+		sr := &result.Steps[len(result.Steps)-1]
+		regions := step.Regions
+		if len(regions) == 0 {
+			regions = []string{""}
+		}
+		first := true
+		for n := 0; n < count; n++ {
+			for _, group := range step.Groups {
+				for _, region := range regions {
+					if !first {
+						if err := c.sleepChecking(ctx, step.Interval); err != nil {
+							return abort("%s", err)
+						}
+					}
+					first = false
+					ev, err := c.TriggerEventInRegion(group, step.Strategy, region)
+					_, outage := err.(*OutageError)
+					_, stopped := err.(*StopError)
+					if outage || stopped || err == ErrHalted {
+						result.CheckFailed = outage
+						return abort("%s", err)
+					}
+					if err != nil {
+						target := group
+						if region != "" {
+							target += " in " + region
+						}
+						sr.Errors = append(sr.Errors, fmt.Sprintf("%s: %s", target, err))
+						if failures++; failures > cp.MaxFailures {
+							return abort("too many failed chaos events")
+						}
+						continue
+					}
+					sr.Events = append(sr.Events, *ev)
+				}
+			}
+		}
+
+		if err := c.sleepChecking(ctx, step.Wait); err != nil {
+			return abort("%s", err)
+		}
+	}
+	return result, nil
+}
+
+// sleep waits for duration d and reports whether it did so before the
+// context was canceled.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,85 @@
+package chaosmonkey_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestRunCampaign(t *testing.T) {
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun: true,
+		Guards: []chaosmonkey.Guard{chaosmonkey.GuardFunc(func(t chaosmonkey.Target) error {
+			if t.AutoScalingGroupName == "frozen" {
+				return errors.New("group is frozen")
+			}
+			return nil
+		})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var started []string
+	campaign := &chaosmonkey.Campaign{
+		Name: "test",
+		Steps: []chaosmonkey.CampaignStep{
+			{Name: "cpu", Groups: []string{"a", "b"}, Strategy: "burncpu"},
+			{Groups: []string{"frozen", "a"}, Count: 2},
+			{Name: "never", Groups: []string{"a"}},
+		},
+		MaxFailures: 1,
+		OnStep: func(i int, step chaosmonkey.CampaignStep) {
+			started = append(started, step.Name)
+		},
+	}
+
+	result, err := client.RunCampaign(context.Background(), campaign)
+	if err == nil {
+		t.Fatal("expected campaign to be aborted")
+	}
+	if diff := cmp.Diff([]string{"cpu", "step 2"}, started); diff != "" {
+		t.Error(diff)
+	}
+
+	var got [][]string
+	for _, s := range result.Steps {
+		var groups []string
+		for _, e := range s.Events {
+			groups = append(groups, e.AutoScalingGroupName+"/"+string(e.Strategy))
+		}
+		got = append(got, append(groups, s.Errors...))
+	}
+	expected := [][]string{
+		{"a/BurnCpu", "b/BurnCpu"},
+		{"a/", "frozen: chaos event refused: group is frozen", "frozen: chaos event refused: group is frozen"},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Error(diff)
+	}
+	if result.Aborted != "too many failed chaos events" {
+		t.Errorf("unexpected abort reason %q", result.Aborted)
+	}
+}
+
+func TestRunCampaignCheck(t *testing.T) {
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	campaign := &chaosmonkey.Campaign{
+		Steps: []chaosmonkey.CampaignStep{{Groups: []string{"a"}}},
+		Check: func() error { return errors.New("unhealthy") },
+	}
+	result, err := client.RunCampaign(context.Background(), campaign)
+	if err == nil || len(result.Steps) != 0 {
+		t.Fatalf("expected campaign to be aborted before first step, got %v", err)
+	}
+	if !result.CheckFailed {
+		t.Error("expected failed check to be recorded")
+	}
+}
@@ -30,13 +30,20 @@ package chaosmonkey
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // API constants
@@ -73,26 +80,33 @@ func (resp *APIResponse) ToEvent() *Event {
 		AutoScalingGroupName: resp.GroupName,
 		Region:               resp.Region,
 		Strategy:             Strategy(resp.ChaosType),
-		TriggeredAt:          time.Unix(resp.EventTime/1000, 0).UTC(),
+		TriggeredAt:          fromMillis(resp.EventTime),
 	}
 }
 
 // Event describes the termination of an EC2 instance by Chaos Monkey.
 type Event struct {
 	// ID of EC2 instance that was terminated
-	InstanceID string
+	InstanceID string `json:"instanceId" yaml:"instanceId"`
 
 	// Name of auto scaling group containing the terminated instance
-	AutoScalingGroupName string
+	AutoScalingGroupName string `json:"autoScalingGroupName" yaml:"autoScalingGroupName"`
 
 	// AWS region of the instance and its auto scaling group
-	Region string
+	Region string `json:"region" yaml:"region"`
 
 	// Chaos strategy used to terminate the instance
-	Strategy Strategy
+	Strategy Strategy `json:"strategy" yaml:"strategy"`
 
 	// Time when the chaos event was triggered
-	TriggeredAt time.Time
+	TriggeredAt time.Time `json:"triggeredAt" yaml:"triggeredAt"`
+
+	// Whether the event was only simulated and not sent to Chaos Monkey
+	DryRun bool `json:"dryRun,omitempty" yaml:"dryRun,omitempty"`
+
+	// Details of the instance, if the client is configured to enrich events
+	// and the instance is still known
+	Instance *InstanceDetails `json:"instance,omitempty" yaml:"instance,omitempty"`
 }
 
 // Config is used to configure the creation of the client.
@@ -114,6 +128,87 @@ type Config struct {
 
 	// Custom HTTP client to use (http.DefaultClient by default)
 	HTTPClient *http.Client
+
+	// Optional Prometheus registerer used to expose client metrics
+	MetricsRegisterer prometheus.Registerer
+
+	// Optional OpenTelemetry tracer provider used to trace API calls
+	TracerProvider trace.TracerProvider
+
+	// If true, TriggerEvent does not send any request to Chaos Monkey but
+	// returns a simulated event instead
+	DryRun bool
+
+	// Optional time to cache results of Events and EventsSince (no caching
+	// by default)
+	EventsCacheTTL time.Duration
+
+	// Optional version and capabilities of the Chaos Monkey deployment,
+	// which the client otherwise detects on first use (see
+	// Client.ServerInfo)
+	ServerInfo *ServerInfo
+
+	// Optional bus to publish notifications about triggered events to
+	Bus *Bus
+
+	// Clock used to tell the current time (SystemClock by default)
+	Clock Clock
+
+	// Optional guards to check before triggering a chaos event, also in
+	// dry-run mode
+	Guards []Guard
+
+	// Optional checkers consulted before each chaos event and while
+	// campaigns run, which halt chaos if they detect an outage
+	OutageCheckers []OutageChecker
+
+	// How often outage checkers are consulted while campaigns wait
+	// (DefaultOutageCheckInterval if zero)
+	OutageCheckInterval time.Duration
+
+	// Optional kill switch shared with other clients (one per client if
+	// nil)
+	HaltSwitch *HaltSwitch
+
+	// Optional sentinels checked before each chaos event and while
+	// campaigns run, which halt chaos if an emergency stop was requested
+	StopSentinels []StopSentinel
+
+	// Optional patterns of auto scaling group names that chaos events may
+	// target, given as shell patterns like "search-*" or as regular
+	// expressions enclosed in slashes like "/^search-(api|web)$/". If set,
+	// chaos events against other groups are refused.
+	AllowGroups []string
+
+	// Optional patterns of auto scaling group names that chaos events must
+	// never target, which take precedence over AllowGroups
+	DenyGroups []string
+
+	// Optional enricher to add instance details to the results of Events
+	// and EventsSince (no enrichment by default)
+	EnrichEvents EventEnricher
+
+	// Optional backend causing the chaos events of TriggerEvent and
+	// TriggerEventInRegion instead of Chaos Monkey, which is still asked for
+	// past events
+	Backend Backend
+
+	// Optional sink recording every call of TriggerEvent in an audit log
+	Audit AuditSink
+
+	// Who triggers chaos events, as passed to guards and recorded in the
+	// audit log. Guards like Approvals rely on it, so it should be an
+	// authenticated identity like the ARN of an AWS identity rather than a
+	// name chosen by the user.
+	Actor string
+
+	// Role of the actor, e.g. the ARN of an AWS identity, as recorded in
+	// the audit log
+	Role string
+
+	// Why chaos events are triggered, as recorded in the audit log
+	// (required if Audit is set)
+	Reason string
 }
 
 // DefaultConfig returns a default configuration for the client. It parses the
@@ -124,6 +219,7 @@ func DefaultConfig() *Config {
 		Endpoint:   "http://127.0.0.1:8080",
 		UserAgent:  "chaosmonkey Go library",
 		HTTPClient: http.DefaultClient,
+		Clock:      SystemClock{},
 	}
 	if v := os.Getenv("CHAOSMONKEY_ENDPOINT"); v != "" {
 		c.Endpoint = v
@@ -139,7 +235,16 @@ func DefaultConfig() *Config {
 
 // Client is the client to the Chaos Monkey API. Create a client with NewClient.
 type Client struct {
-	config *Config
+	config  *Config
+	metrics *metrics
+	tracer  trace.Tracer
+	cache   *eventsCache
+	groups  *groupPolicy
+
+	mu          sync.Mutex
+	info        *ServerInfo
+	probeMu     sync.Mutex // held while probing the deployment
+	probeFailed time.Time  // guarded by probeMu
 }
 
 // NewClient returns a new client for the given configuration.
@@ -163,32 +268,280 @@ func NewClient(c *Config) (*Client, error) {
 	if c.HTTPClient == nil {
 		c.HTTPClient = defConfig.HTTPClient
 	}
-	return &Client{config: c}, nil
+	if c.Clock == nil {
+		c.Clock = defConfig.Clock
+	}
+	if c.HaltSwitch == nil {
+		c.HaltSwitch = &HaltSwitch{}
+	}
+	if c.Audit != nil && c.Reason == "" {
+		return nil, fmt.Errorf("audit log needs a reason")
+	}
+	client := &Client{config: c}
+	groups, err := newGroupPolicy(c.AllowGroups, c.DenyGroups)
+	if err != nil {
+		return nil, err
+	}
+	client.groups = groups
+	if c.MetricsRegisterer != nil {
+		m, err := newMetrics(c.MetricsRegisterer)
+		if err != nil {
+			return nil, err
+		}
+		client.metrics = m
+	}
+	if c.TracerProvider != nil {
+		client.tracer = c.TracerProvider.Tracer(tracerName)
+	}
+	if c.EventsCacheTTL > 0 {
+		client.cache = newEventsCache(c.EventsCacheTTL, c.Clock)
+	}
+	client.info = c.ServerInfo
+	return client, nil
 }
 
 // TriggerEvent triggers a new chaos event which will cause Chaos Monkey to
 // "break" an EC2 instance in the given auto scaling group using the specified
 // chaos strategy.
+//
+// Before the event is triggered, the group is checked against
+// Config.AllowGroups and Config.DenyGroups, and all configured guards are
+// checked. If any of them refuses the event, a *GuardError is returned. If an
+// outage checker then detects an outage, an *OutageError is returned.
+//
+// While chaos is halted via HaltAll, ErrHalted is returned, and while a stop
+// sentinel reports an emergency stop, a *StopError is returned. Halting chaos
+// also cancels the request if it is still running, which returns ErrHalted.
+//
+// If an audit sink is configured, every call is recorded with its outcome.
+// An *AuditError is returned if the record of a successful call could not be
+// written.
+//
+// If the client is configured for a dry run, the request is prepared but not
+// sent, and the returned event has DryRun set.
+//
+// If a backend is configured, it causes the chaos event instead of Chaos
+// Monkey, as if passed to Inject.
 func (c *Client) TriggerEvent(group string, strategy Strategy) (*Event, error) {
-	url := c.config.Endpoint + APIPath
+	return c.TriggerEventInRegion(group, strategy, "")
+}
+
+// TriggerEventInRegion is like TriggerEvent but targets the auto scaling
+// group in the given AWS region instead of the one configured for the client.
+// An empty region falls back to the configured one.
+func (c *Client) TriggerEventInRegion(group string, strategy Strategy, region string) (*Event, error) {
+	if b := c.config.Backend; b != nil {
+		if region == "" {
+			region = c.config.Region
+		}
+		return c.Inject(context.Background(), group, strategy, region, func(ctx context.Context) (*Event, error) {
+			return b.Inject(ctx, group, strategy, region)
+		})
+	}
+	return c.trigger(context.Background(), group, strategy, region, func(ctx context.Context, req *APIRequest) (*Event, error) {
+		if err := c.adjustRequest(ctx, req); err != nil {
+			return nil, err
+		}
+		body, err := json.Marshal(req)
+		if err != nil {
+			return nil, err
+		}
+		var resp APIResponse
+		if err := c.sendRequest(ctx, "POST", c.config.Endpoint+APIPath, bytes.NewReader(body), &resp); err != nil {
+			return nil, err
+		}
+		c.metrics.observeTrigger(strategy)
+		if c.cache != nil {
+			c.cache.invalidate()
+		}
+		return resp.ToEvent(), nil
+	})
+}
+
+// Inject causes a chaos event against the auto scaling group by calling
+// inject rather than Chaos Monkey, e.g. for chaos injected via AWS APIs. Like
+// TriggerEventInRegion, it is subject to the kill switch, stop sentinels,
+// guards, and outage checkers, publishes the chaos event on the bus, and
+// records it in the audit log. inject is only called if all checks pass, and
+// not in a dry run. It returns the event caused, e.g. one with the ID of the
+// affected instance, or nil; group, strategy, region (the configured region if
+// empty), and time are filled in if missing.
+func (c *Client) Inject(ctx context.Context, group string, strategy Strategy, region string, inject func(ctx context.Context) (*Event, error)) (*Event, error) {
+	return c.trigger(ctx, group, strategy, region, func(ctx context.Context, req *APIRequest) (*Event, error) {
+		ev, err := inject(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if ev == nil {
+			ev = &Event{}
+		}
+		if ev.AutoScalingGroupName == "" {
+			ev.AutoScalingGroupName = group
+		}
+		if ev.Strategy == "" {
+			ev.Strategy = strategy
+		}
+		if ev.Region == "" {
+			ev.Region = req.Region
+		}
+		if ev.TriggeredAt.IsZero() {
+			ev.TriggeredAt = c.config.Clock.Now().UTC()
+		}
+		return ev, nil
+	})
+}
+
+// Backend causes chaos events in place of Chaos Monkey, e.g. via a plugin.
+// Configure a backend via Config.Backend to have TriggerEvent use it.
+type Backend interface {
+	// Inject causes a chaos event against the auto scaling group in the
+	// given region and returns the event caused, or nil, like the inject
+	// function passed to Client.Inject.
+	Inject(ctx context.Context, group string, strategy Strategy, region string) (*Event, error)
+}
+
+// trigger checks a chaos event and causes it by calling inject with the
+// request describing it.
+func (c *Client) trigger(ctx context.Context, group string, strategy Strategy, region string, inject func(ctx context.Context, req *APIRequest) (*Event, error)) (ev *Event, err error) {
+	if region == "" {
+		region = c.config.Region
+	}
+
+	ctx, span := c.startSpan(ctx, "chaosmonkey.TriggerEvent",
+		attribute.String("chaosmonkey.group", group),
+		attribute.String("chaosmonkey.strategy", string(strategy)),
+		attribute.String("chaosmonkey.region", region),
+		attribute.Bool("chaosmonkey.dry_run", c.config.DryRun),
+	)
+	defer func() { endSpan(span, err) }()
+
+	record := AuditRecord{
+		Time:                 c.config.Clock.Now().UTC(),
+		AutoScalingGroupName: group,
+		Strategy:             strategy,
+		Region:               region,
+	}
+	defer func() { err = c.audit(ctx, record, ev, err) }()
+
+	if group == "" {
+		return nil, fmt.Errorf("auto scaling group must not be empty")
+	}
+	attempt := atomic.AddUint64(&attempts, 1)
+	if c.config.HaltSwitch.IsHalted() {
+		c.config.Bus.Publish(Message{
+			Topic:                TopicGuardBlocked,
+			Time:                 c.config.Clock.Now().UTC(),
+			AutoScalingGroupName: group,
+			Strategy:             strategy,
+			Region:               region,
+			Attempt:              attempt,
+			Err:                  ErrHalted,
+		})
+		return nil, ErrHalted
+	}
+	if err := c.checkStop(ctx); err != nil {
+		c.config.Bus.Publish(Message{
+			Topic:                TopicGuardBlocked,
+			Time:                 c.config.Clock.Now().UTC(),
+			AutoScalingGroupName: group,
+			Strategy:             strategy,
+			Region:               region,
+			Attempt:              attempt,
+			Err:                  err,
+		})
+		return nil, err
+	}
 
-	body, err := json.Marshal(APIRequest{
+	if err := c.checkGuards(Target{
+		AutoScalingGroupName: group,
+		Strategy:             strategy,
+		Region:               region,
+		Time:                 c.config.Clock.Now().UTC(),
+		Requester:            c.config.Actor,
+		Attempt:              attempt,
+	}); err != nil {
+		return nil, err
+	}
+	if err := c.checkOutages(ctx); err != nil {
+		c.config.Bus.Publish(Message{
+			Topic:                TopicGuardBlocked,
+			Time:                 c.config.Clock.Now().UTC(),
+			AutoScalingGroupName: group,
+			Strategy:             strategy,
+			Region:               region,
+			Attempt:              attempt,
+			Err:                  err,
+		})
+		return nil, err
+	}
+
+	req := APIRequest{
 		EventType: "CHAOS_TERMINATION",
 		GroupType: "ASG",
 		GroupName: group,
 		ChaosType: string(strategy),
-		Region:    c.config.Region,
+		Region:    region,
+	}
+
+	c.config.Bus.Publish(Message{
+		Topic:                TopicTriggerRequested,
+		Time:                 c.config.Clock.Now().UTC(),
+		AutoScalingGroupName: group,
+		Strategy:             strategy,
+		Region:               region,
+		Attempt:              attempt,
+		Request:              &req,
 	})
-	if err != nil {
-		return nil, err
+
+	if c.config.DryRun {
+		ev = &Event{
+			AutoScalingGroupName: group,
+			Region:               region,
+			Strategy:             strategy,
+			TriggeredAt:          c.config.Clock.Now().UTC(),
+			DryRun:               true,
+		}
+		c.publishEvent(ev, attempt)
+		return ev, nil
 	}
 
-	var resp APIResponse
-	if err := c.sendRequest("POST", url, bytes.NewReader(body), &resp); err != nil {
+	// Halting chaos cancels the request if it is still running
+	injectCtx, cancel := c.haltable(ctx)
+	defer cancel()
+	ev, err = inject(injectCtx, &req)
+	if err != nil {
+		if c.config.HaltSwitch.IsHalted() {
+			err = ErrHalted
+		}
+		c.config.Bus.Publish(Message{
+			Topic:                TopicTriggerFailed,
+			Time:                 c.config.Clock.Now().UTC(),
+			AutoScalingGroupName: group,
+			Strategy:             strategy,
+			Region:               region,
+			Attempt:              attempt,
+			Err:                  err,
+		})
 		return nil, err
 	}
+	c.publishEvent(ev, attempt)
+	return ev, nil
+}
 
-	return resp.ToEvent(), nil
+// attempts counts the calls of TriggerEvent of all clients, which share
+// buses and guards, to number them.
+var attempts uint64
+
+func (c *Client) publishEvent(ev *Event, attempt uint64) {
+	c.config.Bus.Publish(Message{
+		Topic:                TopicEventRecorded,
+		Time:                 c.config.Clock.Now().UTC(),
+		AutoScalingGroupName: ev.AutoScalingGroupName,
+		Strategy:             ev.Strategy,
+		Region:               ev.Region,
+		Attempt:              attempt,
+		Event:                ev,
+	})
 }
 
 // Events returns a list of all chaos events.
@@ -198,54 +551,115 @@ func (c *Client) Events() ([]Event, error) {
 
 // EventsSince returns a list of all chaos events since a specific time.
 func (c *Client) EventsSince(t time.Time) ([]Event, error) {
-	return c.events(t.UTC().Unix() * 1000)
+	return c.events(toMillis(t))
+}
+
+func (c *Client) events(since int64) (events []Event, err error) {
+	ctx, span := c.startSpan(context.Background(), "chaosmonkey.Events",
+		attribute.String("chaosmonkey.region", c.config.Region),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if c.cache != nil {
+		return c.cache.get(since, func(since int64) ([]Event, error) {
+			return c.fetchEvents(ctx, since)
+		})
+	}
+	return c.fetchEvents(ctx, since)
 }
 
-func (c *Client) events(since int64) ([]Event, error) {
+func (c *Client) fetchEvents(ctx context.Context, since int64) ([]Event, error) {
 	url := fmt.Sprintf("%s%s?since=%d", c.config.Endpoint, APIPath, since)
 
 	var resp []APIResponse
-	if err := c.sendRequest("GET", url, nil, &resp); err != nil {
+	if err := c.sendRequest(ctx, "GET", url, nil, &resp); err != nil {
 		return nil, err
 	}
 
+	// Filter events in case the server does not honor "since", which is
+	// cheaper than detecting whether it does
 	var events []Event
 	for _, r := range resp {
+		if r.EventTime < since {
+			c.ignoresSince()
+			continue
+		}
 		events = append(events, *r.ToEvent())
 	}
 
+	if c.config.EnrichEvents != nil && len(events) > 0 {
+		if err := c.config.EnrichEvents.EnrichEvents(ctx, events); err != nil {
+			return nil, fmt.Errorf("failed to enrich events: %s", err)
+		}
+	}
+
 	return events, nil
 }
 
-func (c *Client) sendRequest(method, url string, body io.Reader, out interface{}) error {
-	req, err := http.NewRequest(method, url, body)
+func (c *Client) sendRequest(ctx context.Context, method, url string, body io.Reader, out interface{}) error {
+	resp, err := c.do(ctx, method, url, body)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// do sends an HTTP request to the API. The caller must close the response
+// body.
+func (c *Client) do(ctx context.Context, method, url string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	injectTraceHeaders(ctx, req)
 
 	if c.config.Username != "" && c.config.Password != "" {
 		req.SetBasicAuth(c.config.Username, c.config.Password)
 	}
 	req.Header.Add("User-Agent", c.config.UserAgent)
 
+	start := time.Now()
 	resp, err := c.config.HTTPClient.Do(req)
 	if err != nil {
-		return err
+		c.metrics.observeRequest(method, 0, time.Since(start))
+		return nil, err
 	}
-	defer resp.Body.Close()
+	c.metrics.observeRequest(method, resp.StatusCode, time.Since(start))
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 
-	if resp.StatusCode != http.StatusOK {
-		return decodeError(resp)
-	}
+	return resp, nil
+}
 
-	return json.NewDecoder(resp.Body).Decode(out)
+// APIError is returned if the API responds with an error.
+type APIError struct {
+	// HTTP status code of the response
+	StatusCode int
+
+	// Error message returned by the API, if any
+	Message string
+
+	status string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("HTTP error: %s", e.status)
 }
 
 func decodeError(resp *http.Response) error {
 	var r APIResponse
-	err := json.NewDecoder(resp.Body).Decode(&r)
-	if err == nil && r.Message != "" {
-		return fmt.Errorf("%s", r.Message)
+	e := &APIError{StatusCode: resp.StatusCode, status: resp.Status}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err == nil {
+		e.Message = r.Message
 	}
-	return fmt.Errorf("HTTP error: %s", resp.Status)
+	return e
 }
@@ -1,17 +1,20 @@
 package chaosmonkey_test
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 
-	chaosmonkey "github.com/mlafeldt/chaosmonkey/lib"
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
 )
 
 const newEvent = `
@@ -126,3 +129,373 @@ func TestEvents(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func TestTriggerEventDryRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request in dry-run mode: %s %s", r.Method, r.URL)
+	}))
+	defer ts.Close()
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint: ts.URL,
+		Region:   "eu-west-1",
+		DryRun:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := client.TriggerEvent("SomeAutoScalingGroup", chaosmonkey.StrategyShutdownInstance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !event.DryRun {
+		t.Error("expected event to be marked as dry run")
+	}
+	if event.AutoScalingGroupName != "SomeAutoScalingGroup" || event.Region != "eu-west-1" ||
+		event.Strategy != chaosmonkey.StrategyShutdownInstance {
+		t.Errorf("unexpected event: %+v", event)
+	}
+}
+
+func TestEventsCache(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, pastEvents)
+	}))
+	defer ts.Close()
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint:       ts.URL,
+		EventsCacheTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		events, err := client.Events()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 2 {
+			t.Fatalf("expected 2 events, got %d", len(events))
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestEventsCacheMovingSince(t *testing.T) {
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("since"))
+		fmt.Fprint(w, pastEvents)
+	}))
+	defer ts.Close()
+
+	clock := &fakeClock{now: time.Unix(1460117000, 0)}
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint:       ts.URL,
+		EventsCacheTTL: time.Minute,
+		Clock:          clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Like a dashboard polling the events of the last 100 seconds
+	for _, want := range []int{1, 1, 0} {
+		events, err := client.EventsSince(clock.now.Add(-100 * time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != want {
+			t.Fatalf("at %s: expected %d events, got %d", clock.now, want, len(events))
+		}
+		clock.now = clock.now.Add(15 * time.Second)
+	}
+	if diff := cmp.Diff([]string{"1460116860000"}, queries); diff != "" {
+		t.Errorf("unexpected queries (-want +got):\n%s", diff)
+	}
+}
+
+func TestEventsCacheInvalidatedDuringFetch(t *testing.T) {
+	var gets int32
+	arrived := make(chan struct{})
+	unblock := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			fmt.Fprint(w, newEvent)
+			return
+		}
+		if atomic.AddInt32(&gets, 1) == 1 {
+			close(arrived)
+			<-unblock
+		}
+		fmt.Fprint(w, pastEvents)
+	}))
+	defer ts.Close()
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint:       ts.URL,
+		EventsCacheTTL: time.Minute,
+		ServerInfo:     &chaosmonkey.ServerInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := client.Events()
+		done <- err
+	}()
+	<-arrived
+	if _, err := client.TriggerEvent("SomeAutoScalingGroup", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatal(err)
+	}
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Events(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&gets); n != 2 {
+		t.Errorf("expected events fetched before the chaos event not to be cached, got %d request(s)", n)
+	}
+}
+
+func TestEventsEnriched(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, pastEvents)
+	}))
+	defer ts.Close()
+
+	details := &chaosmonkey.InstanceDetails{AvailabilityZone: "eu-west-1a", InstanceType: "m5.large"}
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint: ts.URL,
+		EnrichEvents: chaosmonkey.EventEnricherFunc(func(ctx context.Context, events []chaosmonkey.Event) error {
+			for i := range events {
+				if events[i].InstanceID == "i-12345678" {
+					events[i].Instance = details
+				}
+			}
+			return nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := client.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if diff := cmp.Diff(details, events[0].Instance); diff != "" {
+		t.Errorf("unexpected instance details (-want +got):\n%s", diff)
+	}
+	if events[1].Instance != nil {
+		t.Errorf("expected no instance details, got %+v", events[1].Instance)
+	}
+}
+
+func TestTriggerEventInRegion(t *testing.T) {
+	var regions []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chaosmonkey.APIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		regions = append(regions, req.Region)
+		fmt.Fprint(w, newEvent)
+	}))
+	defer ts.Close()
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint:   ts.URL,
+		Region:     "eu-west-1",
+		ServerInfo: &chaosmonkey.ServerInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.TriggerEvent("SomeAutoScalingGroup", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.TriggerEventInRegion("SomeAutoScalingGroup", chaosmonkey.StrategyShutdownInstance, "us-east-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"eu-west-1", "us-east-1"}, regions); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestInject(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request for injected chaos: %s %s", r.Method, r.URL)
+	}))
+	defer ts.Close()
+
+	now := time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)
+	var records []chaosmonkey.AuditRecord
+	bus := chaosmonkey.NewBus()
+	var topics []chaosmonkey.Topic
+	for _, topic := range []chaosmonkey.Topic{chaosmonkey.TopicGuardBlocked, chaosmonkey.TopicTriggerFailed, chaosmonkey.TopicEventRecorded} {
+		bus.Subscribe(topic, func(m chaosmonkey.Message) { topics = append(topics, m.Topic) })
+	}
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint: ts.URL,
+		Region:   "eu-west-1",
+		Clock:    &fakeClock{now},
+		Bus:      bus,
+		Reason:   "game day",
+		Audit: chaosmonkey.AuditSinkFunc(func(ctx context.Context, r chaosmonkey.AuditRecord) error {
+			records = append(records, r)
+			return nil
+		}),
+		Guards: []chaosmonkey.Guard{
+			chaosmonkey.GuardFunc(func(t chaosmonkey.Target) error {
+				if t.AutoScalingGroupName == "frozen" {
+					return fmt.Errorf("group frozen is frozen")
+				}
+				return nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	injected := 0
+	inject := func(ctx context.Context) (*chaosmonkey.Event, error) {
+		injected++
+		return &chaosmonkey.Event{InstanceID: "i-1"}, nil
+	}
+	ev, err := client.Inject(context.Background(), "payments-api", chaosmonkey.StrategyBlockAllNetworkTraffic, "", inject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &chaosmonkey.Event{
+		AutoScalingGroupName: "payments-api",
+		InstanceID:           "i-1",
+		Region:               "eu-west-1",
+		Strategy:             chaosmonkey.StrategyBlockAllNetworkTraffic,
+		TriggeredAt:          now,
+	}
+	if diff := cmp.Diff(want, ev); diff != "" {
+		t.Errorf("unexpected event (-want +got):\n%s", diff)
+	}
+	if _, err := client.Inject(context.Background(), "frozen", chaosmonkey.StrategyBlockAllNetworkTraffic, "", inject); err == nil {
+		t.Error("expected guard to refuse injected chaos")
+	}
+	if injected != 1 {
+		t.Errorf("expected chaos to be injected once, got %d", injected)
+	}
+	_, err = client.Inject(context.Background(), "payments-api", chaosmonkey.StrategyDetachVolumes, "", func(ctx context.Context) (*chaosmonkey.Event, error) {
+		return nil, fmt.Errorf("volume busy")
+	})
+	if err == nil || err.Error() != "volume busy" {
+		t.Errorf("expected error of injection, got %v", err)
+	}
+
+	var outcomes []string
+	for _, r := range records {
+		outcomes = append(outcomes, fmt.Sprintf("%s %s %s", r.AutoScalingGroupName, r.Outcome, r.InstanceID))
+	}
+	if diff := cmp.Diff([]string{"payments-api triggered i-1", "frozen refused ", "payments-api failed "}, outcomes); diff != "" {
+		t.Errorf("unexpected audit records (-want +got):\n%s", diff)
+	}
+	wantTopics := []chaosmonkey.Topic{chaosmonkey.TopicEventRecorded, chaosmonkey.TopicGuardBlocked, chaosmonkey.TopicTriggerFailed}
+	if diff := cmp.Diff(wantTopics, topics); diff != "" {
+		t.Errorf("unexpected messages (-want +got):\n%s", diff)
+	}
+}
+
+// backendFunc is a chaosmonkey.Backend calling itself.
+type backendFunc func(ctx context.Context, group string, strategy chaosmonkey.Strategy, region string) (*chaosmonkey.Event, error)
+
+func (f backendFunc) Inject(ctx context.Context, group string, strategy chaosmonkey.Strategy, region string) (*chaosmonkey.Event, error) {
+	return f(ctx, group, strategy, region)
+}
+
+func TestBackend(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request with backend: %s %s", r.Method, r.URL)
+	}))
+	defer ts.Close()
+
+	now := time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)
+	var calls []string
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint: ts.URL,
+		Region:   "eu-west-1",
+		Clock:    &fakeClock{now},
+		Backend: backendFunc(func(ctx context.Context, group string, strategy chaosmonkey.Strategy, region string) (*chaosmonkey.Event, error) {
+			calls = append(calls, fmt.Sprintf("%s %s %s", group, strategy, region))
+			return &chaosmonkey.Event{InstanceID: "i-1"}, nil
+		}),
+		DenyGroups: []string{"frozen"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev, err := client.TriggerEvent("payments-api", chaosmonkey.StrategyShutdownInstance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &chaosmonkey.Event{
+		AutoScalingGroupName: "payments-api",
+		InstanceID:           "i-1",
+		Region:               "eu-west-1",
+		Strategy:             chaosmonkey.StrategyShutdownInstance,
+		TriggeredAt:          now,
+	}
+	if diff := cmp.Diff(want, ev); diff != "" {
+		t.Errorf("unexpected event (-want +got):\n%s", diff)
+	}
+	if _, err := client.TriggerEventInRegion("payments-api", chaosmonkey.StrategyBurnCPU, "us-east-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.TriggerEvent("frozen", chaosmonkey.StrategyShutdownInstance); err == nil {
+		t.Error("expected denied group to be refused")
+	}
+	wantCalls := []string{"payments-api ShutdownInstance eu-west-1", "payments-api BurnCpu us-east-1"}
+	if diff := cmp.Diff(wantCalls, calls); diff != "" {
+		t.Errorf("unexpected backend calls (-want +got):\n%s", diff)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "ChaosMonkey is leashed"}`)
+	}))
+	defer ts.Close()
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{Endpoint: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.TriggerEvent("SomeAutoScalingGroup", chaosmonkey.StrategyShutdownInstance)
+
+	apiErr, ok := err.(*chaosmonkey.APIError)
+	if !ok {
+		t.Fatalf("expected *APIError, got %T: %v", err, err)
+	}
+	if apiErr.StatusCode != http.StatusForbidden || apiErr.Error() != "ChaosMonkey is leashed" {
+		t.Errorf("unexpected error: %d %s", apiErr.StatusCode, apiErr)
+	}
+}
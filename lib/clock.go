@@ -0,0 +1,46 @@
+package chaosmonkey
+
+import "time"
+
+// Clock tells the current time. Set Config.Clock to control time in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is a Clock that returns the system time.
+type SystemClock struct{}
+
+// Now returns the current system time.
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// After waits for the duration to elapse and then sends the current time on
+// the returned channel.
+func (SystemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Timer is implemented by clocks that can also wait, like SystemClock or
+// fake clocks in tests. Soak tests wait with Config.Clock if it implements
+// Timer, and with the system clock otherwise.
+type Timer interface {
+	After(d time.Duration) <-chan time.Time
+}
+
+// clockAfter waits for the duration with the clock if it is a Timer, and
+// with the system clock otherwise.
+func clockAfter(clock Clock, d time.Duration) <-chan time.Time {
+	if t, ok := clock.(Timer); ok {
+		return t.After(d)
+	}
+	return time.After(d)
+}
+
+// toMillis converts a time to the millisecond timestamps used by the API,
+// truncated to seconds like the times returned by fromMillis.
+func toMillis(t time.Time) int64 {
+	return t.UTC().Unix() * 1000
+}
+
+// fromMillis converts a millisecond timestamp used by the API to a time in
+// UTC, truncated to seconds.
+func fromMillis(ms int64) time.Time {
+	return time.Unix(ms/1000, 0).UTC()
+}
@@ -0,0 +1,32 @@
+package chaosmonkey
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMillisConversion(t *testing.T) {
+	tests := []struct {
+		ms int64
+		t  time.Time
+	}{
+		{0, time.Unix(0, 0).UTC()},
+		{1460116927000, time.Date(2016, 4, 8, 12, 2, 7, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := fromMillis(tt.ms); !got.Equal(tt.t) {
+			t.Errorf("fromMillis(%d) = %s, want %s", tt.ms, got, tt.t)
+		}
+		if got := toMillis(tt.t); got != tt.ms {
+			t.Errorf("toMillis(%s) = %d, want %d", tt.t, got, tt.ms)
+		}
+	}
+
+	// Sub-second precision is dropped in both directions
+	if got := fromMillis(1460116927834); !got.Equal(time.Unix(1460116927, 0)) {
+		t.Errorf("fromMillis did not truncate to seconds: %s", got)
+	}
+	if got := toMillis(time.Unix(1460116927, 834e6)); got != 1460116927000 {
+		t.Errorf("toMillis did not truncate to seconds: %d", got)
+	}
+}
@@ -0,0 +1,98 @@
+package chaosmonkey
+
+import (
+	"fmt"
+	"time"
+)
+
+// Cooldowns is a Guard that enforces cooldown periods per chaos strategy,
+// e.g. no NetworkLatency within 6 hours of the last chaos event against the
+// same group, regardless of its strategy. This gives groups time to recover
+// before they are hit again.
+//
+// Cooldowns tracks the chaos events recorded on the buses it subscribed to,
+// including simulated ones. To survive restarts and to take events triggered
+// by other processes into account, set History to the event journal of Chaos
+// Monkey, i.e. Client.EventsSince.
+type Cooldowns struct {
+	// Cooldown periods by strategy
+	Strategies map[Strategy]time.Duration
+
+	// Cooldown period of strategies missing from Strategies (none if zero)
+	Default time.Duration
+
+	// Optional source of chaos events triggered since the given time. If
+	// set, events are looked up in it instead of on the buses.
+	History func(since time.Time) ([]Event, error)
+
+	// Clock used to tell the current time (SystemClock if nil)
+	Clock Clock
+
+	history eventHistory
+}
+
+// Subscribe tracks the chaos events recorded on the bus. It returns a
+// function that stops tracking.
+func (c *Cooldowns) Subscribe(bus *Bus) (stop func()) {
+	return c.history.subscribe(bus, nil)
+}
+
+// Cooldown returns the cooldown period of the strategy.
+func (c *Cooldowns) Cooldown(s Strategy) time.Duration {
+	if s == "" {
+		s = StrategyShutdownInstance
+	}
+	if d, ok := c.Strategies[s]; ok {
+		return d
+	}
+	return c.Default
+}
+
+// Check refuses chaos events against groups that had a chaos event within
+// the cooldown period of the strategy. It also refuses them if History
+// fails.
+func (c *Cooldowns) Check(t Target) error {
+	cooldown := c.Cooldown(t.Strategy)
+	if cooldown <= 0 {
+		return nil
+	}
+	now := t.Time
+	if now.IsZero() {
+		now = clockNow(c.Clock)
+	}
+	since := now.Add(-cooldown)
+
+	// Keep tracked events for the longest cooldown period
+	longest := c.Default
+	for _, d := range c.Strategies {
+		if d > longest {
+			longest = d
+		}
+	}
+	events, err := c.history.since(c.History, since, now.Add(-longest))
+	if err != nil {
+		return fmt.Errorf("failed to get recent chaos events: %s", err)
+	}
+	var last *Event
+	for i, ev := range events {
+		if ev.AutoScalingGroupName != t.AutoScalingGroupName || !ev.TriggeredAt.After(since) {
+			continue
+		}
+		if ev.Region != "" && t.Region != "" && ev.Region != t.Region {
+			continue
+		}
+		if last == nil || ev.TriggeredAt.After(last.TriggeredAt) {
+			last = &events[i]
+		}
+	}
+	if last == nil {
+		return nil
+	}
+	strategy := t.Strategy
+	if strategy == "" {
+		strategy = StrategyShutdownInstance
+	}
+	return fmt.Errorf("group %s had a chaos event %s ago, but %s needs a cooldown of %s (until %s)",
+		t.AutoScalingGroupName, now.Sub(last.TriggeredAt).Round(time.Second), strategy, cooldown,
+		last.TriggeredAt.Add(cooldown).Format(time.RFC3339))
+}
@@ -0,0 +1,83 @@
+package chaosmonkey_test
+
+import (
+	"testing"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestCooldowns(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)}
+	bus := chaosmonkey.NewBus()
+	cooldowns := &chaosmonkey.Cooldowns{
+		Strategies: map[chaosmonkey.Strategy]time.Duration{
+			chaosmonkey.StrategyNetworkLatency: 6 * time.Hour,
+		},
+		Default: time.Hour,
+		Clock:   clock,
+	}
+	cooldowns.Subscribe(bus)
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun: true,
+		Clock:  clock,
+		Bus:    bus,
+		Guards: []chaosmonkey.Guard{cooldowns},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigger := func(group string, strategy chaosmonkey.Strategy) error {
+		_, err := client.TriggerEvent(group, strategy)
+		return err
+	}
+
+	if err := trigger("payments-api", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(2 * time.Hour)
+	err = trigger("payments-api", chaosmonkey.StrategyNetworkLatency)
+	if want := "chaos event refused: group payments-api had a chaos event 2h0m0s ago, but NetworkLatency needs a cooldown of 6h0m0s (until 2018-04-02T16:00:00Z)"; err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+	if err := trigger("search-api", chaosmonkey.StrategyNetworkLatency); err != nil {
+		t.Fatal(err)
+	}
+	if err := trigger("payments-api", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatalf("default cooldown did not expire: %s", err)
+	}
+
+	clock.now = clock.now.Add(6 * time.Hour)
+	if err := trigger("search-api", ""); err != nil {
+		t.Fatalf("default cooldown did not expire: %s", err)
+	}
+	if err := trigger("payments-api", chaosmonkey.StrategyNetworkLatency); err != nil {
+		t.Fatalf("cooldown did not expire: %s", err)
+	}
+}
+
+func TestCooldownsHistory(t *testing.T) {
+	now := time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)
+	cooldowns := &chaosmonkey.Cooldowns{
+		Default: 6 * time.Hour,
+		History: func(since time.Time) ([]chaosmonkey.Event, error) {
+			return []chaosmonkey.Event{
+				{AutoScalingGroupName: "payments-api", Region: "us-east-1", TriggeredAt: now.Add(-time.Hour)},
+			}, nil
+		},
+	}
+	for _, tc := range []struct {
+		region string
+		want   bool
+	}{
+		{"us-east-1", true},
+		{"", true},
+		{"eu-west-1", false},
+	} {
+		err := cooldowns.Check(chaosmonkey.Target{AutoScalingGroupName: "payments-api", Region: tc.region, Time: now})
+		if got := err != nil; got != tc.want {
+			t.Errorf("region %q: got error %v, want refusal %t", tc.region, err, tc.want)
+		}
+	}
+}
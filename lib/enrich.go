@@ -0,0 +1,33 @@
+package chaosmonkey
+
+import (
+	"context"
+	"time"
+)
+
+// InstanceDetails describes the EC2 instance of a chaos event.
+type InstanceDetails struct {
+	AvailabilityZone string            `json:"availabilityZone" yaml:"availabilityZone"`
+	InstanceType     string            `json:"instanceType" yaml:"instanceType"`
+	ImageID          string            `json:"imageId" yaml:"imageId"`
+	LaunchTime       time.Time         `json:"launchTime" yaml:"launchTime"`
+	Tags             map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// EventEnricher adds details to chaos events, which Chaos Monkey only reports
+// with an instance ID. Configure an enricher via Config.EnrichEvents to have
+// it applied to the results of Events and EventsSince.
+type EventEnricher interface {
+	// EnrichEvents sets the Instance of the given events where possible.
+	// Events whose instance is unknown are left as they are.
+	EnrichEvents(ctx context.Context, events []Event) error
+}
+
+// EventEnricherFunc is an adapter to use an ordinary function as an
+// EventEnricher.
+type EventEnricherFunc func(ctx context.Context, events []Event) error
+
+// EnrichEvents calls f(ctx, events).
+func (f EventEnricherFunc) EnrichEvents(ctx context.Context, events []Event) error {
+	return f(ctx, events)
+}
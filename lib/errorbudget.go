@@ -0,0 +1,170 @@
+package chaosmonkey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BudgetSource measures the remaining error budget of the service a chaos
+// event targets, typically by querying its SLO metrics.
+type BudgetSource interface {
+	// ErrorBudget returns the fraction of the error budget that remains,
+	// e.g. 0.25 if a quarter remains. It is zero or negative once the
+	// budget is exhausted.
+	ErrorBudget(ctx context.Context, t Target) (float64, error)
+}
+
+// BudgetSourceFunc is an adapter to use an ordinary function as a
+// BudgetSource.
+type BudgetSourceFunc func(ctx context.Context, t Target) (float64, error)
+
+// ErrorBudget calls f(ctx, t).
+func (f BudgetSourceFunc) ErrorBudget(ctx context.Context, t Target) (float64, error) {
+	return f(ctx, t)
+}
+
+// ErrorBudgetGuard is a Guard that refuses chaos events against services
+// whose error budget is exhausted, so that chaos does not burn budget that
+// is needed for real incidents.
+type ErrorBudgetGuard struct {
+	// Source of the remaining error budget, e.g. a PrometheusBudget or an
+	// aws.MetricMathBudget
+	Source BudgetSource
+
+	// Fraction of the error budget that must remain, e.g. 0.1 to stop chaos
+	// with less than 10% of the budget left (exhausted budgets only if zero)
+	MinRemaining float64
+
+	// Maximum time to measure the error budget (10 seconds if zero)
+	Timeout time.Duration
+}
+
+// Check refuses chaos events if no more than MinRemaining of the error budget
+// remains. It also refuses them if the error budget cannot be measured.
+func (g *ErrorBudgetGuard) Check(t Target) error {
+	timeout := g.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	remaining, err := g.Source.ErrorBudget(ctx, t)
+	if err != nil {
+		return fmt.Errorf("failed to measure error budget: %s", err)
+	}
+	if remaining <= g.MinRemaining {
+		if g.MinRemaining <= 0 {
+			return fmt.Errorf("error budget of group %s exhausted: %.2f%% remaining",
+				t.AutoScalingGroupName, remaining*100)
+		}
+		return fmt.Errorf("error budget of group %s too low: %.2f%% remaining (minimum %.2f%%)",
+			t.AutoScalingGroupName, remaining*100, g.MinRemaining*100)
+	}
+	return nil
+}
+
+// Expand replaces $group and $region in s with the auto scaling group and
+// region of the chaos event, e.g. to tailor queries of SLO metrics to it.
+func (t Target) Expand(s string) string {
+	return strings.NewReplacer("$group", t.AutoScalingGroupName, "$region", t.Region).Replace(s)
+}
+
+// PrometheusBudget is a BudgetSource that evaluates an instant query yielding
+// the remaining error budget with the HTTP API of Prometheus, e.g.
+//
+//	1 - (1 - slo:availability:ratio_rate30d{service="$group"}) / (1 - 0.999)
+//
+// The query must yield a scalar or a vector of one sample. $group and $region
+// in it are expanded for every chaos event.
+type PrometheusBudget struct {
+	// Base URL of Prometheus, e.g. http://prometheus:9090
+	URL string
+
+	// Query yielding the remaining error budget
+	Query string
+
+	// HTTP client to use (one with a timeout of 10 seconds if nil)
+	HTTPClient *http.Client
+}
+
+// ErrorBudget evaluates the query for the chaos event.
+func (p *PrometheusBudget) ErrorBudget(ctx context.Context, t Target) (float64, error) {
+	client := p.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	query := t.Expand(p.Query)
+	params := url.Values{"query": {query}}
+	if !t.Time.IsZero() {
+		params.Set("time", strconv.FormatInt(t.Time.Unix(), 10))
+	}
+	req, err := http.NewRequest("GET", strings.TrimSuffix(p.URL, "/")+"/api/v1/query?"+params.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("invalid response of Prometheus (%s): %s", resp.Status, err)
+	}
+	if body.Status != "success" {
+		return 0, fmt.Errorf("query %q failed: %s", query, body.Error)
+	}
+
+	var sample []interface{}
+	switch body.Data.ResultType {
+	case "scalar":
+		err = json.Unmarshal(body.Data.Result, &sample)
+	case "vector":
+		var vector []struct {
+			Value []interface{} `json:"value"`
+		}
+		err = json.Unmarshal(body.Data.Result, &vector)
+		switch {
+		case err != nil:
+		case len(vector) == 0:
+			return 0, fmt.Errorf("query %q returned no data", query)
+		case len(vector) > 1:
+			return 0, fmt.Errorf("query %q returned %d series, expected one", query, len(vector))
+		default:
+			sample = vector[0].Value
+		}
+	default:
+		return 0, fmt.Errorf("query %q returned a %s, expected a scalar or vector", query, body.Data.ResultType)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid result of query %q: %s", query, err)
+	}
+	if len(sample) != 2 {
+		return 0, fmt.Errorf("invalid result of query %q", query)
+	}
+	value, ok := sample[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid result of query %q", query)
+	}
+	budget, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(budget) {
+		return 0, fmt.Errorf("query %q returned %s, expected a number", query, value)
+	}
+	return budget, nil
+}
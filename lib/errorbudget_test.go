@@ -0,0 +1,58 @@
+package chaosmonkey_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestErrorBudgetGuard(t *testing.T) {
+	budgets := map[string]string{
+		"payments-api": `{"resultType":"vector","result":[{"metric":{},"value":[1522663200,"0.42"]}]}`,
+		"search-api":   `{"resultType":"scalar","result":[1522663200,"-0.05"]}`,
+		"orders-api":   `{"resultType":"vector","result":[]}`,
+	}
+	var queries []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" || r.FormValue("time") != "1522663200" {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.FormValue("query")
+		queries = append(queries, query)
+		fmt.Fprintf(w, `{"status":"success","data":%s}`, budgets[query[len("budget:"):]])
+	}))
+	defer ts.Close()
+
+	guard := &chaosmonkey.ErrorBudgetGuard{
+		Source: &chaosmonkey.PrometheusBudget{URL: ts.URL + "/", Query: "budget:$group"},
+	}
+	now := time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		group        string
+		minRemaining float64
+		want         string
+	}{
+		{"payments-api", 0, ""},
+		{"payments-api", 0.5, "error budget of group payments-api too low: 42.00% remaining (minimum 50.00%)"},
+		{"search-api", 0, "error budget of group search-api exhausted: -5.00% remaining"},
+		{"orders-api", 0, `failed to measure error budget: query "budget:orders-api" returned no data`},
+	} {
+		guard.MinRemaining = tc.minRemaining
+		err := guard.Check(chaosmonkey.Target{AutoScalingGroupName: tc.group, Time: now})
+		var got string
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Errorf("%s: got error %q, want %q", tc.group, got, tc.want)
+		}
+	}
+	if len(queries) != 4 {
+		t.Errorf("got %d queries, want 4", len(queries))
+	}
+}
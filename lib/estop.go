@@ -0,0 +1,107 @@
+package chaosmonkey
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultStopVariable is the environment variable checked by EnvSentinel
+// unless configured otherwise.
+const DefaultStopVariable = "CHAOSMONKEY_STOP"
+
+// StopSentinel tells whether an on-caller requested an emergency stop, e.g.
+// by setting an environment variable or creating a file. Configure sentinels
+// via Config.StopSentinels to have them checked before every chaos event and
+// while campaigns run, which allows stopping chaos everywhere without
+// redeploying anything.
+type StopSentinel interface {
+	// CheckStop returns a non-nil error describing the emergency stop if
+	// one was requested. Sentinels that cannot tell should return an error
+	// as well.
+	CheckStop(ctx context.Context) error
+}
+
+// StopSentinelFunc is an adapter to use an ordinary function as a
+// StopSentinel.
+type StopSentinelFunc func(ctx context.Context) error
+
+// CheckStop calls f(ctx).
+func (f StopSentinelFunc) CheckStop(ctx context.Context) error {
+	return f(ctx)
+}
+
+// StopError is returned by TriggerEvent if a sentinel reported an emergency
+// stop.
+type StopError struct {
+	// The emergency stop reported by the sentinel
+	Err error
+}
+
+func (e *StopError) Error() string {
+	return fmt.Sprintf("chaos halted: emergency stop: %s", e.Err)
+}
+
+// checkStop checks all configured sentinels and returns a *StopError for the
+// first one reporting an emergency stop.
+func (c *Client) checkStop(ctx context.Context) error {
+	for _, s := range c.config.StopSentinels {
+		if err := s.CheckStop(ctx); err != nil {
+			return &StopError{Err: err}
+		}
+	}
+	return nil
+}
+
+// EnvSentinel is a StopSentinel reporting an emergency stop while an
+// environment variable is set to anything but a false value like "0" or
+// "false".
+type EnvSentinel struct {
+	// Name of the variable (DefaultStopVariable if empty)
+	Name string
+}
+
+// CheckStop checks the environment variable.
+func (s *EnvSentinel) CheckStop(ctx context.Context) error {
+	name := s.Name
+	if name == "" {
+		name = DefaultStopVariable
+	}
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	if stop, err := strconv.ParseBool(v); err == nil && !stop {
+		return nil
+	}
+	return fmt.Errorf("%s is set to %q", name, v)
+}
+
+// FileSentinel is a StopSentinel reporting an emergency stop while a file
+// exists, e.g. on a shared file system. The first line of the file, if any,
+// is reported as the reason of the stop.
+type FileSentinel struct {
+	Path string
+}
+
+// CheckStop checks whether the file exists.
+func (s *FileSentinel) CheckStop(ctx context.Context) error {
+	f, err := os.Open(s.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot check %s: %s", s.Path, err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if scanner.Scan() {
+		if reason := strings.TrimSpace(scanner.Text()); reason != "" {
+			return fmt.Errorf("%s exists: %s", s.Path, reason)
+		}
+	}
+	return fmt.Errorf("%s exists", s.Path)
+}
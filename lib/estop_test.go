@@ -0,0 +1,62 @@
+package chaosmonkey_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestEnvSentinel(t *testing.T) {
+	const name = "CHAOSMONKEY_TEST_STOP"
+	defer os.Unsetenv(name)
+	sentinel := &chaosmonkey.EnvSentinel{Name: name}
+	for _, tc := range []struct {
+		value string
+		stop  bool
+	}{
+		{"", false},
+		{"0", false},
+		{"false", false},
+		{"1", true},
+		{"incident 1234", true},
+	} {
+		os.Setenv(name, tc.value)
+		if err := sentinel.CheckStop(context.Background()); (err != nil) != tc.stop {
+			t.Errorf("value %q: expected stop %v, got %v", tc.value, tc.stop, err)
+		}
+	}
+}
+
+func TestFileSentinel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaosmonkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stop")
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun:        true,
+		StopSentinels: []chaosmonkey.StopSentinel{&chaosmonkey.FileSentinel{Path: path}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.TriggerEvent("a", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte("incident 1234\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.TriggerEvent("a", chaosmonkey.StrategyShutdownInstance)
+	if _, ok := err.(*chaosmonkey.StopError); !ok {
+		t.Fatalf("expected *StopError, got %v", err)
+	}
+	if expected := "chaos halted: emergency stop: " + path + " exists: incident 1234"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err)
+	}
+}
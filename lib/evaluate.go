@@ -0,0 +1,115 @@
+package chaosmonkey
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Evaluator is implemented by guards whose Check has side effects, such as
+// using up approvals or reserving capacity. Client.Evaluate calls Evaluate
+// instead of Check for them.
+type Evaluator interface {
+	// Evaluate returns the error Check would return, without any side
+	// effects.
+	Evaluate(t Target) error
+}
+
+// Evaluation reports whether a chaos event would be triggered and which
+// checks would refuse it.
+type Evaluation struct {
+	// The evaluated chaos event
+	AutoScalingGroupName string    `json:"autoScalingGroupName" yaml:"autoScalingGroupName"`
+	Strategy             Strategy  `json:"strategy" yaml:"strategy"`
+	Region               string    `json:"region,omitempty" yaml:"region,omitempty"`
+	Time                 time.Time `json:"time" yaml:"time"`
+
+	// Whether all checks passed
+	Allowed bool `json:"allowed" yaml:"allowed"`
+
+	// Results of all checks in the order of TriggerEvent
+	Checks []CheckResult `json:"checks" yaml:"checks"`
+}
+
+// CheckResult is the result of a single check of an Evaluation.
+type CheckResult struct {
+	// Name of the check, e.g. the type of a guard
+	Name string `json:"name" yaml:"name"`
+
+	// Whether the check passed
+	Passed bool `json:"passed" yaml:"passed"`
+
+	// Why the check failed
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Evaluate runs all checks of TriggerEvent for a chaos event, i.e. the kill
+// switch, stop sentinels, allowed and denied groups, guards, and outage
+// checkers, and reports the result of each. Unlike TriggerEvent, it runs all
+// of them even if one fails, and neither triggers the chaos event nor
+// publishes anything on the bus. It helps to debug the configuration of
+// guards and policies.
+func (c *Client) Evaluate(group string, strategy Strategy) *Evaluation {
+	return c.EvaluateInRegion(group, strategy, "")
+}
+
+// EvaluateInRegion is like Evaluate but for the auto scaling group in the
+// given AWS region. An empty region falls back to the configured one.
+func (c *Client) EvaluateInRegion(group string, strategy Strategy, region string) *Evaluation {
+	if region == "" {
+		region = c.config.Region
+	}
+	t := Target{
+		AutoScalingGroupName: group,
+		Strategy:             strategy,
+		Region:               region,
+		Time:                 c.config.Clock.Now().UTC(),
+		Requester:            c.config.Actor,
+	}
+	ev := &Evaluation{
+		AutoScalingGroupName: group,
+		Strategy:             strategy,
+		Region:               region,
+		Time:                 t.Time,
+		Allowed:              true,
+	}
+	add := func(name string, err error) {
+		r := CheckResult{Name: name, Passed: err == nil}
+		if err != nil {
+			r.Reason = err.Error()
+			ev.Allowed = false
+		}
+		ev.Checks = append(ev.Checks, r)
+	}
+
+	var halted error
+	if c.config.HaltSwitch.IsHalted() {
+		halted = ErrHalted
+	}
+	add("kill switch", halted)
+
+	ctx := context.Background()
+	for _, s := range c.config.StopSentinels {
+		add(checkName(s), s.CheckStop(ctx))
+	}
+	if c.groups != nil {
+		add("allowed groups", c.groups.Check(t))
+	}
+	for _, g := range c.config.Guards {
+		if e, ok := g.(Evaluator); ok {
+			add(checkName(g), e.Evaluate(t))
+		} else {
+			add(checkName(g), g.Check(t))
+		}
+	}
+	for _, o := range c.config.OutageCheckers {
+		add(checkName(o), o.CheckOutage(ctx))
+	}
+	return ev
+}
+
+// checkName names a check by its type, e.g. "chaosmonkey.WindowPolicy".
+func checkName(v interface{}) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", v), "*")
+}
@@ -0,0 +1,69 @@
+package chaosmonkey_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestEvaluate(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)}
+	keys, approvers := approverKeys(t, "bob")
+	approvals := &chaosmonkey.Approvals{
+		Approvers: approvers,
+		Protected: []string{"payments-*"},
+		Requester: "alice",
+		Clock:     clock,
+	}
+	ap, err := approvals.Approve(keys["bob"], "bob", "payments-api", chaosmonkey.StrategyShutdownInstance, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := approvals.Present(ap.Token); err != nil {
+		t.Fatal(err)
+	}
+	bus := chaosmonkey.NewBus()
+	published := 0
+	bus.Subscribe(chaosmonkey.TopicGuardBlocked, func(m chaosmonkey.Message) { published++ })
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun:     true,
+		Clock:      clock,
+		Bus:        bus,
+		DenyGroups: []string{"*-api"},
+		Guards: []chaosmonkey.Guard{
+			approvals,
+			chaosmonkey.GuardFunc(func(t chaosmonkey.Target) error {
+				return errors.New("frozen")
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ev := client.Evaluate("payments-api", chaosmonkey.StrategyShutdownInstance)
+	want := &chaosmonkey.Evaluation{
+		AutoScalingGroupName: "payments-api",
+		Strategy:             chaosmonkey.StrategyShutdownInstance,
+		Time:                 clock.now,
+		Checks: []chaosmonkey.CheckResult{
+			{Name: "kill switch", Passed: true},
+			{Name: "allowed groups", Reason: `group payments-api is denied by pattern "*-api"`},
+			{Name: "chaosmonkey.Approvals", Passed: true},
+			{Name: "chaosmonkey.GuardFunc", Reason: "frozen"},
+		},
+	}
+	if diff := cmp.Diff(want, ev); diff != "" {
+		t.Errorf("unexpected evaluation (-want +got):\n%s", diff)
+	}
+	if published != 0 {
+		t.Errorf("evaluation published %d message(s)", published)
+	}
+	if len(approvals.Active()) != 1 {
+		t.Error("evaluation used up approval")
+	}
+}
@@ -0,0 +1,79 @@
+package chaosmonkey
+
+import (
+	"sync"
+	"time"
+)
+
+// eventHistory holds the recent chaos events that guards such as
+// BlastRadiusLimiter, Cooldowns, and InFlightLimiter decide on. It tracks
+// the chaos events recorded on the buses it subscribed to, including
+// simulated ones, unless the guard looks them up in an external source such
+// as the event journal of Chaos Monkey. The zero value is ready to use.
+type eventHistory struct {
+	// mu also guards the state of guards that onRecorded updates
+	mu       sync.Mutex
+	recorded []Event
+}
+
+// subscribe tracks the chaos events recorded on the bus, and calls
+// onRecorded (if not nil) with each of them and the attempt that triggered it
+// while h.mu is held. It returns a function that stops tracking.
+func (h *eventHistory) subscribe(bus *Bus, onRecorded func(ev Event, attempt uint64)) (stop func()) {
+	return bus.Subscribe(TopicEventRecorded, func(m Message) {
+		if m.Event == nil {
+			return
+		}
+		ev := *m.Event
+		if ev.TriggeredAt.IsZero() {
+			ev.TriggeredAt = m.Time
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.recorded = append(h.recorded, ev)
+		if onRecorded != nil {
+			onRecorded(ev, m.Attempt)
+		}
+	})
+}
+
+// since returns the chaos events triggered after the given time from
+// source, or the tracked ones if source is nil. Tracked events triggered
+// before keep are dropped, as no check looks that far back.
+func (h *eventHistory) since(source func(since time.Time) ([]Event, error), since, keep time.Time) ([]Event, error) {
+	h.mu.Lock()
+	recorded := h.recent(since, keep)
+	h.mu.Unlock()
+	if source != nil {
+		return source(since)
+	}
+	return recorded, nil
+}
+
+// recent returns the tracked events triggered after since, and drops those
+// triggered before keep. It must be called with h.mu held.
+func (h *eventHistory) recent(since, keep time.Time) []Event {
+	h.recorded = inWindow(h.recorded, keep)
+	return inWindow(append([]Event{}, h.recorded...), since)
+}
+
+// inWindow returns the events triggered after since, reusing the array of
+// events.
+func inWindow(events []Event, since time.Time) []Event {
+	recent := events[:0]
+	for _, ev := range events {
+		if ev.TriggeredAt.After(since) {
+			recent = append(recent, ev)
+		}
+	}
+	return recent
+}
+
+// clockNow returns the current time in UTC as told by the clock, or by the
+// system clock if it is nil.
+func clockNow(clock Clock) time.Time {
+	if clock == nil {
+		return time.Now().UTC()
+	}
+	return clock.Now().UTC()
+}
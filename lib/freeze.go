@@ -0,0 +1,250 @@
+package chaosmonkey
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxFreezeDuration is the maximum duration of a freeze unless
+// configured otherwise.
+const DefaultMaxFreezeDuration = 7 * 24 * time.Hour
+
+// Freeze temporarily suppresses chaos against auto scaling groups, e.g. while
+// a risky deployment is rolled out.
+type Freeze struct {
+	// Unique ID of the freeze
+	ID string `json:"id"`
+
+	// Names of frozen auto scaling groups, which may contain shell patterns
+	// like "payments-*". An empty list freezes all groups.
+	Groups []string `json:"groups,omitempty"`
+
+	// Why chaos is frozen
+	Reason string `json:"reason"`
+
+	// Who is responsible for the freeze
+	Owner string `json:"owner"`
+
+	// Time when the freeze takes effect (immediately if zero)
+	Start time.Time `json:"start,omitempty"`
+
+	// Time when the freeze expires automatically
+	Expires time.Time `json:"expires"`
+}
+
+// Matches reports whether the freeze applies to the given group.
+func (f *Freeze) Matches(group string) bool {
+	if len(f.Groups) == 0 {
+		return true
+	}
+	for _, pattern := range f.Groups {
+		if ok, _ := path.Match(pattern, group); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Freezer is a Guard that refuses chaos events against frozen groups. Every
+// freeze expires automatically so that forgotten freezes do not suppress
+// chaos forever.
+//
+// Freezer also implements http.Handler, which allows deployment tooling to
+// freeze and unfreeze chaos via HTTP:
+//
+//	GET    /         lists active and scheduled freezes
+//	POST   /         creates a freeze from {"groups", "reason", "owner", "duration"},
+//	                 starting at "start" (RFC 3339) if given
+//	DELETE /<id>     removes a freeze
+type Freezer struct {
+	// Maximum duration of a freeze (DefaultMaxFreezeDuration if zero)
+	MaxDuration time.Duration
+
+	// Clock used to tell the current time (SystemClock if nil)
+	Clock Clock
+
+	// Optional function authorizing requests to the HTTP API, which accepts
+	// all requests if nil
+	Authorize func(r *http.Request) error
+
+	mu      sync.Mutex
+	freezes []Freeze
+	nextID  int
+}
+
+// Freeze suppresses chaos against the given groups for duration d.
+func (f *Freezer) Freeze(groups []string, reason, owner string, d time.Duration) (*Freeze, error) {
+	now := f.now()
+	return f.FreezeBetween(groups, reason, owner, now, now.Add(d))
+}
+
+// FreezeBetween suppresses chaos against the given groups from start until
+// end, e.g. to schedule a freeze for a planned release or GameDay.
+func (f *Freezer) FreezeBetween(groups []string, reason, owner string, start, end time.Time) (*Freeze, error) {
+	max := f.MaxDuration
+	if max == 0 {
+		max = DefaultMaxFreezeDuration
+	}
+	switch {
+	case reason == "":
+		return nil, fmt.Errorf("freeze needs a reason")
+	case owner == "":
+		return nil, fmt.Errorf("freeze needs an owner")
+	case !end.After(start):
+		return nil, fmt.Errorf("freeze needs a positive duration")
+	case end.Sub(start) > max:
+		return nil, fmt.Errorf("freeze must not last longer than %s", max)
+	case !end.After(f.now()):
+		return nil, fmt.Errorf("freeze must not end in the past")
+	}
+	for _, g := range groups {
+		if _, err := path.Match(g, ""); err != nil {
+			return nil, fmt.Errorf("invalid group pattern %q: %s", g, err)
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	fr := Freeze{
+		ID:      fmt.Sprintf("freeze-%d", f.nextID),
+		Groups:  groups,
+		Reason:  reason,
+		Owner:   owner,
+		Start:   start.UTC(),
+		Expires: end.UTC(),
+	}
+	f.freezes = append(f.freezes, fr)
+	return &fr, nil
+}
+
+// Unfreeze removes the freeze with the given ID.
+func (f *Freezer) Unfreeze(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, fr := range f.freezes {
+		if fr.ID == id {
+			f.freezes = append(f.freezes[:i], f.freezes[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("freeze %q not found", id)
+}
+
+// Active returns all freezes that have not expired yet, including those that
+// have not started yet.
+func (f *Freezer) Active() []Freeze {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expire()
+	return append([]Freeze{}, f.freezes...)
+}
+
+// Check refuses chaos events against frozen groups.
+func (f *Freezer) Check(t Target) error {
+	now := f.now()
+	for _, fr := range f.Active() {
+		if now.Before(fr.Start) {
+			continue
+		}
+		if fr.Matches(t.AutoScalingGroupName) {
+			return fmt.Errorf("group %s is frozen by %s until %s: %s",
+				t.AutoScalingGroupName, fr.Owner, fr.Expires.Format(time.RFC3339), fr.Reason)
+		}
+	}
+	return nil
+}
+
+// expire removes expired freezes. It must be called with f.mu held.
+func (f *Freezer) expire() {
+	now := f.now()
+	active := f.freezes[:0]
+	for _, fr := range f.freezes {
+		if now.Before(fr.Expires) {
+			active = append(active, fr)
+		}
+	}
+	f.freezes = active
+}
+
+func (f *Freezer) now() time.Time {
+	if f.Clock == nil {
+		return time.Now().UTC()
+	}
+	return f.Clock.Now().UTC()
+}
+
+// freezeRequest is the body of a POST request to create a freeze.
+type freezeRequest struct {
+	Groups   []string `json:"groups"`
+	Reason   string   `json:"reason"`
+	Owner    string   `json:"owner"`
+	Duration string   `json:"duration"`
+
+	// Optional start time in RFC 3339 format
+	Start string `json:"start"`
+}
+
+// ServeHTTP implements the HTTP API of the freezer.
+func (f *Freezer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.Authorize != nil {
+		if err := f.Authorize(r); err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{err.Error()})
+			return
+		}
+	}
+	id := strings.Trim(r.URL.Path, "/")
+	switch {
+	case r.Method == "GET" && id == "":
+		writeJSON(w, http.StatusOK, f.Active())
+	case r.Method == "POST" && id == "":
+		var req freezeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+		start := f.now()
+		if req.Start != "" {
+			if start, err = time.Parse(time.RFC3339, req.Start); err != nil {
+				writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+				return
+			}
+		}
+		fr, err := f.FreezeBetween(req.Groups, req.Reason, req.Owner, start, start.Add(d))
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, fr)
+	case r.Method == "DELETE" && id != "":
+		if err := f.Unfreeze(id); err != nil {
+			writeJSON(w, http.StatusNotFound, errorResponse{err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// errorResponse is returned by the HTTP API in case of errors, like the
+// message field of APIResponse.
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,124 @@
+package chaosmonkey_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestFreezer(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)}
+	freezer := &chaosmonkey.Freezer{Clock: clock}
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun: true,
+		Clock:  clock,
+		Guards: []chaosmonkey.Guard{freezer},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := freezer.Freeze([]string{"payments-*"}, "deployment", "alice", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.TriggerEvent("payments-api", chaosmonkey.StrategyShutdownInstance)
+	if _, ok := err.(*chaosmonkey.GuardError); !ok {
+		t.Fatalf("expected GuardError, got %v", err)
+	}
+	if _, err := client.TriggerEvent("search-api", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatalf("unfrozen group was refused: %s", err)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := client.TriggerEvent("payments-api", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatalf("freeze did not expire: %s", err)
+	}
+}
+
+func TestFreezerHTTP(t *testing.T) {
+	freezer := &chaosmonkey.Freezer{}
+	ts := httptest.NewServer(freezer)
+	defer ts.Close()
+
+	body := `{"groups": ["payments-api"], "reason": "deployment", "owner": "alice", "duration": "30m"}`
+	resp, err := http.Post(ts.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %s", resp.Status)
+	}
+
+	active := freezer.Active()
+	if len(active) != 1 {
+		t.Fatalf("expected 1 active freeze, got %d", len(active))
+	}
+
+	req, _ := http.NewRequest("DELETE", ts.URL+"/"+active[0].ID, nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := len(freezer.Active()); n != 0 {
+		t.Fatalf("expected no active freezes, got %d", n)
+	}
+}
+
+func TestFreezerScheduled(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)}
+	freezer := &chaosmonkey.Freezer{Clock: clock}
+	target := chaosmonkey.Target{AutoScalingGroupName: "payments-api"}
+
+	start := clock.now.Add(time.Hour)
+	if _, err := freezer.FreezeBetween([]string{"payments-*"}, "GameDay", "alice", start, start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := freezer.Check(target); err != nil {
+		t.Fatalf("freeze applied before its start: %s", err)
+	}
+	clock.now = start
+	if err := freezer.Check(target); err == nil {
+		t.Fatal("expected scheduled freeze to apply")
+	}
+
+	if _, err := freezer.FreezeBetween(nil, "GameDay", "alice", start.Add(-2*time.Hour), start.Add(-time.Hour)); err == nil {
+		t.Fatal("expected error for freeze ending in the past")
+	}
+}
+
+func TestFreezerHTTPAuthorize(t *testing.T) {
+	freezer := &chaosmonkey.Freezer{
+		Authorize: func(r *http.Request) error {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				return errors.New("unauthorized")
+			}
+			return nil
+		},
+	}
+	ts := httptest.NewServer(freezer)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unexpected status: %s", resp.Status)
+	}
+}
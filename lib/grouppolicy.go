@@ -0,0 +1,83 @@
+package chaosmonkey
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// groupPattern matches auto scaling group names, either with a shell pattern
+// like "payments-*" or with a regular expression enclosed in slashes like
+// "/^payments-(api|web)$/".
+type groupPattern struct {
+	expr string
+	re   *regexp.Regexp
+}
+
+func compileGroupPatterns(patterns []string) ([]groupPattern, error) {
+	var compiled []groupPattern
+	for _, expr := range patterns {
+		p := groupPattern{expr: expr}
+		if len(expr) > 1 && strings.HasPrefix(expr, "/") && strings.HasSuffix(expr, "/") {
+			re, err := regexp.Compile(expr[1 : len(expr)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid group pattern %q: %s", expr, err)
+			}
+			p.re = re
+		} else if _, err := path.Match(expr, ""); err != nil {
+			return nil, fmt.Errorf("invalid group pattern %q: %s", expr, err)
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled, nil
+}
+
+func (p groupPattern) match(group string) bool {
+	if p.re != nil {
+		return p.re.MatchString(group)
+	}
+	ok, _ := path.Match(p.expr, group)
+	return ok
+}
+
+// groupPolicy enforces Config.AllowGroups and Config.DenyGroups. It is
+// checked like a guard, before all configured guards, so that violations are
+// published on the bus like other refusals.
+type groupPolicy struct {
+	allow, deny []groupPattern
+}
+
+func newGroupPolicy(allow, deny []string) (*groupPolicy, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	var p groupPolicy
+	var err error
+	if p.allow, err = compileGroupPatterns(allow); err != nil {
+		return nil, err
+	}
+	if p.deny, err = compileGroupPatterns(deny); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Check refuses chaos events against denied groups and, if any groups are
+// allowed, against groups that are not. Deny patterns take precedence.
+func (p *groupPolicy) Check(t Target) error {
+	for _, d := range p.deny {
+		if d.match(t.AutoScalingGroupName) {
+			return fmt.Errorf("group %s is denied by pattern %q", t.AutoScalingGroupName, d.expr)
+		}
+	}
+	if len(p.allow) == 0 {
+		return nil
+	}
+	for _, a := range p.allow {
+		if a.match(t.AutoScalingGroupName) {
+			return nil
+		}
+	}
+	return fmt.Errorf("group %s is not allowed by any pattern", t.AutoScalingGroupName)
+}
@@ -0,0 +1,51 @@
+package chaosmonkey_test
+
+import (
+	"testing"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestAllowDenyGroups(t *testing.T) {
+	bus := chaosmonkey.NewBus()
+	var blocked []string
+	bus.Subscribe(chaosmonkey.TopicGuardBlocked, func(m chaosmonkey.Message) {
+		blocked = append(blocked, m.AutoScalingGroupName)
+	})
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun:      true,
+		Bus:         bus,
+		AllowGroups: []string{"search-*", "/^payments-(api|web)$/"},
+		DenyGroups:  []string{"payments-web"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		group string
+		err   string
+	}{
+		{"search-api", ""},
+		{"payments-api", ""},
+		{"payments-web", `chaos event refused: group payments-web is denied by pattern "payments-web"`},
+		{"payments-db", "chaos event refused: group payments-db is not allowed by any pattern"},
+		{"paymnets-api", "chaos event refused: group paymnets-api is not allowed by any pattern"},
+	}
+	for _, test := range tests {
+		_, err := client.TriggerEvent(test.group, chaosmonkey.StrategyShutdownInstance)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: unexpected error: %s", test.group, err)
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Errorf("%s: got error %v, want %q", test.group, err, test.err)
+		}
+	}
+	if len(blocked) != 3 {
+		t.Errorf("expected 3 refusals on the bus, got %v", blocked)
+	}
+
+	if _, err := chaosmonkey.NewClient(&chaosmonkey.Config{DenyGroups: []string{"/(/"}}); err == nil {
+		t.Error("invalid pattern was accepted")
+	}
+}
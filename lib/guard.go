@@ -0,0 +1,89 @@
+package chaosmonkey
+
+import (
+	"fmt"
+	"time"
+)
+
+// Target describes a chaos event that is about to be triggered.
+type Target struct {
+	// Name of auto scaling group to target
+	AutoScalingGroupName string
+
+	// Chaos strategy to use
+	Strategy Strategy
+
+	// AWS region of the auto scaling group
+	Region string
+
+	// Time when the chaos event is going to be triggered
+	Time time.Time
+
+	// Who triggers the chaos event (Config.Actor)
+	Requester string
+
+	// ID of the call of TriggerEvent, as in the messages it publishes on the
+	// bus, which lets guards learn the outcome of the chaos events they
+	// allowed (zero outside of TriggerEvent)
+	Attempt uint64
+}
+
+// Guard decides whether a chaos event may be triggered. Configure guards via
+// Config.Guards to have them checked by TriggerEvent.
+type Guard interface {
+	// Check returns a non-nil error if the chaos event must not be
+	// triggered, describing the reason.
+	Check(t Target) error
+}
+
+// GuardFunc is an adapter to use an ordinary function as a Guard.
+type GuardFunc func(t Target) error
+
+// Check calls f(t).
+func (f GuardFunc) Check(t Target) error {
+	return f(t)
+}
+
+// GuardError is returned by TriggerEvent if a guard refused a chaos event.
+type GuardError struct {
+	// The refused chaos event
+	Target Target
+
+	// The reason returned by the guard
+	Err error
+}
+
+func (e *GuardError) Error() string {
+	return fmt.Sprintf("chaos event refused: %s", e.Err)
+}
+
+// Unwrap returns the reason returned by the guard, so that errors.Is and
+// errors.As see sentinel errors of guards such as ErrOutsideWindow.
+func (e *GuardError) Unwrap() error {
+	return e.Err
+}
+
+// checkGuards runs all configured guards, preceded by the policy of allowed
+// and denied groups, and returns a *GuardError for the first one refusing the
+// chaos event.
+func (c *Client) checkGuards(t Target) error {
+	guards := c.config.Guards
+	if c.groups != nil {
+		guards = append([]Guard{c.groups}, guards...)
+	}
+	for _, g := range guards {
+		if err := g.Check(t); err != nil {
+			c.config.Bus.Publish(Message{
+				Topic:                TopicGuardBlocked,
+				Time:                 c.config.Clock.Now().UTC(),
+				AutoScalingGroupName: t.AutoScalingGroupName,
+				Strategy:             t.Strategy,
+				Region:               t.Region,
+				Attempt:              t.Attempt,
+				Err:                  err,
+			})
+			return &GuardError{Target: t, Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package chaosmonkey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrHalted is returned by TriggerEvent while chaos is halted.
+var ErrHalted = errors.New("chaos halted")
+
+// HaltSwitch is a kill switch for chaos, which can be shared by several
+// clients via Config.HaltSwitch. While it is halted, TriggerEvent fails with
+// ErrHalted, and running campaigns and soak tests are aborted. The zero value
+// is a switch that is not halted.
+//
+// HaltSwitch also implements http.Handler, which allows halting chaos via
+// HTTP:
+//
+//	GET    /         returns {"halted": true|false}
+//	POST   /halt     halts chaos
+//	POST   /resume   resumes chaos
+type HaltSwitch struct {
+	mu      sync.Mutex
+	init    bool
+	halted  chan struct{}
+	resumed chan struct{}
+}
+
+// lazyInit creates the channels of a zero switch. It must be called with
+// h.mu held.
+func (h *HaltSwitch) lazyInit() {
+	if h.init {
+		return
+	}
+	h.init = true
+	h.halted = make(chan struct{})
+	h.resumed = make(chan struct{})
+	close(h.resumed)
+}
+
+// Halt halts chaos until Resume is called.
+func (h *HaltSwitch) Halt() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lazyInit()
+	select {
+	case <-h.halted:
+		return
+	default:
+	}
+	close(h.halted)
+	h.resumed = make(chan struct{})
+}
+
+// Resume resumes chaos after Halt.
+func (h *HaltSwitch) Resume() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lazyInit()
+	select {
+	case <-h.resumed:
+		return
+	default:
+	}
+	close(h.resumed)
+	h.halted = make(chan struct{})
+}
+
+// IsHalted reports whether chaos is halted.
+func (h *HaltSwitch) IsHalted() bool {
+	select {
+	case <-h.Halted():
+		return true
+	default:
+		return false
+	}
+}
+
+// Halted returns a channel that is closed once chaos is halted.
+func (h *HaltSwitch) Halted() <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lazyInit()
+	return h.halted
+}
+
+// Resumed returns a channel that is closed once chaos is resumed, or right
+// away if it is not halted.
+func (h *HaltSwitch) Resumed() <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lazyInit()
+	return h.resumed
+}
+
+// ServeHTTP implements the HTTP API of the switch.
+func (h *HaltSwitch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	action := strings.Trim(r.URL.Path, "/")
+	switch {
+	case r.Method == "GET" && action == "":
+		writeJSON(w, http.StatusOK, struct {
+			Halted bool `json:"halted"`
+		}{h.IsHalted()})
+	case r.Method == "POST" && action == "halt":
+		h.Halt()
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "POST" && action == "resume":
+		h.Resume()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HaltAll halts all chaos of the client and of other clients sharing its
+// HaltSwitch until Resume is called.
+func (c *Client) HaltAll() {
+	c.config.HaltSwitch.Halt()
+}
+
+// Resume resumes chaos after HaltAll.
+func (c *Client) Resume() {
+	c.config.HaltSwitch.Resume()
+}
+
+// haltable returns a context that is canceled when chaos is halted.
+func (c *Client) haltable(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	halted := c.config.HaltSwitch.Halted()
+	go func() {
+		select {
+		case <-halted:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// canceled returns the reason why the context of a campaign or soak test was
+// canceled: ErrHalted if chaos is halted, and errCanceled otherwise.
+func (c *Client) canceled() error {
+	if c.config.HaltSwitch.IsHalted() {
+		return ErrHalted
+	}
+	return errCanceled
+}
@@ -0,0 +1,102 @@
+package chaosmonkey_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestHaltAll(t *testing.T) {
+	halt := &chaosmonkey.HaltSwitch{}
+	var clients []*chaosmonkey.Client
+	for i := 0; i < 2; i++ {
+		client, err := chaosmonkey.NewClient(&chaosmonkey.Config{DryRun: true, HaltSwitch: halt})
+		if err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, client)
+	}
+
+	clients[0].HaltAll()
+	for _, client := range clients {
+		if _, err := client.TriggerEvent("a", chaosmonkey.StrategyShutdownInstance); err != chaosmonkey.ErrHalted {
+			t.Fatalf("expected ErrHalted, got %v", err)
+		}
+	}
+	clients[1].Resume()
+	for _, client := range clients {
+		if _, err := client.TriggerEvent("a", chaosmonkey.StrategyShutdownInstance); err != nil {
+			t.Fatalf("chaos was not resumed: %s", err)
+		}
+	}
+}
+
+func TestHaltAllRunningTrigger(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{Endpoint: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := client.TriggerEvent("a", chaosmonkey.StrategyShutdownInstance)
+		done <- err
+	}()
+	<-started
+	client.HaltAll()
+	select {
+	case err := <-done:
+		if err != chaosmonkey.ErrHalted {
+			t.Errorf("expected ErrHalted, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("running trigger was not canceled")
+	}
+}
+
+func TestHaltAllCampaign(t *testing.T) {
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	campaign := &chaosmonkey.Campaign{
+		Steps: []chaosmonkey.CampaignStep{
+			{Groups: []string{"a"}, Wait: time.Hour},
+			{Groups: []string{"b"}},
+		},
+		OnStep: func(i int, step chaosmonkey.CampaignStep) {
+			if i == 0 {
+				time.AfterFunc(10*time.Millisecond, client.HaltAll)
+			}
+		},
+	}
+
+	done := make(chan struct{})
+	var result *chaosmonkey.CampaignResult
+	go func() {
+		result, err = client.RunCampaign(context.Background(), campaign)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("campaign was not halted")
+	}
+	if err == nil || result.Aborted != "chaos halted" {
+		t.Errorf("expected campaign to be halted, got %v", err)
+	}
+	if len(result.Steps) != 1 {
+		t.Errorf("expected campaign to be halted during first step, got %+v", result.Steps)
+	}
+}
@@ -0,0 +1,231 @@
+package chaosmonkey
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Incident is a real incident reported by an incident management system.
+type Incident struct {
+	// ID of the incident in the incident management system
+	ID string `json:"id"`
+
+	// Name of the affected service
+	Service string `json:"service"`
+
+	// Severity of the incident, where 1 is the most severe
+	Severity int `json:"severity"`
+
+	// Time when the incident started
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// IncidentSource provides recent incidents.
+type IncidentSource interface {
+	// Incidents returns all incidents started since the given time.
+	Incidents(since time.Time) ([]Incident, error)
+}
+
+// IncidentGuard is a Guard that pauses chaos for services that recently had
+// a severe incident, following the common "no chaos right after an outage"
+// policy.
+type IncidentGuard struct {
+	// Source of incidents
+	Source IncidentSource
+
+	// How long to pause chaos after an incident (7 days if zero)
+	Window time.Duration
+
+	// Least severe severity that pauses chaos (2 if zero, i.e. Sev1 and
+	// Sev2 incidents)
+	MaxSeverity int
+
+	// Optional function mapping auto scaling groups to service names. By
+	// default, the group name is used as service name.
+	ServiceOf func(group string) string
+
+	// Clock used to tell the current time (SystemClock if nil)
+	Clock Clock
+}
+
+// Check refuses chaos events against services with recent severe incidents.
+// It also refuses them if incidents cannot be retrieved.
+func (g *IncidentGuard) Check(t Target) error {
+	window := g.Window
+	if window == 0 {
+		window = 7 * 24 * time.Hour
+	}
+	maxSeverity := g.MaxSeverity
+	if maxSeverity == 0 {
+		maxSeverity = 2
+	}
+	service := t.AutoScalingGroupName
+	if g.ServiceOf != nil {
+		service = g.ServiceOf(service)
+	}
+	var clock Clock = SystemClock{}
+	if g.Clock != nil {
+		clock = g.Clock
+	}
+
+	incidents, err := g.Source.Incidents(clock.Now().Add(-window))
+	if err != nil {
+		return fmt.Errorf("failed to get recent incidents: %s", err)
+	}
+	for _, i := range incidents {
+		if i.Service == service && i.Severity >= 1 && i.Severity <= maxSeverity {
+			return fmt.Errorf("service %s had Sev%d incident %s at %s",
+				service, i.Severity, i.ID, i.StartedAt.UTC().Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// IncidentLog is an IncidentSource that keeps incidents reported to it in
+// memory. It implements http.Handler to receive incidents via webhook, one
+// JSON-encoded Incident per POST request.
+type IncidentLog struct {
+	mu        sync.Mutex
+	incidents []Incident
+}
+
+// Report records an incident.
+func (l *IncidentLog) Report(i Incident) {
+	l.mu.Lock()
+	l.incidents = append(l.incidents, i)
+	l.mu.Unlock()
+}
+
+// Incidents returns all recorded incidents started since the given time.
+func (l *IncidentLog) Incidents(since time.Time) ([]Incident, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var recent []Incident
+	for _, i := range l.incidents {
+		if !i.StartedAt.Before(since) {
+			recent = append(recent, i)
+		}
+	}
+	return recent, nil
+}
+
+// ServeHTTP records an incident posted as JSON.
+func (l *IncidentLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var i Incident
+	if err := json.NewDecoder(r.Body).Decode(&i); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	if i.Service == "" || i.StartedAt.IsZero() {
+		writeJSON(w, http.StatusBadRequest, errorResponse{"incident needs service and startedAt"})
+		return
+	}
+	l.Report(i)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PagerDutyAPI is the default base URL of the PagerDuty REST API.
+const PagerDutyAPI = "https://api.pagerduty.com"
+
+// PagerDuty is an IncidentSource that retrieves incidents from the PagerDuty
+// REST API. The severity of an incident is taken from its priority ("P1" is
+// severity 1). Incidents without priority are considered to have severity 2
+// if they are of high urgency, and severity 4 otherwise.
+type PagerDuty struct {
+	// API token with read access
+	Token string
+
+	// Base URL of the API (PagerDutyAPI if empty)
+	BaseURL string
+
+	// HTTP client to use (http.DefaultClient if nil)
+	HTTPClient *http.Client
+}
+
+type pagerDutyIncidents struct {
+	Incidents []struct {
+		ID        string    `json:"id"`
+		CreatedAt time.Time `json:"created_at"`
+		Urgency   string    `json:"urgency"`
+		Service   struct {
+			Summary string `json:"summary"`
+		} `json:"service"`
+		Priority *struct {
+			Summary string `json:"summary"`
+		} `json:"priority"`
+	} `json:"incidents"`
+	More bool `json:"more"`
+}
+
+// Incidents returns all PagerDuty incidents created since the given time.
+func (p *PagerDuty) Incidents(since time.Time) ([]Incident, error) {
+	base := p.BaseURL
+	if base == "" {
+		base = PagerDutyAPI
+	}
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var incidents []Incident
+	for offset := 0; ; {
+		q := url.Values{}
+		q.Set("since", since.UTC().Format(time.RFC3339))
+		q.Set("limit", "100")
+		q.Set("offset", strconv.Itoa(offset))
+		req, err := http.NewRequest("GET", base+"/incidents?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+		req.Header.Set("Authorization", "Token token="+p.Token)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page pagerDutyIncidents
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("PagerDuty API error: %s", resp.Status)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, i := range page.Incidents {
+			severity := 4
+			if i.Urgency == "high" {
+				severity = 2
+			}
+			if i.Priority != nil && strings.HasPrefix(i.Priority.Summary, "P") {
+				if n, err := strconv.Atoi(i.Priority.Summary[1:]); err == nil {
+					severity = n
+				}
+			}
+			incidents = append(incidents, Incident{
+				ID:        i.ID,
+				Service:   i.Service.Summary,
+				Severity:  severity,
+				StartedAt: i.CreatedAt,
+			})
+		}
+		if !page.More {
+			return incidents, nil
+		}
+		offset += len(page.Incidents)
+	}
+}
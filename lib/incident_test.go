@@ -0,0 +1,42 @@
+package chaosmonkey_test
+
+import (
+	"testing"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestIncidentGuard(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2018, 4, 10, 12, 0, 0, 0, time.UTC)}
+	log := &chaosmonkey.IncidentLog{}
+	log.Report(chaosmonkey.Incident{
+		ID:        "INC-1",
+		Service:   "payments",
+		Severity:  1,
+		StartedAt: time.Date(2018, 4, 8, 9, 0, 0, 0, time.UTC),
+	})
+	log.Report(chaosmonkey.Incident{
+		ID:        "INC-2",
+		Service:   "search",
+		Severity:  3,
+		StartedAt: time.Date(2018, 4, 9, 9, 0, 0, 0, time.UTC),
+	})
+
+	guard := &chaosmonkey.IncidentGuard{
+		Source: log,
+		Clock:  clock,
+	}
+
+	if err := guard.Check(chaosmonkey.Target{AutoScalingGroupName: "payments"}); err == nil {
+		t.Error("expected chaos to be paused after Sev1 incident")
+	}
+	if err := guard.Check(chaosmonkey.Target{AutoScalingGroupName: "search"}); err != nil {
+		t.Errorf("chaos was paused after Sev3 incident: %s", err)
+	}
+
+	clock.now = clock.now.Add(7 * 24 * time.Hour)
+	if err := guard.Check(chaosmonkey.Target{AutoScalingGroupName: "payments"}); err != nil {
+		t.Errorf("chaos was still paused after window: %s", err)
+	}
+}
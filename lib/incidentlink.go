@@ -0,0 +1,304 @@
+package chaosmonkey
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IncidentLink marks a chaos event or campaign as having caused a real
+// incident, which pauses chaos against the affected groups until the incident
+// is resolved.
+type IncidentLink struct {
+	// ID of the incident in the incident management system
+	IncidentID string `json:"incidentId" yaml:"incidentId"`
+
+	// Name of the campaign that caused the incident, if any
+	Campaign string `json:"campaign,omitempty" yaml:"campaign,omitempty"`
+
+	// Chaos event that caused the incident, if known
+	AutoScalingGroupName string    `json:"autoScalingGroupName,omitempty" yaml:"autoScalingGroupName,omitempty"`
+	InstanceID           string    `json:"instanceId,omitempty" yaml:"instanceId,omitempty"`
+	Strategy             Strategy  `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	TriggeredAt          time.Time `json:"triggeredAt" yaml:"triggeredAt"`
+
+	// Names of auto scaling groups, which may contain shell patterns, whose
+	// chaos is paused (the group of the chaos event if empty)
+	Groups []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+
+	// Who linked the incident
+	Owner string `json:"owner" yaml:"owner"`
+
+	// Time when the incident was linked
+	LinkedAt time.Time `json:"linkedAt" yaml:"linkedAt"`
+
+	// Time when the incident was resolved, which resumes chaos (zero while
+	// it is open)
+	ResolvedAt time.Time `json:"resolvedAt" yaml:"resolvedAt"`
+}
+
+// Matches reports whether the link pauses chaos against the given group.
+func (l *IncidentLink) Matches(group string) bool {
+	for _, pattern := range l.Groups {
+		if ok, _ := path.Match(pattern, group); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Cause describes what caused the incident.
+func (l *IncidentLink) Cause() string {
+	var event string
+	switch {
+	case l.InstanceID != "":
+		event = fmt.Sprintf("instance %s of %s", l.InstanceID, l.AutoScalingGroupName)
+	case l.AutoScalingGroupName != "":
+		event = l.AutoScalingGroupName
+	}
+	if l.Strategy != "" && event != "" {
+		event = fmt.Sprintf("%s against %s", l.Strategy, event)
+	}
+	switch {
+	case l.Campaign != "" && event != "":
+		return fmt.Sprintf("campaign %s (%s)", l.Campaign, event)
+	case l.Campaign != "":
+		return "campaign " + l.Campaign
+	}
+	return event
+}
+
+// IncidentLinks records chaos events and campaigns that caused incidents in a
+// JSON file, which several processes may share. It is a Guard that refuses
+// chaos events against the groups of open incidents, and pauses campaigns
+// related to them via CheckCampaign.
+//
+// IncidentLinks also implements http.Handler, which allows incident
+// management systems to link and resolve incidents via HTTP:
+//
+//	GET    /         lists all links
+//	POST   /         links an incident from an IncidentLink
+//	DELETE /<id>     resolves the incident with the given ID
+type IncidentLinks struct {
+	// Path of the JSON file, which is created on the first link
+	Path string
+
+	// Clock used to tell the current time (SystemClock if nil)
+	Clock Clock
+
+	// Optional function authorizing requests to the HTTP API, which accepts
+	// all requests if nil
+	Authorize func(r *http.Request) error
+
+	mu sync.Mutex
+}
+
+// Link records that a chaos event or campaign caused an incident and pauses
+// chaos against the groups of the link until the incident is resolved.
+func (s *IncidentLinks) Link(l IncidentLink) (*IncidentLink, error) {
+	switch {
+	case l.IncidentID == "":
+		return nil, fmt.Errorf("incident link needs an incident ID")
+	case l.Owner == "":
+		return nil, fmt.Errorf("incident link needs an owner")
+	case l.Campaign == "" && l.AutoScalingGroupName == "":
+		return nil, fmt.Errorf("incident link needs a campaign or an auto scaling group")
+	}
+	if len(l.Groups) == 0 && l.AutoScalingGroupName != "" {
+		l.Groups = []string{l.AutoScalingGroupName}
+	}
+	if len(l.Groups) == 0 {
+		return nil, fmt.Errorf("incident link needs the groups of campaign %s", l.Campaign)
+	}
+	for _, g := range l.Groups {
+		if _, err := path.Match(g, ""); err != nil {
+			return nil, fmt.Errorf("invalid group pattern %q: %s", g, err)
+		}
+	}
+	l.LinkedAt = s.now()
+	l.ResolvedAt = time.Time{}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	links, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.write(append(links, l)); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Resolve marks all links to the given incident as resolved, which resumes
+// chaos against their groups.
+func (s *IncidentLinks) Resolve(incidentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	links, err := s.read()
+	if err != nil {
+		return err
+	}
+	found := false
+	for i := range links {
+		if links[i].IncidentID == incidentID && links[i].ResolvedAt.IsZero() {
+			links[i].ResolvedAt = s.now()
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no open incident %q linked", incidentID)
+	}
+	return s.write(links)
+}
+
+// Links returns all links, including resolved ones, sorted by the time they
+// were linked.
+func (s *IncidentLinks) Links() ([]IncidentLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+// Open returns the links to incidents that are not resolved yet.
+func (s *IncidentLinks) Open() ([]IncidentLink, error) {
+	links, err := s.Links()
+	if err != nil {
+		return nil, err
+	}
+	var open []IncidentLink
+	for _, l := range links {
+		if l.ResolvedAt.IsZero() {
+			open = append(open, l)
+		}
+	}
+	return open, nil
+}
+
+// Check refuses chaos events against groups of open incidents. It also
+// refuses them if the links cannot be read.
+func (s *IncidentLinks) Check(t Target) error {
+	open, err := s.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read incident links: %s", err)
+	}
+	for _, l := range open {
+		if l.Matches(t.AutoScalingGroupName) {
+			return fmt.Errorf("chaos against group %s is paused since %s caused incident %s",
+				t.AutoScalingGroupName, l.Cause(), l.IncidentID)
+		}
+	}
+	return nil
+}
+
+// CheckCampaign returns an error if the campaign caused an open incident or
+// targets groups of one, so that it is paused until the incident is resolved.
+func (s *IncidentLinks) CheckCampaign(cp *Campaign) error {
+	open, err := s.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read incident links: %s", err)
+	}
+	for _, l := range open {
+		if l.Campaign != "" && l.Campaign == cp.Name {
+			return fmt.Errorf("campaign %s is paused since it caused incident %s", cp.Name, l.IncidentID)
+		}
+		for _, step := range cp.Steps {
+			for _, g := range step.Groups {
+				if l.Matches(g) {
+					return fmt.Errorf("campaign %s is paused since %s caused incident %s affecting group %s",
+						cp.Name, l.Cause(), l.IncidentID, g)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// read reads the links from the file. It must be called with s.mu held.
+func (s *IncidentLinks) read() ([]IncidentLink, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var links []IncidentLink
+	if err := json.Unmarshal(data, &links); err != nil {
+		return nil, fmt.Errorf("invalid incident links %s: %s", s.Path, err)
+	}
+	sort.SliceStable(links, func(i, j int) bool { return links[i].LinkedAt.Before(links[j].LinkedAt) })
+	return links, nil
+}
+
+// write replaces the file with the given links. It must be called with s.mu
+// held.
+func (s *IncidentLinks) write(links []IncidentLink) error {
+	data, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return err
+	}
+	tmp := s.Path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+func (s *IncidentLinks) now() time.Time {
+	if s.Clock == nil {
+		return time.Now().UTC()
+	}
+	return s.Clock.Now().UTC()
+}
+
+// ServeHTTP implements the HTTP API of the incident links.
+func (s *IncidentLinks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.Authorize != nil {
+		if err := s.Authorize(r); err != nil {
+			writeJSON(w, http.StatusUnauthorized, errorResponse{err.Error()})
+			return
+		}
+	}
+	id := strings.Trim(r.URL.Path, "/")
+	switch {
+	case r.Method == "GET" && id == "":
+		links, err := s.Links()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, links)
+	case r.Method == "POST" && id == "":
+		var req IncidentLink
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+		l, err := s.Link(req)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, l)
+	case r.Method == "DELETE" && id != "":
+		if err := s.Resolve(id); err != nil {
+			writeJSON(w, http.StatusNotFound, errorResponse{err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
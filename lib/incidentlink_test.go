@@ -0,0 +1,173 @@
+package chaosmonkey_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestIncidentLinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaosmonkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	clock := &fakeClock{now: time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)}
+	links := &chaosmonkey.IncidentLinks{Path: filepath.Join(dir, "incidents.json"), Clock: clock}
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun: true,
+		Clock:  clock,
+		Guards: []chaosmonkey.Guard{links},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkout := &chaosmonkey.Campaign{
+		Name:  "checkout resilience",
+		Steps: []chaosmonkey.CampaignStep{{Groups: []string{"checkout-api"}}},
+	}
+	search := &chaosmonkey.Campaign{
+		Name:  "search resilience",
+		Steps: []chaosmonkey.CampaignStep{{Groups: []string{"search-api"}}},
+	}
+
+	if _, err := links.Link(chaosmonkey.IncidentLink{IncidentID: "INC-1", Owner: "alice"}); err == nil {
+		t.Fatal("expected error for link without campaign or group")
+	}
+	l, err := links.Link(chaosmonkey.IncidentLink{
+		IncidentID:           "INC-1",
+		AutoScalingGroupName: "payments-api",
+		InstanceID:           "i-1",
+		Strategy:             chaosmonkey.StrategyShutdownInstance,
+		Owner:                "alice",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"payments-api"}, l.Groups); diff != "" {
+		t.Errorf("unexpected groups (-want +got):\n%s", diff)
+	}
+	if _, err := links.Link(chaosmonkey.IncidentLink{
+		IncidentID: "INC-2",
+		Campaign:   "checkout resilience",
+		Groups:     []string{"checkout-*"},
+		Owner:      "bob",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.TriggerEvent("payments-api", chaosmonkey.StrategyShutdownInstance)
+	if _, ok := err.(*chaosmonkey.GuardError); !ok || !strings.Contains(err.Error(), "INC-1") {
+		t.Fatalf("expected GuardError naming INC-1, got %v", err)
+	}
+	if _, err := client.TriggerEvent("search-api", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatalf("unrelated group was refused: %s", err)
+	}
+	if err := links.CheckCampaign(checkout); err == nil || !strings.Contains(err.Error(), "INC-2") {
+		t.Fatalf("expected campaign to be paused by INC-2, got %v", err)
+	}
+	if err := links.CheckCampaign(search); err != nil {
+		t.Fatalf("unrelated campaign was paused: %s", err)
+	}
+
+	// Links are shared via the file
+	clock.now = clock.now.Add(time.Hour)
+	other := &chaosmonkey.IncidentLinks{Path: links.Path, Clock: clock}
+	if err := other.Resolve("INC-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Resolve("INC-1"); err == nil {
+		t.Fatal("expected error for resolved incident")
+	}
+	if _, err := client.TriggerEvent("payments-api", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatalf("chaos was not resumed: %s", err)
+	}
+
+	all, err := links.Links()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range all {
+		got = append(got, l.IncidentID+" "+l.Cause()+" "+l.ResolvedAt.Format(time.Kitchen))
+	}
+	want := []string{
+		"INC-1 ShutdownInstance against instance i-1 of payments-api 11:00AM",
+		"INC-2 campaign checkout resilience 12:00AM",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected links (-want +got):\n%s", diff)
+	}
+}
+
+func TestIncidentLinksHTTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaosmonkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	links := &chaosmonkey.IncidentLinks{Path: filepath.Join(dir, "incidents.json")}
+	ts := httptest.NewServer(links)
+	defer ts.Close()
+
+	body := `{"incidentId": "INC-1", "campaign": "checkout resilience", "groups": ["checkout-api"], "owner": "alice"}`
+	resp, err := http.Post(ts.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %s", resp.Status)
+	}
+	if err := links.Check(chaosmonkey.Target{AutoScalingGroupName: "checkout-api"}); err == nil {
+		t.Fatal("expected linked group to be paused")
+	}
+
+	req, _ := http.NewRequest("DELETE", ts.URL+"/INC-1", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected status: %s", resp.Status)
+	}
+	if err := links.Check(chaosmonkey.Target{AutoScalingGroupName: "checkout-api"}); err != nil {
+		t.Fatalf("resolved incident still pauses chaos: %s", err)
+	}
+}
+
+func TestSummaryAnnotate(t *testing.T) {
+	now := time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)
+	summary := chaosmonkey.Summarize(nil, now, time.Hour)
+	summary.Suggestions = []chaosmonkey.Suggestion{{AutoScalingGroupName: "search-api", Reason: "never"}}
+	summary.Annotate([]chaosmonkey.IncidentLink{
+		{IncidentID: "INC-2", Groups: []string{"checkout-api"}, LinkedAt: now},
+		{IncidentID: "INC-1", Groups: []string{"payments-api"}, LinkedAt: now.Add(-time.Hour), ResolvedAt: now},
+	})
+
+	var incidents []string
+	for _, l := range summary.Incidents {
+		incidents = append(incidents, l.IncidentID)
+	}
+	if diff := cmp.Diff([]string{"INC-1", "INC-2"}, incidents); diff != "" {
+		t.Errorf("unexpected incidents (-want +got):\n%s", diff)
+	}
+	want := []chaosmonkey.Suggestion{
+		{AutoScalingGroupName: "checkout-api", Reason: "caused incident INC-2; chaos is paused until it is resolved"},
+		{AutoScalingGroupName: "search-api", Reason: "never"},
+	}
+	if diff := cmp.Diff(want, summary.Suggestions); diff != "" {
+		t.Errorf("unexpected suggestions (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,181 @@
+package chaosmonkey
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultInFlight is how long InFlightLimiter considers a chaos event in
+// flight unless configured otherwise.
+const DefaultInFlight = 15 * time.Minute
+
+// InFlightLimiter is a Guard that caps the number of chaos events in flight
+// per service across all regions, e.g. to at most one, so that chaos never
+// hits a service in several regions at once and causes correlated failures.
+// Share one limiter between the clients of all regions, or use
+// TriggerEventInRegion with one client, as campaigns with regions do.
+//
+// As Chaos Monkey does not report when the effects of a chaos event are over,
+// an event is considered in flight for a fixed time after it was triggered.
+// Checks that pass reserve a slot right away, so that concurrent checks
+// cannot exceed the cap. The slot is released if the chaos event is refused
+// or fails after all, which the limiter learns from the buses it subscribed
+// to, and kept for the in-flight time otherwise. Slots are matched by the
+// attempt of TriggerEvent that reserved them (Target.Attempt), so slots
+// reserved by checks outside of TriggerEvent are always kept.
+type InFlightLimiter struct {
+	// Maximum number of chaos events in flight per service (1 if zero)
+	MaxInFlight int
+
+	// How long a chaos event is in flight after it was triggered
+	// (DefaultInFlight if zero)
+	InFlight time.Duration
+
+	// Optional function mapping auto scaling groups to service names. By
+	// default, the group name is used as service name, which matches
+	// services deployed with the same group name in every region.
+	ServiceOf func(group string) string
+
+	// Optional source of chaos events triggered since the given time, e.g.
+	// Client.EventsSince, to count events triggered by other processes as
+	// well
+	History func(since time.Time) ([]Event, error)
+
+	// Clock used to tell the current time (SystemClock if nil)
+	Clock Clock
+
+	history  eventHistory
+	reserved []reservation // guarded by history.mu
+}
+
+// reservation is a slot reserved by InFlightLimiter for the chaos event of an
+// attempt of TriggerEvent.
+type reservation struct {
+	Event
+	attempt uint64
+}
+
+// Subscribe tracks the chaos events recorded, refused, and failed on the
+// bus. It returns a function that stops tracking.
+func (l *InFlightLimiter) Subscribe(bus *Bus) (stop func()) {
+	stopRecorded := l.history.subscribe(bus, func(ev Event, attempt uint64) {
+		l.release(attempt)
+	})
+	released := func(m Message) {
+		l.history.mu.Lock()
+		defer l.history.mu.Unlock()
+		l.release(m.Attempt)
+	}
+	stopBlocked := bus.Subscribe(TopicGuardBlocked, released)
+	stopFailed := bus.Subscribe(TopicTriggerFailed, released)
+	return func() {
+		stopRecorded()
+		stopBlocked()
+		stopFailed()
+	}
+}
+
+// release removes the reservation of the attempt, if any. It must be called
+// with l.history.mu held.
+func (l *InFlightLimiter) release(attempt uint64) {
+	if attempt == 0 {
+		return
+	}
+	for i, r := range l.reserved {
+		if r.attempt == attempt {
+			l.reserved = append(l.reserved[:i], l.reserved[i+1:]...)
+			return
+		}
+	}
+}
+
+// Check refuses chaos events against services that already have the maximum
+// number of chaos events in flight, and reserves a slot otherwise. It also
+// refuses them if History fails.
+func (l *InFlightLimiter) Check(t Target) error {
+	return l.check(t, true)
+}
+
+// Evaluate is like Check but does not reserve a slot.
+func (l *InFlightLimiter) Evaluate(t Target) error {
+	return l.check(t, false)
+}
+
+func (l *InFlightLimiter) check(t Target, reserve bool) error {
+	max := l.MaxInFlight
+	if max <= 0 {
+		max = 1
+	}
+	window := l.InFlight
+	if window <= 0 {
+		window = DefaultInFlight
+	}
+	now := t.Time
+	if now.IsZero() {
+		now = clockNow(l.Clock)
+	}
+	since := now.Add(-window)
+
+	var history []Event
+	if l.History != nil {
+		var err error
+		if history, err = l.History(since); err != nil {
+			return fmt.Errorf("failed to get recent chaos events: %s", err)
+		}
+	}
+
+	// Reservations are released as events are recorded, so look at both
+	// under the same lock to count every event exactly once
+	l.history.mu.Lock()
+	defer l.history.mu.Unlock()
+	reserved := l.reserved[:0]
+	for _, r := range l.reserved {
+		if r.TriggeredAt.After(since) {
+			reserved = append(reserved, r)
+		}
+	}
+	l.reserved = reserved
+	if recorded := l.history.recent(since, since); l.History == nil {
+		history = recorded
+	}
+
+	service := l.service(t.AutoScalingGroupName)
+	var regions []string
+	for _, r := range l.reserved {
+		if l.service(r.AutoScalingGroupName) == service {
+			regions = append(regions, r.Region)
+		}
+	}
+	for _, ev := range history {
+		if ev.TriggeredAt.After(since) && l.service(ev.AutoScalingGroupName) == service {
+			regions = append(regions, ev.Region)
+		}
+	}
+	if len(regions) >= max {
+		sort.Strings(regions)
+		return fmt.Errorf("service %s already has %d chaos event(s) in flight in %s (limit %d)",
+			service, len(regions), strings.Join(regions, ", "), max)
+	}
+	if !reserve {
+		return nil
+	}
+	l.reserved = append(l.reserved, reservation{
+		Event: Event{
+			AutoScalingGroupName: t.AutoScalingGroupName,
+			Region:               t.Region,
+			Strategy:             t.Strategy,
+			TriggeredAt:          now,
+		},
+		attempt: t.Attempt,
+	})
+	return nil
+}
+
+func (l *InFlightLimiter) service(group string) string {
+	if l.ServiceOf == nil {
+		return group
+	}
+	return l.ServiceOf(group)
+}
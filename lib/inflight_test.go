@@ -0,0 +1,121 @@
+package chaosmonkey_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestInFlightLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)}
+	bus := chaosmonkey.NewBus()
+	limiter := &chaosmonkey.InFlightLimiter{Clock: clock}
+	limiter.Subscribe(bus)
+	frozen := false
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun: true,
+		Clock:  clock,
+		Bus:    bus,
+		Guards: []chaosmonkey.Guard{
+			limiter,
+			chaosmonkey.GuardFunc(func(t chaosmonkey.Target) error {
+				if frozen {
+					return errors.New("frozen")
+				}
+				return nil
+			}),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Refusals by later guards release the reserved slot
+	frozen = true
+	if _, err := client.TriggerEventInRegion("payments-api", chaosmonkey.StrategyShutdownInstance, "us-east-1"); err == nil {
+		t.Fatal("expected frozen group to be refused")
+	}
+	frozen = false
+
+	result, err := client.RunCampaign(context.Background(), &chaosmonkey.Campaign{
+		Steps: []chaosmonkey.CampaignStep{{
+			Groups:  []string{"payments-api", "search-api"},
+			Regions: []string{"us-east-1", "eu-west-1"},
+		}},
+		MaxFailures: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var triggered []string
+	for _, ev := range result.Steps[0].Events {
+		triggered = append(triggered, ev.AutoScalingGroupName+" in "+ev.Region)
+	}
+	if diff := cmp.Diff([]string{"payments-api in us-east-1", "search-api in us-east-1"}, triggered); diff != "" {
+		t.Errorf("unexpected events (-want +got):\n%s", diff)
+	}
+	want := []string{
+		"payments-api in eu-west-1: chaos event refused: service payments-api already has 1 chaos event(s) in flight in us-east-1 (limit 1)",
+		"search-api in eu-west-1: chaos event refused: service search-api already has 1 chaos event(s) in flight in us-east-1 (limit 1)",
+	}
+	if diff := cmp.Diff(want, result.Steps[0].Errors); diff != "" {
+		t.Errorf("unexpected errors (-want +got):\n%s", diff)
+	}
+
+	clock.now = clock.now.Add(chaosmonkey.DefaultInFlight)
+	if _, err := client.TriggerEventInRegion("payments-api", chaosmonkey.StrategyShutdownInstance, "eu-west-1"); err != nil {
+		t.Fatalf("chaos event was refused after in-flight time: %s", err)
+	}
+}
+
+func TestInFlightLimiterReleases(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)}
+	fail := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		// Chaos Monkey may not report the region of the event
+		fmt.Fprintf(w, `{"eventId": "i-1", "eventTime": %d, "groupName": "payments-api", "chaosType": "ShutdownInstance"}`,
+			clock.now.UnixNano()/int64(time.Millisecond))
+	}))
+	defer ts.Close()
+
+	bus := chaosmonkey.NewBus()
+	limiter := &chaosmonkey.InFlightLimiter{Clock: clock}
+	limiter.Subscribe(bus)
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint: ts.URL,
+		Clock:    clock,
+		Bus:      bus,
+		Guards:   []chaosmonkey.Guard{limiter},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Failed requests release the reserved slot
+	if _, err := client.TriggerEventInRegion("payments-api", chaosmonkey.StrategyShutdownInstance, "us-east-1"); err == nil {
+		t.Fatal("expected request to fail")
+	}
+	fail = false
+	if _, err := client.TriggerEventInRegion("payments-api", chaosmonkey.StrategyShutdownInstance, "eu-west-1"); err != nil {
+		t.Fatalf("chaos event was refused after failed request: %s", err)
+	}
+	// The slot is released by the recorded event although its region
+	// differs, so that the event is counted once
+	_, err = client.TriggerEventInRegion("payments-api", chaosmonkey.StrategyShutdownInstance, "us-east-1")
+	if want := "already has 1 chaos event(s) in flight"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,105 @@
+package chaosmonkey
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics holds the Prometheus collectors used to instrument a client. A nil
+// *metrics is valid and records nothing.
+type metrics struct {
+	requests  *prometheus.CounterVec
+	errors    *prometheus.CounterVec
+	latency   *prometheus.HistogramVec
+	triggered *prometheus.CounterVec
+}
+
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "chaosmonkey",
+			Subsystem: "client",
+			Name:      "requests_total",
+			Help:      "Number of requests sent to the Chaos Monkey API.",
+		}, []string{"method", "code"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "chaosmonkey",
+			Subsystem: "client",
+			Name:      "errors_total",
+			Help:      "Number of failed requests to the Chaos Monkey API by status code.",
+		}, []string{"code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "chaosmonkey",
+			Subsystem: "client",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests to the Chaos Monkey API.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		triggered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "chaosmonkey",
+			Subsystem: "client",
+			Name:      "events_triggered_total",
+			Help:      "Number of chaos events triggered by strategy.",
+		}, []string{"strategy"}),
+	}
+
+	// Reuse collectors already registered by another client so that several
+	// clients can share the same registry.
+	var err error
+	if m.requests, err = registerCounterVec(reg, m.requests); err != nil {
+		return nil, err
+	}
+	if m.errors, err = registerCounterVec(reg, m.errors); err != nil {
+		return nil, err
+	}
+	if m.triggered, err = registerCounterVec(reg, m.triggered); err != nil {
+		return nil, err
+	}
+	if err := reg.Register(m.latency); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+		m.latency = are.ExistingCollector.(*prometheus.HistogramVec)
+	}
+
+	return m, nil
+}
+
+func registerCounterVec(reg prometheus.Registerer, c *prometheus.CounterVec) (*prometheus.CounterVec, error) {
+	if err := reg.Register(c); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+		return are.ExistingCollector.(*prometheus.CounterVec), nil
+	}
+	return c, nil
+}
+
+// observeRequest records a finished request. A status code of 0 denotes a
+// request that failed before a response was received.
+func (m *metrics) observeRequest(method string, code int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	label := "network"
+	if code != 0 {
+		label = strconv.Itoa(code)
+	}
+	m.requests.WithLabelValues(method, label).Inc()
+	m.latency.WithLabelValues(method).Observe(d.Seconds())
+	if code != http.StatusOK {
+		m.errors.WithLabelValues(label).Inc()
+	}
+}
+
+func (m *metrics) observeTrigger(strategy Strategy) {
+	if m == nil {
+		return
+	}
+	m.triggered.WithLabelValues(string(strategy)).Inc()
+}
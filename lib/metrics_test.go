@@ -0,0 +1,61 @@
+package chaosmonkey_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Write([]byte(newEvent))
+			return
+		}
+		http.Error(w, `{"message": "unavailable"}`, http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	// Clients sharing a registry share the collectors. The deployment is
+	// known, so that they send no requests to probe it.
+	reg := prometheus.NewRegistry()
+	for i := 0; i < 2; i++ {
+		client, err := chaosmonkey.NewClient(&chaosmonkey.Config{Endpoint: ts.URL, MetricsRegisterer: reg, ServerInfo: &chaosmonkey.ServerInfo{}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.TriggerEvent("SomeAutoScalingGroup", chaosmonkey.StrategyShutdownInstance); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Events(); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+
+	expected := `
+# HELP chaosmonkey_client_errors_total Number of failed requests to the Chaos Monkey API by status code.
+# TYPE chaosmonkey_client_errors_total counter
+chaosmonkey_client_errors_total{code="503"} 2
+# HELP chaosmonkey_client_events_triggered_total Number of chaos events triggered by strategy.
+# TYPE chaosmonkey_client_events_triggered_total counter
+chaosmonkey_client_events_triggered_total{strategy="ShutdownInstance"} 2
+# HELP chaosmonkey_client_requests_total Number of requests sent to the Chaos Monkey API.
+# TYPE chaosmonkey_client_requests_total counter
+chaosmonkey_client_requests_total{code="200",method="POST"} 2
+chaosmonkey_client_requests_total{code="503",method="GET"} 2
+`
+	err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"chaosmonkey_client_errors_total",
+		"chaosmonkey_client_events_triggered_total",
+		"chaosmonkey_client_requests_total",
+	)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,122 @@
+package chaosmonkey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultOutageCheckInterval is how often outage checkers are consulted while
+// a campaign waits unless configured otherwise.
+const DefaultOutageCheckInterval = 30 * time.Second
+
+// errCanceled is returned by sleepChecking if the context was canceled.
+var errCanceled = errors.New("canceled")
+
+// OutageChecker detects ongoing outages, during which chaos must stop.
+// Configure outage checkers via Config.OutageCheckers to have them consulted
+// by TriggerEvent and while campaigns run.
+type OutageChecker interface {
+	// CheckOutage returns a non-nil error describing the outage if one is
+	// ongoing. Checkers that cannot tell should return an error as well.
+	CheckOutage(ctx context.Context) error
+}
+
+// OutageCheckerFunc is an adapter to use an ordinary function as an
+// OutageChecker.
+type OutageCheckerFunc func(ctx context.Context) error
+
+// CheckOutage calls f(ctx).
+func (f OutageCheckerFunc) CheckOutage(ctx context.Context) error {
+	return f(ctx)
+}
+
+// OutageError is returned by TriggerEvent if an outage checker detected an
+// outage.
+type OutageError struct {
+	// The outage reported by the checker
+	Err error
+}
+
+func (e *OutageError) Error() string {
+	return fmt.Sprintf("chaos halted: outage detected: %s", e.Err)
+}
+
+// checkOutages consults all configured outage checkers and returns an
+// *OutageError for the first one detecting an outage.
+func (c *Client) checkOutages(ctx context.Context) error {
+	for _, o := range c.config.OutageCheckers {
+		if err := o.CheckOutage(ctx); err != nil {
+			return &OutageError{Err: err}
+		}
+	}
+	return nil
+}
+
+// sleepChecking waits for duration d like sleep, consulting the stop
+// sentinels and outage checkers meanwhile. It returns a *StopError if an
+// emergency stop was requested, an *OutageError if an outage was detected,
+// and ErrHalted or errCanceled if the context was canceled.
+func (c *Client) sleepChecking(ctx context.Context, d time.Duration) error {
+	if len(c.config.OutageCheckers) == 0 && len(c.config.StopSentinels) == 0 {
+		if !sleep(ctx, d) {
+			return c.canceled()
+		}
+		return nil
+	}
+	interval := c.config.OutageCheckInterval
+	if interval <= 0 {
+		interval = DefaultOutageCheckInterval
+	}
+	for d > 0 {
+		step := interval
+		if d < step {
+			step = d
+		}
+		if !sleep(ctx, step) {
+			return c.canceled()
+		}
+		d -= step
+		if err := c.checkStop(ctx); err != nil {
+			return err
+		}
+		if err := c.checkOutages(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HTTPProbe is an OutageChecker that detects an outage if a URL, typically a
+// health check of a critical service, does not respond with a 2xx status
+// code.
+type HTTPProbe struct {
+	// URL to probe with GET requests
+	URL string
+
+	// HTTP client to use (one with a timeout of 10 seconds if nil)
+	HTTPClient *http.Client
+}
+
+// CheckOutage probes the URL.
+func (p *HTTPProbe) CheckOutage(ctx context.Context) error {
+	client := p.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequest("GET", p.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("probe of %s failed: %s", p.URL, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("probe of %s returned %s", p.URL, resp.Status)
+	}
+	return nil
+}
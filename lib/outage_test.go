@@ -0,0 +1,80 @@
+package chaosmonkey_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestTriggerEventOutage(t *testing.T) {
+	healthy := true
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun:         true,
+		OutageCheckers: []chaosmonkey.OutageChecker{&chaosmonkey.HTTPProbe{URL: ts.URL}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.TriggerEvent("a", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatal(err)
+	}
+
+	healthy = false
+	_, err = client.TriggerEvent("a", chaosmonkey.StrategyShutdownInstance)
+	if _, ok := err.(*chaosmonkey.OutageError); !ok {
+		t.Fatalf("expected OutageError, got %v", err)
+	}
+	if want := "chaos halted: outage detected: probe of " + ts.URL + " returned 503 Service Unavailable"; err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+}
+
+func TestRunCampaignOutage(t *testing.T) {
+	checks := 0
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun: true,
+		OutageCheckers: []chaosmonkey.OutageChecker{
+			chaosmonkey.OutageCheckerFunc(func(ctx context.Context) error {
+				// Before the step, before the event, and during the wait
+				if checks++; checks > 2 {
+					return errors.New("checkout is down")
+				}
+				return nil
+			}),
+		},
+		OutageCheckInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	campaign := &chaosmonkey.Campaign{
+		Steps: []chaosmonkey.CampaignStep{
+			{Groups: []string{"a"}, Wait: time.Hour},
+			{Groups: []string{"b"}},
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := client.RunCampaign(ctx, campaign)
+	if err == nil {
+		t.Fatal("expected campaign to be aborted")
+	}
+	if want := "chaos halted: outage detected: checkout is down"; result.Aborted != want {
+		t.Errorf("got abort reason %q, want %q", result.Aborted, want)
+	}
+	if len(result.Steps) != 1 || len(result.Steps[0].Events) != 1 {
+		t.Errorf("expected one event before the outage, got %+v", result.Steps)
+	}
+}
@@ -0,0 +1,164 @@
+package chaosmonkey
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Summary aggregates past chaos events for reporting.
+type Summary struct {
+	// Total number of events
+	Events int
+
+	// Times of the first and last event
+	First, Last time.Time
+
+	// Number of events per auto scaling group, strategy, and region, sorted
+	// by descending number of events
+	ByGroup, ByStrategy, ByRegion []Count
+
+	// Number of events per day (in UTC) from the first to the last event
+	Timeline []Count
+
+	// Longest period without chaos per auto scaling group, if at least as
+	// long as the minimum gap passed to Summarize, sorted by descending
+	// duration
+	Gaps []Gap
+
+	// Suggestions for further chaos, set by Suggest
+	Suggestions []Suggestion
+
+	// Incidents caused by chaos, set by Annotate
+	Incidents []IncidentLink
+}
+
+// Count is the number of events of some kind.
+type Count struct {
+	// Name of the group, strategy, or region, or day in YYYY-MM-DD format
+	Name string
+
+	// Number of events
+	Events int
+}
+
+// Gap is a period without chaos events against an auto scaling group.
+type Gap struct {
+	AutoScalingGroupName string
+	Start, End           time.Time
+}
+
+// Duration returns the length of the gap.
+func (g Gap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
+
+// Summarize aggregates the given events. Gaps are measured up to now and
+// reported if they last at least minGap.
+func Summarize(events []Event, now time.Time, minGap time.Duration) *Summary {
+	s := Summary{Events: len(events)}
+	if len(events) == 0 {
+		return &s
+	}
+
+	sorted := append([]Event{}, events...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].TriggeredAt.Before(sorted[j].TriggeredAt) })
+	s.First = sorted[0].TriggeredAt
+	s.Last = sorted[len(sorted)-1].TriggeredAt
+
+	groups := make(map[string]int)
+	strategies := make(map[string]int)
+	regions := make(map[string]int)
+	days := make(map[string]int)
+	lastByGroup := make(map[string]time.Time)
+	gaps := make(map[string]Gap)
+	for _, e := range sorted {
+		groups[e.AutoScalingGroupName]++
+		strategies[string(e.Strategy)]++
+		if e.Region != "" {
+			regions[e.Region]++
+		}
+		days[e.TriggeredAt.UTC().Format("2006-01-02")]++
+
+		if last, ok := lastByGroup[e.AutoScalingGroupName]; ok {
+			gap := Gap{e.AutoScalingGroupName, last, e.TriggeredAt}
+			if gap.Duration() > gaps[e.AutoScalingGroupName].Duration() {
+				gaps[e.AutoScalingGroupName] = gap
+			}
+		}
+		lastByGroup[e.AutoScalingGroupName] = e.TriggeredAt
+	}
+
+	s.ByGroup = sortCounts(groups)
+	s.ByStrategy = sortCounts(strategies)
+	s.ByRegion = sortCounts(regions)
+
+	for day := s.First.UTC().Truncate(24 * time.Hour); !day.After(s.Last); day = day.Add(24 * time.Hour) {
+		name := day.Format("2006-01-02")
+		s.Timeline = append(s.Timeline, Count{name, days[name]})
+	}
+
+	for group, last := range lastByGroup {
+		gap := Gap{group, last, now}
+		if gap.Duration() > gaps[group].Duration() {
+			gaps[group] = gap
+		}
+	}
+	for _, gap := range gaps {
+		if gap.Duration() >= minGap {
+			s.Gaps = append(s.Gaps, gap)
+		}
+	}
+	sort.Slice(s.Gaps, func(i, j int) bool {
+		if s.Gaps[i].Duration() != s.Gaps[j].Duration() {
+			return s.Gaps[i].Duration() > s.Gaps[j].Duration()
+		}
+		return s.Gaps[i].AutoScalingGroupName < s.Gaps[j].AutoScalingGroupName
+	})
+	return &s
+}
+
+// Suggest adds suggestions to the summary: the given strategies never used
+// against groups found in the events, as suggested by SuggestCoverage, and
+// longer intervals for groups whose campaigns failed a check, as suggested by
+// SuggestIntervals.
+func (s *Summary) Suggest(events []Event, strategies []Strategy, results []CampaignResult) {
+	s.Suggestions = append(SuggestIntervals(results), SuggestCoverage(events, strategies)...)
+}
+
+// Annotate adds the given incidents caused by chaos to the summary, and
+// suggests fixing the weaknesses found before resuming chaos against the
+// groups of open incidents. Call it after Suggest, which replaces the
+// suggestions.
+func (s *Summary) Annotate(links []IncidentLink) {
+	s.Incidents = append(s.Incidents, links...)
+	sort.SliceStable(s.Incidents, func(i, j int) bool { return s.Incidents[i].LinkedAt.Before(s.Incidents[j].LinkedAt) })
+	var suggestions []Suggestion
+	for _, l := range links {
+		if !l.ResolvedAt.IsZero() {
+			continue
+		}
+		for _, g := range l.Groups {
+			suggestions = append(suggestions, Suggestion{
+				AutoScalingGroupName: g,
+				Strategy:             l.Strategy,
+				Reason:               fmt.Sprintf("caused incident %s; chaos is paused until it is resolved", l.IncidentID),
+			})
+		}
+	}
+	s.Suggestions = append(suggestions, s.Suggestions...)
+}
+
+func sortCounts(m map[string]int) []Count {
+	counts := make([]Count, 0, len(m))
+	for name, n := range m {
+		counts = append(counts, Count{name, n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Events != counts[j].Events {
+			return counts[i].Events > counts[j].Events
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts
+}
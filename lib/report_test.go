@@ -0,0 +1,52 @@
+package chaosmonkey_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestSummarize(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2018, 4, d, h, 0, 0, 0, time.UTC) }
+	events := []chaosmonkey.Event{
+		{AutoScalingGroupName: "b", Strategy: chaosmonkey.StrategyBurnCPU, Region: "us-east-1", TriggeredAt: day(4, 10)},
+		{AutoScalingGroupName: "a", Strategy: chaosmonkey.StrategyShutdownInstance, Region: "eu-west-1", TriggeredAt: day(1, 10)},
+		{AutoScalingGroupName: "a", Strategy: chaosmonkey.StrategyShutdownInstance, Region: "eu-west-1", TriggeredAt: day(1, 12)},
+		{AutoScalingGroupName: "a", Strategy: chaosmonkey.StrategyBurnCPU, Region: "eu-west-1", TriggeredAt: day(3, 12)},
+	}
+
+	got := chaosmonkey.Summarize(events, day(5, 10), 36*time.Hour)
+
+	expected := &chaosmonkey.Summary{
+		Events: 4,
+		First:  day(1, 10),
+		Last:   day(4, 10),
+		ByGroup: []chaosmonkey.Count{
+			{Name: "a", Events: 3},
+			{Name: "b", Events: 1},
+		},
+		ByStrategy: []chaosmonkey.Count{
+			{Name: "BurnCpu", Events: 2},
+			{Name: "ShutdownInstance", Events: 2},
+		},
+		ByRegion: []chaosmonkey.Count{
+			{Name: "eu-west-1", Events: 3},
+			{Name: "us-east-1", Events: 1},
+		},
+		Timeline: []chaosmonkey.Count{
+			{Name: "2018-04-01", Events: 2},
+			{Name: "2018-04-02", Events: 0},
+			{Name: "2018-04-03", Events: 1},
+			{Name: "2018-04-04", Events: 1},
+		},
+		Gaps: []chaosmonkey.Gap{
+			{AutoScalingGroupName: "a", Start: day(1, 12), End: day(3, 12)},
+		},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
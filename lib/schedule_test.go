@@ -0,0 +1,44 @@
+package chaosmonkey_test
+
+import (
+	"testing"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Tuesday
+	now := time.Date(2018, 4, 3, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2018, 4, 3, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2018, 4, 3, 10, 45, 0, 0, time.UTC)},
+		{"0 10 * * MON-FRI", time.Date(2018, 4, 4, 10, 0, 0, 0, time.UTC)},
+		{"0 10 * * sat,sun", time.Date(2018, 4, 7, 10, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2018, 4, 8, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 15 * FRI", time.Date(2018, 4, 6, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		s, err := chaosmonkey.ParseSchedule(test.expr)
+		if err != nil {
+			t.Errorf("%q: %s", test.expr, err)
+			continue
+		}
+		if next := s.Next(now); !next.Equal(test.next) {
+			t.Errorf("%q: got %s, want %s", test.expr, next, test.next)
+		}
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * * FOO", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := chaosmonkey.ParseSchedule(expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
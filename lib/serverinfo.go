@@ -0,0 +1,258 @@
+package chaosmonkey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ServerInfo describes a Chaos Monkey deployment as detected by the client.
+type ServerInfo struct {
+	// Value of the Server header returned by the API, if any
+	Server string
+
+	// Best guess of the deployed software or fork, e.g. "SimianArmy"
+	Flavor string
+
+	// Version of the deployed software as reported in the Server header,
+	// e.g. "2.5.3" (empty if unknown)
+	Version string
+
+	// Features supported by the deployment
+	Capabilities Capabilities
+}
+
+// Support tells whether a deployment supports a feature.
+type Support int
+
+const (
+	// SupportUnknown means that support could not be detected, e.g. because
+	// no chaos events were recorded yet
+	SupportUnknown Support = iota
+
+	// Supported means that the feature is supported
+	Supported
+
+	// Unsupported means that the feature is not supported
+	Unsupported
+)
+
+func (s Support) String() string {
+	switch s {
+	case Supported:
+		return "yes"
+	case Unsupported:
+		return "no"
+	}
+	return "unknown"
+}
+
+// Capabilities lists features a Chaos Monkey deployment may or may not
+// support.
+type Capabilities struct {
+	// Whether past chaos events can be listed
+	Events bool
+
+	// Whether the server honors the "since" parameter when listing events.
+	// The client filters events itself in any case, and learns that the
+	// server does not from the events it lists.
+	SinceFilter Support
+
+	// Whether the server supports chaos strategies other than
+	// ShutdownInstance, which it tells by recording the strategy of events.
+	// If it does not, the client omits the strategy from requests and
+	// refuses other strategies, which the server would ignore.
+	Strategies Support
+}
+
+// webServers are products named in Server headers that serve Chaos Monkey
+// rather than being the deployed software.
+var webServers = []string{"Jetty", "Apache", "Apache-Coyote", "nginx", "envoy", "Microsoft-IIS", "AmazonS3", "awselb"}
+
+// ServerInfo probes the Chaos Monkey deployment to detect its version and
+// capabilities. The client remembers the result and adjusts later requests
+// accordingly. Unless Config.ServerInfo is set, clients also probe the
+// deployment before triggering the first chaos event, so calling ServerInfo
+// is only needed to report the result or to probe again.
+func (c *Client) ServerInfo() (_ *ServerInfo, err error) {
+	ctx, span := c.startSpan(context.Background(), "chaosmonkey.ServerInfo")
+	defer func() { endSpan(span, err) }()
+
+	info, err := c.probe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.info = info
+	c.mu.Unlock()
+	return info, nil
+}
+
+// probeRetry is how long clients wait before probing the deployment again
+// after probing failed.
+const probeRetry = time.Minute
+
+// serverInfo returns the capabilities of the deployment: those given by
+// Config.ServerInfo or detected before, or otherwise those it detects now.
+// If detection fails, all capabilities are unknown and detection is tried
+// again on first use after probeRetry.
+func (c *Client) serverInfo(ctx context.Context) *ServerInfo {
+	c.mu.Lock()
+	info := c.info
+	c.mu.Unlock()
+	if info != nil {
+		return info
+	}
+
+	// Probe once for concurrent first uses
+	c.probeMu.Lock()
+	defer c.probeMu.Unlock()
+	c.mu.Lock()
+	info = c.info
+	c.mu.Unlock()
+	if info != nil {
+		return info
+	}
+	now := c.config.Clock.Now()
+	if now.Before(c.probeFailed.Add(probeRetry)) {
+		return &ServerInfo{Flavor: "unknown"}
+	}
+	info, err := c.probe(ctx)
+	if err != nil {
+		c.probeFailed = now
+		return &ServerInfo{Flavor: "unknown"}
+	}
+	c.mu.Lock()
+	c.info = info
+	c.mu.Unlock()
+	return info
+}
+
+// probe detects the version and capabilities of the deployment.
+func (c *Client) probe(ctx context.Context) (*ServerInfo, error) {
+	// Ask for events from the future, which a server honoring "since" will
+	// answer with an empty list, while others return all events
+	since := toMillis(c.config.Clock.Now().Add(24 * time.Hour))
+	events, resp, err := c.probeEvents(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ServerInfo{
+		Server: resp.Header.Get("Server"),
+		Flavor: "unknown",
+	}
+	if resp.StatusCode == http.StatusOK && events != nil {
+		info.Flavor = "SimianArmy"
+		info.Capabilities.Events = true
+		if len(events) > 0 {
+			info.Capabilities.SinceFilter = Unsupported
+		} else {
+			// An empty list may as well mean that there are no events
+			// at all, which tells nothing
+			if events, _, err = c.probeEvents(ctx, 0); err != nil {
+				return nil, err
+			}
+			if len(events) > 0 {
+				info.Capabilities.SinceFilter = Supported
+			}
+		}
+		if len(events) > 0 {
+			info.Capabilities.Strategies = Unsupported
+			for _, ev := range events {
+				if s, ok := ev["chaosType"]; ok && string(s) != `""` && string(s) != "null" {
+					info.Capabilities.Strategies = Supported
+					break
+				}
+			}
+		}
+	}
+	if product, version := parseServer(info.Server); product != "" && !isWebServer(product) {
+		info.Flavor, info.Version = product, version
+	} else if info.Flavor == "unknown" && product != "" {
+		info.Flavor = product
+	}
+	return info, nil
+}
+
+// probeEvents lists the events since the given time, keeping all fields of
+// the events. It returns nil events if the API is not found or answers with
+// something other than a list of events.
+func (c *Client) probeEvents(ctx context.Context, since int64) ([]map[string]json.RawMessage, *http.Response, error) {
+	url := fmt.Sprintf("%s%s?since=%d", c.config.Endpoint, APIPath, since)
+	resp, err := c.do(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, resp, nil
+	default:
+		return nil, nil, decodeError(resp)
+	}
+	var events []map[string]json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		return nil, resp, nil
+	}
+	if events == nil {
+		events = []map[string]json.RawMessage{}
+	}
+	return events, resp, nil
+}
+
+// parseServer returns the first product of a Server header, e.g.
+// "SimianArmy/2.5.3", and its version.
+func parseServer(server string) (product, version string) {
+	fields := strings.Fields(server)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	product = fields[0]
+	if i := strings.IndexAny(product, "/("); i > 0 {
+		if product[i] == '/' {
+			version = product[i+1:]
+		}
+		product = product[:i]
+	}
+	return product, version
+}
+
+func isWebServer(product string) bool {
+	for _, s := range webServers {
+		if strings.EqualFold(product, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoresSince records that the server does not honor the "since"
+// parameter, which the client learned from events it listed.
+func (c *Client) ignoresSince() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.info != nil && c.info.Capabilities.SinceFilter == SupportUnknown {
+		info := *c.info
+		info.Capabilities.SinceFilter = Unsupported
+		c.info = &info
+	}
+}
+
+// adjustRequest adapts a request to the capabilities of the server. It
+// returns an error if the server cannot cause the requested chaos.
+func (c *Client) adjustRequest(ctx context.Context, req *APIRequest) error {
+	if c.serverInfo(ctx).Capabilities.Strategies != Unsupported {
+		return nil
+	}
+	if req.ChaosType != "" && Strategy(req.ChaosType) != StrategyShutdownInstance {
+		return fmt.Errorf("Chaos Monkey at %s does not support strategy %s, only %s",
+			c.config.Endpoint, req.ChaosType, StrategyShutdownInstance)
+	}
+	req.ChaosType = ""
+	return nil
+}
@@ -0,0 +1,168 @@
+package chaosmonkey_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestServerInfoWithoutSinceFilter(t *testing.T) {
+	// Server that ignores the "since" parameter
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "Jetty(9.2.z-SNAPSHOT)")
+		fmt.Fprint(w, pastEvents)
+	}))
+	defer ts.Close()
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{Endpoint: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := client.ServerInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &chaosmonkey.ServerInfo{
+		Server: "Jetty(9.2.z-SNAPSHOT)",
+		Flavor: "SimianArmy",
+		Capabilities: chaosmonkey.Capabilities{
+			Events:      true,
+			SinceFilter: chaosmonkey.Unsupported,
+			Strategies:  chaosmonkey.Supported,
+		},
+	}
+	if diff := cmp.Diff(want, info); diff != "" {
+		t.Fatalf("unexpected server info (-want +got):\n%s", diff)
+	}
+
+	// The client filters events itself
+	events, err := client.EventsSince(time.Unix(1460116900, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].InstanceID != "i-12345678" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
+
+func TestServerInfo(t *testing.T) {
+	tests := []struct {
+		name   string
+		server string
+		events string
+		want   chaosmonkey.ServerInfo
+	}{
+		{
+			name:   "empty event store",
+			server: "SimianArmy/2.5.3",
+			events: `[]`,
+			want: chaosmonkey.ServerInfo{
+				Server:       "SimianArmy/2.5.3",
+				Flavor:       "SimianArmy",
+				Version:      "2.5.3",
+				Capabilities: chaosmonkey.Capabilities{Events: true},
+			},
+		},
+		{
+			name:   "fork honoring since",
+			server: "chaos-fork/1.2 (linux)",
+			events: pastEvents,
+			want: chaosmonkey.ServerInfo{
+				Server:  "chaos-fork/1.2 (linux)",
+				Flavor:  "chaos-fork",
+				Version: "1.2",
+				Capabilities: chaosmonkey.Capabilities{
+					Events:      true,
+					SinceFilter: chaosmonkey.Supported,
+					Strategies:  chaosmonkey.Supported,
+				},
+			},
+		},
+		{
+			name:   "without strategies",
+			server: "Apache-Coyote/1.1",
+			events: `[{"eventId": "i-12345678", "eventTime": 1460116927834, "groupName": "SomeAutoScalingGroup"}]`,
+			want: chaosmonkey.ServerInfo{
+				Server: "Apache-Coyote/1.1",
+				Flavor: "SimianArmy",
+				Capabilities: chaosmonkey.Capabilities{
+					Events:      true,
+					SinceFilter: chaosmonkey.Supported,
+					Strategies:  chaosmonkey.Unsupported,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Server", tt.server)
+			// Honor "since" for events from the future only
+			if since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64); since > time.Now().Unix()*1000 {
+				fmt.Fprint(w, "[]")
+				return
+			}
+			fmt.Fprint(w, tt.events)
+		}))
+		client, err := chaosmonkey.NewClient(&chaosmonkey.Config{Endpoint: ts.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := client.ServerInfo()
+		ts.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if diff := cmp.Diff(&tt.want, info); diff != "" {
+			t.Errorf("%s: unexpected server info (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestServerInfoAdjustsRequests(t *testing.T) {
+	var requests []chaosmonkey.APIRequest
+	probes := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			probes++
+			fmt.Fprint(w, `[{"eventId": "i-12345678", "eventTime": 1460116927834, "groupName": "SomeAutoScalingGroup"}]`)
+			return
+		}
+		var req chaosmonkey.APIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, req)
+		fmt.Fprint(w, newEvent)
+	}))
+	defer ts.Close()
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{Endpoint: ts.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The deployment is probed on first use
+	_, err = client.TriggerEvent("SomeAutoScalingGroup", chaosmonkey.StrategyBurnCPU)
+	if want := "does not support strategy BurnCpu"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+	if _, err := client.TriggerEvent("SomeAutoScalingGroup", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatal(err)
+	}
+	if probes != 1 {
+		t.Errorf("deployment was probed %d times, want once", probes)
+	}
+	want := []chaosmonkey.APIRequest{{EventType: "CHAOS_TERMINATION", GroupName: "SomeAutoScalingGroup", GroupType: "ASG"}}
+	if diff := cmp.Diff(want, requests); diff != "" {
+		t.Errorf("unexpected requests (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,60 @@
+package chaosmonkey
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Decision records whether a chaos event would have been triggered. Decisions
+// are recorded in shadow mode, where the client runs with DryRun so that teams
+// can review what their schedules and guards would do before enforcing them.
+type Decision struct {
+	// Time of the decision
+	Time time.Time `json:"time" yaml:"time"`
+
+	// Name of the targeted auto scaling group
+	AutoScalingGroupName string `json:"autoScalingGroupName" yaml:"autoScalingGroupName"`
+
+	// Chaos strategy that would have been used
+	Strategy Strategy `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+
+	// AWS region of the auto scaling group
+	Region string `json:"region,omitempty" yaml:"region,omitempty"`
+
+	// Whether the chaos event would have been triggered
+	Triggered bool `json:"triggered" yaml:"triggered"`
+
+	// Why the chaos event would have been refused, if it was
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// RecordDecisions writes a Decision as one line of JSON to w for every chaos
+// event triggered or refused by clients publishing to the bus. It returns a
+// function that stops recording.
+func RecordDecisions(bus *Bus, w io.Writer) (stop func()) {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	record := func(m Message) {
+		d := Decision{
+			Time:                 m.Time,
+			AutoScalingGroupName: m.AutoScalingGroupName,
+			Strategy:             m.Strategy,
+			Region:               m.Region,
+			Triggered:            m.Topic == TopicEventRecorded,
+		}
+		if m.Err != nil {
+			d.Reason = m.Err.Error()
+		}
+		mu.Lock()
+		enc.Encode(d)
+		mu.Unlock()
+	}
+	unsubRecorded := bus.Subscribe(TopicEventRecorded, record)
+	unsubBlocked := bus.Subscribe(TopicGuardBlocked, record)
+	return func() {
+		unsubRecorded()
+		unsubBlocked()
+	}
+}
@@ -0,0 +1,70 @@
+package chaosmonkey_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestRecordDecisions(t *testing.T) {
+	now := time.Date(2018, 4, 3, 10, 0, 0, 0, time.UTC)
+	bus := chaosmonkey.NewBus()
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Region: "eu-west-1",
+		DryRun: true,
+		Bus:    bus,
+		Clock:  &fakeClock{now: now},
+		Guards: []chaosmonkey.Guard{chaosmonkey.GuardFunc(func(t chaosmonkey.Target) error {
+			if t.AutoScalingGroupName == "frozen" {
+				return errors.New("group is frozen")
+			}
+			return nil
+		})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	stop := chaosmonkey.RecordDecisions(bus, &buf)
+	client.TriggerEvent("app", chaosmonkey.StrategyShutdownInstance)
+	client.TriggerEvent("frozen", chaosmonkey.StrategyBurnCPU)
+	stop()
+	client.TriggerEvent("app", chaosmonkey.StrategyShutdownInstance)
+
+	var decisions []chaosmonkey.Decision
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var d chaosmonkey.Decision
+		if err := dec.Decode(&d); err != nil {
+			t.Fatal(err)
+		}
+		decisions = append(decisions, d)
+	}
+
+	expected := []chaosmonkey.Decision{
+		{
+			Time:                 now,
+			AutoScalingGroupName: "app",
+			Strategy:             chaosmonkey.StrategyShutdownInstance,
+			Region:               "eu-west-1",
+			Triggered:            true,
+		},
+		{
+			Time:                 now,
+			AutoScalingGroupName: "frozen",
+			Strategy:             chaosmonkey.StrategyBurnCPU,
+			Region:               "eu-west-1",
+			Reason:               "group is frozen",
+		},
+	}
+	if diff := cmp.Diff(expected, decisions); diff != "" {
+		t.Fatal(diff)
+	}
+}
@@ -0,0 +1,163 @@
+package chaosmonkey
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SoakConfig configures a soak test, which sustains low-intensity chaos over a
+// long period of time.
+type SoakConfig struct {
+	// Auto scaling groups to target, one after another
+	Groups []string
+
+	// Chaos strategy to use, typically a non-destructive one like
+	// StrategyNetworkLatency
+	Strategy Strategy
+
+	// Time to wait between chaos events
+	Interval time.Duration
+
+	// Maximum number of chaos events to trigger (unlimited if zero)
+	Budget int
+
+	// Optional check run periodically to verify that the system still
+	// behaves as expected. If it returns an error, the soak test stops.
+	Check func() error
+
+	// Time between checks (defaults to Interval)
+	CheckInterval time.Duration
+
+	// Optional functions to inject chaos into a subset of the instances of
+	// a group, rotating through all of them over time, instead of letting
+	// Chaos Monkey pick a random instance. Instances lists the instances of
+	// a group, e.g. with aws.Client.Instances, and Inject injects chaos into
+	// the given ones, e.g. with ssm.Injector.Inject. Inject must subject
+	// the chaos event to the checks of the client, which the ssm package
+	// does if the Chaos of its AWS client is set.
+	Instances func(ctx context.Context, group string) ([]string, error)
+	Inject    func(ctx context.Context, group string, instanceIDs []string) (*Event, error)
+
+	// Number of instances to inject chaos into at once (1 if zero)
+	SubsetSize int
+
+	// Optional function told about chaos events refused by guards, e.g.
+	// during cooldowns or outside of allowed hours, which the soak test
+	// skips
+	OnRefused func(group string, err *GuardError)
+}
+
+// Soak runs a soak test until the context is canceled, the budget is spent,
+// a check fails, an outage or emergency stop is detected, or chaos is halted.
+// It triggers a chaos event every interval, rotating through the configured
+// groups and, with SoakConfig.Inject, through their instances, and returns all
+// events triggered so far. Chaos events refused by guards are skipped. The
+// soak test waits with Config.Clock if it implements Timer.
+func (c *Client) Soak(ctx context.Context, cfg SoakConfig) ([]Event, error) {
+	if len(cfg.Groups) == 0 {
+		return nil, fmt.Errorf("soak test needs at least one auto scaling group")
+	}
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("soak test needs a positive interval")
+	}
+	if (cfg.Instances == nil) != (cfg.Inject == nil) {
+		return nil, fmt.Errorf("soak test needs both Instances and Inject, or neither")
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = cfg.Interval
+	}
+	if cfg.SubsetSize <= 0 {
+		cfg.SubsetSize = 1
+	}
+
+	ctx, cancel := c.haltable(ctx)
+	defer cancel()
+
+	var events []Event
+	defer func() {
+		c.config.Bus.Publish(Message{
+			Topic:    TopicExperimentFinished,
+			Time:     c.config.Clock.Now().UTC(),
+			Strategy: cfg.Strategy,
+		})
+	}()
+
+	clock := c.config.Clock
+	nextTrigger := clock.Now().Add(cfg.Interval)
+	nextCheck := clock.Now().Add(cfg.CheckInterval)
+	rotation := make(map[string]int) // next instance to target per group
+	for next := 0; cfg.Budget == 0 || len(events) < cfg.Budget; {
+		wake := nextTrigger
+		if nextCheck.Before(wake) {
+			wake = nextCheck
+		}
+		select {
+		case <-ctx.Done():
+			if c.config.HaltSwitch.IsHalted() {
+				return events, ErrHalted
+			}
+			return events, nil
+		case <-clockAfter(clock, wake.Sub(clock.Now())):
+		}
+
+		now := clock.Now()
+		if !now.Before(nextCheck) {
+			nextCheck = now.Add(cfg.CheckInterval)
+			if cfg.Check != nil {
+				if err := cfg.Check(); err != nil {
+					return events, fmt.Errorf("soak test check failed: %s", err)
+				}
+			}
+		}
+		if now.Before(nextTrigger) {
+			continue
+		}
+		nextTrigger = now.Add(cfg.Interval)
+
+		group := cfg.Groups[next%len(cfg.Groups)]
+		next++
+		ev, err := c.soakEvent(ctx, cfg, group, rotation)
+		if refused, ok := err.(*GuardError); ok {
+			if cfg.OnRefused != nil {
+				cfg.OnRefused(group, refused)
+			}
+			continue
+		}
+		if err != nil {
+			return events, err
+		}
+		if ev != nil {
+			events = append(events, *ev)
+		}
+	}
+	return events, nil
+}
+
+// soakEvent causes the next chaos event of a soak test against the group.
+// rotation holds the index of the next instance to target per group.
+func (c *Client) soakEvent(ctx context.Context, cfg SoakConfig, group string, rotation map[string]int) (*Event, error) {
+	if cfg.Inject == nil {
+		return c.TriggerEvent(group, cfg.Strategy)
+	}
+	instances, err := cfg.Instances(ctx, group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances of %s: %s", group, err)
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("auto scaling group %s has no instances", group)
+	}
+	// Rotate in a stable order, so that every instance gets its turn
+	sort.Strings(instances)
+	size := cfg.SubsetSize
+	if size > len(instances) {
+		size = len(instances)
+	}
+	subset := make([]string, size)
+	for i := range subset {
+		subset[i] = instances[(rotation[group]+i)%len(instances)]
+	}
+	rotation[group] = (rotation[group] + size) % len(instances)
+	return cfg.Inject(ctx, group, subset)
+}
@@ -0,0 +1,119 @@
+package chaosmonkey_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestSoak(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint: ts.URL,
+		DryRun:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := client.Soak(context.Background(), chaosmonkey.SoakConfig{
+		Groups:   []string{"a", "b"},
+		Strategy: chaosmonkey.StrategyNetworkLatency,
+		Interval: time.Millisecond,
+		Budget:   3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var groups []string
+	for _, e := range events {
+		groups = append(groups, e.AutoScalingGroupName)
+	}
+	if diff := cmp.Diff([]string{"a", "b", "a"}, groups); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+// steppingClock is a fake clock whose timers fire right away, advancing the
+// clock.
+type steppingClock struct {
+	now time.Time
+}
+
+func (c *steppingClock) Now() time.Time { return c.now }
+
+func (c *steppingClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestSoakInstances(t *testing.T) {
+	start := time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)
+	clock := &steppingClock{now: start}
+	attempts := 0
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Clock: clock,
+		Guards: []chaosmonkey.Guard{chaosmonkey.GuardFunc(func(t chaosmonkey.Target) error {
+			if attempts++; attempts == 2 {
+				return errors.New("cooling down")
+			}
+			return nil
+		})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var subsets [][]string
+	var refused []string
+	events, err := client.Soak(context.Background(), chaosmonkey.SoakConfig{
+		Groups:   []string{"a"},
+		Strategy: chaosmonkey.StrategyNetworkLatency,
+		Interval: 6 * time.Hour,
+		Budget:   3,
+		Instances: func(ctx context.Context, group string) ([]string, error) {
+			return []string{"i-3", "i-1", "i-2"}, nil
+		},
+		Inject: func(ctx context.Context, group string, instanceIDs []string) (*chaosmonkey.Event, error) {
+			return client.Inject(ctx, group, chaosmonkey.StrategyNetworkLatency, "", func(ctx context.Context) (*chaosmonkey.Event, error) {
+				subsets = append(subsets, instanceIDs)
+				return &chaosmonkey.Event{InstanceID: instanceIDs[0]}, nil
+			})
+		},
+		SubsetSize: 2,
+		OnRefused: func(group string, err *chaosmonkey.GuardError) {
+			refused = append(refused, err.Error())
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Refused chaos events are skipped, and the rotation continues
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+	want := [][]string{{"i-1", "i-2"}, {"i-2", "i-3"}, {"i-1", "i-2"}}
+	if diff := cmp.Diff(want, subsets); diff != "" {
+		t.Errorf("unexpected instances (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"chaos event refused: cooling down"}, refused); diff != "" {
+		t.Errorf("unexpected refusals (-want +got):\n%s", diff)
+	}
+	// The soak test waited with the clock of the client
+	if elapsed := clock.now.Sub(start); elapsed != 24*time.Hour {
+		t.Errorf("expected soak test to take 24h, took %s", elapsed)
+	}
+}
@@ -1,5 +1,11 @@
 package chaosmonkey
 
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
 // Strategy defines a chaos strategy for terminating EC2 instances.
 type Strategy string
 
@@ -92,7 +98,7 @@ const (
 
 	// StrategyKillEcs kills any docker containers programs it finds
 	// every second, simulating a docker container services, corrupted
-	// installation or faulty instance. 
+	// installation or faulty instance.
 	// Requires SSH to be configured.
 	StrategyKillEcs Strategy = "KillEcs"
 )
@@ -116,3 +122,129 @@ var Strategies = []Strategy{
 	StrategyNetworkLoss,
 	StrategyKillEcs,
 }
+
+// StrategyInfo describes properties of a chaos strategy.
+type StrategyInfo struct {
+	// Short description of what the strategy does
+	Description string
+
+	// Whether the strategy disrupts network traffic of the instance
+	Network bool
+
+	// Whether the strategy destroys the instance or its data, as opposed to
+	// only degrading it
+	Destructive bool
+
+	// Whether Chaos Monkey needs SSH access to the instance
+	RequiresSSH bool
+}
+
+var (
+	strategiesMu sync.RWMutex
+
+	// strategyInfo holds the properties of all known strategies.
+	strategyInfo = map[Strategy]StrategyInfo{
+		StrategyShutdownInstance:       {Description: "Shut down the instance", Destructive: true},
+		StrategyBlockAllNetworkTraffic: {Description: "Block all network traffic", Network: true},
+		StrategyDetachVolumes:          {Description: "Force-detach all EBS volumes", Destructive: true},
+		StrategyBurnCPU:                {Description: "Run CPU intensive processes", RequiresSSH: true},
+		StrategyBurnIO:                 {Description: "Run disk intensive processes", RequiresSSH: true},
+		StrategyKillProcesses:          {Description: "Kill Java and Python programs", RequiresSSH: true},
+		StrategyNullRoute:              {Description: "Null-route the EC2 internal network", Network: true, RequiresSSH: true},
+		StrategyFailEC2:                {Description: "Make EC2 API calls fail", RequiresSSH: true},
+		StrategyFailDNS:                {Description: "Block DNS traffic", Network: true, RequiresSSH: true},
+		StrategyFailDynamoDB:           {Description: "Make DynamoDB calls fail", RequiresSSH: true},
+		StrategyFailS3:                 {Description: "Make S3 calls fail", RequiresSSH: true},
+		StrategyFillDisk:               {Description: "Fill up the root disk", Destructive: true, RequiresSSH: true},
+		StrategyNetworkCorruption:      {Description: "Corrupt network packets", Network: true, RequiresSSH: true},
+		StrategyNetworkLatency:         {Description: "Add latency to network packets", Network: true, RequiresSSH: true},
+		StrategyNetworkLoss:            {Description: "Drop network packets", Network: true, RequiresSSH: true},
+		StrategyKillEcs:                {Description: "Kill Docker containers", RequiresSSH: true},
+	}
+
+	// customStrategies lists registered strategies in registration order.
+	customStrategies []Strategy
+)
+
+// RegisterStrategy adds a custom chaos strategy, e.g. one supported by a
+// fork of Chaos Monkey. Registered strategies are accepted by ParseStrategy
+// and listed by KnownStrategies. It is an error to register a strategy that
+// is already known, ignoring case.
+func RegisterStrategy(s Strategy, info StrategyInfo) error {
+	if s == "" {
+		return fmt.Errorf("strategy name must not be empty")
+	}
+
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	// ParseStrategy matches names case-insensitively, so names differing in
+	// case only would be ambiguous
+	for known := range strategyInfo {
+		if strings.EqualFold(string(known), string(s)) {
+			return fmt.Errorf("chaos strategy %q is already known", known)
+		}
+	}
+	strategyInfo[s] = info
+	customStrategies = append(customStrategies, s)
+	return nil
+}
+
+// LookupStrategy returns the properties of a known chaos strategy.
+func LookupStrategy(s Strategy) (StrategyInfo, bool) {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	info, ok := strategyInfo[s]
+	return info, ok
+}
+
+// ParseStrategy returns the known chaos strategy with the given name. Names
+// are matched case-insensitively. An error is returned for unknown names.
+func ParseStrategy(name string) (Strategy, error) {
+	for _, s := range KnownStrategies() {
+		if strings.EqualFold(string(s), name) {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown chaos strategy %q", name)
+}
+
+// KnownStrategies returns a list of all known chaos strategies: the default
+// ones followed by registered ones.
+func KnownStrategies() []Strategy {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	known := append([]Strategy(nil), Strategies...)
+	return append(known, customStrategies...)
+}
+
+// String returns the name of the strategy.
+func (s Strategy) String() string {
+	return string(s)
+}
+
+// IsKnown reports whether the strategy is a known chaos strategy.
+func (s Strategy) IsKnown() bool {
+	_, ok := LookupStrategy(s)
+	return ok
+}
+
+// IsNetwork reports whether the strategy disrupts network traffic of the
+// instance.
+func (s Strategy) IsNetwork() bool {
+	info, _ := LookupStrategy(s)
+	return info.Network
+}
+
+// IsDestructive reports whether the strategy destroys the instance or its
+// data, as opposed to only degrading it.
+func (s Strategy) IsDestructive() bool {
+	info, _ := LookupStrategy(s)
+	return info.Destructive
+}
+
+// RequiresSSH reports whether Chaos Monkey needs SSH access to the instance
+// to apply the strategy.
+func (s Strategy) RequiresSSH() bool {
+	info, _ := LookupStrategy(s)
+	return info.RequiresSSH
+}
@@ -0,0 +1,82 @@
+package chaosmonkey_test
+
+import (
+	"testing"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestParseStrategy(t *testing.T) {
+	for _, name := range []string{"ShutdownInstance", "shutdowninstance", "BURNCPU"} {
+		s, err := chaosmonkey.ParseStrategy(name)
+		if err != nil {
+			t.Errorf("ParseStrategy(%q) failed: %s", name, err)
+			continue
+		}
+		if !s.IsKnown() {
+			t.Errorf("ParseStrategy(%q) = %s, which is unknown", name, s)
+		}
+	}
+
+	if _, err := chaosmonkey.ParseStrategy("NoSuchStrategy"); err == nil {
+		t.Error("expected error for unknown strategy")
+	}
+}
+
+func TestKnownStrategies(t *testing.T) {
+	for _, s := range chaosmonkey.KnownStrategies() {
+		if !s.IsKnown() {
+			t.Errorf("strategy %s is listed but not known", s)
+		}
+	}
+}
+
+func TestStrategyPredicates(t *testing.T) {
+	if !chaosmonkey.StrategyShutdownInstance.IsDestructive() {
+		t.Error("expected ShutdownInstance to be destructive")
+	}
+	if chaosmonkey.StrategyShutdownInstance.IsNetwork() {
+		t.Error("expected ShutdownInstance not to be a network strategy")
+	}
+	if !chaosmonkey.StrategyNetworkLatency.IsNetwork() {
+		t.Error("expected NetworkLatency to be a network strategy")
+	}
+	if chaosmonkey.StrategyBlockAllNetworkTraffic.RequiresSSH() {
+		t.Error("expected BlockAllNetworkTraffic not to require SSH")
+	}
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	custom := chaosmonkey.Strategy("FailKafka")
+	err := chaosmonkey.RegisterStrategy(custom, chaosmonkey.StrategyInfo{
+		Description: "Make Kafka calls fail",
+		Network:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err := chaosmonkey.ParseStrategy("failkafka"); err != nil || s != custom {
+		t.Errorf("ParseStrategy() = %q, %v", s, err)
+	}
+	if !custom.IsNetwork() || custom.IsDestructive() {
+		t.Error("custom strategy has unexpected properties")
+	}
+	known := chaosmonkey.KnownStrategies()
+	if known[len(known)-1] != custom {
+		t.Error("custom strategy is not listed")
+	}
+
+	if err := chaosmonkey.RegisterStrategy(custom, chaosmonkey.StrategyInfo{}); err == nil {
+		t.Error("expected error when registering strategy twice")
+	}
+	if err := chaosmonkey.RegisterStrategy(chaosmonkey.StrategyShutdownInstance, chaosmonkey.StrategyInfo{}); err == nil {
+		t.Error("expected error when registering default strategy")
+	}
+	if err := chaosmonkey.RegisterStrategy("FAILKAFKA", chaosmonkey.StrategyInfo{}); err == nil {
+		t.Error("expected error when registering strategy differing in case only")
+	}
+	if err := chaosmonkey.RegisterStrategy("shutdowninstance", chaosmonkey.StrategyInfo{}); err == nil {
+		t.Error("expected error when registering default strategy in lower case")
+	}
+}
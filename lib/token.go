@@ -0,0 +1,471 @@
+package chaosmonkey
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTokenTTL is how long scoped tokens are valid unless requested
+// otherwise.
+const DefaultTokenTTL = 15 * time.Minute
+
+// DefaultMaxTokenTTL is the longest validity of scoped tokens unless
+// configured otherwise.
+const DefaultMaxTokenTTL = 24 * time.Hour
+
+// ErrTokenUsed is returned for single-use tokens that were used before.
+var ErrTokenUsed = errors.New("token was already used")
+
+// ScopedToken allows a single chaos event with one strategy against one
+// group until it expires. Scoped tokens are handed to automation like CI
+// jobs, which cannot reuse them to trigger other chaos later.
+type ScopedToken struct {
+	// Short ID of the token, which identifies it without revealing it
+	ID string `json:"id"`
+
+	// The token itself, to be presented to the Daemon
+	Token string `json:"token,omitempty"`
+
+	// Name of the auto scaling group the token allows chaos against
+	Group string `json:"group"`
+
+	// Chaos strategy the token allows
+	Strategy Strategy `json:"strategy"`
+
+	// Time when the token expires
+	Expires time.Time `json:"expires"`
+}
+
+// scopedClaims is the signed payload of a scoped token.
+type scopedClaims struct {
+	Group    string   `json:"g"`
+	Strategy Strategy `json:"s"`
+	Expires  int64    `json:"e"`
+	Nonce    string   `json:"n"`
+}
+
+// UsedTokens records which single-use tokens were used.
+type UsedTokens interface {
+	// Use marks the token with the given ID as used. It returns
+	// ErrTokenUsed if the token was used before. Records of tokens may be
+	// dropped once they expired.
+	Use(id string, expires time.Time) error
+
+	// Release marks the token as unused again, e.g. because the chaos event
+	// it was used for failed.
+	Release(id string) error
+
+	// IsUsed reports whether the token with the given ID is marked as used.
+	IsUsed(id string) (bool, error)
+}
+
+// memoryTokens is UsedTokens kept in memory.
+type memoryTokens struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+func (m *memoryTokens) Use(id string, expires time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for k, exp := range m.used {
+		if now.After(exp) {
+			delete(m.used, k)
+		}
+	}
+	if _, ok := m.used[id]; ok {
+		return ErrTokenUsed
+	}
+	if m.used == nil {
+		m.used = make(map[string]time.Time)
+	}
+	m.used[id] = expires
+	return nil
+}
+
+func (m *memoryTokens) Release(id string) error {
+	m.mu.Lock()
+	delete(m.used, id)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memoryTokens) IsUsed(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.used[id]
+	return ok, nil
+}
+
+// TokenDir is UsedTokens persisted in a directory with one file per used
+// token. Files are created exclusively, so the directory can be shared by
+// several processes, e.g. on a shared file system, and survives restarts.
+type TokenDir struct {
+	// Path of the directory, which is created if needed
+	Path string
+
+	// Clock used to tell the current time (SystemClock if nil)
+	Clock Clock
+}
+
+// Use creates the file of the token, which fails if it exists. It also
+// removes files of expired tokens.
+func (d *TokenDir) Use(id string, expires time.Time) error {
+	if !validTokenID(id) {
+		return fmt.Errorf("invalid token ID %q", id)
+	}
+	if err := os.MkdirAll(d.Path, 0700); err != nil {
+		return err
+	}
+	d.prune()
+	f, err := os.OpenFile(filepath.Join(d.Path, id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return ErrTokenUsed
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(expires.UTC().Format(time.RFC3339))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Release removes the file of the token.
+func (d *TokenDir) Release(id string) error {
+	if !validTokenID(id) {
+		return fmt.Errorf("invalid token ID %q", id)
+	}
+	if err := os.Remove(filepath.Join(d.Path, id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// IsUsed reports whether the file of the token exists.
+func (d *TokenDir) IsUsed(id string) (bool, error) {
+	if !validTokenID(id) {
+		return false, fmt.Errorf("invalid token ID %q", id)
+	}
+	_, err := os.Stat(filepath.Join(d.Path, id))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// prune removes the files of expired tokens.
+func (d *TokenDir) prune() {
+	files, err := ioutil.ReadDir(d.Path)
+	if err != nil {
+		return
+	}
+	now := d.now()
+	for _, fi := range files {
+		data, err := ioutil.ReadFile(filepath.Join(d.Path, fi.Name()))
+		if err != nil {
+			continue
+		}
+		if expires, err := time.Parse(time.RFC3339, string(data)); err == nil && now.After(expires) {
+			os.Remove(filepath.Join(d.Path, fi.Name()))
+		}
+	}
+}
+
+func (d *TokenDir) now() time.Time {
+	if d.Clock == nil {
+		return time.Now().UTC()
+	}
+	return d.Clock.Now().UTC()
+}
+
+// validTokenID reports whether id looks like the ID of a token, which makes
+// it safe to use as a file name.
+func validTokenID(id string) bool {
+	if id == "" {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// TokenIssuer mints and verifies scoped tokens, which are signed with a key
+// only known to the issuer.
+type TokenIssuer struct {
+	// Key used to sign and verify tokens
+	Key []byte
+
+	// Record of used tokens (kept in memory if nil, which forgets them on
+	// restart)
+	Used UsedTokens
+
+	// Longest validity of tokens (DefaultMaxTokenTTL if zero)
+	MaxTTL time.Duration
+
+	// Clock used to tell the current time (SystemClock if nil)
+	Clock Clock
+
+	once sync.Once
+}
+
+// Issue mints a token allowing a single chaos event with the given strategy
+// (ShutdownInstance if empty) against the given group, which expires after
+// the TTL (DefaultTokenTTL if zero).
+func (i *TokenIssuer) Issue(group string, strategy Strategy, ttl time.Duration) (*ScopedToken, error) {
+	if ttl == 0 {
+		ttl = DefaultTokenTTL
+	}
+	maxTTL := i.MaxTTL
+	if maxTTL == 0 {
+		maxTTL = DefaultMaxTokenTTL
+	}
+	if strategy == "" {
+		strategy = StrategyShutdownInstance
+	}
+	switch {
+	case len(i.Key) == 0:
+		return nil, fmt.Errorf("tokens need a key")
+	case group == "":
+		return nil, fmt.Errorf("token needs a group")
+	case !strategy.IsKnown():
+		return nil, fmt.Errorf("unknown chaos strategy %q", strategy)
+	case ttl < 0 || ttl > maxTTL:
+		return nil, fmt.Errorf("token TTL must be positive and at most %s", maxTTL)
+	}
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	claims := scopedClaims{
+		Group:    group,
+		Strategy: strategy,
+		Expires:  i.now().Add(ttl).Unix(),
+		Nonce:    hex.EncodeToString(nonce),
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	enc := base64.RawURLEncoding
+	return i.Verify(enc.EncodeToString(payload) + "." + enc.EncodeToString(i.sign(payload)))
+}
+
+// Verify checks the signature and expiry of a token and returns its scope.
+func (i *TokenIssuer) Verify(token string) (*ScopedToken, error) {
+	invalid := fmt.Errorf("invalid token")
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, invalid
+	}
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return nil, invalid
+	}
+	sig, err := enc.DecodeString(parts[1])
+	if err != nil || len(i.Key) == 0 || !hmac.Equal(sig, i.sign(payload)) {
+		return nil, invalid
+	}
+	var claims scopedClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, invalid
+	}
+	st := &ScopedToken{
+		ID:       hex.EncodeToString(sig[:8]),
+		Token:    token,
+		Group:    claims.Group,
+		Strategy: claims.Strategy,
+		Expires:  time.Unix(claims.Expires, 0).UTC(),
+	}
+	if !i.now().Before(st.Expires) {
+		return nil, fmt.Errorf("token %s expired at %s", st.ID, st.Expires.Format(time.RFC3339))
+	}
+	return st, nil
+}
+
+// Redeem verifies that the token allows a chaos event with the given strategy
+// against the given group and marks it as used. Call the returned function to
+// release the token again if the chaos event failed.
+func (i *TokenIssuer) Redeem(token, group string, strategy Strategy) (release func(), err error) {
+	st, err := i.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if strategy == "" {
+		strategy = StrategyShutdownInstance
+	}
+	if st.Group != group || st.Strategy != strategy {
+		return nil, fmt.Errorf("token %s only allows %s against %s", st.ID, st.Strategy, st.Group)
+	}
+	used := i.used()
+	if err := used.Use(st.ID, st.Expires); err == ErrTokenUsed {
+		return nil, fmt.Errorf("token %s was already used", st.ID)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to record use of token %s: %s", st.ID, err)
+	}
+	return func() { used.Release(st.ID) }, nil
+}
+
+func (i *TokenIssuer) used() UsedTokens {
+	i.once.Do(func() {
+		if i.Used == nil {
+			i.Used = &memoryTokens{}
+		}
+	})
+	return i.Used
+}
+
+func (i *TokenIssuer) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, i.Key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (i *TokenIssuer) now() time.Time {
+	if i.Clock == nil {
+		return time.Now().UTC()
+	}
+	return i.Clock.Now().UTC()
+}
+
+// Daemon triggers chaos events on behalf of automation presenting scoped
+// tokens, so that automation never holds the credentials of Chaos Monkey
+// and cannot trigger chaos beyond what its token allows. It implements
+// http.Handler:
+//
+//	POST /tokens  mints a token from {"group", "strategy", "ttl"}, authorized by the admin secret
+//	POST /events  triggers a chaos event from {"group", "strategy", "region"}, authorized by a token for it
+//
+// Both expect their credential as bearer token in the Authorization header.
+type Daemon struct {
+	// Client triggering chaos events, including its guards
+	Client *Client
+
+	// Issuer of tokens
+	Tokens *TokenIssuer
+
+	// Secret required to mint tokens
+	AdminSecret []byte
+}
+
+// tokenRequest is the body of a POST request to mint a token.
+type tokenRequest struct {
+	Group    string   `json:"group"`
+	Strategy Strategy `json:"strategy"`
+	TTL      string   `json:"ttl"`
+}
+
+// triggerRequest is the body of a POST request to trigger a chaos event.
+type triggerRequest struct {
+	Group    string   `json:"group"`
+	Strategy Strategy `json:"strategy"`
+	Region   string   `json:"region"`
+}
+
+// ServeHTTP implements the HTTP API of the daemon.
+func (d *Daemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch strings.Trim(r.URL.Path, "/") {
+	case "tokens":
+		d.mint(w, r)
+	case "events":
+		d.trigger(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// AuthorizeAdmin returns an error unless the request carries the admin
+// secret as bearer token. It may authorize other administrative APIs served
+// along with the daemon, like the one of a Freezer.
+func (d *Daemon) AuthorizeAdmin(r *http.Request) error {
+	secret := bearerToken(r)
+	if len(d.AdminSecret) == 0 || subtle.ConstantTimeCompare([]byte(secret), d.AdminSecret) != 1 {
+		return fmt.Errorf("request requires the admin secret")
+	}
+	return nil
+}
+
+func (d *Daemon) mint(w http.ResponseWriter, r *http.Request) {
+	if d.AuthorizeAdmin(r) != nil {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{"minting tokens requires the admin secret"})
+		return
+	}
+	var req tokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+	}
+	st, err := d.Tokens.Issue(req.Group, req.Strategy, ttl)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (d *Daemon) trigger(w http.ResponseWriter, r *http.Request) {
+	var req triggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	release, err := d.Tokens.Redeem(bearerToken(r), req.Group, req.Strategy)
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, errorResponse{err.Error()})
+		return
+	}
+	ev, err := d.Client.TriggerEventInRegion(req.Group, req.Strategy, req.Region)
+	if ev == nil {
+		release()
+		status := http.StatusBadGateway
+		switch err.(type) {
+		case *GuardError, *OutageError, *StopError:
+			status = http.StatusConflict
+		}
+		if err == ErrHalted {
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, errorResponse{err.Error()})
+		return
+	}
+	// The chaos event happened even if only its audit record failed
+	writeJSON(w, http.StatusOK, ev)
+}
+
+// bearerToken returns the bearer token of the request, if any.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(prefix):])
+}
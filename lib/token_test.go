@@ -0,0 +1,158 @@
+package chaosmonkey_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestDaemon(t *testing.T) {
+	refuse := false
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun: true,
+		Guards: []chaosmonkey.Guard{chaosmonkey.GuardFunc(func(chaosmonkey.Target) error {
+			if refuse {
+				return fmt.Errorf("not now")
+			}
+			return nil
+		})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(&chaosmonkey.Daemon{
+		Client:      client,
+		Tokens:      &chaosmonkey.TokenIssuer{Key: []byte("key")},
+		AdminSecret: []byte("admin"),
+	})
+	defer ts.Close()
+
+	post := func(path, credential, body string) (int, string) {
+		req, err := http.NewRequest("POST", ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+credential)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	mint := `{"group": "payments-api", "strategy": "BurnCpu", "ttl": "5m"}`
+	if status, _ := post("/tokens", "guess", mint); status != http.StatusUnauthorized {
+		t.Fatalf("minting without admin secret: got status %d", status)
+	}
+	status, body := post("/tokens", "admin", mint)
+	if status != http.StatusOK {
+		t.Fatalf("minting: got status %d: %s", status, body)
+	}
+	var token chaosmonkey.ScopedToken
+	if err := json.Unmarshal([]byte(body), &token); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		body   string
+		refuse bool
+		want   int
+	}{
+		{`{"group": "search-api", "strategy": "BurnCpu"}`, false, http.StatusForbidden},
+		{`{"group": "payments-api", "strategy": "FillDisk"}`, false, http.StatusForbidden},
+		{`{"group": "payments-api", "strategy": "BurnCpu"}`, true, http.StatusConflict},
+		// Refused chaos events do not use up the token
+		{`{"group": "payments-api", "strategy": "BurnCpu"}`, false, http.StatusOK},
+		{`{"group": "payments-api", "strategy": "BurnCpu"}`, false, http.StatusForbidden},
+	} {
+		refuse = tc.refuse
+		if status, body := post("/events", token.Token, tc.body); status != tc.want {
+			t.Errorf("%s: got status %d, want %d: %s", tc.body, status, tc.want, body)
+		}
+	}
+	if status, _ := post("/events", "admin", `{"group": "payments-api", "strategy": "BurnCpu"}`); status != http.StatusForbidden {
+		t.Errorf("triggering with admin secret: got status %d", status)
+	}
+}
+
+func TestTokenIssuer(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)}
+	issuer := &chaosmonkey.TokenIssuer{Key: []byte("key"), Clock: clock}
+	if _, err := issuer.Issue("payments-api", "", 48*time.Hour); err == nil {
+		t.Error("expected error for TTL above maximum")
+	}
+	token, err := issuer.Issue("payments-api", "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Strategy != chaosmonkey.StrategyShutdownInstance {
+		t.Errorf("got strategy %s, want ShutdownInstance", token.Strategy)
+	}
+
+	forger := &chaosmonkey.TokenIssuer{Key: []byte("guess")}
+	forged, err := forger.Issue("payments-api", "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.Verify(forged.Token); err == nil {
+		t.Error("expected error for token signed with another key")
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := issuer.Redeem(token.Token, "payments-api", ""); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("got error %v, expected token to be expired", err)
+	}
+}
+
+func TestTokenDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaosmonkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	clock := &fakeClock{now: time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)}
+
+	// Separate instances, like separate processes, share the record
+	used := &chaosmonkey.TokenDir{Path: dir, Clock: clock}
+	other := &chaosmonkey.TokenDir{Path: dir, Clock: clock}
+	expires := clock.now.Add(time.Hour)
+	if err := used.Use("0123abcd", expires); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Use("0123abcd", expires); err != chaosmonkey.ErrTokenUsed {
+		t.Fatalf("got error %v, want ErrTokenUsed", err)
+	}
+	if used, err := other.IsUsed("0123abcd"); err != nil || !used {
+		t.Fatalf("got %t, %v, want token to be used", used, err)
+	}
+	if err := other.Release("0123abcd"); err != nil {
+		t.Fatal(err)
+	}
+	if used, err := other.IsUsed("0123abcd"); err != nil || used {
+		t.Fatalf("got %t, %v, want released token to be unused", used, err)
+	}
+	if err := used.Use("0123abcd", expires); err != nil {
+		t.Fatalf("released token: %s", err)
+	}
+	if err := used.Use("../passwd", expires); err == nil {
+		t.Error("expected error for invalid ID")
+	}
+
+	clock.now = expires.Add(time.Second)
+	if err := used.Use("4567cdef", clock.now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir + "/0123abcd"); !os.IsNotExist(err) {
+		t.Errorf("expired token was not removed: %v", err)
+	}
+}
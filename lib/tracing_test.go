@@ -0,0 +1,87 @@
+package chaosmonkey_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// recordedSpan counts how often a span was ended and with which status.
+type recordedSpan struct {
+	noop.Span
+	name   string
+	ends   int
+	status codes.Code
+}
+
+func (s *recordedSpan) End(...trace.SpanEndOption) { s.ends++ }
+
+func (s *recordedSpan) SetStatus(code codes.Code, _ string) { s.status = code }
+
+// spanRecorder is a tracer provider recording all started spans.
+type spanRecorder struct {
+	noop.TracerProvider
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *spanRecorder) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return &recordingTracer{recorder: r}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	recorder *spanRecorder
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	s := &recordedSpan{name: name}
+	t.recorder.mu.Lock()
+	t.recorder.spans = append(t.recorder.spans, s)
+	t.recorder.mu.Unlock()
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+func TestTracing(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusInternalServerError} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			w.Write([]byte("[]"))
+		}))
+		recorder := &spanRecorder{}
+		client, err := chaosmonkey.NewClient(&chaosmonkey.Config{Endpoint: ts.URL, TracerProvider: recorder})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, infoErr := client.ServerInfo()
+		_, eventsErr := client.Events()
+		ts.Close()
+
+		want := codes.Unset
+		if status != http.StatusOK {
+			want = codes.Error
+			if infoErr == nil || eventsErr == nil {
+				t.Fatalf("status %d: expected errors, got %v and %v", status, infoErr, eventsErr)
+			}
+		}
+		if len(recorder.spans) != 2 {
+			t.Fatalf("status %d: got %d spans, want 2", status, len(recorder.spans))
+		}
+		for _, s := range recorder.spans {
+			if s.ends != 1 {
+				t.Errorf("status %d: span %s ended %d times, want once", status, s.name, s.ends)
+			}
+			if s.status != want {
+				t.Errorf("status %d: span %s has status %v, want %v", status, s.name, s.status, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package chaosmonkey
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// WatchUpdate is an update of the chaos events streamed by Watch.
+type WatchUpdate struct {
+	// New chaos events, sorted by time
+	Events []Event
+
+	// Error of a failed poll, if any
+	Err error
+}
+
+// Watch streams chaos events triggered since the given time, as Chaos Monkey
+// offers no way to subscribe to them: it lists the events and then polls for
+// new events every interval until the context is canceled, when it closes
+// the returned channel. The first update holds all events since the given
+// time, even if there are none. Later updates hold events not seen before,
+// or the error of a failed poll, after which polling continues.
+func (c *Client) Watch(ctx context.Context, since time.Time, interval time.Duration) <-chan WatchUpdate {
+	updates := make(chan WatchUpdate)
+	go func() {
+		defer close(updates)
+		seen := make(map[string]time.Time)
+		last := c.config.Clock.Now()
+		for first := true; ; first = false {
+			events, err := c.EventsSince(since)
+			u := WatchUpdate{Err: err}
+			for _, e := range events {
+				key := e.InstanceID + "/" + e.TriggeredAt.String()
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = e.TriggeredAt
+				u.Events = append(u.Events, e)
+				if e.TriggeredAt.After(last) {
+					last = e.TriggeredAt
+				}
+			}
+			sort.SliceStable(u.Events, func(i, j int) bool { return u.Events[i].TriggeredAt.Before(u.Events[j].TriggeredAt) })
+
+			if first || len(u.Events) > 0 || u.Err != nil {
+				select {
+				case updates <- u:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			// Events of the last poll may be returned again since timestamps
+			// have second precision; forget those that cannot be returned
+			// anymore. After failed polls, poll the same period again.
+			if err == nil {
+				since = last.Add(-time.Second)
+				for key, t := range seen {
+					if t.Before(last.Add(-time.Minute)) {
+						delete(seen, key)
+					}
+				}
+			}
+
+			select {
+			case <-clockAfter(c.config.Clock, interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return updates
+}
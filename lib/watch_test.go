@@ -0,0 +1,83 @@
+package chaosmonkey_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestWatch(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []string
+		failed bool
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failed {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(events, ","))
+	}))
+	defer ts.Close()
+	event := func(id string, sec int64) string {
+		return fmt.Sprintf(`{"eventId": %q, "eventTime": %d, "groupName": "a"}`, id, sec*1000)
+	}
+	set := func(e []string, f bool) {
+		mu.Lock()
+		events, failed = e, f
+		mu.Unlock()
+	}
+
+	start := time.Unix(1460116000, 0)
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		Endpoint:   ts.URL,
+		Clock:      &steppingClock{now: start},
+		ServerInfo: &chaosmonkey.ServerInfo{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := func(u chaosmonkey.WatchUpdate) []string {
+		var ids []string
+		for _, e := range u.Events {
+			ids = append(ids, e.InstanceID)
+		}
+		return ids
+	}
+
+	set([]string{event("i-2", 1460115900), event("i-1", 1460115800)}, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := client.Watch(ctx, start.Add(-time.Hour), time.Minute)
+
+	u := <-updates
+	if diff := cmp.Diff([]string{"i-1", "i-2"}, ids(u)); diff != "" || u.Err != nil {
+		t.Fatalf("unexpected first update %v: %s", u.Err, diff)
+	}
+
+	set([]string{event("i-2", 1460115900), event("i-3", 1460116100)}, false)
+	u = <-updates
+	if diff := cmp.Diff([]string{"i-3"}, ids(u)); diff != "" || u.Err != nil {
+		t.Fatalf("unexpected update %v: %s", u.Err, diff)
+	}
+
+	set(nil, true)
+	if u = <-updates; u.Err == nil {
+		t.Fatal("expected update with error")
+	}
+
+	cancel()
+	for range updates {
+	}
+}
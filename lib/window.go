@@ -0,0 +1,164 @@
+package chaosmonkey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrOutsideWindow is returned by WindowPolicy for chaos events outside of
+// the allowed windows or on holidays.
+var ErrOutsideWindow = errors.New("outside of allowed chaos windows")
+
+// Window is a daily period in which chaos is allowed.
+type Window struct {
+	// Days of the week the window applies to (all days if empty)
+	Days []time.Weekday
+
+	// Start and end of the window as offsets from midnight. The end is
+	// exclusive.
+	Start, End time.Duration
+}
+
+// ParseWindow parses a window like "MON-FRI 09:00-16:00" or "09:00-16:00",
+// which applies to every day. Days may be given like the day of week field
+// of a cron expression, i.e. as lists, ranges, and numbers or English names.
+func ParseWindow(spec string) (*Window, error) {
+	fields := strings.Fields(spec)
+	var days, hours string
+	switch len(fields) {
+	case 1:
+		days, hours = "*", fields[0]
+	case 2:
+		days, hours = fields[0], fields[1]
+	default:
+		return nil, fmt.Errorf("invalid window %q: expected [<days>] <start>-<end>", spec)
+	}
+
+	var w Window
+	if days != "*" {
+		set, err := cronFields[4].parse(days)
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %s", spec, err)
+		}
+		// Sunday may be given as 0 or 7
+		if set&(1<<7) != 0 {
+			set |= 1
+		}
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if set&(1<<uint(d)) != 0 {
+				w.Days = append(w.Days, d)
+			}
+		}
+	}
+
+	parts := strings.Split(hours, "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid window %q: expected <start>-<end>", spec)
+	}
+	var err error
+	if w.Start, err = parseClock(parts[0]); err != nil {
+		return nil, fmt.Errorf("invalid window %q: %s", spec, err)
+	}
+	if w.End, err = parseClock(parts[1]); err != nil {
+		return nil, fmt.Errorf("invalid window %q: %s", spec, err)
+	}
+	if w.End <= w.Start {
+		return nil, fmt.Errorf("invalid window %q: end must be after start", spec)
+	}
+	return &w, nil
+}
+
+// parseClock parses a time of day like "09:00" or "24:00" as offset from
+// midnight.
+func parseClock(s string) (time.Duration, error) {
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether the window contains t, in the location of t.
+func (w *Window) Contains(t time.Time) bool {
+	if len(w.Days) > 0 {
+		match := false
+		for _, d := range w.Days {
+			if d == t.Weekday() {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+	return offset >= w.Start && offset < w.End
+}
+
+// WindowPolicy is a Guard that restricts chaos to the given windows, e.g.
+// business hours on weekdays when engineers are around to respond, and
+// suppresses it on holidays. It refuses chaos events with ErrOutsideWindow.
+type WindowPolicy struct {
+	// Windows in which chaos is allowed (any time if empty)
+	Windows []Window
+
+	// Days on which chaos is not allowed at all. Only their dates count,
+	// which are compared to dates in Location.
+	Holidays []time.Time
+
+	// Time zone of windows and holidays (UTC if nil)
+	Location *time.Location
+
+	// Clock used to tell the current time if the target has none
+	// (SystemClock if nil)
+	Clock Clock
+}
+
+// Allows reports whether chaos is allowed at time t.
+func (p *WindowPolicy) Allows(t time.Time) bool {
+	loc := p.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	y, m, d := t.Date()
+	for _, h := range p.Holidays {
+		hy, hm, hd := h.Date()
+		if hy == y && hm == m && hd == d {
+			return false
+		}
+	}
+	if len(p.Windows) == 0 {
+		return true
+	}
+	for _, w := range p.Windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check refuses chaos events outside of the allowed windows with
+// ErrOutsideWindow.
+func (p *WindowPolicy) Check(t Target) error {
+	now := t.Time
+	if now.IsZero() {
+		var clock Clock = SystemClock{}
+		if p.Clock != nil {
+			clock = p.Clock
+		}
+		now = clock.Now()
+	}
+	if !p.Allows(now) {
+		return ErrOutsideWindow
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package chaosmonkey_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		spec   string
+		window chaosmonkey.Window
+	}{
+		{"09:00-16:00", chaosmonkey.Window{Start: 9 * time.Hour, End: 16 * time.Hour}},
+		{"MON-FRI 09:30-24:00", chaosmonkey.Window{
+			Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+			Start: 9*time.Hour + 30*time.Minute,
+			End:   24 * time.Hour,
+		}},
+		{"sat,7 10:00-12:00", chaosmonkey.Window{
+			Days:  []time.Weekday{time.Sunday, time.Saturday},
+			Start: 10 * time.Hour,
+			End:   12 * time.Hour,
+		}},
+	}
+	for _, test := range tests {
+		w, err := chaosmonkey.ParseWindow(test.spec)
+		if err != nil {
+			t.Errorf("%q: %s", test.spec, err)
+			continue
+		}
+		if diff := cmp.Diff(test.window, *w); diff != "" {
+			t.Errorf("%q: window differs (-want +got):\n%s", test.spec, diff)
+		}
+	}
+
+	for _, spec := range []string{"", "MON-FRI", "FOO 09:00-16:00", "09:00", "16:00-09:00", "09:00-25:00", "MON 09:00-10:00 UTC"} {
+		if _, err := chaosmonkey.ParseWindow(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestWindowPolicy(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	w, err := chaosmonkey.ParseWindow("MON-FRI 09:00-16:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &chaosmonkey.WindowPolicy{
+		Windows:  []chaosmonkey.Window{*w},
+		Holidays: []time.Time{time.Date(2018, 4, 2, 0, 0, 0, 0, time.UTC)},
+		Location: berlin,
+	}
+
+	tests := []struct {
+		time    time.Time
+		allowed bool
+	}{
+		// Easter Monday
+		{time.Date(2018, 4, 2, 10, 0, 0, 0, berlin), false},
+		{time.Date(2018, 4, 3, 8, 59, 0, 0, berlin), false},
+		{time.Date(2018, 4, 3, 9, 0, 0, 0, berlin), true},
+		{time.Date(2018, 4, 3, 13, 59, 0, 0, time.UTC), true},
+		{time.Date(2018, 4, 3, 14, 0, 0, 0, time.UTC), false},
+		// Saturday
+		{time.Date(2018, 4, 7, 10, 0, 0, 0, berlin), false},
+	}
+	for _, test := range tests {
+		err := policy.Check(chaosmonkey.Target{AutoScalingGroupName: "payments-api", Time: test.time})
+		switch {
+		case test.allowed && err != nil:
+			t.Errorf("%s: unexpected error: %s", test.time, err)
+		case !test.allowed && err != chaosmonkey.ErrOutsideWindow:
+			t.Errorf("%s: got %v, want ErrOutsideWindow", test.time, err)
+		}
+	}
+}
+
+func TestWindowPolicyTriggerEvent(t *testing.T) {
+	w, err := chaosmonkey.ParseWindow("MON-FRI 09:00-16:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saturday
+	clock := &fakeClock{now: time.Date(2018, 4, 7, 10, 0, 0, 0, time.UTC)}
+	client, err := chaosmonkey.NewClient(&chaosmonkey.Config{
+		DryRun: true,
+		Clock:  clock,
+		Guards: []chaosmonkey.Guard{&chaosmonkey.WindowPolicy{Windows: []chaosmonkey.Window{*w}, Clock: clock}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.TriggerEvent("payments-api", chaosmonkey.StrategyShutdownInstance)
+	if _, ok := err.(*chaosmonkey.GuardError); !ok {
+		t.Fatalf("got error %v, want *GuardError", err)
+	}
+	if !errors.Is(err, chaosmonkey.ErrOutsideWindow) {
+		t.Fatalf("got error %v, want it to wrap ErrOutsideWindow", err)
+	}
+
+	clock.now = time.Date(2018, 4, 9, 10, 0, 0, 0, time.UTC)
+	if _, err := client.TriggerEvent("payments-api", chaosmonkey.StrategyShutdownInstance); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,127 @@
+package loadgen
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultSignificance is the significance level used by Compare unless
+// configured otherwise.
+const DefaultSignificance = 0.05
+
+// Comparison is the outcome of comparing a run under chaos against a
+// baseline run without chaos.
+type Comparison struct {
+	// Difference in error rate (run minus baseline)
+	ErrorRateDelta float64
+
+	// One-sided p-value of the hypothesis that the error rate of the run is
+	// higher than that of the baseline
+	ErrorRateP float64
+
+	// Difference in median latency (run minus baseline)
+	MedianLatencyDelta time.Duration
+
+	// One-sided p-value of the hypothesis that latencies of the run are
+	// higher than those of the baseline
+	LatencyP float64
+
+	// Whether the run is significantly worse than the baseline in error
+	// rate or latency
+	Degraded bool
+}
+
+// Compare tests whether a run under chaos is significantly worse than a
+// baseline run without chaos, instead of relying on fixed thresholds. Error
+// rates are compared with a two-proportion z-test and latencies with a
+// Mann-Whitney U test. Significance is the level below which p-values count
+// as significant (DefaultSignificance if zero).
+func Compare(baseline, run *Result, significance float64) Comparison {
+	if significance == 0 {
+		significance = DefaultSignificance
+	}
+	c := Comparison{
+		ErrorRateDelta:     run.ErrorRate() - baseline.ErrorRate(),
+		ErrorRateP:         proportionTest(baseline.Errors, baseline.Requests, run.Errors, run.Requests),
+		MedianLatencyDelta: percentile(run.Latencies, 0.5) - percentile(baseline.Latencies, 0.5),
+		LatencyP:           mannWhitneyTest(baseline.Latencies, run.Latencies),
+	}
+	c.Degraded = c.ErrorRateP < significance || c.LatencyP < significance
+	return c
+}
+
+// proportionTest returns the one-sided p-value of the hypothesis that
+// proportion x2/n2 is greater than x1/n1.
+func proportionTest(x1, n1, x2, n2 int) float64 {
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	p1, p2 := float64(x1)/float64(n1), float64(x2)/float64(n2)
+	p := float64(x1+x2) / float64(n1+n2)
+	se := math.Sqrt(p * (1 - p) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 1
+	}
+	return upperTail((p2 - p1) / se)
+}
+
+// mannWhitneyTest returns the one-sided p-value of the hypothesis that
+// values of b tend to be greater than values of a, using the normal
+// approximation of the U statistic.
+func mannWhitneyTest(a, b []time.Duration) float64 {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type sample struct {
+		value time.Duration
+		inB   bool
+	}
+	samples := make([]sample, 0, n1+n2)
+	for _, v := range a {
+		samples = append(samples, sample{v, false})
+	}
+	for _, v := range b {
+		samples = append(samples, sample{v, true})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].value < samples[j].value })
+
+	// Sum the ranks of b, assigning tied values their average rank, and sum
+	// t^3 - t over groups of t tied values for the variance
+	var rankSum, ties float64
+	for i := 0; i < len(samples); {
+		j := i
+		for j < len(samples) && samples[j].value == samples[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if samples[k].inB {
+				rankSum += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+
+	// Use floats throughout, as products of the sample sizes overflow ints
+	// for large samples
+	f1, f2 := float64(n1), float64(n2)
+	n := f1 + f2
+	u := rankSum - f2*(f2+1)/2
+	mean := f1 * f2 / 2
+	variance := f1 * f2 / 12 * ((n + 1) - ties/(n*(n-1)))
+	if variance <= 0 {
+		// All values are tied
+		return 1
+	}
+	return upperTail((u - mean) / math.Sqrt(variance))
+}
+
+// upperTail returns P(Z > z) for a standard normal variable Z.
+func upperTail(z float64) float64 {
+	return 0.5 * math.Erfc(z/math.Sqrt2)
+}
@@ -0,0 +1,190 @@
+// Package loadgen generates synthetic HTTP load, so that the behavior of a
+// service can be verified under realistic traffic while chaos is injected,
+// e.g. by running Run concurrently with chaosmonkey.Client.Soak.
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config describes the load to generate.
+type Config struct {
+	// URL to send requests to
+	Target string
+
+	// HTTP method to use (GET if empty)
+	Method string
+
+	// Optional request body and headers
+	Body   []byte
+	Header http.Header
+
+	// Requests per second at full load
+	Rate float64
+
+	// Total duration of the load, including ramp-up
+	Duration time.Duration
+
+	// Time to increase the load linearly from zero to Rate (optional)
+	RampUp time.Duration
+
+	// HTTP client to use (http.DefaultClient if nil)
+	HTTPClient *http.Client
+}
+
+// Result summarizes the responses to the generated load.
+type Result struct {
+	// Number of requests sent
+	Requests int
+
+	// Number of requests that failed, either without a response or with a
+	// 5xx status code
+	Errors int
+
+	// Number of responses per status code
+	StatusCodes map[int]int
+
+	// Latency percentiles and maximum of all requests
+	P50, P95, P99, Max time.Duration
+
+	// Latencies of all requests in ascending order
+	Latencies []time.Duration
+}
+
+// ErrorRate returns the fraction of failed requests.
+func (r *Result) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// Run generates load as configured until the duration has passed or ctx is
+// canceled, waits for all outstanding requests, and returns the result.
+func Run(ctx context.Context, cfg Config) (*Result, error) {
+	switch {
+	case cfg.Target == "":
+		return nil, fmt.Errorf("target must not be empty")
+	case cfg.Rate <= 0:
+		return nil, fmt.Errorf("rate must be positive")
+	case cfg.Duration <= 0:
+		return nil, fmt.Errorf("duration must be positive")
+	case cfg.RampUp < 0 || cfg.RampUp > cfg.Duration:
+		return nil, fmt.Errorf("ramp-up must be between zero and duration")
+	}
+	if cfg.Method == "" {
+		cfg.Method = "GET"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if _, err := http.NewRequest(cfg.Method, cfg.Target, nil); err != nil {
+		return nil, err
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		latencies []time.Duration
+		result    = Result{StatusCodes: make(map[int]int)}
+	)
+	start := time.Now()
+
+loop:
+	for k := 0; ; k++ {
+		at := cfg.offset(k)
+		if at >= cfg.Duration {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-time.After(time.Until(start.Add(at))):
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, latency := cfg.send(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			result.Requests++
+			if code == 0 || code >= 500 {
+				result.Errors++
+			}
+			if code != 0 {
+				result.StatusCodes[code]++
+			}
+			latencies = append(latencies, latency)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50 = percentile(latencies, 0.50)
+	result.P95 = percentile(latencies, 0.95)
+	result.P99 = percentile(latencies, 0.99)
+	if n := len(latencies); n > 0 {
+		result.Max = latencies[n-1]
+	}
+	result.Latencies = latencies
+	return &result, nil
+}
+
+// offset returns when the k-th request (starting at zero) is due, relative
+// to the start of the load. During ramp-up, the rate increases linearly.
+func (cfg *Config) offset(k int) time.Duration {
+	rampUp := cfg.RampUp.Seconds()
+	rampUpRequests := cfg.Rate * rampUp / 2
+	var secs float64
+	if float64(k) < rampUpRequests {
+		secs = math.Sqrt(2 * float64(k) * rampUp / cfg.Rate)
+	} else {
+		secs = rampUp + (float64(k)-rampUpRequests)/cfg.Rate
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// send sends a single request and returns its status code, which is zero if
+// there was no response, and latency.
+func (cfg *Config) send(ctx context.Context) (int, time.Duration) {
+	req, err := http.NewRequest(cfg.Method, cfg.Target, bytes.NewReader(cfg.Body))
+	if err != nil {
+		return 0, 0
+	}
+	req = req.WithContext(ctx)
+	for name, values := range cfg.Header {
+		req.Header[name] = values
+	}
+
+	start := time.Now()
+	resp, err := cfg.HTTPClient.Do(req)
+	if err != nil {
+		return 0, time.Since(start)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, time.Since(start)
+}
+
+// percentile returns the p-th percentile of sorted durations using the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
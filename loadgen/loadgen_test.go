@@ -0,0 +1,131 @@
+package loadgen_test
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/FlyLevin/chaosmonkey/loadgen"
+)
+
+func TestRun(t *testing.T) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&n, 1)%4 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	result, err := loadgen.Run(context.Background(), loadgen.Config{
+		Target:   ts.URL,
+		Rate:     100,
+		Duration: 200 * time.Millisecond,
+		RampUp:   100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 5 requests during ramp-up and 10 afterwards
+	if result.Requests != 15 {
+		t.Errorf("got %d requests, want 15", result.Requests)
+	}
+	if int(n) != result.Requests {
+		t.Errorf("server got %d requests, result says %d", n, result.Requests)
+	}
+	if result.Errors != 3 || result.StatusCodes[503] != 3 || result.StatusCodes[200] != 12 {
+		t.Errorf("unexpected errors %d and status codes %v", result.Errors, result.StatusCodes)
+	}
+	if result.Max < result.P99 || result.P99 < result.P50 {
+		t.Errorf("inconsistent latencies: %+v", result)
+	}
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	configs := []loadgen.Config{
+		{Rate: 1, Duration: time.Second},
+		{Target: "http://localhost", Duration: time.Second},
+		{Target: "http://localhost", Rate: 1},
+		{Target: "http://localhost", Rate: 1, Duration: time.Second, RampUp: 2 * time.Second},
+	}
+	for _, cfg := range configs {
+		if _, err := loadgen.Run(context.Background(), cfg); err == nil {
+			t.Errorf("expected error for config %+v", cfg)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	latencies := func(ms ...int) []time.Duration {
+		var d []time.Duration
+		for _, m := range ms {
+			d = append(d, time.Duration(m)*time.Millisecond)
+		}
+		return d
+	}
+	baseline := &loadgen.Result{
+		Requests:  1000,
+		Errors:    10,
+		Latencies: latencies(10, 11, 12, 12, 13, 14, 15, 15, 16, 18),
+	}
+
+	similar := &loadgen.Result{
+		Requests:  1000,
+		Errors:    12,
+		Latencies: latencies(10, 11, 11, 12, 13, 14, 15, 16, 16, 17),
+	}
+	if c := loadgen.Compare(baseline, similar, 0); c.Degraded {
+		t.Errorf("similar run reported as degraded: %+v", c)
+	}
+
+	slow := &loadgen.Result{
+		Requests:  1000,
+		Errors:    10,
+		Latencies: latencies(30, 31, 33, 35, 35, 36, 38, 40, 42, 45),
+	}
+	if c := loadgen.Compare(baseline, slow, 0); !c.Degraded || c.LatencyP >= 0.05 {
+		t.Errorf("slow run not reported as degraded: %+v", c)
+	}
+
+	failing := &loadgen.Result{
+		Requests:  1000,
+		Errors:    50,
+		Latencies: baseline.Latencies,
+	}
+	if c := loadgen.Compare(baseline, failing, 0); !c.Degraded || c.ErrorRateP >= 0.05 {
+		t.Errorf("failing run not reported as degraded: %+v", c)
+	}
+}
+
+func TestCompareTies(t *testing.T) {
+	same := &loadgen.Result{Requests: 10, Latencies: []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}}
+	if c := loadgen.Compare(same, same, 0); c.LatencyP != 1 || c.Degraded {
+		t.Errorf("identical runs with tied latencies: %+v", c)
+	}
+}
+
+func TestCompareLarge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("large samples")
+	}
+	// Products of the sample sizes exceed the range of int64
+	const n = 2000000
+	baseline := &loadgen.Result{Requests: n, Latencies: make([]time.Duration, n)}
+	run := &loadgen.Result{Requests: n, Latencies: make([]time.Duration, n)}
+	for i := 0; i < n; i++ {
+		baseline.Latencies[i] = time.Duration(10+i%3) * time.Millisecond
+		run.Latencies[i] = time.Duration(10+i%4) * time.Millisecond
+	}
+	c := loadgen.Compare(baseline, run, 0)
+	if math.IsNaN(c.LatencyP) || c.LatencyP >= 0.05 {
+		t.Errorf("slower run not reported as degraded: %+v", c)
+	}
+	if c := loadgen.Compare(baseline, baseline, 0); math.IsNaN(c.LatencyP) || c.Degraded {
+		t.Errorf("identical runs reported as degraded: %+v", c)
+	}
+}
@@ -0,0 +1,407 @@
+// Package plugin runs external programs as out-of-process plugins.
+//
+// A plugin is any executable that reads messages from standard input, one
+// JSON object per line, for example:
+//
+//	{"topic":"event.recorded","time":"2016-04-08T12:02:07Z","group":"ExampleAutoScalingGroup","strategy":"ShutdownInstance","region":"eu-west-1","event":{...}}
+//
+// Plugins subscribe to the chaosmonkey.Bus of a client, which makes it
+// possible to add proprietary notifiers or integrations without forking.
+//
+// Plugins may also serve requests, which carry an ID and a method instead of
+// a topic:
+//
+//	{"id":1,"method":"probe","params":{}}
+//
+// Plugins answer requests by writing a response with the same ID to standard
+// output, again one JSON object per line, with either a result or an error:
+//
+//	{"id":1,"result":{"outage":""}}
+//	{"id":2,"error":"cannot reach inventory"}
+//
+// Requests may be answered in any order. Other output is passed through. The
+// following methods are used:
+//
+//	probe   checks for an outage, see Plugin.CheckOutage
+//	        params: {}
+//	        result: {"outage": "<description, empty if there is none>"}
+//	enrich  adds instance details to events, see Plugin.EnrichEvents
+//	        params: {"events": [<event>, ...]}
+//	        result: {"events": [<event with "instance">, ...]}
+//	inject  causes a chaos event instead of Chaos Monkey, see Plugin.Inject
+//	        params: {"group": "<name>", "strategy": "<strategy>", "region": "<region>"}
+//	        result: <event, or null>
+//
+// Plugins answer methods they do not implement with an error.
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// Prefix is the file name prefix of plugin executables found by Discover.
+const Prefix = "chaosmonkey-plugin-"
+
+// QueueSize is the number of messages queued for a plugin that does not keep
+// up with them. Further messages are dropped.
+const QueueSize = 100
+
+// maxLine is the longest line of output read from plugins.
+const maxLine = 1 << 20
+
+// Plugin is a running plugin process. It implements
+// chaosmonkey.OutageChecker, chaosmonkey.EventEnricher, and
+// chaosmonkey.Backend by sending requests to the plugin.
+type Plugin struct {
+	// Name of the plugin
+	Name string
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	// Messages and requests waiting to be written by the writer goroutine,
+	// which closes done once the queue is closed and drained
+	queue chan interface{}
+	done  chan struct{}
+
+	// Closed by the reader goroutine once the plugin closed its output
+	read chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+
+	mu      sync.Mutex
+	err     error
+	closed  bool
+	exited  bool
+	lastID  uint64
+	pending map[uint64]chan response
+}
+
+// message is the JSON representation of a chaosmonkey.Message sent to
+// plugins.
+type message struct {
+	Topic    chaosmonkey.Topic    `json:"topic"`
+	Time     time.Time            `json:"time"`
+	Group    string               `json:"group,omitempty"`
+	Strategy chaosmonkey.Strategy `json:"strategy,omitempty"`
+	Region   string               `json:"region,omitempty"`
+	Event    *chaosmonkey.Event   `json:"event,omitempty"`
+	Error    string               `json:"error,omitempty"`
+}
+
+// request is a request sent to plugins.
+type request struct {
+	ID     uint64      `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+// response is the answer of a plugin to a request.
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+// Start starts the plugin executable at path with the given arguments. The
+// plugin's standard error and any output other than responses are passed
+// through.
+func Start(path string, args ...string) (*Plugin, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %s", path, err)
+	}
+	p := &Plugin{
+		Name:    strings.TrimPrefix(filepath.Base(path), Prefix),
+		cmd:     cmd,
+		stdin:   stdin,
+		queue:   make(chan interface{}, QueueSize),
+		done:    make(chan struct{}),
+		read:    make(chan struct{}),
+		pending: make(map[uint64]chan response),
+	}
+	go p.write()
+	go p.readResponses(stdout)
+	return p, nil
+}
+
+// write writes queued messages to the plugin's standard input until the
+// queue is closed. Once writing failed, further messages are discarded.
+func (p *Plugin) write() {
+	defer close(p.done)
+	enc := json.NewEncoder(p.stdin)
+	for msg := range p.queue {
+		p.mu.Lock()
+		failed := p.err != nil
+		p.mu.Unlock()
+		if failed {
+			continue
+		}
+		if err := enc.Encode(msg); err != nil {
+			p.mu.Lock()
+			p.err = fmt.Errorf("plugin %s: %s", p.Name, err)
+			p.mu.Unlock()
+		}
+	}
+}
+
+// readResponses hands responses read from the plugin's standard output to
+// the pending requests, and passes other output through. Once the plugin
+// closed its output, pending and later requests fail.
+func (p *Plugin) readResponses(stdout io.Reader) {
+	defer close(p.read)
+	in := bufio.NewScanner(stdout)
+	in.Buffer(make([]byte, 64*1024), maxLine)
+	for in.Scan() {
+		var resp response
+		if json.Unmarshal(in.Bytes(), &resp) == nil && resp.ID != 0 {
+			p.mu.Lock()
+			ch, ok := p.pending[resp.ID]
+			delete(p.pending, resp.ID)
+			p.mu.Unlock()
+			if ok {
+				ch <- resp
+				continue
+			}
+		}
+		fmt.Fprintln(os.Stdout, in.Text())
+	}
+	// Drain output the scanner gave up on, so that the plugin is not blocked
+	io.Copy(os.Stdout, stdout)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.exited = true
+	for id, ch := range p.pending {
+		close(ch)
+		delete(p.pending, id)
+	}
+}
+
+// Discover returns the paths of all plugin executables in dir, that is all
+// executable files whose name starts with Prefix.
+func Discover(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, Prefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	var plugins []string
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil || fi.IsDir() || fi.Mode()&0111 == 0 {
+			continue
+		}
+		plugins = append(plugins, f)
+	}
+	return plugins, nil
+}
+
+// Find returns the path of the plugin executable with the given name in dir,
+// as found by Discover.
+func Find(dir, name string) (string, error) {
+	plugins, err := Discover(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, path := range plugins {
+		if filepath.Base(path) == Prefix+name {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("plugin %s not found in %s", name, dir)
+}
+
+// Send queues a message for the plugin without waiting for it to be written,
+// so that slow plugins do not hold up the bus. If the queue is full, the
+// message is dropped and an error returned. Once writing failed, e.g. because
+// the plugin exited, all further calls return the same error.
+func (p *Plugin) Send(m chaosmonkey.Message) error {
+	msg := message{
+		Topic:    m.Topic,
+		Time:     m.Time,
+		Group:    m.AutoScalingGroupName,
+		Strategy: m.Strategy,
+		Region:   m.Region,
+		Event:    m.Event,
+	}
+	if m.Err != nil {
+		msg.Error = m.Err.Error()
+	}
+	if err := p.enqueue(msg); err != nil {
+		return fmt.Errorf("%s, dropped %s message", err, m.Topic)
+	}
+	return nil
+}
+
+// enqueue queues a message or request for the plugin unless the queue is
+// full, the plugin was closed, or writing to it failed.
+func (p *Plugin) enqueue(msg interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	if p.closed {
+		return fmt.Errorf("plugin %s: closed", p.Name)
+	}
+	select {
+	case p.queue <- msg:
+		return nil
+	default:
+		return fmt.Errorf("plugin %s: %d messages are still queued", p.Name, QueueSize)
+	}
+}
+
+// Call sends a request to the plugin and waits for its response, whose
+// result it decodes into result unless that is nil. It returns the error
+// the plugin responded with, or an error if the plugin exited before
+// responding or the context is done first.
+func (p *Plugin) Call(ctx context.Context, method string, params, result interface{}) error {
+	ch := make(chan response, 1)
+	p.mu.Lock()
+	if p.exited {
+		p.mu.Unlock()
+		return fmt.Errorf("plugin %s: exited", p.Name)
+	}
+	p.lastID++
+	id := p.lastID
+	p.pending[id] = ch
+	p.mu.Unlock()
+	forget := func() {
+		p.mu.Lock()
+		delete(p.pending, id)
+		p.mu.Unlock()
+	}
+
+	if err := p.enqueue(request{ID: id, Method: method, Params: params}); err != nil {
+		forget()
+		return err
+	}
+	select {
+	case resp, ok := <-ch:
+		switch {
+		case !ok:
+			return fmt.Errorf("plugin %s: exited before responding to %s request", p.Name, method)
+		case resp.Error != "":
+			return fmt.Errorf("plugin %s: %s", p.Name, resp.Error)
+		case result != nil && len(resp.Result) > 0:
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("plugin %s: invalid %s response: %s", p.Name, method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		forget()
+		return ctx.Err()
+	}
+}
+
+// CheckOutage asks the plugin whether there is an outage.
+func (p *Plugin) CheckOutage(ctx context.Context) error {
+	var result struct {
+		Outage string `json:"outage"`
+	}
+	if err := p.Call(ctx, "probe", struct{}{}, &result); err != nil {
+		return err
+	}
+	if result.Outage != "" {
+		return errors.New(result.Outage)
+	}
+	return nil
+}
+
+// EnrichEvents asks the plugin for details of the instances of the given
+// events, and sets those it knows.
+func (p *Plugin) EnrichEvents(ctx context.Context, events []chaosmonkey.Event) error {
+	params := struct {
+		Events []chaosmonkey.Event `json:"events"`
+	}{events}
+	var result struct {
+		Events []chaosmonkey.Event `json:"events"`
+	}
+	if err := p.Call(ctx, "enrich", params, &result); err != nil {
+		return err
+	}
+	if len(result.Events) != len(events) {
+		return fmt.Errorf("plugin %s: enriched %d of %d events", p.Name, len(result.Events), len(events))
+	}
+	for i, e := range result.Events {
+		if e.Instance != nil {
+			events[i].Instance = e.Instance
+		}
+	}
+	return nil
+}
+
+// Inject asks the plugin to cause a chaos event, and returns the event it
+// caused, if any.
+func (p *Plugin) Inject(ctx context.Context, group string, strategy chaosmonkey.Strategy, region string) (*chaosmonkey.Event, error) {
+	params := struct {
+		Group    string               `json:"group"`
+		Strategy chaosmonkey.Strategy `json:"strategy"`
+		Region   string               `json:"region"`
+	}{group, strategy, region}
+	var ev *chaosmonkey.Event
+	if err := p.Call(ctx, "inject", params, &ev); err != nil {
+		return nil, err
+	}
+	return ev, nil
+}
+
+// Subscribe forwards all messages of the given topics published on bus to
+// the plugin. Errors are reported to errorf, which may be nil. It returns a
+// function that removes the subscriptions again.
+func (p *Plugin) Subscribe(bus *chaosmonkey.Bus, errorf func(error), topics ...chaosmonkey.Topic) (unsubscribe func()) {
+	var unsubs []func()
+	for _, t := range topics {
+		unsubs = append(unsubs, bus.Subscribe(t, func(m chaosmonkey.Message) {
+			if err := p.Send(m); err != nil && errorf != nil {
+				errorf(err)
+			}
+		}))
+	}
+	return func() {
+		for _, u := range unsubs {
+			u()
+		}
+	}
+}
+
+// Close writes the queued messages, closes the plugin's standard input, and
+// waits for it to exit. Calling it again returns the same result.
+func (p *Plugin) Close() error {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		close(p.queue)
+		p.mu.Unlock()
+		<-p.done
+		p.stdin.Close()
+		<-p.read
+		p.closeErr = p.cmd.Wait()
+	})
+	return p.closeErr
+}
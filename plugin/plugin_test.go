@@ -0,0 +1,228 @@
+package plugin_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+	"github.com/FlyLevin/chaosmonkey/plugin"
+)
+
+func TestPlugin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaosmonkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "messages")
+	p, err := plugin.Start("sh", "-c", "cat > "+out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := chaosmonkey.NewBus()
+	var errs []error
+	p.Subscribe(bus, func(err error) { errs = append(errs, err) }, chaosmonkey.TopicEventRecorded)
+	now := time.Date(2016, 4, 8, 12, 2, 7, 0, time.UTC)
+	bus.Publish(chaosmonkey.Message{
+		Topic:                chaosmonkey.TopicEventRecorded,
+		Time:                 now,
+		AutoScalingGroupName: "ExampleAutoScalingGroup",
+		Strategy:             chaosmonkey.StrategyShutdownInstance,
+	})
+	bus.Publish(chaosmonkey.Message{Topic: chaosmonkey.TopicGuardBlocked, Time: now})
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []map[string]interface{}
+	in := bufio.NewScanner(f)
+	for in.Scan() {
+		var m map[string]interface{}
+		if err := json.Unmarshal(in.Bytes(), &m); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, m)
+	}
+	want := []map[string]interface{}{{
+		"topic":    string(chaosmonkey.TopicEventRecorded),
+		"time":     "2016-04-08T12:02:07Z",
+		"group":    "ExampleAutoScalingGroup",
+		"strategy": "ShutdownInstance",
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestPluginSlow(t *testing.T) {
+	// The plugin does not read its input for a while
+	p, err := plugin.Start("sleep", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := chaosmonkey.Message{
+		Topic:                chaosmonkey.TopicEventRecorded,
+		AutoScalingGroupName: strings.Repeat("x", 1000),
+	}
+	start := time.Now()
+	var dropped error
+	for i := 0; i < 1000; i++ {
+		if err := p.Send(msg); err != nil {
+			dropped = err
+			break
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("sending took %s, expected not to wait for the plugin", elapsed)
+	}
+	if dropped == nil || !strings.Contains(dropped.Error(), "dropped") {
+		t.Errorf("got error %v, expected messages to be dropped", dropped)
+	}
+	p.Close()
+
+	if err := p.Send(msg); err == nil {
+		t.Error("expected error after the plugin exited")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chaosmonkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, mode := range map[string]os.FileMode{
+		plugin.Prefix + "notify": 0755,
+		plugin.Prefix + "readme": 0644,
+		"other":                  0755,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, plugin.Prefix+"dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins, err := plugin.Discover(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{filepath.Join(dir, plugin.Prefix+"notify")}, plugins); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+// server is a plugin answering requests: probes report an outage from the
+// second one on, enrichers set the instance type of the second event,
+// injections shut down instance i-1, and other methods fail.
+const server = `
+n=0
+while read -r line; do
+	id=$(echo "$line" | sed -n 's/.*"id":\([0-9]*\).*/\1/p')
+	method=$(echo "$line" | sed -n 's/.*"method":"\([a-z]*\)".*/\1/p')
+	case "$method" in
+	probe)
+		n=$((n+1))
+		outage=""
+		[ $n -gt 1 ] && outage="database down"
+		echo "{\"id\":$id,\"result\":{\"outage\":\"$outage\"}}";;
+	enrich)
+		echo "{\"id\":$id,\"result\":{\"events\":[{},{\"instance\":{\"instanceType\":\"m5.large\"}}]}}";;
+	inject)
+		echo "{\"id\":$id,\"result\":{\"instanceId\":\"i-1\"}}";;
+	*)
+		echo "{\"id\":$id,\"error\":\"unknown method $method\"}";;
+	esac
+done
+`
+
+func TestPluginCall(t *testing.T) {
+	p, err := plugin.Start("sh", "-c", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ctx := context.Background()
+
+	if err := p.CheckOutage(ctx); err != nil {
+		t.Errorf("unexpected outage: %s", err)
+	}
+	if err := p.CheckOutage(ctx); err == nil || err.Error() != "database down" {
+		t.Errorf("got %v, expected outage", err)
+	}
+
+	events := []chaosmonkey.Event{{InstanceID: "i-1"}, {InstanceID: "i-2"}}
+	if err := p.EnrichEvents(ctx, events); err != nil {
+		t.Fatal(err)
+	}
+	want := []chaosmonkey.Event{{InstanceID: "i-1"}, {InstanceID: "i-2", Instance: &chaosmonkey.InstanceDetails{InstanceType: "m5.large"}}}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("unexpected events (-want +got):\n%s", diff)
+	}
+
+	ev, err := p.Inject(ctx, "ExampleAutoScalingGroup", chaosmonkey.StrategyShutdownInstance, "eu-west-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&chaosmonkey.Event{InstanceID: "i-1"}, ev); diff != "" {
+		t.Errorf("unexpected event (-want +got):\n%s", diff)
+	}
+
+	if err := p.Call(ctx, "unknown", nil, nil); err == nil || !strings.Contains(err.Error(), "unknown method unknown") {
+		t.Errorf("got %v, expected error of plugin", err)
+	}
+}
+
+func TestPluginCallExited(t *testing.T) {
+	// The plugin exits without responding
+	p, err := plugin.Start("sh", "-c", "read line")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.CheckOutage(context.Background()); err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Errorf("got %v, expected plugin to exit", err)
+	}
+	if err := p.CheckOutage(context.Background()); err == nil {
+		t.Error("expected error after the plugin exited")
+	}
+
+	// Closing twice returns the same result
+	first := p.Close()
+	if err := p.Close(); err != first {
+		t.Errorf("got %v, expected %v of first close", err, first)
+	}
+}
+
+func TestPluginCallCanceled(t *testing.T) {
+	// The plugin never responds
+	p, err := plugin.Start("sh", "-c", "cat > /dev/null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := p.CheckOutage(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got %v, expected deadline to be exceeded", err)
+	}
+}
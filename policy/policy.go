@@ -0,0 +1,250 @@
+// Package policy admits chaos events according to policies written in Rego,
+// the policy language of the Open Policy Agent, which allows enforcing
+// organization-specific rules without forking.
+//
+// Policies decide on an Input and deny chaos events with messages, for
+// example:
+//
+//	package chaosmonkey
+//
+//	deny[msg] {
+//		input.strategy == "FillDisk"
+//		startswith(input.group, "db-")
+//		msg := sprintf("%s must not fill disks of databases", [input.requester])
+//	}
+//
+//	deny[msg] {
+//		input.state.instancesInService < 3
+//		msg := sprintf("group %s has too few instances in service", [input.group])
+//	}
+package policy
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+)
+
+// DefaultQuery is the query evaluated by Guard unless configured otherwise.
+// It yields the messages of the deny rules in package chaosmonkey.
+const DefaultQuery = "data.chaosmonkey.deny"
+
+// Input is the input document of policies, describing a planned chaos event.
+type Input struct {
+	// Name of the targeted auto scaling group
+	Group string `json:"group"`
+
+	// Chaos strategy
+	Strategy chaosmonkey.Strategy `json:"strategy"`
+
+	// AWS region of the auto scaling group
+	Region string `json:"region,omitempty"`
+
+	// Time when the chaos event is going to be triggered
+	Time time.Time `json:"time"`
+
+	// Who triggers the chaos event
+	Requester string `json:"requester,omitempty"`
+
+	// Live state of the auto scaling group, if Guard.State is set
+	State interface{} `json:"state,omitempty"`
+}
+
+// Guard is a chaosmonkey.Guard that evaluates a query against Rego policies
+// for every planned chaos event. If the query yields messages, e.g. of deny
+// rules, the chaos event is refused with them. A query yielding true refuses
+// the chaos event as well, while undefined queries and other results admit
+// it. Create a guard with NewGuard or LoadGuard.
+type Guard struct {
+	// Optional function returning the live state of the targeted auto
+	// scaling group, e.g. the result of aws.Client.AutoScalingGroup, which
+	// is passed to policies as input.state
+	State func(ctx context.Context, t chaosmonkey.Target) (interface{}, error)
+
+	// Maximum time to evaluate policies (10 seconds if zero)
+	Timeout time.Duration
+
+	query rego.PreparedEvalQuery
+}
+
+// NewGuard returns a guard evaluating the query (DefaultQuery if empty)
+// against the given policy modules, which map file names to Rego source
+// code.
+func NewGuard(ctx context.Context, query string, modules map[string]string) (*Guard, error) {
+	pq, err := prepare(ctx, query, modules, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Guard{query: pq}, nil
+}
+
+// NewSandboxedGuard is like NewGuard but restricts policies to deterministic
+// built-in functions, so that they can neither send requests, look up hosts,
+// nor inspect the environment. Use it for policies from less trusted sources,
+// e.g. those embedded in campaign files.
+func NewSandboxedGuard(ctx context.Context, query string, modules map[string]string) (*Guard, error) {
+	pq, err := prepare(ctx, query, modules, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Guard{query: pq}, nil
+}
+
+// prepare prepares the query (DefaultQuery if empty) against the given
+// policy modules, in a sandbox if requested.
+func prepare(ctx context.Context, query string, modules map[string]string, sandboxed bool) (rego.PreparedEvalQuery, error) {
+	if len(modules) == 0 {
+		return rego.PreparedEvalQuery{}, fmt.Errorf("no policies given")
+	}
+	if query == "" {
+		query = DefaultQuery
+	}
+	options := []func(*rego.Rego){rego.Query(query)}
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		options = append(options, rego.Module(name, modules[name]))
+	}
+	if sandboxed {
+		options = append(options, rego.Capabilities(sandbox()))
+	}
+	pq, err := rego.New(options...).PrepareForEval(ctx)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, fmt.Errorf("invalid policy: %s", err)
+	}
+	return pq, nil
+}
+
+// sandbox returns the capabilities of sandboxed policies, which lack all
+// non-deterministic built-in functions like http.send, net.lookup_ip_addr,
+// and opa.runtime.
+func sandbox() *ast.Capabilities {
+	caps := ast.CapabilitiesForThisVersion()
+	var builtins []*ast.Builtin
+	for _, b := range caps.Builtins {
+		if !b.Nondeterministic {
+			builtins = append(builtins, b)
+		}
+	}
+	caps.Builtins = builtins
+	caps.AllowNet = []string{}
+	return caps
+}
+
+// LoadGuard is like NewGuard but reads the policy modules from the given
+// files, or from all .rego files in the given directories.
+func LoadGuard(ctx context.Context, query string, paths ...string) (*Guard, error) {
+	modules := make(map[string]string)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		files := []string{path}
+		if info.IsDir() {
+			if files, err = filepath.Glob(filepath.Join(path, "*.rego")); err != nil {
+				return nil, err
+			}
+		}
+		for _, file := range files {
+			src, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			modules[file] = string(src)
+		}
+	}
+	return NewGuard(ctx, query, modules)
+}
+
+// Check evaluates the policies for the planned chaos event. It also refuses
+// the chaos event if the state of its group cannot be retrieved or the
+// evaluation fails.
+func (g *Guard) Check(t chaosmonkey.Target) error {
+	timeout := g.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	input := Input{
+		Group:     t.AutoScalingGroupName,
+		Strategy:  t.Strategy,
+		Region:    t.Region,
+		Time:      t.Time,
+		Requester: t.Requester,
+	}
+	if input.Strategy == "" {
+		input.Strategy = chaosmonkey.StrategyShutdownInstance
+	}
+	if g.State != nil {
+		state, err := g.State(ctx, t)
+		if err != nil {
+			return fmt.Errorf("failed to get state of group %s for policies: %s", t.AutoScalingGroupName, err)
+		}
+		input.State = state
+	}
+
+	messages, err := evaluate(ctx, g.query, input)
+	if err != nil {
+		return err
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("denied by policy: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// evaluate evaluates the query for the input and returns the messages it
+// yields.
+func evaluate(ctx context.Context, query rego.PreparedEvalQuery, input interface{}) ([]string, error) {
+	rs, err := query.Eval(ctx, rego.EvalInput(input))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate policies: %s", err)
+	}
+	var messages []string
+	for _, r := range rs {
+		for _, expr := range r.Expressions {
+			messages = append(messages, denials(expr.Value)...)
+		}
+	}
+	return messages, nil
+}
+
+// denials returns the messages of a query result, which is either a set of
+// messages, a single message, or a boolean.
+func denials(v interface{}) []string {
+	switch v := v.(type) {
+	case bool:
+		if v {
+			return []string{"chaos event not allowed"}
+		}
+	case string:
+		return []string{v}
+	case []interface{}:
+		var messages []string
+		for _, m := range v {
+			if s, ok := m.(string); ok {
+				messages = append(messages, s)
+			} else {
+				messages = append(messages, fmt.Sprint(m))
+			}
+		}
+		return messages
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package policy_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	chaosmonkey "github.com/FlyLevin/chaosmonkey/lib"
+	"github.com/FlyLevin/chaosmonkey/policy"
+)
+
+const testPolicy = `
+package chaosmonkey
+
+deny[msg] {
+	input.strategy == "FillDisk"
+	startswith(input.group, "db-")
+	msg := sprintf("%s must not fill disks of databases", [input.requester])
+}
+
+deny[msg] {
+	input.state.instancesInService < 3
+	msg := sprintf("group %s has too few instances in service", [input.group])
+}
+`
+
+func TestGuard(t *testing.T) {
+	guard, err := policy.NewGuard(context.Background(), "", map[string]string{"test.rego": testPolicy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard.State = func(ctx context.Context, t chaosmonkey.Target) (interface{}, error) {
+		size := 3
+		if t.AutoScalingGroupName == "search-api" {
+			size = 2
+		}
+		return map[string]int{"instancesInService": size}, nil
+	}
+
+	now := time.Date(2018, 4, 2, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		group    string
+		strategy chaosmonkey.Strategy
+		want     string
+	}{
+		{"db-orders", chaosmonkey.StrategyShutdownInstance, ""},
+		{"db-orders", chaosmonkey.StrategyFillDisk, "denied by policy: alice must not fill disks of databases"},
+		{"search-api", "", "denied by policy: group search-api has too few instances in service"},
+	} {
+		err := guard.Check(chaosmonkey.Target{
+			AutoScalingGroupName: tc.group,
+			Strategy:             tc.strategy,
+			Time:                 now,
+			Requester:            "alice",
+		})
+		var got string
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Errorf("%s against %s: got error %q, want %q", tc.strategy, tc.group, got, tc.want)
+		}
+	}
+}
+
+func TestNewGuardInvalid(t *testing.T) {
+	if _, err := policy.NewGuard(context.Background(), "", map[string]string{"test.rego": "package"}); err == nil {
+		t.Error("expected error for invalid policy")
+	}
+}
+
+func TestSandboxedGuard(t *testing.T) {
+	const exfiltrate = `
+package chaosmonkey
+
+deny[msg] {
+	resp := http.send({"method": "GET", "url": "http://169.254.169.254/latest/meta-data/"})
+	msg := resp.raw_body
+}
+`
+	if _, err := policy.NewSandboxedGuard(context.Background(), "", map[string]string{"plan.rego": exfiltrate}); err == nil {
+		t.Error("expected error for policy sending requests")
+	}
+	if _, err := policy.NewSandboxedGuard(context.Background(), "", map[string]string{"plan.rego": testPolicy}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	errorRate := 0.002
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"errorRate": %g}`, errorRate)
+	}))
+	defer ts.Close()
+
+	probe, err := policy.NewProbe(context.Background(), ts.URL, "", map[string]string{"probe.rego": `
+package chaosmonkey
+
+deny[msg] {
+	input.status != 200
+	msg := sprintf("status %d", [input.status])
+}
+
+deny[msg] {
+	input.body.errorRate > 0.01
+	msg := sprintf("error rate %v too high", [input.body.errorRate])
+}
+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := probe.CheckOutage(context.Background()); err != nil {
+		t.Errorf("unexpected outage: %s", err)
+	}
+	errorRate = 0.05
+	err = probe.CheckOutage(context.Background())
+	if want := fmt.Sprintf("probe %s failed: error rate 0.05 too high", ts.URL); err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+}
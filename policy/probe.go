@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// ProbeInput is the input document of probe policies, describing the
+// response to the probe's request.
+type ProbeInput struct {
+	// HTTP status code of the response
+	Status int `json:"status"`
+
+	// Body of the response, decoded if it is JSON and a string otherwise
+	Body interface{} `json:"body"`
+
+	// Time when the response was received
+	Time time.Time `json:"time"`
+}
+
+// Probe is a chaosmonkey.OutageChecker that requests a URL, e.g. of a
+// metrics or status API, and evaluates sandboxed Rego policies on the
+// response, for example:
+//
+//	package chaosmonkey
+//
+//	deny[msg] {
+//		input.body.errorRate > 0.01
+//		msg := sprintf("error rate is %.2f%%", [input.body.errorRate * 100])
+//	}
+//
+// If the query yields messages, an outage is reported with them. Policies
+// only see the response, which is the only request they can cause. Create a
+// probe with NewProbe.
+type Probe struct {
+	// URL to request with GET requests
+	URL string
+
+	// HTTP client to use (one with a timeout of 10 seconds if nil)
+	HTTPClient *http.Client
+
+	query rego.PreparedEvalQuery
+}
+
+// NewProbe returns a probe requesting the URL and evaluating the query
+// (DefaultQuery if empty) against the given policy modules in a sandbox, like
+// NewSandboxedGuard.
+func NewProbe(ctx context.Context, url, query string, modules map[string]string) (*Probe, error) {
+	pq, err := prepare(ctx, query, modules, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Probe{URL: url, query: pq}, nil
+}
+
+// CheckOutage requests the URL and evaluates the policies on the response. It
+// also reports an outage if the request or the evaluation fails.
+func (p *Probe) CheckOutage(ctx context.Context) error {
+	client := p.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequest("GET", p.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("probe %s failed: %s", p.URL, err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("probe %s failed: %s", p.URL, err)
+	}
+
+	input := ProbeInput{Status: resp.StatusCode, Time: time.Now().UTC()}
+	if err := json.Unmarshal(data, &input.Body); err != nil {
+		input.Body = string(data)
+	}
+	messages, err := evaluate(ctx, p.query, input)
+	if err != nil {
+		return err
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("probe %s failed: %s", p.URL, strings.Join(messages, "; "))
+	}
+	return nil
+}